@@ -140,6 +140,12 @@ func TestSerialization(t *testing.T) {
 				ID:               uuid.New(),
 			},
 		},
+		{
+			Type: QueryProtocolDiscoveryProgress, ID: "qpdp", Payload: &ProtocolDiscoveryProgress{
+				ID: uuid.New(), Probed: "COM3", Percent: 50,
+				Found: &DiscoveryEntry{ServiceKey: ServiceKey{ProtocolZWave, TransportSerial, "COM3"}, Description: "Some description"},
+			},
+		},
 		{
 			Type: QueryProtocolDiscoveryFinished, ID: "qpdf", Payload: &ProtocolDiscoveryResult{
 				ID: uuid.New(), Error: &ErrorInfo{ErrorDiscoveryBusy, "message", nil, nil},
@@ -180,7 +186,7 @@ func TestSerialization(t *testing.T) {
 
 		{Type: QueryServiceStatus, ID: "qss", Payload: &ServiceID{nil, "Some alias"}},
 		{
-			Type: QueryServiceStatusResult, ID: "qrss", Payload: &StatusReply{nil, true},
+			Type: QueryServiceStatusResult, ID: "qrss", Payload: &ServiceStatusResult{&StatusReply{nil, true}, "/dev/ttyACM1"},
 		},
 
 		{Type: QueryListServices, ID: "qls", Payload: &ListServices{
@@ -193,8 +199,8 @@ func TestSerialization(t *testing.T) {
 		{
 			Type: QueryListServicesResult, ID: "qrls", Payload: &ListServicesResult{
 				Services: []ListServicesEntry{
-					{&ServiceEntry{&ServiceKey{ProtocolZWave, TransportSerial, "COM3"}, RawParamValues{"p1": "v1", "p2": "v2"}, "Some alias"}, &StatusReply{nil, true}},
-					{&ServiceEntry{&ServiceKey{ProtocolZWave, TransportSerial, "COM1"}, nil, ""}, &StatusReply{nil, false}},
+					{&ServiceEntry{&ServiceKey{ProtocolZWave, TransportSerial, "COM3"}, RawParamValues{"p1": "v1", "p2": "v2"}, "Some alias"}, &StatusReply{nil, true}, "/dev/ttyACM0"},
+					{&ServiceEntry{&ServiceKey{ProtocolZWave, TransportSerial, "COM1"}, nil, ""}, &StatusReply{nil, false}, ""},
 				},
 			},
 		},
@@ -208,6 +214,59 @@ func TestSerialization(t *testing.T) {
 			},
 		},
 
+		{Type: QuerySendMulticast, ID: "qsm", Payload: &SendMulticast{
+			&ServiceID{nil, "Alias"}, []uint16{2, 3, 5}, false, true, []byte{0x25, 0x01, 0x00},
+		}},
+		{
+			Type: QuerySendMulticastResult, ID: "qrsm", Payload: &SendMulticastResult{
+				&StatusReply{nil, false},
+				[]*NodeSendResult{{2, true, "ok"}, {3, false, "noAck"}},
+			},
+		},
+
+		{Type: QueryServiceNodes, ID: "qsn", Payload: &ServiceID{nil, "Alias"}},
+		{
+			Type: QueryServiceNodesResult, ID: "qrsn", Payload: &ServiceNodesResult{
+				&StatusReply{nil, true},
+				[]*NodeInfo{
+					{1, false, 2, 1, 0, nil, nil},
+					{5, false, 4, 16, 1, []uint16{0x5e, 0x25, 0x86}, nil},
+					{257, true, 4, 7, 1, []uint16{0x80}, &NodeBattery{15, true, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}},
+				},
+			},
+		},
+
+		{Type: QueryServiceStatistics, ID: "qsst", Payload: &ServiceID{&ServiceKey{ProtocolZWave, TransportSerial, "COM1"}, ""}},
+		{
+			Type: QueryServiceStatisticsResult, ID: "qrsst", Payload: &ServiceStatisticsResult{
+				&StatusReply{nil, true},
+				&ServiceStatistics{
+					[]int8{-95, -97},
+					[]*NodeStatistics{
+						{5, 10, 1, 10, 32, 110, -70, []uint16{3}, []int8{-65, -70}, "ok", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)},
+					},
+				},
+			},
+		},
+
+		{Type: QueryReadRegisters, ID: "qrr", Payload: &ReadRegisters{
+			&ServiceID{nil, "meter"}, 1, RegisterTableHolding, 0x100, 2,
+		}},
+		{Type: QueryReadRegistersResult, ID: "qrrr", Payload: &ReadRegistersResult{&StatusReply{nil, true}, []uint16{0x1234, 5}}},
+		{Type: QueryWriteRegisters, ID: "qwr", Payload: &WriteRegisters{
+			&ServiceID{&ServiceKey{ProtocolModbus, TransportTCP, "10.0.0.5:502"}, ""}, 3, RegisterTableCoil, 10, []uint16{1, 0, 1},
+		}},
+		{Type: QueryWriteRegistersResult, ID: "qwrr", Payload: &StatusReply{nil, true}},
+		{Type: QueryDeviceCommand, ID: "qdc", Payload: &DeviceCommand{
+			&ServiceID{nil, "plm"}, "1A.2B.3C", DeviceCommandLevel, 128,
+		}},
+		{Type: QueryDeviceCommandResult, ID: "qdcr", Payload: &DeviceCommandResult{&StatusReply{nil, true}, 128}},
+		{Type: QueryDeviceLinks, ID: "qdl", Payload: &ServiceID{&ServiceKey{ProtocolInsteon, TransportSerial, "COM4"}, ""}},
+		{Type: QueryDeviceLinksResult, ID: "qdlr", Payload: &DeviceLinksResult{&StatusReply{nil, true}, []*DeviceLink{
+			{"1A.2B.3C", 1, true, [3]uint8{3, 28, 1}},
+			{"4D.5E.6F", 0, false, [3]uint8{255, 31, 0}},
+		}}},
+
 		{Type: QueryGetMessage, ID: "qgm", Payload: uuid.New()},
 		{
 			Type: QueryGetMessageResult, ID: "qrgm", Payload: &MessageEntry{
@@ -274,6 +333,18 @@ func TestSerialization(t *testing.T) {
 			},
 		},
 
+		{Type: QueryNodeStatistics, ID: "qns", Payload: &NodeStatisticsEntry{
+			&ServiceKey{ProtocolZWave, TransportSerial, "COM1"},
+			&NodeStatistics{7, 3, 0, 3, 20, 20, -55, nil, []int8{-55}, "ok", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)},
+		}},
+
+		{Type: QueryLowBattery, ID: "qlb", Payload: &LowBattery{
+			&ServiceKey{ProtocolZWave, TransportSerial, "COM1"}, 257, 15, LowBatteryLevel, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+		}},
+		{Type: QueryServiceGaveUp, ID: "qsgu", Payload: &ServiceGaveUp{
+			&ServiceKey{ProtocolZWave, TransportSerial, "COM1"}, 10, "no such file or directory",
+		}},
+
 		{Type: QueryEventSubscribe, ID: "qes", Payload: Subscription{
 			Subscribe: true, AllEvents: true, Events: []SubscriptionEvent{EventNewMessage, EventUpdateMessageState},
 		}},
@@ -7,6 +7,8 @@ type Config struct {
 	HTTPServer       *HTTPServerConfig `yaml:"httpServer,omitempty" json:"httpServer,omitempty"`
 	MessageLog       *MessageLogConfig `yaml:"messageLog,omitempty" json:"messageLog,omitempty"`
 	Services         []ServiceConfig   `yaml:"services,omitempty" json:"services,omitempty"`
+	Bridges          []BridgeConfig    `yaml:"bridges,omitempty" json:"bridges,omitempty"`
+	Plugins          []PluginConfig    `yaml:"plugins,omitempty" json:"plugins,omitempty"`
 }
 
 // LogConfig defines configuration entries for the serivce logging
@@ -37,8 +39,28 @@ type HTTPServerConfig struct {
 // ServiceConfig defines configuration of active services
 type ServiceConfig struct {
 	Alias     string            `yaml:"alias,omitempty" json:"alias,omitempty"`
-	Protocol  string            `yaml:"protocol" json:"protocol"`
-	Transport string            `yaml:"transport" json:"transport"`
+	Protocol  string            `yaml:"protocol" json:"protocol"`   // the protocol identifier, the protocol name is accepted as well
+	Transport string            `yaml:"transport" json:"transport"` // the transport identifier, the transport name is accepted as well
 	Entry     string            `yaml:"entry,omitempty" json:"entry,omitempty"`
 	Params    map[string]string `yaml:"params,omitempty" json:"params,omitempty"`
 }
+
+// BridgeConfig defines the bridge which shares the local serial port, or the raw byte stream of the service, over TCP
+type BridgeConfig struct {
+	Listen   string            `yaml:"listen" json:"listen"`                         // host:port to accept the connection on
+	Port     string            `yaml:"port,omitempty" json:"port,omitempty"`         // the local serial port to share
+	Params   map[string]string `yaml:"params,omitempty" json:"params,omitempty"`     // the serial port parameters
+	Service  string            `yaml:"service,omitempty" json:"service,omitempty"`   // the alias of the service to share, the services of the plugin protocols have no byte stream and are rejected
+	Token    string            `yaml:"token,omitempty" json:"token,omitempty"`       // the token the client must send first, followed by the new line, masked in the API output
+	HoldTime DurationType      `yaml:"holdTime,omitempty" json:"holdTime,omitempty"` // the time the service's writes are held after the client's write
+}
+
+// PluginConfig defines the external executable which implements the protocol, see services/plugin
+type PluginConfig struct {
+	ID              ProtocolIdentifier `yaml:"id" json:"id"`                                               // the protocol identifier, like "acme-bus"
+	Path            string             `yaml:"path" json:"path"`                                           // the executable path
+	Args            []string           `yaml:"args,omitempty" json:"args,omitempty"`                       // the executable arguments
+	Env             []string           `yaml:"env,omitempty" json:"env,omitempty"`                         // the additional environment variables, name=value
+	StartTimeout    DurationType       `yaml:"startTimeout,omitempty" json:"startTimeout,omitempty"`       // the time to wait for the plugin description
+	RestartInterval DurationType       `yaml:"restartInterval,omitempty" json:"restartInterval,omitempty"` // the initial interval between restarts of the exited plugin
+}
@@ -27,6 +27,8 @@ const (
 	ErrorServiceBadPayload
 	ErrorServiceSendBusy
 	ErrorOtherError
+	ErrorServiceBadNode
+	ErrorServiceNotSupported
 )
 
 // ErrorInfo - error
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -38,10 +40,17 @@ type ListServices struct {
 	Aliases    []string              `json:"aliases,omitempty"`
 }
 
+// ServiceStatusResult - get service status query result
+type ServiceStatusResult struct {
+	*StatusReply
+	ResolvedEntry string `json:"resolvedEntry,omitempty"`
+}
+
 // ListServicesEntry - service information for services list result
 type ListServicesEntry struct {
 	*ServiceEntry
 	*StatusReply
+	ResolvedEntry string `json:"resolvedEntry,omitempty"`
 }
 
 // ListServicesResult - get list of services query result
@@ -61,6 +70,177 @@ type SendToServiceResult struct {
 	*Message
 }
 
+// SendMulticast - send message to several nodes of the service request payload.
+// Unlike SendToService the payload is the application data (command class, command, and parameters),
+// the service builds the protocol frames itself
+type SendMulticast struct {
+	*ServiceID
+	Nodes     []uint16 `json:"nodes,omitempty"`
+	Broadcast bool     `json:"broadcast,omitempty"`
+	FollowUp  bool     `json:"followUp,omitempty"`
+	Payload   []byte   `json:"payload,omitempty"`
+}
+
+// NodeSendResult - the outcome of sending the message to the single node
+type NodeSendResult struct {
+	Node    uint16 `json:"node"`
+	Success bool   `json:"success"`
+	Status  string `json:"status,omitempty"`
+}
+
+// SendMulticastResult - send message to several nodes of the service result payload
+type SendMulticastResult struct {
+	*StatusReply
+	Nodes []*NodeSendResult `json:"nodes,omitempty"`
+}
+
+// NodeInfo - the node of the service's network
+type NodeInfo struct {
+	ID             uint16       `json:"id"`
+	LongRange      bool         `json:"longRange,omitempty"`
+	Basic          uint8        `json:"basic,omitempty"`
+	Generic        uint8        `json:"generic,omitempty"`
+	Specific       uint8        `json:"specific,omitempty"`
+	CommandClasses []uint16     `json:"commandClasses,omitempty"`
+	Battery        *NodeBattery `json:"battery,omitempty"`
+}
+
+// NodeBattery - the last reported battery state of the node
+type NodeBattery struct {
+	Level   uint8     `json:"level"` // battery level, percents
+	Low     bool      `json:"low,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// LowBattery reasons
+const (
+	LowBatteryLevel   = "level"   // the battery level is below the threshold
+	LowBatteryTimeout = "timeout" // the node stopped reporting its battery level
+)
+
+// LowBattery - low battery notification payload
+type LowBattery struct {
+	*ServiceKey
+	Node    uint16    `json:"node"`
+	Level   uint8     `json:"level"`
+	Reason  string    `json:"reason"`
+	Updated time.Time `json:"updated"` // the time of the last battery report
+}
+
+// ServiceGaveUp - the service stopped the attempts to open the transport notification payload
+type ServiceGaveUp struct {
+	*ServiceKey
+	Attempts uint32 `json:"attempts"`
+	Error    string `json:"error,omitempty"` // the error of the last attempt
+}
+
+// ServiceNodesResult - get list of the service's network nodes result payload
+type ServiceNodesResult struct {
+	*StatusReply
+	Nodes []*NodeInfo `json:"nodes,omitempty"`
+}
+
+// NodeStatistics - the radio statistics of the node of the service's network
+type NodeStatistics struct {
+	Node       uint16    `json:"node"`
+	Sent       uint32    `json:"sent"`
+	Failed     uint32    `json:"failed"`
+	Recent     uint32    `json:"recent"`               // the number of recent transmissions the averages are calculated on
+	TxTime     uint32    `json:"txTime"`               // average transmit time of recent transmissions, milliseconds
+	TxTimeMax  uint32    `json:"txTimeMax"`            // maximal transmit time of recent transmissions, milliseconds
+	RSSI       int8      `json:"rssi,omitempty"`       // average acknowledgement RSSI of recent transmissions, dBm
+	Route      []uint16  `json:"route,omitempty"`      // the repeaters used by the last transmission
+	RouteRSSI  []int8    `json:"routeRSSI,omitempty"`  // RSSI of each hop of the last transmission, dBm
+	LastStatus string    `json:"lastStatus,omitempty"` // the status of the last transmission
+	Updated    time.Time `json:"updated"`
+}
+
+// ServiceStatistics - the radio statistics of the service
+type ServiceStatistics struct {
+	BackgroundRSSI []int8            `json:"backgroundRSSI,omitempty"` // background RSSI per channel, dBm
+	Nodes          []*NodeStatistics `json:"nodes,omitempty"`
+}
+
+// ServiceStatisticsResult - get radio statistics of the service result payload
+type ServiceStatisticsResult struct {
+	*StatusReply
+	*ServiceStatistics
+}
+
+// Register tables of the device (Modbus)
+const (
+	RegisterTableCoil          = "coil"          // read-write single bits
+	RegisterTableDiscreteInput = "discreteInput" // read-only single bits
+	RegisterTableHolding       = "holding"       // read-write 16-bit registers
+	RegisterTableInput         = "input"         // read-only 16-bit registers
+)
+
+// ReadRegisters - read the coils or registers of the service's device request payload
+type ReadRegisters struct {
+	*ServiceID
+	Unit    uint8  `json:"unit"` // the unit (slave) address of the device
+	Table   string `json:"table"`
+	Address uint16 `json:"address"`
+	Count   uint16 `json:"count"`
+}
+
+// ReadRegistersResult - read the coils or registers of the service's device result payload, the bits are returned as 0 or 1 values
+type ReadRegistersResult struct {
+	*StatusReply
+	Values []uint16 `json:"values,omitempty"`
+}
+
+// WriteRegisters - write the coils or holding registers of the service's device request payload, the non-zero values turn the coils on
+type WriteRegisters struct {
+	*ServiceID
+	Unit    uint8    `json:"unit"` // the unit (slave) address of the device
+	Table   string   `json:"table"`
+	Address uint16   `json:"address"`
+	Values  []uint16 `json:"values"`
+}
+
+// Device commands (Insteon)
+const (
+	DeviceCommandOn     = "on"     // turn the device on, to the level provided or to the full level if the level is 0
+	DeviceCommandOff    = "off"    // turn the device off
+	DeviceCommandLevel  = "level"  // set the level of the device, 0 turns the device off
+	DeviceCommandStatus = "status" // request the current level of the device
+)
+
+// DeviceCommand - send the typed command to the service's device request payload
+type DeviceCommand struct {
+	*ServiceID
+	Device  string `json:"device"` // the device address, like 1A.2B.3C
+	Command string `json:"command"`
+	Level   uint8  `json:"level,omitempty"`
+}
+
+// DeviceCommandResult - send the typed command to the service's device result payload
+type DeviceCommandResult struct {
+	*StatusReply
+	Level uint8 `json:"level"` // the device level reported in the command acknowledgement
+}
+
+// DeviceLink - the link record of the service's controller (Insteon ALL-Link database record)
+type DeviceLink struct {
+	Device     string   `json:"device"`
+	Group      uint8    `json:"group"`
+	Controller bool     `json:"controller,omitempty"` // the controller of the service controls the device, otherwise it responds to the device
+	Data       [3]uint8 `json:"data"`                 // the link data, like on level and ramp rate of the responder
+}
+
+// DeviceLinksResult - the link records of the service result payload
+type DeviceLinksResult struct {
+	*StatusReply
+	Links []*DeviceLink `json:"links,omitempty"`
+}
+
+// NodeStatisticsEntry - the radio statistics of the node along with the service key
+type NodeStatisticsEntry struct {
+	*ServiceKey
+	*NodeStatistics
+}
+
 // ProtocolDiscover - discover query request payload
 type ProtocolDiscover struct {
 	Protocol  ProtocolIdentifier  `json:"protocol"`
@@ -94,6 +274,15 @@ type ProtocolDiscoveryResult struct {
 	Error   *ErrorInfo        `json:"error,omitempty"`
 }
 
+// ProtocolDiscoveryProgress - the progress of running discovery query notification payload,
+// it is sent when the entry (port, host) is probed or when the service entry is found
+type ProtocolDiscoveryProgress struct {
+	ID      uuid.UUID       `json:"id"`
+	Probed  string          `json:"probed,omitempty"`
+	Found   *DiscoveryEntry `json:"found,omitempty"`
+	Percent uint8           `json:"percent"`
+}
+
 // DiscoveryRequest contains information about started discovery query
 type ProtocolDiscoveryStarted struct {
 	ProtocolDiscover
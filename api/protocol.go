@@ -1,27 +1,63 @@
 package api
 
-// ProtocolIdentifier type
-type ProtocolIdentifier uint8
+// ProtocolIdentifier is the stable string identifier of the protocol, like "zwave".
+// The identifiers are registered dynamically by the protocols implementations, see defs.Protocols
+type ProtocolIdentifier string
 
-// Supported protocols identifiers
+// Built-in protocols identifiers
 const (
-	ProtocolZWave = ProtocolIdentifier(iota + 1)
+	ProtocolZWave   ProtocolIdentifier = "zwave"
+	ProtocolModbus  ProtocolIdentifier = "modbus"
+	ProtocolZigbee  ProtocolIdentifier = "zigbee"
+	ProtocolInsteon ProtocolIdentifier = "insteon"
+	ProtocolRaw     ProtocolIdentifier = "raw"
+	ProtocolKNX     ProtocolIdentifier = "knx"
+	ProtocolMQTT    ProtocolIdentifier = "mqtt"
 )
 
-// IsValid verifies if protocol identifer is valid
+// IsValid verifies if protocol identifer is well-formed, it does not mean the protocol is registered
 func (protocol ProtocolIdentifier) IsValid() bool {
-	return protocol == ProtocolZWave
+	return isValidIdentifier(string(protocol))
 }
 
-// TransportIdentifier type
-type TransportIdentifier uint8
+// TransportIdentifier is the stable string identifier of the transport, like "serial".
+// The identifiers are registered dynamically by the transports implementations, see defs.Transports
+type TransportIdentifier string
 
-// Supported transport identifiers
+// Built-in transport identifiers
 const (
-	TransportSerial = TransportIdentifier(iota + 1)
+	TransportSerial    TransportIdentifier = "serial"
+	TransportTCP       TransportIdentifier = "tcp"
+	TransportRFC2217   TransportIdentifier = "rfc2217"
+	TransportSimulator TransportIdentifier = "simulator"
+	TransportReplay    TransportIdentifier = "replay"
+	TransportUnix      TransportIdentifier = "unix"
+	TransportPTY       TransportIdentifier = "pty"
+	TransportUDP       TransportIdentifier = "udp"
+	TransportMQTT      TransportIdentifier = "mqtt"
 )
 
-// IsValid verifies if protocol identifer is valid
+// TransportMock is the identifier of in-memory transport used by tests, it is registered by the tests only (see services/mock)
+const TransportMock TransportIdentifier = "mock"
+
+// IsValid verifies if transport identifer is well-formed, it does not mean the transport is registered
 func (transport TransportIdentifier) IsValid() bool {
-	return transport == TransportSerial
+	return isValidIdentifier(string(transport))
+}
+
+// MaxIdentifierLength is the maximal length of protocol or transport identifier
+const MaxIdentifierLength = 64
+
+// isValidIdentifier checks the identifier is not empty and consists of lower case latin letters, digits, '.', '-', and '_'
+func isValidIdentifier(id string) bool {
+	if id == "" || len(id) > MaxIdentifierLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
 }
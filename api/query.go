@@ -31,6 +31,7 @@ const (
 	QueryProtocolDiscoveryResult
 	QueryProtocolDiscoveryStarted
 	QueryProtocolDiscoveryFinished
+	QueryProtocolDiscoveryProgress
 	QueryAddService
 	QueryAddServiceResult
 	QueryRemoveService
@@ -43,6 +44,20 @@ const (
 	QueryListServicesResult
 	QuerySendToService
 	QuerySendToServiceResult
+	QuerySendMulticast
+	QuerySendMulticastResult
+	QueryServiceNodes
+	QueryServiceNodesResult
+	QueryServiceStatistics
+	QueryServiceStatisticsResult
+	QueryReadRegisters
+	QueryReadRegistersResult
+	QueryWriteRegisters
+	QueryWriteRegistersResult
+	QueryDeviceCommand
+	QueryDeviceCommandResult
+	QueryDeviceLinks
+	QueryDeviceLinksResult
 	QueryGetMessage
 	QueryGetMessageResult
 	QueryListMessages
@@ -50,6 +65,9 @@ const (
 	QueryNewMessage
 	QueryDropMessage
 	QueryUpdateMessageState
+	QueryNodeStatistics
+	QueryLowBattery
+	QueryServiceGaveUp
 	QueryEventSubscribe
 	QueryEventSubscribeResult
 )
@@ -63,15 +81,24 @@ var queryTypeMap = map[string]QueryType{
 	"discover": QueryProtocolDiscover, "discoverResult": QueryProtocolDiscoverResult,
 	"discovery": QueryProtocolDiscovery, "discoveryResult": QueryProtocolDiscoveryResult,
 	"discoveryStarted": QueryProtocolDiscoveryStarted, "discoveryFinished": QueryProtocolDiscoveryFinished,
-	"addService": QueryAddService, "addServiceResult": QueryAddServiceResult,
+	"discoveryProgress": QueryProtocolDiscoveryProgress,
+	"addService":        QueryAddService, "addServiceResult": QueryAddServiceResult,
 	"removeService": QueryRemoveService, "removeServiceResult": QueryRemoveServiceResult,
 	"changeServiceAlias": QueryChangeServiceAlias, "changeServiceAliasResult": QueryChangeServiceAliasResult,
 	"serviceStatus": QueryServiceStatus, "serviceStatusResult": QueryServiceStatusResult,
 	"listServices": QueryListServices, "listServicesResult": QueryListServicesResult,
 	"sendTo": QuerySendToService, "sendToResult": QuerySendToServiceResult,
+	"sendMulticast": QuerySendMulticast, "sendMulticastResult": QuerySendMulticastResult,
+	"serviceNodes": QueryServiceNodes, "serviceNodesResult": QueryServiceNodesResult,
+	"serviceStatistics": QueryServiceStatistics, "serviceStatisticsResult": QueryServiceStatisticsResult,
+	"readRegisters": QueryReadRegisters, "readRegistersResult": QueryReadRegistersResult,
+	"writeRegisters": QueryWriteRegisters, "writeRegistersResult": QueryWriteRegistersResult,
+	"deviceCommand": QueryDeviceCommand, "deviceCommandResult": QueryDeviceCommandResult,
+	"deviceLinks": QueryDeviceLinks, "deviceLinksResult": QueryDeviceLinksResult,
 	"getMessage": QueryGetMessage, "getMessageResult": QueryGetMessageResult,
 	"messagesList": QueryListMessages, "messagesListResult": QueryListMessagesResult,
 	"newMessage": QueryNewMessage, "dropMessage": QueryDropMessage, "updateMessageState": QueryUpdateMessageState,
+	"nodeStatistics": QueryNodeStatistics, "lowBattery": QueryLowBattery, "serviceGaveUp": QueryServiceGaveUp,
 	"eventSubscribe": QueryEventSubscribe, "eventSubscribeResult": QueryEventSubscribeResult,
 }
 var queryNameMap map[QueryType]string
@@ -203,19 +230,31 @@ func (c *Query) unmarshalPayload(data []byte) error {
 			return err
 		}
 		c.Payload = &p
+	case QueryProtocolDiscoveryProgress:
+		var p ProtocolDiscoveryProgress
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
 	case QueryAddService:
 		var p ServiceEntry
 		if err := json.Unmarshal(data, &p); err != nil {
 			return err
 		}
 		c.Payload = &p
-	case QueryAddServiceResult, QueryRemoveServiceResult, QueryChangeServiceAliasResult, QueryServiceStatusResult:
+	case QueryAddServiceResult, QueryRemoveServiceResult, QueryChangeServiceAliasResult, QueryWriteRegistersResult:
 		var p StatusReply
 		if err := json.Unmarshal(data, &p); err != nil {
 			return err
 		}
 		c.Payload = &p
-	case QueryRemoveService, QueryServiceStatus:
+	case QueryServiceStatusResult:
+		var p ServiceStatusResult
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryRemoveService, QueryServiceStatus, QueryServiceNodes, QueryServiceStatistics, QueryDeviceLinks:
 		var p ServiceID
 		if err := json.Unmarshal(data, &p); err != nil {
 			return err
@@ -255,6 +294,66 @@ func (c *Query) unmarshalPayload(data []byte) error {
 			return err
 		}
 		c.Payload = &p
+	case QuerySendMulticast:
+		var p SendMulticast
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QuerySendMulticastResult:
+		var p SendMulticastResult
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryServiceNodesResult:
+		var p ServiceNodesResult
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryServiceStatisticsResult:
+		var p ServiceStatisticsResult
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryReadRegisters:
+		var p ReadRegisters
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryReadRegistersResult:
+		var p ReadRegistersResult
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryWriteRegisters:
+		var p WriteRegisters
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryDeviceCommand:
+		var p DeviceCommand
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryDeviceCommandResult:
+		var p DeviceCommandResult
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryDeviceLinksResult:
+		var p DeviceLinksResult
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
 	case QueryGetMessage:
 		var p uuid.UUID
 		if err := json.Unmarshal(data, &p); err != nil {
@@ -285,6 +384,24 @@ func (c *Query) unmarshalPayload(data []byte) error {
 			return err
 		}
 		c.Payload = &p
+	case QueryNodeStatistics:
+		var p NodeStatisticsEntry
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryLowBattery:
+		var p LowBattery
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
+	case QueryServiceGaveUp:
+		var p ServiceGaveUp
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		c.Payload = &p
 	case QueryEventSubscribe:
 		var p Subscription
 		if err := json.Unmarshal(data, &p); err != nil {
@@ -15,6 +15,10 @@ const (
 	EventNewMessage
 	EventDropMessage
 	EventUpdateMessageState
+	EventNodeStatistics
+	EventLowBattery
+	EventServiceGaveUp
+	EventDiscoveryProgress
 )
 
 var subscriptionEventTypeMap = map[string]SubscriptionEvent{
@@ -23,6 +27,10 @@ var subscriptionEventTypeMap = map[string]SubscriptionEvent{
 	"newMessage":         EventNewMessage,
 	"dropMessage":        EventDropMessage,
 	"updateMessageState": EventUpdateMessageState,
+	"nodeStatistics":     EventNodeStatistics,
+	"lowBattery":         EventLowBattery,
+	"serviceGaveUp":      EventServiceGaveUp,
+	"discoveryProgress":  EventDiscoveryProgress,
 }
 var subscriptionEventNameMap map[SubscriptionEvent]string
 
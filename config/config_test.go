@@ -44,6 +44,15 @@ services:
       params:
         param1: value1
         param2: value2
+bridges:
+    - listen: :4001
+      port: /dev/ttyACM0
+      params:
+        baudRate: "115200"
+    - listen: 127.0.0.1:4002
+      service: homezw
+      token: secret
+      holdTime: 2 s
 `
 
 	var config api.Config
@@ -32,8 +32,12 @@ const (
 const (
 	ParamFlagConst ParamFlags = 1 << iota
 	ParamFlagRequired
+	ParamFlagSecret // the value is masked in the services list and in the configuration returned by API, like the password
 )
 
+// SecretMask replaces the value of the secret parameter
+const SecretMask = "********"
+
 func (pt ParamType) String() string {
 	switch pt {
 	case ParamTypeInt8:
@@ -60,6 +64,16 @@ func (pt ParamType) String() string {
 	return api.ParamTypeString
 }
 
+// ParamTypeByName resolves the name of parameter type, like api.ParamTypeUint32, into the type
+func ParamTypeByName(name string) (ParamType, bool) {
+	for pt := ParamTypeInt8; pt <= ParamTypeEnum; pt++ {
+		if pt.String() == name {
+			return pt, true
+		}
+	}
+	return ParamTypeString, false
+}
+
 // ParamInfo parameter description
 type ParamInfo struct {
 	Description  string
@@ -205,6 +219,20 @@ func (p Params) ParseValues(values api.RawParamValues) (api.ParamValues, error)
 	return rv, nil
 }
 
+// Mask returns copy of provided values where the non-empty values of the secret parameters are replaced by SecretMask
+func (p Params) Mask(values api.RawParamValues) (result api.RawParamValues) {
+	if len(values) > 0 {
+		result = make(api.RawParamValues)
+		for name, value := range values {
+			if param, ok := p[name]; ok && param.Flags&ParamFlagSecret != 0 && value != "" {
+				value = SecretMask
+			}
+			result[name] = value
+		}
+	}
+	return
+}
+
 // Merge returns copy of combined parameters with subordinate parameters
 func (p Params) Merge(subp Params) (result Params) {
 	result = make(Params)
@@ -3,7 +3,10 @@ package defs
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"testing"
+
+	"github.com/stas-makutin/howeve/api"
 )
 
 func TestParamsParse(t *testing.T) {
@@ -64,3 +67,24 @@ func TestParamsParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParamsMask(t *testing.T) {
+	params := Params{
+		"user":     &ParamInfo{Type: ParamTypeString},
+		"password": &ParamInfo{Type: ParamTypeString, Flags: ParamFlagSecret},
+		"token":    &ParamInfo{Type: ParamTypeString, Flags: ParamFlagSecret},
+	}
+	values := api.RawParamValues{"user": "admin", "password": "secret", "token": "", "unknown": "value"}
+
+	masked := params.Mask(values)
+	expected := api.RawParamValues{"user": "admin", "password": SecretMask, "token": "", "unknown": "value"}
+	if !reflect.DeepEqual(masked, expected) {
+		t.Errorf("Unexpected masked values %v, expected %v", masked, expected)
+	}
+	if values["password"] != "secret" {
+		t.Error("The masked values must be the copy")
+	}
+	if params.Mask(nil) != nil {
+		t.Error("The empty values must stay empty")
+	}
+}
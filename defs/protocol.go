@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/stas-makutin/howeve/api"
 )
@@ -14,6 +16,83 @@ type ServiceFunc func(entry string, params api.ParamValues) (Service, error)
 // DiscoveryFunc is a method which returns discovered service entries or error
 type DiscoveryFunc func(ctx context.Context, params api.ParamValues) ([]*api.DiscoveryEntry, error)
 
+// DiscoveryProgress receives the progress of running discovery query
+type DiscoveryProgress interface {
+	// Probed is called when the entry (port, host) is probed, done of total entries are probed so far
+	Probed(entry string, done, total int)
+	// Found is called when the service entry is found, the entry must be returned by the discovery function as well
+	Found(entry *api.DiscoveryEntry)
+}
+
+// discoveryProgressKey is the context key of the discovery progress receiver
+type discoveryProgressKey struct{}
+
+// WithDiscoveryProgress returns the context which carries the discovery progress receiver
+func WithDiscoveryProgress(ctx context.Context, progress DiscoveryProgress) context.Context {
+	return context.WithValue(ctx, discoveryProgressKey{}, progress)
+}
+
+// DiscoveryProbed reports the probed entry to the discovery progress receiver of the context, if any
+func DiscoveryProbed(ctx context.Context, entry string, done, total int) {
+	if progress, ok := ctx.Value(discoveryProgressKey{}).(DiscoveryProgress); ok {
+		progress.Probed(entry, done, total)
+	}
+}
+
+// DiscoveryFound reports the found service entry to the discovery progress receiver of the context, if any
+func DiscoveryFound(ctx context.Context, entry *api.DiscoveryEntry) {
+	if progress, ok := ctx.Value(discoveryProgressKey{}).(DiscoveryProgress); ok {
+		progress.Found(entry)
+	}
+}
+
+// DiscoverConcurrently probes the entries by up to provided number of workers, the probe returns the found service entry or nil.
+// The progress is reported to the discovery progress receiver of the context, the probing stops when the context is done.
+// Returns the found service entries in the order of the probed entries
+func DiscoverConcurrently(ctx context.Context, entries []string, workers int, probe func(i int) *api.DiscoveryEntry) []*api.DiscoveryEntry {
+	results := make([]*api.DiscoveryEntry, len(entries))
+	var wg sync.WaitGroup
+	var done int32
+	next := make(chan int)
+	for w := 0; w < workers && w < len(entries); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if ctx.Err() != nil {
+					continue
+				}
+				if results[i] = probe(i); results[i] != nil {
+					DiscoveryFound(ctx, results[i])
+				}
+				DiscoveryProbed(ctx, entries[i], int(atomic.AddInt32(&done, 1)), len(entries))
+			}
+		}()
+	}
+Loop:
+	for i := range entries {
+		select {
+		case <-ctx.Done():
+			break Loop
+		case next <- i:
+		}
+	}
+	close(next)
+	wg.Wait()
+	return compactEntries(results)
+}
+
+// compactEntries returns the found entries, skipping the entries which are not found
+func compactEntries(results []*api.DiscoveryEntry) []*api.DiscoveryEntry {
+	var rv []*api.DiscoveryEntry
+	for _, r := range results {
+		if r != nil {
+			rv = append(rv, r)
+		}
+	}
+	return rv
+}
+
 // ProtocolTransportOptions defines transport options specific for the protocol
 type ProtocolTransportOptions struct {
 	ServiceFunc     // required
@@ -28,25 +107,56 @@ type ProtocolInfo struct {
 	Transports map[api.TransportIdentifier]*ProtocolTransportOptions
 }
 
-// Protocols contains protocols definitions (defined in service module)
+// Protocols contains protocols definitions (defined in service module), once the services are started
+// it must be accessed through the functions below since the plugins are registered at runtime
 var Protocols map[api.ProtocolIdentifier]*ProtocolInfo
 
+var protocolsLock sync.RWMutex
+
+// LookupProtocol returns the protocol definition for provided identifier
+func LookupProtocol(p api.ProtocolIdentifier) (*ProtocolInfo, bool) {
+	protocolsLock.RLock()
+	defer protocolsLock.RUnlock()
+	pi, ok := Protocols[p]
+	return pi, ok
+}
+
+// ListProtocols returns the copy of the protocols definitions
+func ListProtocols() map[api.ProtocolIdentifier]*ProtocolInfo {
+	protocolsLock.RLock()
+	defer protocolsLock.RUnlock()
+	protocols := make(map[api.ProtocolIdentifier]*ProtocolInfo, len(Protocols))
+	for id, pi := range Protocols {
+		protocols[id] = pi
+	}
+	return protocols
+}
+
 // ProtocolName return name of the transport for provided identifier
 func ProtocolName(p api.ProtocolIdentifier) string {
-	if pi, ok := Protocols[p]; ok {
+	if pi, ok := LookupProtocol(p); ok {
 		return pi.Name
 	}
 	return ""
 }
 
-// ProtocolByName resolves protocol name into identifier
+// ProtocolByName resolves protocol identifier or name into identifier
 func ProtocolByName(name string) (api.ProtocolIdentifier, bool) {
+	protocolsLock.RLock()
+	defer protocolsLock.RUnlock()
+	return protocolByName(name)
+}
+
+func protocolByName(name string) (api.ProtocolIdentifier, bool) {
+	if _, ok := Protocols[api.ProtocolIdentifier(name)]; ok {
+		return api.ProtocolIdentifier(name), true
+	}
 	for id, pi := range Protocols {
 		if strings.EqualFold(name, pi.Name) {
 			return id, true
 		}
 	}
-	return 0, false
+	return "", false
 }
 
 // errors
@@ -57,11 +167,39 @@ var (
 	ErrTransportNotSupported error = errors.New("the transport is not supported")
 	// ErrNoProtocolTransport is the error in case if provided transport is not supported for given protocol
 	ErrNoProtocolTransport error = errors.New("the transport is not supported for the protocol")
+	// ErrProtocolExists is the error in case if the protocol with the same identifier or name is registered already
+	ErrProtocolExists error = errors.New("the protocol already exists")
+	// ErrBadIdentifier is the error in case if the identifier is not well-formed
+	ErrBadIdentifier error = errors.New("the identifier is not valid")
 )
 
+// RegisterProtocol adds the protocol definition
+func RegisterProtocol(id api.ProtocolIdentifier, pi *ProtocolInfo) error {
+	if !id.IsValid() {
+		return ErrBadIdentifier
+	}
+	protocolsLock.Lock()
+	defer protocolsLock.Unlock()
+	if _, ok := Protocols[id]; ok {
+		return ErrProtocolExists
+	}
+	if _, ok := protocolByName(pi.Name); ok {
+		return ErrProtocolExists
+	}
+	Protocols[id] = pi
+	return nil
+}
+
+// UnregisterProtocol removes the protocol definition
+func UnregisterProtocol(id api.ProtocolIdentifier) {
+	protocolsLock.Lock()
+	defer protocolsLock.Unlock()
+	delete(Protocols, id)
+}
+
 // ResolveProtocolAndTransport resolves protocol-transport pair
 func ResolveProtocolAndTransport(p api.ProtocolIdentifier, t api.TransportIdentifier) (*ProtocolTransportOptions, *TransportInfo, error) {
-	pi := Protocols[p]
+	pi, _ := LookupProtocol(p)
 	if pi == nil {
 		return nil, nil, ErrProtocolNotSupported
 	}
@@ -0,0 +1,59 @@
+package defs
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+)
+
+func TestDiscoverConcurrently(t *testing.T) {
+	entries := make([]string, 20)
+	for i := range entries {
+		entries[i] = strconv.Itoa(i)
+	}
+
+	var running, maxRunning int32
+	found := DiscoverConcurrently(context.Background(), entries, 3, func(i int) *api.DiscoveryEntry {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 5)
+		atomic.AddInt32(&running, -1)
+		if i%2 != 0 {
+			return nil
+		}
+		return &api.DiscoveryEntry{ServiceKey: api.ServiceKey{Entry: entries[i]}}
+	})
+	if maxRunning > 3 {
+		t.Errorf("%d probes were running at the same time, expected no more than 3", maxRunning)
+	}
+	if len(found) != len(entries)/2 {
+		t.Fatalf("unexpected number of found entries %d", len(found))
+	}
+	for i, e := range found {
+		if e.Entry != entries[i*2] {
+			t.Errorf("found entry %d is '%s', expected '%s'", i, e.Entry, entries[i*2])
+		}
+	}
+
+	// no probes after the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	var probed int32
+	DiscoverConcurrently(ctx, entries, 2, func(i int) *api.DiscoveryEntry {
+		if atomic.AddInt32(&probed, 1) == 1 {
+			cancel()
+		}
+		return nil
+	})
+	if probed > 2 {
+		t.Errorf("%d entries were probed after the context is done", probed-1)
+	}
+}
@@ -1,7 +1,9 @@
 package defs
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stas-makutin/howeve/api"
@@ -21,6 +23,46 @@ type Service interface {
 	Send(payload []byte) (*api.Message, error)
 }
 
+// MulticastSender is the optional interface of the service which is able to send the payload to several nodes at once
+type MulticastSender interface {
+	SendMulticast(ctx context.Context, nodes []uint16, broadcast bool, followUp bool, payload []byte) ([]*api.NodeSendResult, error)
+}
+
+// NodeLister is the optional interface of the service which keeps the list of its network nodes
+type NodeLister interface {
+	Nodes() []*api.NodeInfo
+}
+
+// StatisticsProvider is the optional interface of the service which collects the radio statistics
+type StatisticsProvider interface {
+	Statistics() *api.ServiceStatistics
+}
+
+// StreamSharer is the raw byte stream of the service shared with the remote side, like the network bridge. The registry provides
+// it for the services of all built-in protocols, the plugin protocols have no byte stream
+type StreamSharer interface {
+	// Tap registers the function which receives the copy of all the data read from the transport, the returned function unregisters it
+	Tap(fn func(data []byte)) (untap func())
+	// WriteShared writes the data on behalf of the remote side, the service's own writes are held until the hold time passes after the last shared write
+	WriteShared(p []byte, hold time.Duration) (int, error)
+}
+
+// RegisterAccessor is the optional interface of the service which reads and writes the coils and registers of its devices (Modbus)
+type RegisterAccessor interface {
+	ReadRegisters(ctx context.Context, unit uint8, table string, address uint16, count uint16) ([]uint16, error)
+	WriteRegisters(ctx context.Context, unit uint8, table string, address uint16, values []uint16) error
+}
+
+// DeviceCommander is the optional interface of the service which sends the typed commands to its devices (Insteon)
+type DeviceCommander interface {
+	DeviceCommand(ctx context.Context, device string, command string, level uint8) (uint8, error)
+}
+
+// LinkLister is the optional interface of the service which reads the link records of its controller (Insteon ALL-Link database)
+type LinkLister interface {
+	DeviceLinks(ctx context.Context) ([]*api.DeviceLink, error)
+}
+
 // errors
 var (
 	// ErrServiceExists is the error in case if service already exists
@@ -34,6 +76,14 @@ var (
 	ErrBadPayload error = errors.New("the message's payload is not valid")
 	// ErrSendBusy returned by Send method in case if service is unable to send message at this time
 	ErrSendBusy error = errors.New("the service is too busy and unable to send the message")
+	// ErrBadNodeID returned by SendMulticast method in case if node identifiers are not valid or not provided
+	ErrBadNodeID error = errors.New("the node identifier is not valid")
+	// ErrNotSupported returned in case if the operation is not supported by the service
+	ErrNotSupported error = errors.New("the operation is not supported by the service")
+	// ErrNoResponse returned in case if the device did not respond in time
+	ErrNoResponse error = errors.New("no response from the device")
+	// ErrOpenGaveUp is the service status in case if the service stopped the attempts to open the transport
+	ErrOpenGaveUp error = errors.New("gave up opening the transport")
 
 	// ErrNoDiscovery returned by Discover method in case if service is not providing discovery function
 	ErrNoDiscovery error = errors.New("no discovery service")
@@ -48,11 +98,32 @@ var (
 // ParamNameOpenAttemptsInterval parameter name for the time interval between attempts to open (serial port)
 const ParamNameOpenAttemptsInterval = "openAttemptsInterval"
 
+// ParamNameOpenMaxInterval parameter name for the maximal time interval between attempts to open
+const ParamNameOpenMaxInterval = "openMaxInterval"
+
+// ParamNameOpenBackoffMultiplier parameter name for the multiplier of the time interval between attempts to open, percents
+const ParamNameOpenBackoffMultiplier = "openBackoffMultiplier"
+
+// ParamNameOpenJitter parameter name for the random deviation of the time interval between attempts to open, percents
+const ParamNameOpenJitter = "openJitter"
+
+// ParamNameOpenMaxAttempts parameter name for the number of failed attempts to open after which the service gives up
+const ParamNameOpenMaxAttempts = "openMaxAttempts"
+
 // ParamNameOutgoingMaxTTL parameter name for the maximum time to live of outgoing messages
 const ParamNameOutgoingMaxTTL = "outgoingMaxTTL"
 
+// ParamNameCaptureFile parameter name for the file to capture the transport data into
+const ParamNameCaptureFile = "captureFile"
+
+// EntryResolver is implemented by the transports and the services which resolve the service entry into the actual one on open,
+// like the USB device identification into the serial port name. Returns empty string if the entry is not resolved yet
+type EntryResolver interface {
+	ResolvedEntry() string
+}
+
 // ListFunc is a the callback function used in ServiceRegistry List method. Returnning true will stop services iteration
-type ListFunc func(key *api.ServiceKey, alias string, status ServiceStatus, params api.ParamValues) bool
+type ListFunc func(key *api.ServiceKey, alias string, status ServiceStatus, resolvedEntry string, params api.ParamValues) bool
 
 // ResolveIDsInput is the input iteration method for ServiceREgistry ResoveIDs
 type ResolveIDsInput func() (key *api.ServiceKey, alias string, stop bool)
@@ -69,11 +140,20 @@ type ServiceRegistry interface {
 	Alias(key *api.ServiceKey, oldAlias string, newAlias string) error
 	Remove(key *api.ServiceKey, alias string) error
 	Status(key *api.ServiceKey, alias string) (ServiceStatus, bool)
+	ResolvedEntry(key *api.ServiceKey, alias string) string
+	SharedStream(key *api.ServiceKey, alias string) (StreamSharer, error)
 	List(listFn ListFunc)
 
 	ResolveIDs(out ResolveIDsOutput, in ResolveIDsInput)
 
 	Send(key *api.ServiceKey, alias string, payload []byte) (*api.Message, error)
+	SendMulticast(ctx context.Context, key *api.ServiceKey, alias string, nodes []uint16, broadcast bool, followUp bool, payload []byte) ([]*api.NodeSendResult, error)
+	Nodes(key *api.ServiceKey, alias string) ([]*api.NodeInfo, error)
+	Statistics(key *api.ServiceKey, alias string) (*api.ServiceStatistics, error)
+	ReadRegisters(ctx context.Context, key *api.ServiceKey, alias string, unit uint8, table string, address uint16, count uint16) ([]uint16, error)
+	WriteRegisters(ctx context.Context, key *api.ServiceKey, alias string, unit uint8, table string, address uint16, values []uint16) error
+	DeviceCommand(ctx context.Context, key *api.ServiceKey, alias string, device string, command string, level uint8) (uint8, error)
+	DeviceLinks(ctx context.Context, key *api.ServiceKey, alias string) ([]*api.DeviceLink, error)
 }
 
 // Services provides access to ServiceRegistry implementation (set in services module)
@@ -25,6 +25,10 @@ type Transport interface {
 // ErrNotOpen error
 var ErrNotOpen error = errors.New("the transport entry is not open")
 
+// ErrTransportHeld error is returned by the write of the shared transport if the remote side holds it for too long, the data is
+// not written but the transport stays operational, so only the write fails
+var ErrTransportHeld error = errors.New("the transport is held by the remote side")
+
 // Transports contains transports definitions (defined in services module)
 var Transports map[api.TransportIdentifier]*TransportInfo
 
@@ -36,12 +40,15 @@ func TransportName(t api.TransportIdentifier) string {
 	return ""
 }
 
-// TransportByName resolves transport name into identifier
+// TransportByName resolves transport identifier or name into identifier
 func TransportByName(name string) (api.TransportIdentifier, bool) {
+	if _, ok := Transports[api.TransportIdentifier(name)]; ok {
+		return api.TransportIdentifier(name), true
+	}
 	for id, ti := range Transports {
 		if strings.EqualFold(name, ti.Name) {
 			return id, true
 		}
 	}
-	return 0, false
+	return "", false
 }
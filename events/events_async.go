@@ -71,6 +71,7 @@ func (d *AsyncDispatcher) asyncDispatcher() {
 		case <-d.senderCh:
 		}
 
+		d.stopWg.Add(1)
 		go d.send(packet)
 	}
 }
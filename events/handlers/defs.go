@@ -105,7 +105,7 @@ type ServiceStatus struct {
 // ServiceStatusResult - get service status
 type ServiceStatusResult struct {
 	ResponseHeader
-	*api.StatusReply
+	*api.ServiceStatusResult
 }
 
 // ListServices - get list of services request
@@ -132,6 +132,90 @@ type SendToServiceResult struct {
 	*api.SendToServiceResult
 }
 
+// SendMulticast - send message to several nodes of the service
+type SendMulticast struct {
+	RequestHeader
+	*api.SendMulticast
+}
+
+// SendMulticastResult - send message to several nodes of the service result
+type SendMulticastResult struct {
+	ResponseHeader
+	*api.SendMulticastResult
+}
+
+// ServiceNodes - get list of the service's network nodes
+type ServiceNodes struct {
+	RequestHeader
+	*api.ServiceID
+}
+
+// ServiceNodesResult - get list of the service's network nodes result
+type ServiceNodesResult struct {
+	ResponseHeader
+	*api.ServiceNodesResult
+}
+
+// ServiceStatistics - get radio statistics of the service
+type ServiceStatistics struct {
+	RequestHeader
+	*api.ServiceID
+}
+
+// ServiceStatisticsResult - get radio statistics of the service result
+type ServiceStatisticsResult struct {
+	ResponseHeader
+	*api.ServiceStatisticsResult
+}
+
+// ReadRegisters - read the coils or registers of the service's device
+type ReadRegisters struct {
+	RequestHeader
+	*api.ReadRegisters
+}
+
+// ReadRegistersResult - read the coils or registers of the service's device result
+type ReadRegistersResult struct {
+	ResponseHeader
+	*api.ReadRegistersResult
+}
+
+// WriteRegisters - write the coils or holding registers of the service's device
+type WriteRegisters struct {
+	RequestHeader
+	*api.WriteRegisters
+}
+
+// WriteRegistersResult - write the coils or holding registers of the service's device result
+type WriteRegistersResult struct {
+	ResponseHeader
+	*api.StatusReply
+}
+
+// DeviceCommand - send the typed command to the service's device
+type DeviceCommand struct {
+	RequestHeader
+	*api.DeviceCommand
+}
+
+// DeviceCommandResult - send the typed command to the service's device result
+type DeviceCommandResult struct {
+	ResponseHeader
+	*api.DeviceCommandResult
+}
+
+// DeviceLinks - get the link records of the service's controller
+type DeviceLinks struct {
+	RequestHeader
+	*api.ServiceID
+}
+
+// DeviceLinksResult - get the link records of the service's controller result
+type DeviceLinksResult struct {
+	ResponseHeader
+	*api.DeviceLinksResult
+}
+
 // ProtocolDiscoveryStarted event contains information about started discovery query
 type ProtocolDiscoveryStarted struct {
 	Header
@@ -144,6 +228,12 @@ type ProtocolDiscoveryFinished struct {
 	*api.ProtocolDiscoveryResult
 }
 
+// ProtocolDiscoveryProgress event contains the progress and the found entry of running discovery query
+type ProtocolDiscoveryProgress struct {
+	Header
+	*api.ProtocolDiscoveryProgress
+}
+
 // ProtocolDiscover defines protocol discover query event
 type ProtocolDiscover struct {
 	RequestHeader
@@ -186,6 +276,24 @@ type UpdateMessageState struct {
 	*api.UpdateMessageState
 }
 
+// NodeStatistics event notifies about updated radio statistics of the node
+type NodeStatistics struct {
+	Header
+	*api.NodeStatisticsEntry
+}
+
+// LowBattery event notifies about the node with low battery or the node which stopped reporting its battery level
+type LowBattery struct {
+	Header
+	*api.LowBattery
+}
+
+// ServiceGaveUp event notifies about the service which stopped the attempts to open its transport
+type ServiceGaveUp struct {
+	Header
+	*api.ServiceGaveUp
+}
+
 // GetMessage - get message request
 type GetMessage struct {
 	RequestHeader
@@ -13,12 +13,12 @@ func newErrorInfo(code api.ErrorCode, err error, args ...interface{}) (e *api.Er
 	e = &api.ErrorInfo{Code: code, Params: args, Err: err}
 	switch code {
 	case api.ErrorUnknownProtocol:
-		e.Message = fmt.Sprintf("Unknown protocol identifier %d", args...)
+		e.Message = fmt.Sprintf("Unknown protocol identifier %s", args...)
 	case api.ErrorUnknownTransport:
-		e.Message = fmt.Sprintf("Unknown transport identifier %d", args...)
+		e.Message = fmt.Sprintf("Unknown transport identifier %s", args...)
 	case api.ErrorInvalidProtocolTransport:
 		e.Message = fmt.Sprintf(
-			"The protocol %s (%d) doesn't support the transport %s (%d)",
+			"The protocol %s (%s) doesn't support the transport %s (%s)",
 			defs.ProtocolName(args[0].(api.ProtocolIdentifier)), args[0],
 			defs.TransportName(args[1].(api.TransportIdentifier)), args[1],
 		)
@@ -30,7 +30,7 @@ func newErrorInfo(code api.ErrorCode, err error, args ...interface{}) (e *api.Er
 		e.Message = fmt.Sprintf("Required parameter \"%s\" is missing", args...)
 	case api.ErrorNoDiscovery:
 		e.Message = fmt.Sprintf(
-			"The discovery is not supported for %s (%d) protocol and %s (%d) transport",
+			"The discovery is not supported for %s (%s) protocol and %s (%s) transport",
 			defs.ProtocolName(args[0].(api.ProtocolIdentifier)), args[0],
 			defs.TransportName(args[1].(api.TransportIdentifier)), args[1],
 		)
@@ -48,7 +48,7 @@ func newErrorInfo(code api.ErrorCode, err error, args ...interface{}) (e *api.Er
 		e.Message = "Either service key fields (protocol, transport, entry) or service alias are required"
 	case api.ErrorServiceExists:
 		e.Message = fmt.Sprintf(
-			"The service exists already for %s (%d) protocol, %s (%d) transport, and %s entry",
+			"The service exists already for %s (%s) protocol, %s (%s) transport, and %s entry",
 			defs.ProtocolName(args[0].(api.ProtocolIdentifier)), args[0],
 			defs.TransportName(args[1].(api.TransportIdentifier)), args[1],
 			args[2],
@@ -57,14 +57,14 @@ func newErrorInfo(code api.ErrorCode, err error, args ...interface{}) (e *api.Er
 		e.Message = fmt.Sprintf("The service's alias %s exists already", args...)
 	case api.ErrorServiceInitialize:
 		e.Message = fmt.Sprintf(
-			"The service initialization failed, %s (%d) protocol, %s (%d) transport, and %s entry, reason: %s",
+			"The service initialization failed, %s (%s) protocol, %s (%s) transport, and %s entry, reason: %s",
 			defs.ProtocolName(args[0].(api.ProtocolIdentifier)), args[0],
 			defs.TransportName(args[1].(api.TransportIdentifier)), args[1],
 			args[2], err.Error(),
 		)
 	case api.ErrorServiceKeyNotExists:
 		e.Message = fmt.Sprintf(
-			"The service not exists for %s (%d) protocol, %s (%d) transport, and %s entry",
+			"The service not exists for %s (%s) protocol, %s (%s) transport, and %s entry",
 			defs.ProtocolName(args[0].(api.ProtocolIdentifier)), args[0],
 			defs.TransportName(args[1].(api.TransportIdentifier)), args[1],
 			args[2],
@@ -77,6 +77,10 @@ func newErrorInfo(code api.ErrorCode, err error, args ...interface{}) (e *api.Er
 		e.Message = "The payload is not valid for the service"
 	case api.ErrorServiceSendBusy:
 		e.Message = "The service is too busy and unable to send the message"
+	case api.ErrorServiceBadNode:
+		e.Message = "The node identifier is not valid or no node identifiers provided"
+	case api.ErrorServiceNotSupported:
+		e.Message = "The operation is not supported by the service"
 	case api.ErrorOtherError:
 		e.Message = err.Error()
 	}
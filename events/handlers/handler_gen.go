@@ -12,12 +12,12 @@ func handleRestart(event *Restart) {
 }
 
 func handleConfigGet(event *ConfigGet, cfg *api.Config) {
-	Dispatcher.Send(&ConfigGetResult{Config: cfg, ResponseHeader: event.Associate()})
+	Dispatcher.Send(&ConfigGetResult{Config: maskConfig(cfg), ResponseHeader: event.Associate()})
 }
 
 func handleProtocolList(event *ProtocolList) {
 	r := &ProtocolListResult{ResponseHeader: event.Associate(), ProtocolListResult: &api.ProtocolListResult{}}
-	for k, v := range defs.Protocols {
+	for k, v := range defs.ListProtocols() {
 		r.Protocols = append(r.Protocols, &api.ProtocolListEntry{ID: k, Name: v.Name})
 	}
 	Dispatcher.Send(r)
@@ -66,14 +66,14 @@ func handleProtocolInfo(event *ProtocolInfo) {
 
 	if event.ProtocolInfo != nil && len(event.Protocols) > 0 {
 		for _, pid := range event.Protocols {
-			if pi, ok := defs.Protocols[pid]; ok {
+			if pi, ok := defs.LookupProtocol(pid); ok {
 				pf(pid, pi)
 			} else {
 				r.Protocols = append(r.Protocols, &api.ProtocolInfoEntry{ID: pid, Valid: false})
 			}
 		}
 	} else {
-		for pid, pi := range defs.Protocols {
+		for pid, pi := range defs.ListProtocols() {
 			pf(pid, pi)
 		}
 	}
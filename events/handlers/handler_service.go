@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/stas-makutin/howeve/api"
 	"github.com/stas-makutin/howeve/defs"
@@ -62,7 +65,7 @@ func handleChangeServiceAlias(event *ChangeServiceAlias) {
 }
 
 func handleServiceStatus(event *ServiceStatus) {
-	r := &ServiceStatusResult{ResponseHeader: event.Associate(), StatusReply: &api.StatusReply{Success: false}}
+	r := &ServiceStatusResult{ResponseHeader: event.Associate(), ServiceStatusResult: &api.ServiceStatusResult{StatusReply: &api.StatusReply{Success: false}}}
 	errorInfo := validateServiceID(event.ServiceKey, event.Alias)
 	if errorInfo == nil {
 		if status, exists := defs.Services.Status(event.ServiceKey, event.Alias); exists {
@@ -71,6 +74,7 @@ func handleServiceStatus(event *ServiceStatus) {
 			} else {
 				errorInfo = newErrorInfo(api.ErrorServiceStatusBad, status)
 			}
+			r.ResolvedEntry = defs.Services.ResolvedEntry(event.ServiceKey, event.Alias)
 		} else {
 			errorInfo = handleServiceNotExistsError(event.ServiceKey, event.Alias)
 		}
@@ -81,7 +85,7 @@ func handleServiceStatus(event *ServiceStatus) {
 
 func handleListServices(event *ListServices) {
 	r := &ListServicesResult{ResponseHeader: event.Associate(), ListServicesResult: &api.ListServicesResult{}}
-	defs.Services.List(func(key *api.ServiceKey, alias string, status defs.ServiceStatus, params api.ParamValues) bool {
+	defs.Services.List(func(key *api.ServiceKey, alias string, status defs.ServiceStatus, resolvedEntry string, params api.ParamValues) bool {
 		found := 0b1111
 		if len(event.Protocols) > 0 {
 			mask := 0b0001
@@ -131,8 +135,9 @@ func handleListServices(event *ListServices) {
 				statusReply.Error = newErrorInfo(api.ErrorServiceStatusBad, status)
 			}
 			r.Services = append(r.Services, api.ListServicesEntry{
-				ServiceEntry: &api.ServiceEntry{ServiceKey: key, Alias: alias, Params: params.Raw()},
-				StatusReply:  statusReply,
+				ServiceEntry:  &api.ServiceEntry{ServiceKey: key, Alias: alias, Params: maskServiceParams(key.Protocol, key.Transport, params.Raw())},
+				StatusReply:   statusReply,
+				ResolvedEntry: resolvedEntry,
 			})
 		}
 		return false
@@ -164,6 +169,224 @@ func handleSendToService(event *SendToService) {
 	Dispatcher.Send(r)
 }
 
+func handleSendMulticast(event *SendMulticast) {
+	ctx := event.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go func() {
+		r := &SendMulticastResult{ResponseHeader: event.Associate(), SendMulticastResult: &api.SendMulticastResult{StatusReply: &api.StatusReply{Success: false}}}
+		errorInfo := validateServiceID(event.ServiceKey, event.Alias)
+		if errorInfo == nil {
+			if nodes, err := defs.Services.SendMulticast(ctx, event.ServiceKey, event.Alias, event.Nodes, event.Broadcast, event.FollowUp, event.Payload); err == nil {
+				r.Nodes = nodes
+				r.Success = true
+				for _, node := range nodes {
+					if !node.Success {
+						r.Success = false
+						break
+					}
+				}
+			} else {
+				switch err {
+				case defs.ErrServiceNotExists:
+					errorInfo = handleServiceNotExistsError(event.ServiceKey, event.Alias)
+				case defs.ErrBadPayload:
+					errorInfo = newErrorInfo(api.ErrorServiceBadPayload, err)
+				case defs.ErrBadNodeID:
+					errorInfo = newErrorInfo(api.ErrorServiceBadNode, err)
+				case defs.ErrNotSupported:
+					errorInfo = newErrorInfo(api.ErrorServiceNotSupported, err)
+				case defs.ErrSendBusy:
+					errorInfo = newErrorInfo(api.ErrorServiceSendBusy, err)
+				default:
+					errorInfo = newErrorInfo(api.ErrorOtherError, err)
+				}
+			}
+		}
+		r.Error = errorInfo
+		Dispatcher.Send(r)
+	}()
+}
+
+func handleServiceNodes(event *ServiceNodes) {
+	r := &ServiceNodesResult{ResponseHeader: event.Associate(), ServiceNodesResult: &api.ServiceNodesResult{StatusReply: &api.StatusReply{Success: false}}}
+	errorInfo := validateServiceID(event.ServiceKey, event.Alias)
+	if errorInfo == nil {
+		if nodes, err := defs.Services.Nodes(event.ServiceKey, event.Alias); err == nil {
+			r.Nodes = nodes
+			r.Success = true
+		} else {
+			switch err {
+			case defs.ErrServiceNotExists:
+				errorInfo = handleServiceNotExistsError(event.ServiceKey, event.Alias)
+			case defs.ErrNotSupported:
+				errorInfo = newErrorInfo(api.ErrorServiceNotSupported, err)
+			default:
+				errorInfo = newErrorInfo(api.ErrorOtherError, err)
+			}
+		}
+	}
+	r.Error = errorInfo
+	Dispatcher.Send(r)
+}
+
+func handleServiceStatistics(event *ServiceStatistics) {
+	r := &ServiceStatisticsResult{ResponseHeader: event.Associate(), ServiceStatisticsResult: &api.ServiceStatisticsResult{StatusReply: &api.StatusReply{Success: false}}}
+	errorInfo := validateServiceID(event.ServiceKey, event.Alias)
+	if errorInfo == nil {
+		if statistics, err := defs.Services.Statistics(event.ServiceKey, event.Alias); err == nil {
+			r.ServiceStatistics = statistics
+			r.Success = true
+		} else {
+			switch err {
+			case defs.ErrServiceNotExists:
+				errorInfo = handleServiceNotExistsError(event.ServiceKey, event.Alias)
+			case defs.ErrNotSupported:
+				errorInfo = newErrorInfo(api.ErrorServiceNotSupported, err)
+			default:
+				errorInfo = newErrorInfo(api.ErrorOtherError, err)
+			}
+		}
+	}
+	r.Error = errorInfo
+	Dispatcher.Send(r)
+}
+
+func handleReadRegisters(event *ReadRegisters) {
+	ctx := event.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go func() {
+		r := &ReadRegistersResult{ResponseHeader: event.Associate(), ReadRegistersResult: &api.ReadRegistersResult{StatusReply: &api.StatusReply{Success: false}}}
+		errorInfo := validateServiceID(event.ServiceKey, event.Alias)
+		if errorInfo == nil {
+			if values, err := defs.Services.ReadRegisters(ctx, event.ServiceKey, event.Alias, event.Unit, event.Table, event.Address, event.Count); err == nil {
+				r.Values = values
+				r.Success = true
+			} else {
+				errorInfo = handleRegistersError(event.ServiceKey, event.Alias, err)
+			}
+		}
+		r.Error = errorInfo
+		Dispatcher.Send(r)
+	}()
+}
+
+func handleWriteRegisters(event *WriteRegisters) {
+	ctx := event.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go func() {
+		r := &WriteRegistersResult{ResponseHeader: event.Associate(), StatusReply: &api.StatusReply{Success: false}}
+		errorInfo := validateServiceID(event.ServiceKey, event.Alias)
+		if errorInfo == nil {
+			if err := defs.Services.WriteRegisters(ctx, event.ServiceKey, event.Alias, event.Unit, event.Table, event.Address, event.Values); err == nil {
+				r.Success = true
+			} else {
+				errorInfo = handleRegistersError(event.ServiceKey, event.Alias, err)
+			}
+		}
+		r.Error = errorInfo
+		Dispatcher.Send(r)
+	}()
+}
+
+func handleDeviceCommand(event *DeviceCommand) {
+	ctx := event.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go func() {
+		r := &DeviceCommandResult{ResponseHeader: event.Associate(), DeviceCommandResult: &api.DeviceCommandResult{StatusReply: &api.StatusReply{Success: false}}}
+		errorInfo := validateServiceID(event.ServiceKey, event.Alias)
+		if errorInfo == nil {
+			if level, err := defs.Services.DeviceCommand(ctx, event.ServiceKey, event.Alias, event.Device, event.Command, event.Level); err == nil {
+				r.Level = level
+				r.Success = true
+			} else {
+				errorInfo = handleDeviceError(event.ServiceKey, event.Alias, err)
+			}
+		}
+		r.Error = errorInfo
+		Dispatcher.Send(r)
+	}()
+}
+
+func handleDeviceLinks(event *DeviceLinks) {
+	ctx := event.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go func() {
+		r := &DeviceLinksResult{ResponseHeader: event.Associate(), DeviceLinksResult: &api.DeviceLinksResult{StatusReply: &api.StatusReply{Success: false}}}
+		errorInfo := validateServiceID(event.ServiceKey, event.Alias)
+		if errorInfo == nil {
+			if links, err := defs.Services.DeviceLinks(ctx, event.ServiceKey, event.Alias); err == nil {
+				r.Links = links
+				r.Success = true
+			} else {
+				errorInfo = handleDeviceError(event.ServiceKey, event.Alias, err)
+			}
+		}
+		r.Error = errorInfo
+		Dispatcher.Send(r)
+	}()
+}
+
+func handleDeviceError(key *api.ServiceKey, alias string, err error) *api.ErrorInfo {
+	if err == defs.ErrBadNodeID {
+		return newErrorInfo(api.ErrorServiceBadNode, err)
+	}
+	return handleRegistersError(key, alias, err)
+}
+
+func handleRegistersError(key *api.ServiceKey, alias string, err error) *api.ErrorInfo {
+	switch err {
+	case defs.ErrServiceNotExists:
+		return handleServiceNotExistsError(key, alias)
+	case defs.ErrBadPayload:
+		return newErrorInfo(api.ErrorServiceBadPayload, err)
+	case defs.ErrNotSupported:
+		return newErrorInfo(api.ErrorServiceNotSupported, err)
+	case defs.ErrSendBusy:
+		return newErrorInfo(api.ErrorServiceSendBusy, err)
+	}
+	return newErrorInfo(api.ErrorOtherError, err)
+}
+
+// SendNodeStatistics sends NodeStatistics event
+func SendNodeStatistics(service *api.ServiceKey, statistics *api.NodeStatistics) {
+	Dispatcher.SendAsync(&NodeStatistics{
+		Header: *NewHeader(""),
+		NodeStatisticsEntry: &api.NodeStatisticsEntry{
+			ServiceKey: service, NodeStatistics: statistics,
+		},
+	})
+}
+
+// SendLowBattery sends LowBattery event
+func SendLowBattery(service *api.ServiceKey, node uint16, level uint8, reason string, updated time.Time) {
+	Dispatcher.SendAsync(&LowBattery{
+		Header: *NewHeader(""),
+		LowBattery: &api.LowBattery{
+			ServiceKey: service, Node: node, Level: level, Reason: reason, Updated: updated,
+		},
+	})
+}
+
+// SendServiceGaveUp sends ServiceGaveUp event
+func SendServiceGaveUp(service *api.ServiceKey, attempts uint32, err error) {
+	Dispatcher.SendAsync(&ServiceGaveUp{
+		Header: *NewHeader(""),
+		ServiceGaveUp: &api.ServiceGaveUp{
+			ServiceKey: service, Attempts: attempts, Error: err.Error(),
+		},
+	})
+}
+
 func SendDiscoveryStarted(id uuid.UUID, protocol api.ProtocolIdentifier, transport api.TransportIdentifier, params api.RawParamValues) {
 	Dispatcher.SendAsync(&ProtocolDiscoveryStarted{
 		Header: *NewHeader(""),
@@ -179,12 +402,22 @@ func SendDiscoveryStarted(id uuid.UUID, protocol api.ProtocolIdentifier, transpo
 }
 
 func SendDiscoveryFinished(id uuid.UUID, entries []*api.DiscoveryEntry, err error) {
+	r := &api.ProtocolDiscoveryResult{ID: id, Entries: entries}
+	if err != nil {
+		r.Error = newErrorInfo(api.ErrorDiscoveryFailed, err)
+	}
 	Dispatcher.SendAsync(&ProtocolDiscoveryFinished{
+		Header:                  *NewHeader(""),
+		ProtocolDiscoveryResult: r,
+	})
+}
+
+// SendDiscoveryProgress sends ProtocolDiscoveryProgress event
+func SendDiscoveryProgress(id uuid.UUID, probed string, found *api.DiscoveryEntry, percent uint8) {
+	Dispatcher.SendAsync(&ProtocolDiscoveryProgress{
 		Header: *NewHeader(""),
-		ProtocolDiscoveryResult: &api.ProtocolDiscoveryResult{
-			ID:      id,
-			Entries: entries,
-			Error:   newErrorInfo(api.ErrorDiscoveryFailed, err),
+		ProtocolDiscoveryProgress: &api.ProtocolDiscoveryProgress{
+			ID: id, Probed: probed, Found: found, Percent: percent,
 		},
 	})
 }
@@ -28,6 +28,20 @@ func (t *Task) handleEvents(event interface{}) {
 		handleListServices(e)
 	case *SendToService:
 		handleSendToService(e)
+	case *SendMulticast:
+		handleSendMulticast(e)
+	case *ServiceNodes:
+		handleServiceNodes(e)
+	case *ServiceStatistics:
+		handleServiceStatistics(e)
+	case *ReadRegisters:
+		handleReadRegisters(e)
+	case *WriteRegisters:
+		handleWriteRegisters(e)
+	case *DeviceCommand:
+		handleDeviceCommand(e)
+	case *DeviceLinks:
+		handleDeviceLinks(e)
 	case *GetMessage:
 		handleGetMessage(e)
 	case *ListMessages:
@@ -20,6 +20,42 @@ func buildParamsInfo(p defs.Params) (pie map[string]*api.ParamInfoEntry) {
 	return
 }
 
+// maskServiceParams masks the values of the secret parameters of the service
+func maskServiceParams(protocol api.ProtocolIdentifier, transport api.TransportIdentifier, params api.RawParamValues) api.RawParamValues {
+	to, ti, err := defs.ResolveProtocolAndTransport(protocol, transport)
+	if err != nil {
+		return params
+	}
+	return ti.Params.Merge(to.Params).Mask(params)
+}
+
+// maskConfig returns copy of the configuration where the secrets, like the service passwords and the bridge tokens, are masked
+func maskConfig(cfg *api.Config) *api.Config {
+	if cfg == nil {
+		return nil
+	}
+	c := *cfg
+	if len(cfg.Services) > 0 {
+		c.Services = make([]api.ServiceConfig, len(cfg.Services))
+		for i, sc := range cfg.Services {
+			protocol, _ := defs.ProtocolByName(sc.Protocol)
+			transport, _ := defs.TransportByName(sc.Transport)
+			sc.Params = maskServiceParams(protocol, transport, sc.Params)
+			c.Services[i] = sc
+		}
+	}
+	if len(cfg.Bridges) > 0 {
+		c.Bridges = make([]api.BridgeConfig, len(cfg.Bridges))
+		for i, bc := range cfg.Bridges {
+			if bc.Token != "" {
+				bc.Token = defs.SecretMask
+			}
+			c.Bridges[i] = bc
+		}
+	}
+	return &c
+}
+
 func validateServiceKey(key *api.ServiceKey) *api.ErrorInfo {
 	if key != nil {
 		_, _, err := defs.ResolveProtocolAndTransport(key.Protocol, key.Transport)
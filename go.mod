@@ -10,7 +10,7 @@ require (
 	github.com/gobwas/ws v1.4.0
 	github.com/kardianos/service v1.2.2
 	golang.org/x/net v0.33.0
-	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/sys v0.28.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -72,20 +72,12 @@ func parseProtocolInfo(w http.ResponseWriter, r *http.Request) (events.TargetedR
 		q = &api.ProtocolInfo{}
 		for _, v := range r.Form["protocols"] {
 			for _, vp := range strings.FieldsFunc(v, func(c rune) bool { return c == ',' || c == ';' || c == ':' || c == '|' }) {
-				n, err := strconv.ParseUint(vp, 10, 8)
-				if err != nil {
-					return nil, true, err
-				}
-				q.Protocols = append(q.Protocols, api.ProtocolIdentifier(n))
+				q.Protocols = append(q.Protocols, api.ProtocolIdentifier(vp))
 			}
 		}
 		for _, v := range r.Form["transports"] {
 			for _, vp := range strings.FieldsFunc(v, func(c rune) bool { return c == ',' || c == ';' || c == ':' || c == '|' }) {
-				n, err := strconv.ParseUint(vp, 10, 8)
-				if err != nil {
-					return nil, true, err
-				}
-				q.Transports = append(q.Transports, api.TransportIdentifier(n))
+				q.Transports = append(q.Transports, api.TransportIdentifier(vp))
 			}
 		}
 	}
@@ -104,19 +96,10 @@ func parseProtocolDiscover(w http.ResponseWriter, r *http.Request) (events.Targe
 		}
 		q = &api.ProtocolDiscover{}
 
-		n, err := strconv.ParseUint(r.Form.Get("protocol"), 10, 8)
-		if err != nil {
-			return nil, true, err
-		}
-		q.Protocol = api.ProtocolIdentifier(n)
-
-		n, err = strconv.ParseUint(r.Form.Get("transport"), 10, 8)
-		if err != nil {
-			return nil, true, err
-		}
-		q.Transport = api.TransportIdentifier(n)
+		q.Protocol = api.ProtocolIdentifier(r.Form.Get("protocol"))
+		q.Transport = api.TransportIdentifier(r.Form.Get("transport"))
 
-		if pi, ok := defs.Protocols[q.Protocol]; ok {
+		if pi, ok := defs.LookupProtocol(q.Protocol); ok {
 			if pti, ok := pi.Transports[q.Transport]; ok {
 				for name, p := range pti.DiscoveryParams {
 					if p.Flags&defs.ParamFlagConst == 0 {
@@ -173,21 +156,12 @@ func parseAddService(w http.ResponseWriter, r *http.Request) (events.TargetedReq
 		}
 		q = &api.ServiceEntry{}
 
-		n, err := strconv.ParseUint(r.Form.Get("protocol"), 10, 8)
-		if err != nil {
-			return nil, true, err
-		}
-		q.Protocol = api.ProtocolIdentifier(n)
-
-		n, err = strconv.ParseUint(r.Form.Get("transport"), 10, 8)
-		if err != nil {
-			return nil, true, err
-		}
-		q.Transport = api.TransportIdentifier(n)
+		q.Protocol = api.ProtocolIdentifier(r.Form.Get("protocol"))
+		q.Transport = api.TransportIdentifier(r.Form.Get("transport"))
 
 		q.Entry = r.Form.Get("entry")
 		q.Alias = r.Form.Get("alias")
-		if pi, ok := defs.Protocols[q.Protocol]; ok {
+		if pi, ok := defs.LookupProtocol(q.Protocol); ok {
 			if pti, ok := pi.Transports[q.Transport]; ok {
 				if ti, ok := defs.Transports[q.Transport]; ok {
 					for name, p := range pti.Params.Merge(ti.Params) {
@@ -222,17 +196,9 @@ func parseRemoveService(w http.ResponseWriter, r *http.Request) (events.Targeted
 
 		if protocol := r.Form.Get("protocol"); protocol != "" {
 			if transport := r.Form.Get("transport"); transport != "" {
-				pid, err := strconv.ParseUint(protocol, 10, 8)
-				if err != nil {
-					return nil, true, err
-				}
-				tid, err := strconv.ParseUint(transport, 10, 8)
-				if err != nil {
-					return nil, true, err
-				}
 				q.ServiceKey = &api.ServiceKey{
-					Protocol:  api.ProtocolIdentifier(pid),
-					Transport: api.TransportIdentifier(tid),
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
 					Entry:     r.Form.Get("entry"),
 				}
 			}
@@ -256,17 +222,9 @@ func parseChangeServiceAlias(w http.ResponseWriter, r *http.Request) (events.Tar
 
 		if protocol := r.Form.Get("protocol"); protocol != "" {
 			if transport := r.Form.Get("transport"); transport != "" {
-				pid, err := strconv.ParseUint(protocol, 10, 8)
-				if err != nil {
-					return nil, true, err
-				}
-				tid, err := strconv.ParseUint(transport, 10, 8)
-				if err != nil {
-					return nil, true, err
-				}
 				q.ServiceKey = &api.ServiceKey{
-					Protocol:  api.ProtocolIdentifier(pid),
-					Transport: api.TransportIdentifier(tid),
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
 					Entry:     r.Form.Get("entry"),
 				}
 			}
@@ -291,17 +249,9 @@ func parseServiceStatus(w http.ResponseWriter, r *http.Request) (events.Targeted
 
 		if protocol := r.Form.Get("protocol"); protocol != "" {
 			if transport := r.Form.Get("transport"); transport != "" {
-				pid, err := strconv.ParseUint(protocol, 10, 8)
-				if err != nil {
-					return nil, true, err
-				}
-				tid, err := strconv.ParseUint(transport, 10, 8)
-				if err != nil {
-					return nil, true, err
-				}
 				q.ServiceKey = &api.ServiceKey{
-					Protocol:  api.ProtocolIdentifier(pid),
-					Transport: api.TransportIdentifier(tid),
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
 					Entry:     r.Form.Get("entry"),
 				}
 			}
@@ -324,20 +274,12 @@ func parseListServices(w http.ResponseWriter, r *http.Request) (events.TargetedR
 		q = &api.ListServices{}
 		for _, v := range r.Form["protocols"] {
 			for _, vp := range strings.FieldsFunc(v, func(c rune) bool { return c == ',' || c == ';' || c == ':' || c == '|' }) {
-				n, err := strconv.ParseUint(vp, 10, 8)
-				if err != nil {
-					return nil, true, err
-				}
-				q.Protocols = append(q.Protocols, api.ProtocolIdentifier(n))
+				q.Protocols = append(q.Protocols, api.ProtocolIdentifier(vp))
 			}
 		}
 		for _, v := range r.Form["transports"] {
 			for _, vp := range strings.FieldsFunc(v, func(c rune) bool { return c == ',' || c == ';' || c == ':' || c == '|' }) {
-				n, err := strconv.ParseUint(vp, 10, 8)
-				if err != nil {
-					return nil, true, err
-				}
-				q.Transports = append(q.Transports, api.TransportIdentifier(n))
+				q.Transports = append(q.Transports, api.TransportIdentifier(vp))
 			}
 		}
 		for _, v := range r.Form["entries"] {
@@ -372,27 +314,262 @@ func parseSendToService(w http.ResponseWriter, r *http.Request) (events.Targeted
 
 		if protocol := r.Form.Get("protocol"); protocol != "" {
 			if transport := r.Form.Get("transport"); transport != "" {
-				pid, err := strconv.ParseUint(protocol, 10, 8)
+				q.ServiceKey = &api.ServiceKey{
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
+					Entry:     r.Form.Get("entry"),
+				}
+			}
+		}
+		q.Alias = r.Form.Get("alias")
+		if q.Payload, err = base64.StdEncoding.DecodeString(r.Form.Get("payload")); err != nil {
+			return nil, true, err
+		}
+	}
+	return &handlers.SendToService{SendToService: q}, true, nil
+}
+
+func parseSendMulticast(w http.ResponseWriter, r *http.Request) (events.TargetedRequest, bool, error) {
+	var q *api.SendMulticast
+	if ok, err := parseJSONRequest(&q, w, r, 4096); ok {
+		if err != nil {
+			return nil, true, err
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, true, err
+		}
+		q = &api.SendMulticast{}
+
+		if protocol := r.Form.Get("protocol"); protocol != "" {
+			if transport := r.Form.Get("transport"); transport != "" {
+				q.ServiceKey = &api.ServiceKey{
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
+					Entry:     r.Form.Get("entry"),
+				}
+			}
+		}
+		q.Alias = r.Form.Get("alias")
+		for _, v := range r.Form["nodes"] {
+			for _, vp := range strings.FieldsFunc(v, func(c rune) bool { return c == ',' || c == ';' || c == ':' || c == '|' }) {
+				n, err := strconv.ParseUint(vp, 10, 16)
 				if err != nil {
 					return nil, true, err
 				}
-				tid, err := strconv.ParseUint(transport, 10, 8)
+				q.Nodes = append(q.Nodes, uint16(n))
+			}
+		}
+		broadcast := strings.ToLower(r.Form.Get("broadcast"))
+		q.Broadcast = broadcast == "true" || broadcast == "1" || broadcast == "yes"
+		followUp := strings.ToLower(r.Form.Get("followUp"))
+		q.FollowUp = followUp == "true" || followUp == "1" || followUp == "yes"
+		if q.Payload, err = base64.StdEncoding.DecodeString(r.Form.Get("payload")); err != nil {
+			return nil, true, err
+		}
+	}
+	return &handlers.SendMulticast{SendMulticast: q}, true, nil
+}
+
+func parseServiceNodes(w http.ResponseWriter, r *http.Request) (events.TargetedRequest, bool, error) {
+	var q *api.ServiceID
+	if ok, err := parseJSONRequest(&q, w, r, 4096); ok {
+		if err != nil {
+			return nil, true, err
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, true, err
+		}
+		q = &api.ServiceID{}
+
+		if protocol := r.Form.Get("protocol"); protocol != "" {
+			if transport := r.Form.Get("transport"); transport != "" {
+				q.ServiceKey = &api.ServiceKey{
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
+					Entry:     r.Form.Get("entry"),
+				}
+			}
+		}
+		q.Alias = r.Form.Get("alias")
+	}
+	return &handlers.ServiceNodes{ServiceID: q}, true, nil
+}
+
+func parseServiceStatistics(w http.ResponseWriter, r *http.Request) (events.TargetedRequest, bool, error) {
+	var q *api.ServiceID
+	if ok, err := parseJSONRequest(&q, w, r, 4096); ok {
+		if err != nil {
+			return nil, true, err
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, true, err
+		}
+		q = &api.ServiceID{}
+
+		if protocol := r.Form.Get("protocol"); protocol != "" {
+			if transport := r.Form.Get("transport"); transport != "" {
+				q.ServiceKey = &api.ServiceKey{
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
+					Entry:     r.Form.Get("entry"),
+				}
+			}
+		}
+		q.Alias = r.Form.Get("alias")
+	}
+	return &handlers.ServiceStatistics{ServiceID: q}, true, nil
+}
+
+func parseReadRegisters(w http.ResponseWriter, r *http.Request) (events.TargetedRequest, bool, error) {
+	var q *api.ReadRegisters
+	if ok, err := parseJSONRequest(&q, w, r, 4096); ok {
+		if err != nil {
+			return nil, true, err
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, true, err
+		}
+		q = &api.ReadRegisters{ServiceID: &api.ServiceID{}}
+
+		if protocol := r.Form.Get("protocol"); protocol != "" {
+			if transport := r.Form.Get("transport"); transport != "" {
+				q.ServiceKey = &api.ServiceKey{
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
+					Entry:     r.Form.Get("entry"),
+				}
+			}
+		}
+		q.Alias = r.Form.Get("alias")
+		unit, err := strconv.ParseUint(r.Form.Get("unit"), 10, 8)
+		if err != nil {
+			return nil, true, err
+		}
+		q.Unit = uint8(unit)
+		q.Table = r.Form.Get("table")
+		address, err := strconv.ParseUint(r.Form.Get("address"), 10, 16)
+		if err != nil {
+			return nil, true, err
+		}
+		q.Address = uint16(address)
+		count, err := strconv.ParseUint(r.Form.Get("count"), 10, 16)
+		if err != nil {
+			return nil, true, err
+		}
+		q.Count = uint16(count)
+	}
+	return &handlers.ReadRegisters{ReadRegisters: q}, true, nil
+}
+
+func parseWriteRegisters(w http.ResponseWriter, r *http.Request) (events.TargetedRequest, bool, error) {
+	var q *api.WriteRegisters
+	if ok, err := parseJSONRequest(&q, w, r, 4096); ok {
+		if err != nil {
+			return nil, true, err
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, true, err
+		}
+		q = &api.WriteRegisters{ServiceID: &api.ServiceID{}}
+
+		if protocol := r.Form.Get("protocol"); protocol != "" {
+			if transport := r.Form.Get("transport"); transport != "" {
+				q.ServiceKey = &api.ServiceKey{
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
+					Entry:     r.Form.Get("entry"),
+				}
+			}
+		}
+		q.Alias = r.Form.Get("alias")
+		unit, err := strconv.ParseUint(r.Form.Get("unit"), 10, 8)
+		if err != nil {
+			return nil, true, err
+		}
+		q.Unit = uint8(unit)
+		q.Table = r.Form.Get("table")
+		address, err := strconv.ParseUint(r.Form.Get("address"), 10, 16)
+		if err != nil {
+			return nil, true, err
+		}
+		q.Address = uint16(address)
+		for _, v := range r.Form["values"] {
+			for _, vp := range strings.FieldsFunc(v, func(c rune) bool { return c == ',' || c == ';' || c == ':' || c == '|' }) {
+				n, err := strconv.ParseUint(vp, 0, 16)
 				if err != nil {
 					return nil, true, err
 				}
+				q.Values = append(q.Values, uint16(n))
+			}
+		}
+	}
+	return &handlers.WriteRegisters{WriteRegisters: q}, true, nil
+}
+
+func parseDeviceCommand(w http.ResponseWriter, r *http.Request) (events.TargetedRequest, bool, error) {
+	var q *api.DeviceCommand
+	if ok, err := parseJSONRequest(&q, w, r, 4096); ok {
+		if err != nil {
+			return nil, true, err
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, true, err
+		}
+		q = &api.DeviceCommand{ServiceID: &api.ServiceID{}}
+
+		if protocol := r.Form.Get("protocol"); protocol != "" {
+			if transport := r.Form.Get("transport"); transport != "" {
 				q.ServiceKey = &api.ServiceKey{
-					Protocol:  api.ProtocolIdentifier(pid),
-					Transport: api.TransportIdentifier(tid),
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
 					Entry:     r.Form.Get("entry"),
 				}
 			}
 		}
 		q.Alias = r.Form.Get("alias")
-		if q.Payload, err = base64.StdEncoding.DecodeString(r.Form.Get("payload")); err != nil {
+		q.Device = r.Form.Get("device")
+		q.Command = r.Form.Get("command")
+		if level := r.Form.Get("level"); level != "" {
+			v, err := strconv.ParseUint(level, 10, 8)
+			if err != nil {
+				return nil, true, err
+			}
+			q.Level = uint8(v)
+		}
+	}
+	return &handlers.DeviceCommand{DeviceCommand: q}, true, nil
+}
+
+func parseDeviceLinks(w http.ResponseWriter, r *http.Request) (events.TargetedRequest, bool, error) {
+	var q *api.ServiceID
+	if ok, err := parseJSONRequest(&q, w, r, 4096); ok {
+		if err != nil {
 			return nil, true, err
 		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, true, err
+		}
+		q = &api.ServiceID{}
+
+		if protocol := r.Form.Get("protocol"); protocol != "" {
+			if transport := r.Form.Get("transport"); transport != "" {
+				q.ServiceKey = &api.ServiceKey{
+					Protocol:  api.ProtocolIdentifier(protocol),
+					Transport: api.TransportIdentifier(transport),
+					Entry:     r.Form.Get("entry"),
+				}
+			}
+		}
+		q.Alias = r.Form.Get("alias")
 	}
-	return &handlers.SendToService{SendToService: q}, true, nil
+	return &handlers.DeviceLinks{ServiceID: q}, true, nil
 }
 
 func parseGetMessage(w http.ResponseWriter, r *http.Request) (events.TargetedRequest, bool, error) {
@@ -49,6 +49,20 @@ func queryToEvent(c *api.Query) interface{} {
 		return &handlers.ListServices{RequestHeader: *handlers.NewRequestHeader(c.ID), ListServices: payload}
 	case api.QuerySendToService:
 		return &handlers.SendToService{RequestHeader: *handlers.NewRequestHeader(c.ID), SendToService: c.Payload.(*api.SendToService)}
+	case api.QuerySendMulticast:
+		return &handlers.SendMulticast{RequestHeader: *handlers.NewRequestHeader(c.ID), SendMulticast: c.Payload.(*api.SendMulticast)}
+	case api.QueryServiceNodes:
+		return &handlers.ServiceNodes{RequestHeader: *handlers.NewRequestHeader(c.ID), ServiceID: c.Payload.(*api.ServiceID)}
+	case api.QueryServiceStatistics:
+		return &handlers.ServiceStatistics{RequestHeader: *handlers.NewRequestHeader(c.ID), ServiceID: c.Payload.(*api.ServiceID)}
+	case api.QueryReadRegisters:
+		return &handlers.ReadRegisters{RequestHeader: *handlers.NewRequestHeader(c.ID), ReadRegisters: c.Payload.(*api.ReadRegisters)}
+	case api.QueryWriteRegisters:
+		return &handlers.WriteRegisters{RequestHeader: *handlers.NewRequestHeader(c.ID), WriteRegisters: c.Payload.(*api.WriteRegisters)}
+	case api.QueryDeviceCommand:
+		return &handlers.DeviceCommand{RequestHeader: *handlers.NewRequestHeader(c.ID), DeviceCommand: c.Payload.(*api.DeviceCommand)}
+	case api.QueryDeviceLinks:
+		return &handlers.DeviceLinks{RequestHeader: *handlers.NewRequestHeader(c.ID), ServiceID: c.Payload.(*api.ServiceID)}
 	case api.QueryGetMessage:
 		return &handlers.GetMessage{RequestHeader: *handlers.NewRequestHeader(c.ID), ID: c.Payload.(uuid.UUID)}
 	case api.QueryListMessages:
@@ -86,6 +100,8 @@ func queryFromEvent(event interface{}) *api.Query {
 		return &api.Query{Type: api.QueryProtocolDiscoveryStarted, ID: e.TraceID(), Payload: e.ProtocolDiscoveryStarted}
 	case *handlers.ProtocolDiscoveryFinished:
 		return &api.Query{Type: api.QueryProtocolDiscoveryFinished, ID: e.TraceID(), Payload: e.ProtocolDiscoveryResult}
+	case *handlers.ProtocolDiscoveryProgress:
+		return &api.Query{Type: api.QueryProtocolDiscoveryProgress, ID: e.TraceID(), Payload: e.ProtocolDiscoveryProgress}
 	case *handlers.AddServiceResult:
 		return &api.Query{Type: api.QueryAddServiceResult, ID: e.TraceID(), Payload: e.StatusReply}
 	case *handlers.RemoveServiceResult:
@@ -93,15 +109,35 @@ func queryFromEvent(event interface{}) *api.Query {
 	case *handlers.ChangeServiceAliasResult:
 		return &api.Query{Type: api.QueryChangeServiceAliasResult, ID: e.TraceID(), Payload: e.StatusReply}
 	case *handlers.ServiceStatusResult:
-		return &api.Query{Type: api.QueryServiceStatusResult, ID: e.TraceID(), Payload: e.StatusReply}
+		return &api.Query{Type: api.QueryServiceStatusResult, ID: e.TraceID(), Payload: e.ServiceStatusResult}
 	case *handlers.ListServicesResult:
 		return &api.Query{Type: api.QueryListServicesResult, ID: e.TraceID(), Payload: e.ListServicesResult}
 	case *handlers.SendToServiceResult:
 		return &api.Query{Type: api.QuerySendToServiceResult, ID: e.TraceID(), Payload: e.SendToServiceResult}
+	case *handlers.SendMulticastResult:
+		return &api.Query{Type: api.QuerySendMulticastResult, ID: e.TraceID(), Payload: e.SendMulticastResult}
+	case *handlers.ServiceNodesResult:
+		return &api.Query{Type: api.QueryServiceNodesResult, ID: e.TraceID(), Payload: e.ServiceNodesResult}
+	case *handlers.ServiceStatisticsResult:
+		return &api.Query{Type: api.QueryServiceStatisticsResult, ID: e.TraceID(), Payload: e.ServiceStatisticsResult}
+	case *handlers.ReadRegistersResult:
+		return &api.Query{Type: api.QueryReadRegistersResult, ID: e.TraceID(), Payload: e.ReadRegistersResult}
+	case *handlers.WriteRegistersResult:
+		return &api.Query{Type: api.QueryWriteRegistersResult, ID: e.TraceID(), Payload: e.StatusReply}
+	case *handlers.DeviceCommandResult:
+		return &api.Query{Type: api.QueryDeviceCommandResult, ID: e.TraceID(), Payload: e.DeviceCommandResult}
+	case *handlers.DeviceLinksResult:
+		return &api.Query{Type: api.QueryDeviceLinksResult, ID: e.TraceID(), Payload: e.DeviceLinksResult}
 	case *handlers.GetMessageResult:
 		return &api.Query{Type: api.QueryGetMessageResult, ID: e.TraceID(), Payload: e.MessageEntry}
 	case *handlers.ListMessagesResult:
 		return &api.Query{Type: api.QueryListMessagesResult, ID: e.TraceID(), Payload: e.ListMessagesResult}
+	case *handlers.NodeStatistics:
+		return &api.Query{Type: api.QueryNodeStatistics, ID: e.TraceID(), Payload: e.NodeStatisticsEntry}
+	case *handlers.LowBattery:
+		return &api.Query{Type: api.QueryLowBattery, ID: e.TraceID(), Payload: e.LowBattery}
+	case *handlers.ServiceGaveUp:
+		return &api.Query{Type: api.QueryServiceGaveUp, ID: e.TraceID(), Payload: e.ServiceGaveUp}
 	case *handlers.NewMessage:
 		return &api.Query{Type: api.QueryNewMessage, ID: e.TraceID(), Payload: e.MessageEntry}
 	case *handlers.DropMessage:
@@ -114,6 +114,55 @@ func setupRoutes(mux *http.ServeMux, assets []api.HTTPAsset) {
 				})
 			},
 		},
+		{
+			"/service/multicast", func(w http.ResponseWriter, r *http.Request) {
+				handleEvents(w, r, reflect.TypeOf(&handlers.SendMulticastResult{}), func(h *http.Request) (events.TargetedRequest, bool, error) {
+					return parseSendMulticast(w, r)
+				})
+			},
+		},
+		{
+			"/service/nodes", func(w http.ResponseWriter, r *http.Request) {
+				handleEvents(w, r, reflect.TypeOf(&handlers.ServiceNodesResult{}), func(h *http.Request) (events.TargetedRequest, bool, error) {
+					return parseServiceNodes(w, r)
+				})
+			},
+		},
+		{
+			"/service/statistics", func(w http.ResponseWriter, r *http.Request) {
+				handleEvents(w, r, reflect.TypeOf(&handlers.ServiceStatisticsResult{}), func(h *http.Request) (events.TargetedRequest, bool, error) {
+					return parseServiceStatistics(w, r)
+				})
+			},
+		},
+		{
+			"/service/readRegisters", func(w http.ResponseWriter, r *http.Request) {
+				handleEvents(w, r, reflect.TypeOf(&handlers.ReadRegistersResult{}), func(h *http.Request) (events.TargetedRequest, bool, error) {
+					return parseReadRegisters(w, r)
+				})
+			},
+		},
+		{
+			"/service/writeRegisters", func(w http.ResponseWriter, r *http.Request) {
+				handleEvents(w, r, reflect.TypeOf(&handlers.WriteRegistersResult{}), func(h *http.Request) (events.TargetedRequest, bool, error) {
+					return parseWriteRegisters(w, r)
+				})
+			},
+		},
+		{
+			"/service/deviceCommand", func(w http.ResponseWriter, r *http.Request) {
+				handleEvents(w, r, reflect.TypeOf(&handlers.DeviceCommandResult{}), func(h *http.Request) (events.TargetedRequest, bool, error) {
+					return parseDeviceCommand(w, r)
+				})
+			},
+		},
+		{
+			"/service/deviceLinks", func(w http.ResponseWriter, r *http.Request) {
+				handleEvents(w, r, reflect.TypeOf(&handlers.DeviceLinksResult{}), func(h *http.Request) (events.TargetedRequest, bool, error) {
+					return parseDeviceLinks(w, r)
+				})
+			},
+		},
 		{
 			"/messages/get", func(w http.ResponseWriter, r *http.Request) {
 				handleEvents(w, r, reflect.TypeOf(&handlers.GetMessageResult{}), func(h *http.Request) (events.TargetedRequest, bool, error) {
@@ -14,6 +14,10 @@ var subscriptionEventTypeMap = map[api.SubscriptionEvent]reflect.Type{
 	api.EventNewMessage:         reflect.TypeOf(&handlers.NewMessage{}),
 	api.EventDropMessage:        reflect.TypeOf(&handlers.DropMessage{}),
 	api.EventUpdateMessageState: reflect.TypeOf(&handlers.UpdateMessageState{}),
+	api.EventNodeStatistics:     reflect.TypeOf(&handlers.NodeStatistics{}),
+	api.EventLowBattery:         reflect.TypeOf(&handlers.LowBattery{}),
+	api.EventServiceGaveUp:      reflect.TypeOf(&handlers.ServiceGaveUp{}),
+	api.EventDiscoveryProgress:  reflect.TypeOf(&handlers.ProtocolDiscoveryProgress{}),
 }
 
 type socketSubscription struct {
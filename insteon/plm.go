@@ -0,0 +1,299 @@
+package insteon
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PLM (PowerLinc Modem) serial frame structure:
+//   STX byte
+//   Command code byte
+//   ... Command parameters, the length is defined by the command code
+// The commands sent by the host are echoed by PLM, the echo is followed by ACK or NAK byte.
+
+// Frame bytes
+const (
+	// Start of text, the beginning of the frame
+	STX = 0x02
+	// The command is accepted by PLM
+	ACK = 0x06
+	// The command is not accepted by PLM, it is busy or the command is not valid
+	NAK = 0x15
+)
+
+// PLM command codes
+const (
+	// received from PLM
+	StandardMessageReceived = 0x50
+	ExtendedMessageReceived = 0x51
+	X10Received             = 0x52
+	AllLinkingCompleted     = 0x53
+	ButtonEventReport       = 0x54
+	UserResetDetected       = 0x55
+	AllLinkCleanupFailure   = 0x56
+	AllLinkRecordResponse   = 0x57
+	AllLinkCleanupStatus    = 0x58
+
+	// sent to PLM
+	GetIMInfo                 = 0x60
+	SendAllLinkCommand        = 0x61
+	SendMessage               = 0x62
+	SendX10                   = 0x63
+	StartAllLinking           = 0x64
+	CancelAllLinking          = 0x65
+	SetHostDeviceCategory     = 0x66
+	ResetIM                   = 0x67
+	SetAckMessageByte         = 0x68
+	GetFirstAllLinkRecord     = 0x69
+	GetNextAllLinkRecord      = 0x6a
+	SetIMConfiguration        = 0x6b
+	GetAllLinkRecordForSender = 0x6c
+	LEDOn                     = 0x6d
+	LEDOff                    = 0x6e
+	ManageAllLinkRecord       = 0x6f
+	SetNakMessageByte         = 0x70
+	SetAckMessageTwoBytes     = 0x71
+	RFSleep                   = 0x72
+	GetIMConfiguration        = 0x73
+)
+
+// frameLengths contains the frame length, including STX and the command code, of the frames received from PLM.
+// The echo of the command sent to PLM includes ACK or NAK byte.
+var frameLengths = map[byte]int{
+	StandardMessageReceived: 11,
+	ExtendedMessageReceived: 25,
+	X10Received:             4,
+	AllLinkingCompleted:     10,
+	ButtonEventReport:       3,
+	UserResetDetected:       2,
+	AllLinkCleanupFailure:   7,
+	AllLinkRecordResponse:   10,
+	AllLinkCleanupStatus:    3,
+
+	GetIMInfo:                 9,
+	SendAllLinkCommand:        6,
+	SendMessage:               9, // 23 for the extended message
+	SendX10:                   5,
+	StartAllLinking:           5,
+	CancelAllLinking:          3,
+	SetHostDeviceCategory:     6,
+	ResetIM:                   3,
+	SetAckMessageByte:         4,
+	GetFirstAllLinkRecord:     3,
+	GetNextAllLinkRecord:      3,
+	SetIMConfiguration:        4,
+	GetAllLinkRecordForSender: 3,
+	LEDOn:                     3,
+	LEDOff:                    3,
+	ManageAllLinkRecord:       12,
+	SetNakMessageByte:         4,
+	SetAckMessageTwoBytes:     5,
+	RFSleep:                   3,
+	GetIMConfiguration:        6,
+}
+
+// FrameLength returns the length of the frame received from PLM: -1 if the frame is not valid, 0 if more data is needed to determine the length
+func FrameLength(data []byte) int {
+	if len(data) < 2 {
+		if len(data) == 1 && data[0] != STX {
+			return -1
+		}
+		return 0
+	}
+	if data[0] != STX {
+		return -1
+	}
+	length, ok := frameLengths[data[1]]
+	if !ok {
+		return -1
+	}
+	if data[1] == SendMessage {
+		if len(data) < 6 {
+			return 0
+		}
+		if Flags(data[5]).Extended() {
+			length = 23
+		}
+	}
+	return length
+}
+
+// CommandLength returns the length of the command sent to PLM, it is the echo length without ACK or NAK byte
+func CommandLength(data []byte) int {
+	length := FrameLength(data)
+	if length <= 0 || data[1] < GetIMInfo {
+		return -1
+	}
+	return length - 1
+}
+
+// Address is Insteon device address
+type Address [3]byte
+
+// ErrBadAddress is returned if the device address is not valid
+var ErrBadAddress = errors.New("the device address is not valid")
+
+// ParseAddress parses the device address in AA.BB.CC, AABBCC, or AA:BB:CC format
+func ParseAddress(s string) (a Address, err error) {
+	s = strings.NewReplacer(".", "", ":", "", " ", "").Replace(s)
+	if len(s) != 6 {
+		return a, ErrBadAddress
+	}
+	for i := range a {
+		v, err := strconv.ParseUint(s[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return a, ErrBadAddress
+		}
+		a[i] = byte(v)
+	}
+	return a, nil
+}
+
+func (a Address) String() string {
+	return fmt.Sprintf("%02X.%02X.%02X", a[0], a[1], a[2])
+}
+
+// Flags is Insteon message flags byte:
+//
+//	bits 7-5 message type
+//	bit 4 extended message
+//	bits 3-2 hops left
+//	bits 1-0 maximal hops
+type Flags byte
+
+// Message types
+const (
+	MessageDirect            = 0x00
+	MessageDirectAck         = 0x01
+	MessageAllLinkCleanup    = 0x02
+	MessageAllLinkCleanupAck = 0x03
+	MessageBroadcast         = 0x04
+	MessageDirectNak         = 0x05
+	MessageAllLinkBroadcast  = 0x06
+	MessageAllLinkCleanupNak = 0x07
+)
+
+// DirectFlags the flags of the direct standard message with 3 hops
+const DirectFlags = Flags(0x0f)
+
+// DirectExtendedFlags the flags of the direct extended message with 3 hops
+const DirectExtendedFlags = Flags(0x1f)
+
+// Type returns the message type
+func (f Flags) Type() byte {
+	return byte(f) >> 5
+}
+
+// Extended returns true if the message is extended
+func (f Flags) Extended() bool {
+	return f&0x10 != 0
+}
+
+// Device commands (cmd1)
+const (
+	CmdEngineVersion = 0x0d
+	CmdPing          = 0x0f
+	CmdIDRequest     = 0x10
+	CmdOn            = 0x11
+	CmdFastOn        = 0x12
+	CmdOff           = 0x13
+	CmdFastOff       = 0x14
+	CmdStatusRequest = 0x19
+)
+
+// Message is Insteon message
+type Message struct {
+	From  Address
+	To    Address
+	Flags Flags
+	Cmd1  byte
+	Cmd2  byte
+	Data  []byte // the user data of the extended message, 14 bytes
+}
+
+// StandardMessage creates the command which sends the standard message to the device
+func StandardMessage(to Address, flags Flags, cmd1, cmd2 byte) []byte {
+	return []byte{STX, SendMessage, to[0], to[1], to[2], byte(flags &^ 0x10), cmd1, cmd2}
+}
+
+// ExtendedMessage creates the command which sends the extended message to the device, the last byte of the user data is the checksum
+func ExtendedMessage(to Address, flags Flags, cmd1, cmd2 byte, data [13]byte) []byte {
+	frame := []byte{STX, SendMessage, to[0], to[1], to[2], byte(flags | 0x10), cmd1, cmd2}
+	frame = append(frame, data[:]...)
+	return append(frame, Checksum(cmd1, cmd2, data[:]))
+}
+
+// Checksum calculates the extended message checksum: two's complement of the sum of the commands and the user data
+func Checksum(cmd1, cmd2 byte, data []byte) byte {
+	sum := cmd1 + cmd2
+	for _, b := range data {
+		sum += b
+	}
+	return -sum
+}
+
+// ParseMessage parses the standard or extended message received frame
+func ParseMessage(frame []byte) (*Message, bool) {
+	if len(frame) < 11 || frame[0] != STX || (frame[1] != StandardMessageReceived && frame[1] != ExtendedMessageReceived) {
+		return nil, false
+	}
+	m := &Message{Flags: Flags(frame[8]), Cmd1: frame[9], Cmd2: frame[10]}
+	copy(m.From[:], frame[2:5])
+	copy(m.To[:], frame[5:8])
+	if frame[1] == ExtendedMessageReceived {
+		if len(frame) < 25 {
+			return nil, false
+		}
+		m.Data = frame[11:25]
+	}
+	return m, true
+}
+
+// IMInfo is the information about PLM
+type IMInfo struct {
+	Address     Address
+	Category    byte
+	Subcategory byte
+	Firmware    byte
+}
+
+// ParseIMInfo parses the echo of get IM info command
+func ParseIMInfo(frame []byte) (*IMInfo, bool) {
+	if len(frame) < 9 || frame[0] != STX || frame[1] != GetIMInfo || frame[8] != ACK {
+		return nil, false
+	}
+	info := &IMInfo{Category: frame[5], Subcategory: frame[6], Firmware: frame[7]}
+	copy(info.Address[:], frame[2:5])
+	return info, true
+}
+
+// AllLinkRecord is the record of PLM ALL-Link database
+type AllLinkRecord struct {
+	Flags   byte
+	Group   byte
+	Address Address
+	Data    [3]byte
+}
+
+// InUse returns true if the record is used
+func (r *AllLinkRecord) InUse() bool {
+	return r.Flags&0x80 != 0
+}
+
+// Controller returns true if PLM is the controller of the link, otherwise it is the responder
+func (r *AllLinkRecord) Controller() bool {
+	return r.Flags&0x40 != 0
+}
+
+// ParseAllLinkRecord parses ALL-Link record response frame
+func ParseAllLinkRecord(frame []byte) (*AllLinkRecord, bool) {
+	if len(frame) < 10 || frame[0] != STX || frame[1] != AllLinkRecordResponse {
+		return nil, false
+	}
+	r := &AllLinkRecord{Flags: frame[2], Group: frame[3]}
+	copy(r.Address[:], frame[4:7])
+	copy(r.Data[:], frame[7:10])
+	return r, true
+}
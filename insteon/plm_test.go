@@ -0,0 +1,111 @@
+package insteon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAddress(t *testing.T) {
+	for _, s := range []string{"1A.2B.3C", "1a2b3c", "1A:2B:3C"} {
+		a, err := ParseAddress(s)
+		if err != nil || a != (Address{0x1a, 0x2b, 0x3c}) {
+			t.Errorf("Unexpected address %v, error %v for %s", a, err, s)
+		}
+		if a.String() != "1A.2B.3C" {
+			t.Errorf("Unexpected address string %s", a.String())
+		}
+	}
+	for _, s := range []string{"", "1A.2B", "1A.2B.3G", "1A.2B.3C.4D"} {
+		if _, err := ParseAddress(s); err != ErrBadAddress {
+			t.Errorf("The address %s must not be valid", s)
+		}
+	}
+}
+
+func TestStandardMessage(t *testing.T) {
+	to := Address{0x1a, 0x2b, 0x3c}
+	frame := StandardMessage(to, DirectFlags, CmdOn, 0xff)
+	if !bytes.Equal(frame, []byte{0x02, 0x62, 0x1a, 0x2b, 0x3c, 0x0f, 0x11, 0xff}) {
+		t.Errorf("Unexpected standard message %x", frame)
+	}
+	if n := CommandLength(frame); n != len(frame) {
+		t.Errorf("Unexpected command length %d", n)
+	}
+	if n := FrameLength(append(frame, ACK)); n != len(frame)+1 {
+		t.Errorf("Unexpected echo length %d", n)
+	}
+	for i := 0; i < 6; i++ {
+		if n := FrameLength(frame[:i]); n != 0 {
+			t.Errorf("The length of %d bytes must be unknown, got %d", i, n)
+		}
+	}
+	if n := FrameLength([]byte{0x06}); n != -1 {
+		t.Errorf("The frame without STX must not be valid, got %d", n)
+	}
+	if n := FrameLength([]byte{STX, 0x42}); n != -1 {
+		t.Errorf("Unknown command must not be recognized, got %d", n)
+	}
+}
+
+func TestExtendedMessage(t *testing.T) {
+	// read the operating flags, the checksum of 0x2e 0x00 0x01 is 0xd1
+	frame := ExtendedMessage(Address{0x1a, 0x2b, 0x3c}, DirectExtendedFlags, 0x2e, 0x00, [13]byte{0x01})
+	if len(frame) != 22 || frame[5] != 0x1f || frame[21] != 0xd1 {
+		t.Errorf("Unexpected extended message %x", frame)
+	}
+	if n := CommandLength(frame); n != len(frame) {
+		t.Errorf("Unexpected command length %d", n)
+	}
+	if c := Checksum(0x2e, 0x00, frame[8:21]); c != 0xd1 {
+		t.Errorf("Unexpected checksum %02x", c)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	// the direct ACK of ON command with level 0x80
+	frame := []byte{0x02, 0x50, 0x1a, 0x2b, 0x3c, 0x11, 0x22, 0x33, 0x2b, 0x11, 0x80}
+	if n := FrameLength(frame); n != len(frame) {
+		t.Errorf("Unexpected frame length %d", n)
+	}
+	m, ok := ParseMessage(frame)
+	if !ok {
+		t.Fatal("The message must be parsed")
+	}
+	if m.From != (Address{0x1a, 0x2b, 0x3c}) || m.To != (Address{0x11, 0x22, 0x33}) || m.Cmd1 != CmdOn || m.Cmd2 != 0x80 || m.Data != nil {
+		t.Errorf("Unexpected message %+v", m)
+	}
+	if m.Flags.Type() != MessageDirectAck || m.Flags.Extended() {
+		t.Errorf("Unexpected flags %02x", m.Flags)
+	}
+	if _, ok := ParseMessage(frame[:10]); ok {
+		t.Error("The truncated message must not be parsed")
+	}
+
+	extended := append([]byte{0x02, 0x51, 0x1a, 0x2b, 0x3c, 0x11, 0x22, 0x33, 0x1b, 0x2e, 0x00}, make([]byte, 14)...)
+	if m, ok = ParseMessage(extended); !ok || !m.Flags.Extended() || len(m.Data) != 14 {
+		t.Errorf("Unexpected extended message %+v", m)
+	}
+}
+
+func TestParseIMInfo(t *testing.T) {
+	info, ok := ParseIMInfo([]byte{0x02, 0x60, 0x11, 0x22, 0x33, 0x03, 0x15, 0x9e, ACK})
+	if !ok || info.Address != (Address{0x11, 0x22, 0x33}) || info.Category != 0x03 || info.Subcategory != 0x15 || info.Firmware != 0x9e {
+		t.Errorf("Unexpected IM info %+v", info)
+	}
+	if _, ok := ParseIMInfo([]byte{0x02, 0x60, 0x11, 0x22, 0x33, 0x03, 0x15, 0x9e, NAK}); ok {
+		t.Error("The NAK must not be parsed")
+	}
+}
+
+func TestParseAllLinkRecord(t *testing.T) {
+	r, ok := ParseAllLinkRecord([]byte{0x02, 0x57, 0xe2, 0x01, 0x1a, 0x2b, 0x3c, 0x01, 0x20, 0x41})
+	if !ok {
+		t.Fatal("The record must be parsed")
+	}
+	if !r.InUse() || !r.Controller() || r.Group != 1 || r.Address != (Address{0x1a, 0x2b, 0x3c}) || r.Data != [3]byte{0x01, 0x20, 0x41} {
+		t.Errorf("Unexpected record %+v", r)
+	}
+	if r, _ = ParseAllLinkRecord([]byte{0x02, 0x57, 0xa2, 0x00, 0x1a, 0x2b, 0x3c, 0x00, 0x00, 0x00}); r.Controller() {
+		t.Error("The record must be the responder")
+	}
+}
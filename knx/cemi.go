@@ -0,0 +1,176 @@
+package knx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// cEMI L_Data frame structure:
+//   Message code
+//   Additional information length
+//   ... Additional information
+//   Control field 1
+//   Control field 2: destination address type (bit 7), hop count (bits 6-4), extended frame format (bits 3-0)
+//   Source individual address, 2 bytes
+//   Destination group or individual address, 2 bytes
+//   NPDU length, the number of bytes after TPCI
+//   TPCI/APCI, 2 bytes: TPCI (bits 15-10) and APCI (bits 9-0), the data up to 6 bits is encoded in APCI
+//   ... Data
+
+// cEMI message codes
+const (
+	L_DATA_REQ = 0x11
+	L_DATA_CON = 0x2e
+	L_DATA_IND = 0x29
+)
+
+// APCI group value services
+const (
+	GroupValueRead     = 0x000
+	GroupValueResponse = 0x040
+	GroupValueWrite    = 0x080
+)
+
+// default control fields: standard frame, no repeat, broadcast, low priority; group address, hop count 6
+const (
+	defaultControl1 = 0xbc
+	defaultControl2 = 0xe0
+)
+
+// control field 1 bit which signals the confirmation error of L_Data.con frame
+const control1ConfirmError = 0x01
+
+// ErrBadAddress is returned if the address is not valid
+var ErrBadAddress = errors.New("the address is not valid")
+
+// GroupAddress is KNX group address in 3-level format main/middle/sub (5/3/8 bits)
+type GroupAddress uint16
+
+// ParseGroupAddress parses the group address in 3-level (1/2/3), 2-level (1/515) or free (2563) format
+func ParseGroupAddress(s string) (GroupAddress, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) > 3 {
+		return 0, ErrBadAddress
+	}
+	limits := [][]uint64{{0xffff}, {0x1f, 0x7ff}, {0x1f, 0x7, 0xff}}[len(parts)-1]
+	var a uint64
+	for i, part := range parts {
+		v, err := strconv.ParseUint(part, 10, 16)
+		if err != nil || v > limits[i] {
+			return 0, ErrBadAddress
+		}
+		a = a<<bits.Len64(limits[i]) | v
+	}
+	return GroupAddress(a), nil
+}
+
+func (a GroupAddress) String() string {
+	return fmt.Sprintf("%d/%d/%d", a>>11, (a>>8)&0x7, a&0xff)
+}
+
+// IndividualAddress is KNX individual (physical) address area.line.device (4/4/8 bits)
+type IndividualAddress uint16
+
+// ParseIndividualAddress parses the individual address in area.line.device format
+func ParseIndividualAddress(s string) (IndividualAddress, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return 0, ErrBadAddress
+	}
+	var a uint64
+	for i, limit := range []uint64{0xf, 0xf, 0xff} {
+		v, err := strconv.ParseUint(parts[i], 10, 8)
+		if err != nil || v > limit {
+			return 0, ErrBadAddress
+		}
+		a = a<<bits.Len64(limit) | v
+	}
+	return IndividualAddress(a), nil
+}
+
+func (a IndividualAddress) String() string {
+	return fmt.Sprintf("%d.%d.%d", a>>12, (a>>8)&0xf, a&0xff)
+}
+
+// LData is cEMI L_Data frame
+type LData struct {
+	MessageCode uint8
+	Control1    uint8
+	Control2    uint8
+	Source      IndividualAddress
+	Destination uint16 // GroupAddress or IndividualAddress, depending on Group
+	Group       bool   // the destination is the group address
+	APCI        uint16
+	Data        []byte // the data after APCI, or the 6 bits encoded in APCI if the data is short
+	Short       bool   // the data is encoded in APCI
+}
+
+// GroupData creates cEMI L_Data.req frame of the group value service. The short data (up to 6 bits, like DPT 1.x) is encoded into APCI
+func GroupData(destination GroupAddress, apci uint16, data []byte, short bool) []byte {
+	frame := []byte{L_DATA_REQ, 0, defaultControl1, defaultControl2, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(frame[6:], uint16(destination))
+	binary.BigEndian.PutUint16(frame[9:], apci&0x3ff)
+	if short {
+		if len(data) > 0 {
+			frame[10] |= data[0] & 0x3f
+		}
+		frame[8] = 1
+		return frame
+	}
+	frame[8] = byte(1 + len(data))
+	return append(frame, data...)
+}
+
+// ParseLData parses cEMI L_Data frame
+func ParseLData(cemi []byte) (*LData, bool) {
+	if len(cemi) < 2 {
+		return nil, false
+	}
+	f := &LData{MessageCode: cemi[0]}
+	if f.MessageCode != L_DATA_REQ && f.MessageCode != L_DATA_CON && f.MessageCode != L_DATA_IND {
+		return nil, false
+	}
+	body := cemi[2+int(cemi[1]):]
+	if len(body) < 9 {
+		return nil, false
+	}
+	f.Control1, f.Control2 = body[0], body[1]
+	f.Source = IndividualAddress(binary.BigEndian.Uint16(body[2:]))
+	f.Destination = binary.BigEndian.Uint16(body[4:])
+	f.Group = f.Control2&0x80 != 0
+	length := int(body[6])
+	if len(body) != 8+length {
+		return nil, false
+	}
+	tpdu := binary.BigEndian.Uint16(body[7:])
+	f.APCI = tpdu & 0x3ff
+	if length == 1 {
+		f.Short = true
+		f.Data = []byte{byte(f.APCI & 0x3f)}
+		f.APCI &= 0x3c0
+	} else {
+		f.Data = body[9:]
+	}
+	return f, true
+}
+
+// Confirmed returns true if L_Data.con frame confirms the successful transmission
+func (f *LData) Confirmed() bool {
+	return f.MessageCode == L_DATA_CON && f.Control1&control1ConfirmError == 0
+}
+
+// GroupService returns the group value service (GroupValueRead, GroupValueResponse, or GroupValueWrite) and true if the frame is group value service
+func (f *LData) GroupService() (uint16, bool) {
+	if !f.Group {
+		return 0, false
+	}
+	switch apci := f.APCI & 0x3c0; apci {
+	case GroupValueRead, GroupValueResponse, GroupValueWrite:
+		return apci, true
+	}
+	return 0, false
+}
@@ -0,0 +1,245 @@
+package knx
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Datapoint types (DPT) are identified by main number and subtype, like 9.001 (temperature, °C).
+// The main number defines the encoding, the subtype defines the meaning and the unit
+
+// DPT errors
+var (
+	ErrDPTNotSupported = errors.New("the datapoint type is not supported")
+	ErrDPTBadData      = errors.New("the data does not match the datapoint type")
+	ErrDPTBadValue     = errors.New("the value is out of the datapoint type range")
+)
+
+// dptUnits the units of the common datapoint subtypes
+var dptUnits = map[string]string{
+	"5.001":  "%",
+	"5.003":  "°",
+	"7.007":  "h",
+	"7.013":  "lx",
+	"9.001":  "°C",
+	"9.002":  "K",
+	"9.004":  "lx",
+	"9.005":  "m/s",
+	"9.006":  "Pa",
+	"9.007":  "%",
+	"9.008":  "ppm",
+	"9.020":  "mV",
+	"9.021":  "mA",
+	"9.024":  "kW",
+	"12.001": "pulses",
+	"13.010": "Wh",
+	"13.013": "kWh",
+	"14.019": "A",
+	"14.027": "V",
+	"14.056": "W",
+}
+
+// DPTMain returns the main number of the datapoint type
+func DPTMain(dpt string) (int, error) {
+	main, _, _ := strings.Cut(dpt, ".")
+	n, err := strconv.Atoi(main)
+	if err != nil {
+		return 0, ErrDPTNotSupported
+	}
+	return n, nil
+}
+
+// DPTShort returns true if the datapoint type values are encoded in APCI (6 bits or less)
+func DPTShort(dpt string) bool {
+	main, _ := DPTMain(dpt)
+	return main == 1 || main == 2 || main == 3
+}
+
+// DecodeDPT decodes the data of the group value service as the datapoint type value: bool for DPT 1.x, string for DPT 16.x,
+// float64 for DPT 9.x, 14.x and scaled DPT 5.001, 5.003, int64 for other numeric types
+func DecodeDPT(dpt string, data []byte) (interface{}, error) {
+	main, err := DPTMain(dpt)
+	if err != nil {
+		return nil, err
+	}
+	size := 0
+	switch main {
+	case 1, 2, 3, 5, 6, 17:
+		size = 1
+	case 7, 8, 9:
+		size = 2
+	case 12, 13, 14:
+		size = 4
+	case 16:
+		size = 14
+	default:
+		return nil, ErrDPTNotSupported
+	}
+	if len(data) != size {
+		return nil, ErrDPTBadData
+	}
+
+	switch main {
+	case 1:
+		return data[0]&1 != 0, nil
+	case 2, 3:
+		return int64(data[0] & 0xf), nil
+	case 5:
+		switch dpt {
+		case "5.001":
+			return math.Round(float64(data[0])*10000/255) / 100, nil
+		case "5.003":
+			return math.Round(float64(data[0])*36000/255) / 100, nil
+		}
+		return int64(data[0]), nil
+	case 6:
+		return int64(int8(data[0])), nil
+	case 7:
+		return int64(binary.BigEndian.Uint16(data)), nil
+	case 8:
+		return int64(int16(binary.BigEndian.Uint16(data))), nil
+	case 9:
+		return decodeFloat16(binary.BigEndian.Uint16(data)), nil
+	case 12:
+		return int64(binary.BigEndian.Uint32(data)), nil
+	case 13:
+		return int64(int32(binary.BigEndian.Uint32(data))), nil
+	case 14:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 16:
+		return strings.TrimRight(string(data), "\x00"), nil
+	case 17:
+		return int64(data[0] & 0x3f), nil
+	}
+	return nil, ErrDPTNotSupported
+}
+
+// EncodeDPT encodes the numeric value (0 or 1 for DPT 1.x) as the datapoint type data, returns the data and true if the data must be encoded in APCI
+func EncodeDPT(dpt string, value float64) ([]byte, bool, error) {
+	main, err := DPTMain(dpt)
+	if err != nil {
+		return nil, false, err
+	}
+	inRange := func(min, max float64) bool {
+		return value >= min && value <= max
+	}
+	switch main {
+	case 1:
+		if value != 0 && value != 1 {
+			return nil, false, ErrDPTBadValue
+		}
+		return []byte{byte(value)}, true, nil
+	case 5:
+		switch dpt {
+		case "5.001":
+			if !inRange(0, 100) {
+				return nil, false, ErrDPTBadValue
+			}
+			value = math.Round(value * 255 / 100)
+		case "5.003":
+			if !inRange(0, 360) {
+				return nil, false, ErrDPTBadValue
+			}
+			value = math.Round(value * 255 / 360)
+		}
+		if !inRange(0, math.MaxUint8) {
+			return nil, false, ErrDPTBadValue
+		}
+		return []byte{byte(value)}, false, nil
+	case 6:
+		if !inRange(math.MinInt8, math.MaxInt8) {
+			return nil, false, ErrDPTBadValue
+		}
+		return []byte{byte(int8(value))}, false, nil
+	case 7:
+		if !inRange(0, math.MaxUint16) {
+			return nil, false, ErrDPTBadValue
+		}
+		return binary.BigEndian.AppendUint16(nil, uint16(value)), false, nil
+	case 8:
+		if !inRange(math.MinInt16, math.MaxInt16) {
+			return nil, false, ErrDPTBadValue
+		}
+		return binary.BigEndian.AppendUint16(nil, uint16(int16(value))), false, nil
+	case 9:
+		v, ok := encodeFloat16(value)
+		if !ok {
+			return nil, false, ErrDPTBadValue
+		}
+		return binary.BigEndian.AppendUint16(nil, v), false, nil
+	case 12:
+		if !inRange(0, math.MaxUint32) {
+			return nil, false, ErrDPTBadValue
+		}
+		return binary.BigEndian.AppendUint32(nil, uint32(value)), false, nil
+	case 13:
+		if !inRange(math.MinInt32, math.MaxInt32) {
+			return nil, false, ErrDPTBadValue
+		}
+		return binary.BigEndian.AppendUint32(nil, uint32(int32(value))), false, nil
+	case 14:
+		return binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(value))), false, nil
+	case 17:
+		if !inRange(0, 63) {
+			return nil, false, ErrDPTBadValue
+		}
+		return []byte{byte(value)}, false, nil
+	}
+	return nil, false, ErrDPTNotSupported
+}
+
+// FormatDPT decodes the data and formats the value along with the unit of the datapoint subtype
+func FormatDPT(dpt string, data []byte) (string, error) {
+	v, err := DecodeDPT(dpt, data)
+	if err != nil {
+		return "", err
+	}
+	var s string
+	switch v := v.(type) {
+	case bool:
+		s = strconv.FormatBool(v)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v, nil
+	}
+	if unit, ok := dptUnits[dpt]; ok {
+		s += " " + unit
+	}
+	return s, nil
+}
+
+// decodeFloat16 decodes KNX 2-byte float: MEEEEMMM MMMMMMMM, the value is 0.01 * M * 2^E, M is 12-bit two's complement mantissa
+func decodeFloat16(v uint16) float64 {
+	mantissa := int(v & 0x07ff)
+	if v&0x8000 != 0 {
+		mantissa -= 0x800
+	}
+	exponent := int(v>>11) & 0xf
+	return math.Round(float64(mantissa)*float64(int(1)<<exponent)) / 100
+}
+
+// encodeFloat16 encodes KNX 2-byte float, returns false if the value is out of range
+func encodeFloat16(value float64) (uint16, bool) {
+	if value < -671088.64 || value > 670760.96 {
+		return 0, false
+	}
+	mantissa := math.Round(value * 100)
+	exponent := 0
+	for mantissa < -2048 || mantissa > 2047 {
+		exponent++
+		mantissa = math.Round(value * 100 / float64(int(1)<<exponent))
+	}
+	m := int(mantissa)
+	var v uint16
+	if m < 0 {
+		v = 0x8000
+		m += 0x800
+	}
+	return v | uint16(exponent)<<11 | uint16(m&0x7ff), true
+}
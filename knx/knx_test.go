@@ -0,0 +1,169 @@
+package knx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+)
+
+func TestAddresses(t *testing.T) {
+	for s, expected := range map[string]GroupAddress{"1/2/3": 0x0a03, "31/7/255": 0xffff, "1/515": 0x0a03, "2563": 0x0a03} {
+		if a, err := ParseGroupAddress(s); err != nil || a != expected {
+			t.Errorf("Unexpected group address %04x of %s, error %v", a, s, err)
+		}
+	}
+	for _, s := range []string{"", "32/0/0", "1/8/0", "1/2/256", "1/2/3/4", "1/2048", "a/b/c"} {
+		if _, err := ParseGroupAddress(s); err != ErrBadAddress {
+			t.Errorf("The group address %s must not be valid", s)
+		}
+	}
+	if s := GroupAddress(0x0a03).String(); s != "1/2/3" {
+		t.Errorf("Unexpected group address string %s", s)
+	}
+
+	if a, err := ParseIndividualAddress("1.1.5"); err != nil || a != 0x1105 || a.String() != "1.1.5" {
+		t.Errorf("Unexpected individual address %04x, error %v", a, err)
+	}
+	for _, s := range []string{"1.1", "16.0.0", "1.1.256"} {
+		if _, err := ParseIndividualAddress(s); err != ErrBadAddress {
+			t.Errorf("The individual address %s must not be valid", s)
+		}
+	}
+}
+
+func TestLData(t *testing.T) {
+	// GroupValueWrite of "on" to 1/2/3 from 1.1.5
+	cemi, _ := hex.DecodeString("2900bce011050a03010081")
+	f, ok := ParseLData(cemi)
+	if !ok {
+		t.Fatal("The frame must be parsed")
+	}
+	if service, ok := f.GroupService(); !ok || service != GroupValueWrite {
+		t.Errorf("Unexpected group service %03x", service)
+	}
+	if f.MessageCode != L_DATA_IND || f.Source != 0x1105 || GroupAddress(f.Destination) != 0x0a03 || !f.Short || !bytes.Equal(f.Data, []byte{1}) {
+		t.Errorf("Unexpected frame %+v", f)
+	}
+
+	request := GroupData(0x0a03, GroupValueWrite, []byte{1}, true)
+	if hex.EncodeToString(request) != "1100bce000000a03010081" {
+		t.Errorf("Unexpected group write %x", request)
+	}
+	request = GroupData(0x0a03, GroupValueWrite, []byte{0x0c, 0x33}, false)
+	if f, ok = ParseLData(request); !ok || f.Short || !bytes.Equal(f.Data, []byte{0x0c, 0x33}) || f.APCI != GroupValueWrite {
+		t.Errorf("Unexpected long group write %+v", f)
+	}
+
+	// the confirmation with additional information
+	con, _ := hex.DecodeString("2e020301bce011050a03010080")
+	if f, ok = ParseLData(con); !ok || !f.Confirmed() {
+		t.Errorf("Unexpected confirmation %+v", f)
+	}
+	if _, ok = ParseLData(cemi[:10]); ok {
+		t.Error("The truncated frame must not be parsed")
+	}
+}
+
+func TestDPT(t *testing.T) {
+	tests := []struct {
+		dpt   string
+		data  string
+		value interface{}
+		text  string
+	}{
+		{"1.001", "01", true, "true"},
+		{"5.001", "ff", 100.0, "100 %"},
+		{"5.001", "80", 50.2, "50.2 %"},
+		{"5.010", "2a", int64(42), "42"},
+		{"6.010", "fe", int64(-2), "-2"},
+		{"7.001", "1234", int64(0x1234), "4660"},
+		{"8.001", "ff9c", int64(-100), "-100"},
+		{"9.001", "0c33", 21.5, "21.5 °C"},
+		{"9.001", "8a24", -30.0, "-30 °C"},
+		{"12.001", "00010000", int64(65536), "65536 pulses"},
+		{"13.010", "fffffc18", int64(-1000), "-1000 Wh"},
+		{"14.056", "42c80000", 100.0, "100 W"},
+		{"16.000", "4b4e5820697320636f6f6c0000", "KNX is cool", "KNX is cool"},
+		{"17.001", "05", int64(5), "5"},
+	}
+	for _, test := range tests {
+		data, _ := hex.DecodeString(test.data)
+		if test.dpt == "16.000" {
+			data = append(data, 0) // 14 bytes
+		}
+		v, err := DecodeDPT(test.dpt, data)
+		if err != nil || v != test.value {
+			t.Errorf("%s: unexpected value %v (%T), error %v", test.dpt, v, v, err)
+		}
+		if s, err := FormatDPT(test.dpt, data); err != nil || s != test.text {
+			t.Errorf("%s: unexpected text %s, error %v", test.dpt, s, err)
+		}
+	}
+
+	if _, err := DecodeDPT("9.001", []byte{1}); err != ErrDPTBadData {
+		t.Errorf("Bad data error expected, got %v", err)
+	}
+	if _, err := DecodeDPT("232.600", []byte{1, 2, 3}); err != ErrDPTNotSupported {
+		t.Errorf("Not supported error expected, got %v", err)
+	}
+
+	for _, test := range []struct {
+		dpt   string
+		value float64
+		data  string
+		short bool
+	}{
+		{"1.001", 1, "01", true},
+		{"5.001", 50, "80", false},
+		{"9.001", 21.5, "0c33", false},
+		{"9.001", -30, "8a24", false},
+		{"9.004", 670760.96, "7fff", false},
+		{"13.010", -1000, "fffffc18", false},
+	} {
+		data, short, err := EncodeDPT(test.dpt, test.value)
+		if err != nil || hex.EncodeToString(data) != test.data || short != test.short {
+			t.Errorf("%s: unexpected data %x of %v, error %v", test.dpt, data, test.value, err)
+		}
+	}
+	if _, _, err := EncodeDPT("5.001", 101); err != ErrDPTBadValue {
+		t.Errorf("Bad value error expected, got %v", err)
+	}
+}
+
+func TestKNXnetIP(t *testing.T) {
+	control := HPAI{IP: net.IPv4(192, 168, 1, 10), Port: 3671}
+	frame := ConnectRequest(HPAI{}, HPAI{})
+	if hex.EncodeToString(frame) != "06100205001a0801000000000000080100000000000004040200" {
+		t.Errorf("Unexpected connect request %x", frame)
+	}
+	if n := FrameLength(frame[:HeaderLength]); n != len(frame) {
+		t.Errorf("Unexpected frame length %d", n)
+	}
+	if n := FrameLength([]byte{0x06, 0x20}); n != -1 {
+		t.Errorf("The frame of unknown version must not be valid, got %d", n)
+	}
+
+	response := NewFrame(CONNECT_RESPONSE, append(control.append([]byte{0x15, E_NO_ERROR}), 4, TUNNEL_CONNECTION, 0x11, 0xff))
+	f, ok := ParseFrame(response)
+	if !ok || f.ServiceType != CONNECT_RESPONSE {
+		t.Fatalf("Unexpected frame %+v", f)
+	}
+	r, ok := ParseConnectResponse(f.Body)
+	if !ok || r.Channel != 0x15 || r.Status != E_NO_ERROR || r.Address.String() != "1.1.255" || r.Data.String() != "192.168.1.10:3671" {
+		t.Errorf("Unexpected connect response %+v", r)
+	}
+
+	cemi, _ := hex.DecodeString("2900bce011050a03010081")
+	f, _ = ParseFrame(TunnelingRequest(0x15, 7, cemi))
+	if channel, sequence, _, data, ok := ParseTunneling(f.Body); !ok || channel != 0x15 || sequence != 7 || !bytes.Equal(data, cemi) {
+		t.Errorf("Unexpected tunneling request %x", f.Body)
+	}
+
+	sr := &SearchResponse{Control: control, Medium: 0x20, Address: 0x1100, MAC: net.HardwareAddr{0, 1, 2, 3, 4, 5}, Name: "IP Interface", Tunneling: true}
+	f, _ = ParseFrame(SearchResponseFrame(sr))
+	if parsed, ok := ParseSearchResponse(f.Body); !ok || parsed.Name != sr.Name || parsed.Address != sr.Address || !parsed.Tunneling ||
+		parsed.MAC.String() != "00:01:02:03:04:05" || parsed.Control.String() != "192.168.1.10:3671" {
+		t.Errorf("Unexpected search response %+v", parsed)
+	}
+}
@@ -0,0 +1,279 @@
+package knx
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// KNXnet/IP frame structure:
+//   Header length (6)
+//   Protocol version (0x10)
+//   Service type, 2 bytes
+//   Total length including the header, 2 bytes
+//   ... Service body
+
+// KNXnet/IP constants
+const (
+	HeaderLength    = 6
+	ProtocolVersion = 0x10
+	DefaultPort     = 3671
+	// MulticastAddress the system setup multicast address, used by the search requests
+	MulticastAddress = "224.0.23.12:3671"
+)
+
+// Service types
+const (
+	SEARCH_REQUEST           = 0x0201
+	SEARCH_RESPONSE          = 0x0202
+	DESCRIPTION_REQUEST      = 0x0203
+	DESCRIPTION_RESPONSE     = 0x0204
+	CONNECT_REQUEST          = 0x0205
+	CONNECT_RESPONSE         = 0x0206
+	CONNECTIONSTATE_REQUEST  = 0x0207
+	CONNECTIONSTATE_RESPONSE = 0x0208
+	DISCONNECT_REQUEST       = 0x0209
+	DISCONNECT_RESPONSE      = 0x020a
+	TUNNELING_REQUEST        = 0x0420
+	TUNNELING_ACK            = 0x0421
+)
+
+// Status codes of the responses
+const (
+	E_NO_ERROR              = 0x00
+	E_HOST_PROTOCOL_TYPE    = 0x01
+	E_VERSION_NOT_SUPPORTED = 0x02
+	E_SEQUENCE_NUMBER       = 0x04
+	E_CONNECTION_ID         = 0x21
+	E_CONNECTION_TYPE       = 0x22
+	E_CONNECTION_OPTION     = 0x23
+	E_NO_MORE_CONNECTIONS   = 0x24
+	E_DATA_CONNECTION       = 0x26
+	E_KNX_CONNECTION        = 0x27
+	E_TUNNELING_LAYER       = 0x29
+)
+
+// Connection request information constants
+const (
+	IPV4_UDP               = 0x01
+	TUNNEL_CONNECTION      = 0x04
+	TUNNEL_LINKLAYER       = 0x02
+	DEVICE_INFO            = 0x01
+	SUPP_SVC_FAMILIES      = 0x02
+	hpaiLength             = 8
+	connectionHeaderLength = 4
+)
+
+// Frame is KNXnet/IP frame
+type Frame struct {
+	ServiceType uint16
+	Body        []byte
+}
+
+// NewFrame creates KNXnet/IP frame of provided service type and body
+func NewFrame(serviceType uint16, body []byte) []byte {
+	frame := make([]byte, HeaderLength, HeaderLength+len(body))
+	frame[0] = HeaderLength
+	frame[1] = ProtocolVersion
+	binary.BigEndian.PutUint16(frame[2:], serviceType)
+	binary.BigEndian.PutUint16(frame[4:], uint16(HeaderLength+len(body)))
+	return append(frame, body...)
+}
+
+// FrameLength returns the length of the frame by its beginning: -1 if the frame is not valid, 0 if more data is needed to determine the length
+func FrameLength(data []byte) int {
+	if len(data) > 0 && data[0] != HeaderLength {
+		return -1
+	}
+	if len(data) > 1 && data[1] != ProtocolVersion {
+		return -1
+	}
+	if len(data) < HeaderLength {
+		return 0
+	}
+	length := int(binary.BigEndian.Uint16(data[4:]))
+	if length < HeaderLength {
+		return -1
+	}
+	return length
+}
+
+// ParseFrame parses KNXnet/IP frame
+func ParseFrame(data []byte) (*Frame, bool) {
+	if FrameLength(data) != len(data) {
+		return nil, false
+	}
+	return &Frame{ServiceType: binary.BigEndian.Uint16(data[2:]), Body: data[HeaderLength:]}, true
+}
+
+// HPAI is the host protocol address information: IPv4 address and port. The zero address and port mean the route back (NAT) mode,
+// the gateway responds to the address the request came from
+type HPAI struct {
+	IP   net.IP
+	Port uint16
+}
+
+func (h HPAI) append(data []byte) []byte {
+	data = append(data, hpaiLength, IPV4_UDP)
+	if ip := h.IP.To4(); ip != nil {
+		data = append(data, ip...)
+	} else {
+		data = append(data, 0, 0, 0, 0)
+	}
+	return binary.BigEndian.AppendUint16(data, h.Port)
+}
+
+func parseHPAI(data []byte) (HPAI, bool) {
+	if len(data) < hpaiLength || data[0] != hpaiLength {
+		return HPAI{}, false
+	}
+	return HPAI{IP: net.IPv4(data[2], data[3], data[4], data[5]).To4(), Port: binary.BigEndian.Uint16(data[6:])}, true
+}
+
+func (h HPAI) String() string {
+	return net.JoinHostPort(h.IP.String(), strconv.Itoa(int(h.Port)))
+}
+
+// SearchRequest creates SEARCH_REQUEST frame, the response is sent to the provided discovery endpoint
+func SearchRequest(discovery HPAI) []byte {
+	return NewFrame(SEARCH_REQUEST, discovery.append(nil))
+}
+
+// ConnectRequest creates tunneling CONNECT_REQUEST frame
+func ConnectRequest(control, data HPAI) []byte {
+	body := data.append(control.append(nil))
+	return NewFrame(CONNECT_REQUEST, append(body, 4, TUNNEL_CONNECTION, TUNNEL_LINKLAYER, 0))
+}
+
+// ConnectResponse is the content of CONNECT_RESPONSE frame
+type ConnectResponse struct {
+	Channel uint8
+	Status  uint8
+	Data    HPAI
+	Address IndividualAddress // the individual address assigned to the tunnel
+}
+
+// ParseConnectResponse parses CONNECT_RESPONSE frame body
+func ParseConnectResponse(body []byte) (*ConnectResponse, bool) {
+	if len(body) < 2 {
+		return nil, false
+	}
+	r := &ConnectResponse{Channel: body[0], Status: body[1]}
+	if r.Status != E_NO_ERROR {
+		return r, true
+	}
+	var ok bool
+	if r.Data, ok = parseHPAI(body[2:]); !ok {
+		return nil, false
+	}
+	if crd := body[2+hpaiLength:]; len(crd) >= 4 && crd[0] >= 4 && crd[1] == TUNNEL_CONNECTION {
+		r.Address = IndividualAddress(binary.BigEndian.Uint16(crd[2:]))
+	}
+	return r, true
+}
+
+// ConnectionStateRequest creates CONNECTIONSTATE_REQUEST frame, the heartbeat of the connection
+func ConnectionStateRequest(channel uint8, control HPAI) []byte {
+	return NewFrame(CONNECTIONSTATE_REQUEST, control.append([]byte{channel, 0}))
+}
+
+// DisconnectRequest creates DISCONNECT_REQUEST frame
+func DisconnectRequest(channel uint8, control HPAI) []byte {
+	return NewFrame(DISCONNECT_REQUEST, control.append([]byte{channel, 0}))
+}
+
+// DisconnectResponse creates DISCONNECT_RESPONSE frame
+func DisconnectResponse(channel uint8, status uint8) []byte {
+	return NewFrame(DISCONNECT_RESPONSE, []byte{channel, status})
+}
+
+// ParseChannelStatus parses the body of CONNECTIONSTATE_RESPONSE, DISCONNECT_REQUEST and DISCONNECT_RESPONSE frames: the channel and the status
+func ParseChannelStatus(body []byte) (channel, status uint8, ok bool) {
+	if len(body) < 2 {
+		return 0, 0, false
+	}
+	return body[0], body[1], true
+}
+
+// TunnelingRequest creates TUNNELING_REQUEST frame carrying cEMI frame
+func TunnelingRequest(channel, sequence uint8, cemi []byte) []byte {
+	return NewFrame(TUNNELING_REQUEST, append([]byte{connectionHeaderLength, channel, sequence, 0}, cemi...))
+}
+
+// TunnelingAck creates TUNNELING_ACK frame
+func TunnelingAck(channel, sequence, status uint8) []byte {
+	return NewFrame(TUNNELING_ACK, []byte{connectionHeaderLength, channel, sequence, status})
+}
+
+// ParseTunneling parses TUNNELING_REQUEST or TUNNELING_ACK frame body: the connection header and the cEMI frame (the request only).
+// The status is the last byte of the connection header, it is reserved in the request
+func ParseTunneling(body []byte) (channel, sequence, status uint8, cemi []byte, ok bool) {
+	if len(body) < connectionHeaderLength || body[0] != connectionHeaderLength {
+		return 0, 0, 0, nil, false
+	}
+	return body[1], body[2], body[3], body[connectionHeaderLength:], true
+}
+
+// SearchResponse is the content of SEARCH_RESPONSE frame
+type SearchResponse struct {
+	Control      HPAI
+	Medium       uint8
+	Address      IndividualAddress
+	SerialNumber [6]byte
+	MAC          net.HardwareAddr
+	Name         string
+	Tunneling    bool // the device supports the tunneling
+}
+
+// ParseSearchResponse parses SEARCH_RESPONSE frame body
+func ParseSearchResponse(body []byte) (*SearchResponse, bool) {
+	control, ok := parseHPAI(body)
+	if !ok {
+		return nil, false
+	}
+	r := &SearchResponse{Control: control}
+	for dib := body[hpaiLength:]; len(dib) >= 2; dib = dib[dib[0]:] {
+		length := int(dib[0])
+		if length < 2 || length > len(dib) {
+			return nil, false
+		}
+		switch dib[1] {
+		case DEVICE_INFO:
+			if length < 54 {
+				return nil, false
+			}
+			r.Medium = dib[2]
+			r.Address = IndividualAddress(binary.BigEndian.Uint16(dib[4:]))
+			copy(r.SerialNumber[:], dib[8:14])
+			r.MAC = net.HardwareAddr(append([]byte(nil), dib[18:24]...))
+			r.Name = strings.TrimRight(string(dib[24:54]), "\x00")
+		case SUPP_SVC_FAMILIES:
+			for f := dib[2:length]; len(f) >= 2; f = f[2:] {
+				if f[0] == TUNNEL_CONNECTION {
+					r.Tunneling = true
+				}
+			}
+		}
+	}
+	return r, true
+}
+
+// SearchResponseFrame creates SEARCH_RESPONSE frame, it is intended for the gateway stand-ins
+func SearchResponseFrame(r *SearchResponse) []byte {
+	body := r.Control.append(nil)
+	dib := make([]byte, 54)
+	dib[0], dib[1], dib[2] = 54, DEVICE_INFO, r.Medium
+	binary.BigEndian.PutUint16(dib[4:], uint16(r.Address))
+	copy(dib[8:14], r.SerialNumber[:])
+	copy(dib[14:18], []byte{224, 0, 23, 12})
+	copy(dib[18:24], r.MAC)
+	copy(dib[24:54], r.Name)
+	body = append(body, dib...)
+	families := []byte{4, SUPP_SVC_FAMILIES, 0x02, 1}
+	if r.Tunneling {
+		families[0] += 2
+		families = append(families, TUNNEL_CONNECTION, 1)
+	}
+	return NewFrame(SEARCH_RESPONSE, append(body, families...))
+}
@@ -14,3 +14,9 @@ const SrcSVC = "S"
 
 // SrcMsg - messages log sources
 const SrcMsg = "M"
+
+// SrcBridge - network bridges log sources
+const SrcBridge = "B"
+
+// SrcPlugin - protocol plugins log sources
+const SrcPlugin = "P"
@@ -20,8 +20,10 @@ import (
 	<fileHeader>
 	<servicesCount> uint16
 	<serviceEntries:
-		protocol identifier uint8
-		transport identifier uint8
+		protocol identifier length uint8
+		protocol identifier string
+		transport identifier length uint8
+		transport identifier string
 		entry string length uint16
 		entry string (utf-8)
 	>
@@ -33,6 +35,9 @@ import (
 		payload length uint16
 		payload
 	>
+
+	the files of version 1 (legacyFileHeader) store the protocol and transport identifiers as uint8 numbers,
+	see legacyProtocols and legacyTransports
 */
 
 func (m *messages) load(file string, lengthLimit int) (int, error) {
@@ -72,11 +77,24 @@ func (m *messages) save(file string, dirMode os.FileMode, fileMode os.FileMode)
 	return writeMessages(w, m)
 }
 
-var fileHeader = []byte("ml01")
+var fileHeader = []byte("ml02")
 var minimalLength int = len(fileHeader) + 2 /* services count */
 
-func serviceEntryLength(entryLength int) int {
-	return 1 /* protocol id */ + 1 /* transport id */ + 2 /* entry length */ + entryLength
+// legacyFileHeader is the header of version 1 files, they are read but never written
+var legacyFileHeader = []byte("ml01")
+
+// the numeric identifiers of version 1 files, only Z-Wave over the serial port was supported then
+var legacyProtocols = map[uint8]api.ProtocolIdentifier{
+	1: api.ProtocolZWave,
+}
+
+var legacyTransports = map[uint8]api.TransportIdentifier{
+	1: api.TransportSerial,
+}
+
+func serviceEntryLength(service *api.ServiceKey) int {
+	return 1 /* protocol id length */ + len(service.Protocol) + 1 /* transport id length */ + len(service.Transport) +
+		2 /* entry length */ + len(service.Entry)
 }
 
 func messageEntryLength(payloadLength int) int {
@@ -84,7 +102,8 @@ func messageEntryLength(payloadLength int) int {
 }
 
 func readMessages(r io.Reader, lengthLimit int) (*messages, int, error) {
-	if err := readHeader(r); err != nil {
+	legacy, err := readHeader(r)
+	if err != nil {
 		return nil, 0, err
 	}
 	messages := newMessages()
@@ -96,12 +115,12 @@ func readMessages(r io.Reader, lengthLimit int) (*messages, int, error) {
 	serviceIndices := make(map[uint16]*api.ServiceKey)
 	var serviceIndex uint16 = 0
 	for ; serviceIndex < serviceCount; serviceIndex++ {
-		if service, err := readServicesEntry(r, serviceIndex); err != nil {
+		if service, err := readServicesEntry(r, serviceIndex, legacy); err != nil {
 			return nil, 0, err
 		} else {
 			messages.services[*service] = 0
 			serviceIndices[serviceIndex] = service
-			length += serviceEntryLength(len(service.Entry))
+			length += serviceEntryLength(service)
 		}
 	}
 
@@ -176,15 +195,19 @@ func writeError(name string, err error) error {
 	return fmt.Errorf("the message log file write failure: %s; %v", name, err)
 }
 
-func readHeader(r io.Reader) error {
+// readHeader reads the file header, returns true if the file is of version 1
+func readHeader(r io.Reader) (bool, error) {
 	header := make([]byte, len(fileHeader))
 	if _, err := io.ReadFull(r, header); err != nil {
-		return readError("header", err)
+		return false, readError("header", err)
+	}
+	if bytes.Equal(header, legacyFileHeader) {
+		return true, nil
 	}
 	if !bytes.Equal(header, fileHeader) {
-		return fmt.Errorf("the message log header is not valid")
+		return false, fmt.Errorf("the message log header is not valid")
 	}
-	return nil
+	return false, nil
 }
 
 func writeHeader(w io.Writer) error {
@@ -209,20 +232,35 @@ func writeServicesCount(w io.Writer, servicesCount uint16) error {
 	return nil
 }
 
-func readServicesEntry(r io.Reader, serviceIndex uint16) (*api.ServiceKey, error) {
+func readServicesEntry(r io.Reader, serviceIndex uint16, legacy bool) (*api.ServiceKey, error) {
 	var protocol api.ProtocolIdentifier
-	if err := binary.Read(r, binary.LittleEndian, &protocol); err != nil {
-		return nil, readError(fmt.Sprintf("service %d protocol identifier", serviceIndex), err)
-	}
-	if !protocol.IsValid() {
-		return nil, fmt.Errorf("service %d protocol identifier %d is not valid", serviceIndex, protocol)
-	}
 	var transport api.TransportIdentifier
-	if err := binary.Read(r, binary.LittleEndian, &transport); err != nil {
-		return nil, readError(fmt.Sprintf("service %d transport identifier", serviceIndex), err)
-	}
-	if !transport.IsValid() {
-		return nil, fmt.Errorf("service %d transport identifier %d is not valid", serviceIndex, transport)
+	if legacy {
+		var ids [2]uint8
+		if _, err := io.ReadFull(r, ids[:]); err != nil {
+			return nil, readError(fmt.Sprintf("service %d identifiers", serviceIndex), err)
+		}
+		var ok bool
+		if protocol, ok = legacyProtocols[ids[0]]; !ok {
+			return nil, fmt.Errorf("service %d protocol identifier %d is not valid", serviceIndex, ids[0])
+		}
+		if transport, ok = legacyTransports[ids[1]]; !ok {
+			return nil, fmt.Errorf("service %d transport identifier %d is not valid", serviceIndex, ids[1])
+		}
+	} else {
+		id, err := readIdentifier(r)
+		if err != nil {
+			return nil, readError(fmt.Sprintf("service %d protocol identifier", serviceIndex), err)
+		}
+		if protocol = api.ProtocolIdentifier(id); !protocol.IsValid() {
+			return nil, fmt.Errorf("service %d protocol identifier '%s' is not valid", serviceIndex, protocol)
+		}
+		if id, err = readIdentifier(r); err != nil {
+			return nil, readError(fmt.Sprintf("service %d transport identifier", serviceIndex), err)
+		}
+		if transport = api.TransportIdentifier(id); !transport.IsValid() {
+			return nil, fmt.Errorf("service %d transport identifier '%s' is not valid", serviceIndex, transport)
+		}
 	}
 	var entryLen uint16
 	if err := binary.Read(r, binary.LittleEndian, &entryLen); err != nil {
@@ -238,10 +276,10 @@ func readServicesEntry(r io.Reader, serviceIndex uint16) (*api.ServiceKey, error
 }
 
 func writeServicesEntry(w io.Writer, service api.ServiceKey, serviceIndex uint16) error {
-	if err := binary.Write(w, binary.LittleEndian, service.Protocol); err != nil {
+	if err := writeIdentifier(w, string(service.Protocol)); err != nil {
 		return writeError(fmt.Sprintf("service %d protocol identifier", serviceIndex), err)
 	}
-	if err := binary.Write(w, binary.LittleEndian, service.Transport); err != nil {
+	if err := writeIdentifier(w, string(service.Transport)); err != nil {
 		return writeError(fmt.Sprintf("service %d transport identifier", serviceIndex), err)
 	}
 	if err := binary.Write(w, binary.LittleEndian, uint16(len(service.Entry))); err != nil {
@@ -253,6 +291,30 @@ func writeServicesEntry(w io.Writer, service api.ServiceKey, serviceIndex uint16
 	return nil
 }
 
+// readIdentifier reads the identifier prefixed by its uint8 length
+func readIdentifier(r io.Reader) (string, error) {
+	var length [1]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return "", err
+	}
+	id := make([]byte, length[0])
+	if _, err := io.ReadFull(r, id); err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func writeIdentifier(w io.Writer, id string) error {
+	if len(id) > 255 {
+		return fmt.Errorf("the identifier '%s' is too long", id)
+	}
+	if _, err := w.Write([]byte{byte(len(id))}); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte(id))
+	return err
+}
+
 func readMessageEntry(r io.Reader, serviceIndices map[uint16]*api.ServiceKey) (*message, error) {
 	readMessageError := func(name string, err error) (*message, error) {
 		if err == io.EOF || err == io.ErrUnexpectedEOF {
@@ -2,6 +2,7 @@ package messages
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/rand"
 	"os"
 	"testing"
@@ -57,7 +58,7 @@ func TestMessagesPersistence(t *testing.T) {
 
 		length += messageEntryLength(payloadLen)
 		if m.push(service, &api.Message{Time: time.Now().UTC(), ID: uuid.New(), State: state, Payload: payload}) {
-			length += serviceEntryLength(len(service.Entry))
+			length += serviceEntryLength(service)
 		}
 
 		msgCount--
@@ -122,3 +123,70 @@ func TestMessagesPersistence(t *testing.T) {
 		}
 	})
 }
+
+func TestLegacyMessagesPersistence(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("ml01")
+	binary.Write(&b, binary.LittleEndian, uint16(2))
+	for _, service := range []struct {
+		protocol, transport uint8
+		entry               string
+	}{{1, 1, "COM1"}, {1, 1, "COM2"}} {
+		b.Write([]byte{service.protocol, service.transport})
+		binary.Write(&b, binary.LittleEndian, uint16(len(service.entry)))
+		b.WriteString(service.entry)
+	}
+	for i := uint16(0); i < 2; i++ {
+		binary.Write(&b, binary.LittleEndian, i)
+		binary.Write(&b, binary.LittleEndian, time.Now().UnixNano())
+		id := uuid.New()
+		b.Write(id[:])
+		b.WriteByte(byte(api.Incoming))
+		binary.Write(&b, binary.LittleEndian, uint16(1))
+		b.WriteByte(byte(i))
+	}
+
+	m, _, err := readMessages(bytes.NewReader(b.Bytes()), 0)
+	if err != nil {
+		t.Fatalf("unable to read version 1 file: %v", err)
+	}
+	expected := []api.ServiceKey{
+		{Protocol: api.ProtocolZWave, Transport: api.TransportSerial, Entry: "COM1"},
+		{Protocol: api.ProtocolZWave, Transport: api.TransportSerial, Entry: "COM2"},
+	}
+	if len(m.entries) != 2 {
+		t.Fatalf("unexpected number of entries %d", len(m.entries))
+	}
+	for i, key := range expected {
+		if *m.entries[i].ServiceKey != key {
+			t.Errorf("message %d: unexpected service %v", i, *m.entries[i].ServiceKey)
+		}
+	}
+
+	// the file is written as version 2
+	var w bytes.Buffer
+	if err := writeMessages(&w, m); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(w.Bytes(), fileHeader) {
+		t.Error("the file is not written as version 2")
+	}
+	m2, _, err := readMessages(&w, 0)
+	if err != nil {
+		t.Fatalf("unable to read version 2 file: %v", err)
+	}
+	for i, key := range expected {
+		if *m2.entries[i].ServiceKey != key {
+			t.Errorf("message %d: unexpected service %v", i, *m2.entries[i].ServiceKey)
+		}
+	}
+
+	b.Bytes()[6] = 2 // only Z-Wave was written in version 1
+	if _, _, err := readMessages(bytes.NewReader(b.Bytes()), 0); err == nil {
+		t.Error("unknown version 1 protocol identifier must be rejected")
+	}
+	b.Bytes()[6], b.Bytes()[7] = 1, 2 // only serial was written in version 1
+	if _, _, err := readMessages(bytes.NewReader(b.Bytes()), 0); err == nil {
+		t.Error("unknown version 1 transport identifier must be rejected")
+	}
+}
@@ -160,7 +160,7 @@ func (ml *messageLog) Register(key *api.ServiceKey, payload []byte, state api.Me
 
 	newSize := ml.size + messageEntryLength(len(payload))
 	if ml.log.services[*key] == 0 {
-		newSize += serviceEntryLength(len(key.Entry))
+		newSize += serviceEntryLength(key)
 	}
 	for newSize > ml.maxSize {
 		svc, message, svcLast := ml.log.pop()
@@ -169,7 +169,7 @@ func (ml *messageLog) Register(key *api.ServiceKey, payload []byte, state api.Me
 		}
 		newSize -= messageEntryLength(len(message.Payload))
 		if svcLast {
-			newSize -= serviceEntryLength(len(svc.Entry))
+			newSize -= serviceEntryLength(svc)
 		}
 		handlers.SendDropMessage(svc, message)
 	}
@@ -0,0 +1,150 @@
+package modbus
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCRC16(t *testing.T) {
+	// read 2 holding registers starting from 0 of unit 1
+	if crc := CRC16([]byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x02}); crc != 0x0bc4 {
+		t.Errorf("Unexpected CRC %04x", crc)
+	}
+	frame := RTUFrame(1, ReadRequest(FuncReadHoldingRegisters, 0, 2))
+	if !bytes.Equal(frame, []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x02, 0xc4, 0x0b}) {
+		t.Errorf("Unexpected RTU frame %x", frame)
+	}
+	if !ValidateRTUFrame(frame) {
+		t.Error("The RTU frame must be valid")
+	}
+	frame[3]++
+	if ValidateRTUFrame(frame) {
+		t.Error("The RTU frame with wrong CRC must not be valid")
+	}
+}
+
+func TestRTUResponse(t *testing.T) {
+	request := ReadRequest(FuncReadHoldingRegisters, 0x10, 2)
+	frame := RTUFrame(5, []byte{FuncReadHoldingRegisters, 4, 0x12, 0x34, 0xab, 0xcd})
+	for i := 0; i < 3; i++ {
+		if n := RTUResponseLength(frame[:i]); n != 0 {
+			t.Errorf("The length of %d bytes must be unknown, got %d", i, n)
+		}
+	}
+	if n := RTUResponseLength(frame[:3]); n != len(frame) {
+		t.Errorf("Unexpected response length %d", n)
+	}
+	unit, pdu := UnpackRTUFrame(frame)
+	if unit != 5 || CheckResponse(request, pdu) != nil {
+		t.Fatalf("Unexpected response %d %x", unit, pdu)
+	}
+	if values := ReadValues(request, pdu); len(values) != 2 || values[0] != 0x1234 || values[1] != 0xabcd {
+		t.Errorf("Unexpected values %v", values)
+	}
+
+	exception := RTUFrame(5, []byte{FuncReadHoldingRegisters | FuncException, ExceptionIllegalAddress})
+	if n := RTUResponseLength(exception); n != len(exception) {
+		t.Errorf("Unexpected exception response length %d", n)
+	}
+	var ee *ExceptionError
+	if _, pdu := UnpackRTUFrame(exception); !errors.As(CheckResponse(request, pdu), &ee) || ee.Code != ExceptionIllegalAddress {
+		t.Errorf("The exception is expected, got %v", CheckResponse(request, pdu))
+	}
+	if n := RTUResponseLength([]byte{5, 0x42}); n != -1 {
+		t.Errorf("Unknown function must not be recognized, got %d", n)
+	}
+}
+
+func TestCoils(t *testing.T) {
+	request := ReadRequest(FuncReadCoils, 0, 10)
+	response := []byte{FuncReadCoils, 2, 0b10100101, 0b10}
+	if err := CheckResponse(request, response); err != nil {
+		t.Fatal(err)
+	}
+	values := ReadValues(request, response)
+	if expected := []uint16{1, 0, 1, 0, 0, 1, 0, 1, 0, 1}; len(values) != len(expected) {
+		t.Fatalf("Unexpected values %v", values)
+	} else {
+		for i := range expected {
+			if values[i] != expected[i] {
+				t.Fatalf("Unexpected values %v", values)
+			}
+		}
+	}
+	if CheckResponse(request, []byte{FuncReadCoils, 1, 0xff}) == nil {
+		t.Error("The response with wrong number of bytes must not be valid")
+	}
+
+	pdu := WriteMultipleCoils(0x13, []uint16{1, 0, 1, 1, 0, 0, 1, 1, 1, 0})
+	if !bytes.Equal(pdu, []byte{0x0f, 0x00, 0x13, 0x00, 0x0a, 0x02, 0xcd, 0x01}) {
+		t.Errorf("Unexpected write multiple coils request %x", pdu)
+	}
+	if err := CheckResponse(pdu, []byte{0x0f, 0x00, 0x13, 0x00, 0x0a}); err != nil {
+		t.Error(err)
+	}
+	if pdu := WriteSingleCoil(0xac, true); !bytes.Equal(pdu, []byte{0x05, 0x00, 0xac, 0xff, 0x00}) {
+		t.Errorf("Unexpected write single coil request %x", pdu)
+	}
+}
+
+func TestRegisters(t *testing.T) {
+	pdu := WriteMultipleRegisters(1, []uint16{0x000a, 0x0102})
+	if !bytes.Equal(pdu, []byte{0x10, 0x00, 0x01, 0x00, 0x02, 0x04, 0x00, 0x0a, 0x01, 0x02}) {
+		t.Errorf("Unexpected write multiple registers request %x", pdu)
+	}
+	if CheckResponse(pdu, []byte{0x10, 0x00, 0x01, 0x00, 0x03}) == nil {
+		t.Error("The response with wrong quantity must not be valid")
+	}
+	pdu = WriteSingleRegister(1, 3)
+	if err := CheckResponse(pdu, pdu); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTCPFrame(t *testing.T) {
+	frame := TCPFrame(0x1501, 0xff, ReadRequest(FuncReadInputRegisters, 8, 1))
+	if !bytes.Equal(frame, []byte{0x15, 0x01, 0x00, 0x00, 0x00, 0x06, 0xff, 0x04, 0x00, 0x08, 0x00, 0x01}) {
+		t.Errorf("Unexpected TCP frame %x", frame)
+	}
+	if n := TCPFrameLength(frame[:5]); n != 0 {
+		t.Errorf("The length must be unknown, got %d", n)
+	}
+	if n := TCPFrameLength(frame); n != len(frame) {
+		t.Errorf("Unexpected frame length %d", n)
+	}
+	if transaction, unit, pdu := UnpackTCPFrame(frame); transaction != 0x1501 || unit != 0xff || len(pdu) != 5 {
+		t.Errorf("Unexpected frame content %x %x %x", transaction, unit, pdu)
+	}
+	frame[2] = 1
+	if n := TCPFrameLength(frame); n != -1 {
+		t.Errorf("The frame with wrong protocol identifier must not be valid, got %d", n)
+	}
+}
+
+func TestRTUFrameDelay(t *testing.T) {
+	if d := RTUFrameDelay(9600); d < 4*time.Millisecond || d > 4100*time.Microsecond {
+		t.Errorf("Unexpected frame delay %v for 9600 baud", d)
+	}
+	if d := RTUFrameDelay(115200); d != 1750*time.Microsecond {
+		t.Errorf("Unexpected frame delay %v for 115200 baud", d)
+	}
+}
+
+func TestDeviceIdentification(t *testing.T) {
+	if pdu := ReadDeviceIdentification(DeviceIDBasic, DeviceObjectVendorName); !bytes.Equal(pdu, []byte{0x2b, 0x0e, 0x01, 0x00}) {
+		t.Errorf("Unexpected request %x", pdu)
+	}
+	objects, err := DeviceIdentification([]byte{0x2b, 0x0e, 0x01, 0x01, 0x00, 0x00, 0x02, 0x00, 0x04, 'A', 'c', 'm', 'e', 0x01, 0x02, 'P', '1'})
+	if err != nil || len(objects) != 2 || objects[DeviceObjectVendorName] != "Acme" || objects[DeviceObjectProductCode] != "P1" {
+		t.Errorf("Unexpected objects %v, error %v", objects, err)
+	}
+	if _, err := DeviceIdentification([]byte{0x2b, 0x0e, 0x01, 0x01, 0x00, 0x00, 0x01, 0x00, 0x04, 'A'}); !errors.Is(err, ErrBadResponse) {
+		t.Errorf("Truncated response is accepted, error %v", err)
+	}
+	var ee *ExceptionError
+	if _, err := DeviceIdentification([]byte{0xab, 0x01}); !errors.As(err, &ee) || ee.Code != ExceptionIllegalFunction {
+		t.Errorf("Unexpected exception error %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Function codes
+const (
+	FuncReadCoils              = 0x01
+	FuncReadDiscreteInputs     = 0x02
+	FuncReadHoldingRegisters   = 0x03
+	FuncReadInputRegisters     = 0x04
+	FuncWriteSingleCoil        = 0x05
+	FuncWriteSingleRegister    = 0x06
+	FuncWriteMultipleCoils     = 0x0f
+	FuncWriteMultipleRegisters = 0x10
+	FuncEncapsulatedInterface  = 0x2b
+
+	// the function code bit which marks the exception response
+	FuncException = 0x80
+)
+
+// MEI type of encapsulated interface transport function which reads the device identification
+const MEIReadDeviceIdentification = 0x0e
+
+// Read device identification codes and basic objects
+const (
+	DeviceIDBasic    = 0x01 // the stream access to the basic device identification objects
+	DeviceIDRegular  = 0x02 // the stream access to the regular device identification objects
+	DeviceIDExtended = 0x03 // the stream access to the extended device identification objects
+	DeviceIDSpecific = 0x04 // the access to the individual object
+
+	DeviceObjectVendorName  = 0x00
+	DeviceObjectProductCode = 0x01
+	DeviceObjectRevision    = 0x02
+)
+
+// Exception codes
+const (
+	ExceptionIllegalFunction     = 0x01
+	ExceptionIllegalAddress      = 0x02
+	ExceptionIllegalValue        = 0x03
+	ExceptionDeviceFailure       = 0x04
+	ExceptionAcknowledge         = 0x05
+	ExceptionDeviceBusy          = 0x06
+	ExceptionNegativeAcknowledge = 0x07
+	ExceptionMemoryParityError   = 0x08
+	ExceptionGatewayPath         = 0x0a
+	ExceptionGatewayNoResponse   = 0x0b
+)
+
+// The limits of the number of items per request
+const (
+	MaxReadBits       = 2000
+	MaxReadRegisters  = 125
+	MaxWriteBits      = 1968
+	MaxWriteRegisters = 123
+)
+
+// ErrBadResponse is returned if the response PDU does not match the request or is malformed
+var ErrBadResponse = errors.New("the response is not valid")
+
+// ExceptionError is the exception response of the device
+type ExceptionError struct {
+	Function byte
+	Code     byte
+}
+
+func (e *ExceptionError) Error() string {
+	var reason string
+	switch e.Code {
+	case ExceptionIllegalFunction:
+		reason = "illegal function"
+	case ExceptionIllegalAddress:
+		reason = "illegal data address"
+	case ExceptionIllegalValue:
+		reason = "illegal data value"
+	case ExceptionDeviceFailure:
+		reason = "server device failure"
+	case ExceptionAcknowledge:
+		reason = "acknowledge"
+	case ExceptionDeviceBusy:
+		reason = "server device busy"
+	case ExceptionNegativeAcknowledge:
+		reason = "negative acknowledge"
+	case ExceptionMemoryParityError:
+		reason = "memory parity error"
+	case ExceptionGatewayPath:
+		reason = "gateway path unavailable"
+	case ExceptionGatewayNoResponse:
+		reason = "gateway target device failed to respond"
+	default:
+		reason = "unknown exception"
+	}
+	return fmt.Sprintf("modbus exception %d (%s) for function %d", e.Code, reason, e.Function)
+}
+
+// IsBitFunction returns true if the function reads or writes the single-bit items (coils or discrete inputs)
+func IsBitFunction(function byte) bool {
+	switch function {
+	case FuncReadCoils, FuncReadDiscreteInputs, FuncWriteSingleCoil, FuncWriteMultipleCoils:
+		return true
+	}
+	return false
+}
+
+// ReadRequest creates the PDU which reads count items starting from provided address using one of the reading functions
+func ReadRequest(function byte, address, count uint16) []byte {
+	pdu := []byte{function, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	binary.BigEndian.PutUint16(pdu[3:], count)
+	return pdu
+}
+
+// WriteSingleCoil creates the PDU which writes one coil
+func WriteSingleCoil(address uint16, value bool) []byte {
+	pdu := []byte{FuncWriteSingleCoil, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	if value {
+		pdu[3] = 0xff
+	}
+	return pdu
+}
+
+// WriteSingleRegister creates the PDU which writes one holding register
+func WriteSingleRegister(address, value uint16) []byte {
+	pdu := []byte{FuncWriteSingleRegister, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	binary.BigEndian.PutUint16(pdu[3:], value)
+	return pdu
+}
+
+// WriteMultipleCoils creates the PDU which writes several coils, the non-zero values are "on"
+func WriteMultipleCoils(address uint16, values []uint16) []byte {
+	byteCount := (len(values) + 7) / 8
+	pdu := make([]byte, 6+byteCount)
+	pdu[0] = FuncWriteMultipleCoils
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	binary.BigEndian.PutUint16(pdu[3:], uint16(len(values)))
+	pdu[5] = byte(byteCount)
+	for i, v := range values {
+		if v != 0 {
+			pdu[6+i/8] |= 1 << (i % 8)
+		}
+	}
+	return pdu
+}
+
+// WriteMultipleRegisters creates the PDU which writes several holding registers
+func WriteMultipleRegisters(address uint16, values []uint16) []byte {
+	pdu := make([]byte, 6+2*len(values))
+	pdu[0] = FuncWriteMultipleRegisters
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	binary.BigEndian.PutUint16(pdu[3:], uint16(len(values)))
+	pdu[5] = byte(2 * len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(pdu[6+2*i:], v)
+	}
+	return pdu
+}
+
+// ReadDeviceIdentification creates the PDU which reads the device identification objects starting from provided object
+func ReadDeviceIdentification(code, object byte) []byte {
+	return []byte{FuncEncapsulatedInterface, MEIReadDeviceIdentification, code, object}
+}
+
+// DeviceIdentification returns the objects of read device identification response PDU, ExceptionError in case of exception response
+func DeviceIdentification(response []byte) (map[byte]string, error) {
+	if len(response) >= 2 && response[0] == FuncEncapsulatedInterface|FuncException {
+		return nil, &ExceptionError{Function: FuncEncapsulatedInterface, Code: response[1]}
+	}
+	if len(response) < 7 || response[0] != FuncEncapsulatedInterface || response[1] != MEIReadDeviceIdentification {
+		return nil, ErrBadResponse
+	}
+	objects := make(map[byte]string)
+	data := response[7:]
+	for i := 0; i < int(response[6]); i++ {
+		if len(data) < 2 || len(data) < 2+int(data[1]) {
+			return nil, ErrBadResponse
+		}
+		objects[data[0]] = string(data[2 : 2+int(data[1])])
+		data = data[2+int(data[1]):]
+	}
+	return objects, nil
+}
+
+// CheckResponse verifies the response PDU matches the request PDU, returns ExceptionError in case of exception response
+func CheckResponse(request, response []byte) error {
+	if len(request) == 0 || len(response) < 2 {
+		return ErrBadResponse
+	}
+	if response[0] == request[0]|FuncException {
+		return &ExceptionError{Function: request[0], Code: response[1]}
+	}
+	if response[0] != request[0] {
+		return ErrBadResponse
+	}
+	switch request[0] {
+	case FuncReadCoils, FuncReadDiscreteInputs, FuncReadHoldingRegisters, FuncReadInputRegisters:
+		if len(request) < 5 || len(response) != 2+int(response[1]) {
+			return ErrBadResponse
+		}
+		count := int(binary.BigEndian.Uint16(request[3:]))
+		expected := 2 * count
+		if IsBitFunction(request[0]) {
+			expected = (count + 7) / 8
+		}
+		if int(response[1]) != expected {
+			return ErrBadResponse
+		}
+	case FuncWriteSingleCoil, FuncWriteSingleRegister, FuncWriteMultipleCoils, FuncWriteMultipleRegisters:
+		// the response echoes the address and the value (single write) or the number of items (multiple write)
+		if len(response) != 5 || len(request) < 5 || string(response[1:5]) != string(request[1:5]) {
+			return ErrBadResponse
+		}
+	}
+	return nil
+}
+
+// ReadValues returns the values of the read response PDU, the bits are returned as 0 or 1 values.
+// The response must be checked against the request by CheckResponse first
+func ReadValues(request, response []byte) []uint16 {
+	count := int(binary.BigEndian.Uint16(request[3:]))
+	values := make([]uint16, count)
+	data := response[2:]
+	for i := range values {
+		if IsBitFunction(request[0]) {
+			values[i] = uint16(data[i/8]>>(i%8)) & 1
+		} else {
+			values[i] = binary.BigEndian.Uint16(data[2*i:])
+		}
+	}
+	return values
+}
+
+// ResponseLength returns the length of the response PDU by its beginning, or 0 if more bytes are needed to determine it.
+// Returns -1 if the function code is not known
+func ResponseLength(pdu []byte) int {
+	if len(pdu) < 1 {
+		return 0
+	}
+	if pdu[0]&FuncException != 0 {
+		return 2
+	}
+	switch pdu[0] {
+	case FuncReadCoils, FuncReadDiscreteInputs, FuncReadHoldingRegisters, FuncReadInputRegisters:
+		if len(pdu) < 2 {
+			return 0
+		}
+		return 2 + int(pdu[1])
+	case FuncWriteSingleCoil, FuncWriteSingleRegister, FuncWriteMultipleCoils, FuncWriteMultipleRegisters:
+		return 5
+	}
+	return -1
+}
@@ -0,0 +1,77 @@
+package modbus
+
+import "time"
+
+// RTU frame structure:
+//   Unit (slave) address byte
+//   PDU: function code and data
+//   CRC-16, low byte first
+
+// RTU frame limits
+const (
+	RTUMinLength = 4
+	RTUMaxLength = 256
+)
+
+// CRC16 calculates Modbus CRC-16 (polynomial 0xA001, initial value 0xFFFF)
+func CRC16(data []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// RTUFrame creates RTU frame of provided unit address and PDU
+func RTUFrame(unit byte, pdu []byte) []byte {
+	frame := append([]byte{unit}, pdu...)
+	crc := CRC16(frame)
+	return append(frame, byte(crc), byte(crc>>8))
+}
+
+// ValidateRTUFrame verifies the frame CRC
+func ValidateRTUFrame(frame []byte) bool {
+	if len(frame) < RTUMinLength || len(frame) > RTUMaxLength {
+		return false
+	}
+	crc := CRC16(frame[:len(frame)-2])
+	return frame[len(frame)-2] == byte(crc) && frame[len(frame)-1] == byte(crc>>8)
+}
+
+// RTUResponseLength returns the length of the RTU response frame by its beginning, or 0 if more bytes are needed to determine it.
+// Returns -1 if the frame is not recognized
+func RTUResponseLength(frame []byte) int {
+	if len(frame) < 2 {
+		return 0
+	}
+	n := ResponseLength(frame[1:])
+	if n <= 0 {
+		return n
+	}
+	return n + 3
+}
+
+// UnpackRTUFrame returns the unit address and PDU of the RTU frame
+func UnpackRTUFrame(frame []byte) (byte, []byte) {
+	if len(frame) < RTUMinLength {
+		return 0, nil
+	}
+	return frame[0], frame[1 : len(frame)-2]
+}
+
+// RTUFrameDelay returns the silent interval between RTU frames (3.5 character times) for provided baud rate.
+// The fixed value of 1.75 ms is used for baud rates above 19200
+func RTUFrameDelay(baudRate int) time.Duration {
+	if baudRate <= 0 || baudRate > 19200 {
+		return 1750 * time.Microsecond
+	}
+	// 11 bits per character: start, 8 data, parity (or second stop) and stop bits
+	return time.Duration(35*11) * time.Second / time.Duration(10*baudRate)
+}
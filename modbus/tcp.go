@@ -0,0 +1,46 @@
+package modbus
+
+import "encoding/binary"
+
+// Modbus TCP frame structure, MBAP header followed by PDU:
+//   Transaction identifier, 2 bytes
+//   Protocol identifier, 2 bytes, always 0
+//   Length of the following bytes (unit identifier and PDU), 2 bytes
+//   Unit identifier byte
+//   PDU: function code and data
+
+// MBAP header length
+const MBAPHeaderLength = 7
+
+// TCP frame limits
+const TCPMaxLength = 260
+
+// TCPFrame creates Modbus TCP frame of provided transaction identifier, unit identifier and PDU
+func TCPFrame(transaction uint16, unit byte, pdu []byte) []byte {
+	frame := make([]byte, MBAPHeaderLength, MBAPHeaderLength+len(pdu))
+	binary.BigEndian.PutUint16(frame[0:], transaction)
+	binary.BigEndian.PutUint16(frame[4:], uint16(len(pdu)+1))
+	frame[6] = unit
+	return append(frame, pdu...)
+}
+
+// TCPFrameLength returns the length of Modbus TCP frame by its beginning, or 0 if more bytes are needed to determine it.
+// Returns -1 if the header is not valid
+func TCPFrameLength(frame []byte) int {
+	if len(frame) < 6 {
+		return 0
+	}
+	length := int(binary.BigEndian.Uint16(frame[4:]))
+	if binary.BigEndian.Uint16(frame[2:]) != 0 || length < 2 || length+6 > TCPMaxLength {
+		return -1
+	}
+	return length + 6
+}
+
+// UnpackTCPFrame returns the transaction identifier, unit identifier and PDU of Modbus TCP frame
+func UnpackTCPFrame(frame []byte) (uint16, byte, []byte) {
+	if len(frame) < MBAPHeaderLength+1 {
+		return 0, 0, nil
+	}
+	return binary.BigEndian.Uint16(frame), frame[6], frame[MBAPHeaderLength:]
+}
@@ -0,0 +1,350 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// client errors
+var (
+	ErrClosed       = errors.New("the client is closed")
+	ErrNoResponse   = errors.New("no response from the broker")
+	ErrNotSupported = errors.New("QoS 2 is not supported")
+)
+
+// maxPacketSize the maximal size of the packet the client accepts
+const maxPacketSize = 1 << 20
+
+// ConnectError is returned if the broker refused the connection
+type ConnectError struct {
+	Code byte
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("the connection is refused by the broker with code 0x%02x", e.Code)
+}
+
+// SubscribeError is returned if the broker refused the subscription
+type SubscribeError struct {
+	Filter string
+	Code   byte
+}
+
+func (e *SubscribeError) Error() string {
+	return fmt.Sprintf("the subscription to '%s' is refused by the broker with code 0x%02x", e.Filter, e.Code)
+}
+
+// Options are MQTT client options
+type Options struct {
+	Version      byte // Version311 or Version5
+	ClientID     string
+	Username     string
+	Password     string
+	KeepAlive    time.Duration // 0 to disable
+	CleanSession bool
+	Timeout      time.Duration // the timeout of the connection and the responses
+}
+
+// Message is the message received from the broker
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Client is MQTT client, it supports QoS 0 and 1
+type Client struct {
+	conn    net.Conn
+	options Options
+
+	writeLock sync.Mutex
+	lock      sync.Mutex
+	packetID  uint16
+	pending   map[uint16]chan *Packet
+
+	messages chan *Message
+	pong     chan struct{}
+	done     chan struct{}
+	err      error
+	wg       sync.WaitGroup
+}
+
+// Dial connects to the broker (host:port) and returns the connected client
+func Dial(ctx context.Context, address string, options Options) (*Client, error) {
+	if options.Version == 0 {
+		options.Version = Version311
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: options.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(options.Timeout))
+	connect := &Connect{
+		Version:      options.Version,
+		ClientID:     options.ClientID,
+		Username:     options.Username,
+		Password:     options.Password,
+		KeepAlive:    uint16(options.KeepAlive / time.Second),
+		CleanSession: options.CleanSession,
+	}
+	if _, err := conn.Write(connect.Packet().Encode()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	p, err := ReadPacket(r, maxPacketSize)
+	if err == nil && p.Type != CONNACK {
+		err = ErrMalformedPacket
+	}
+	var code byte
+	if err == nil {
+		code, err = ParseConnack(p)
+	}
+	if err == nil && code != 0 {
+		err = &ConnectError{Code: code}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Client{
+		conn:     conn,
+		options:  options,
+		pending:  make(map[uint16]chan *Packet),
+		messages: make(chan *Message, 64),
+		pong:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go c.readLoop(r)
+	if options.KeepAlive > 0 {
+		c.wg.Add(1)
+		go c.keepAliveLoop()
+	}
+	return c, nil
+}
+
+// Messages returns the channel of the messages received from the broker, the channel is closed when the client is closed
+func (c *Client) Messages() <-chan *Message {
+	return c.messages
+}
+
+// Done returns the channel which is closed when the connection is lost or the client is closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason the connection is lost
+func (c *Client) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+// Close disconnects from the broker
+func (c *Client) Close() error {
+	c.write(&Packet{Type: DISCONNECT})
+	c.fail(ErrClosed)
+	c.wg.Wait()
+	return nil
+}
+
+// fail closes the connection with provided reason
+func (c *Client) fail(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	c.err = err
+	close(c.done)
+	c.conn.Close()
+}
+
+func (c *Client) write(p *Packet) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.options.Timeout))
+	_, err := c.conn.Write(p.Encode())
+	return err
+}
+
+// request sends the packet with the new packet identifier and waits for the response
+func (c *Client) request(ctx context.Context, build func(packetID uint16) *Packet) (*Packet, error) {
+	response := make(chan *Packet, 1)
+	c.lock.Lock()
+	for c.packetID++; c.packetID == 0 || c.pending[c.packetID] != nil; c.packetID++ {
+	}
+	packetID := c.packetID
+	c.pending[packetID] = response
+	c.lock.Unlock()
+	defer func() {
+		c.lock.Lock()
+		delete(c.pending, packetID)
+		c.lock.Unlock()
+	}()
+
+	if err := c.write(build(packetID)); err != nil {
+		c.fail(err)
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, c.Err()
+	case <-time.After(c.options.Timeout):
+		return nil, ErrNoResponse
+	case p := <-response:
+		return p, nil
+	}
+}
+
+// Subscribe subscribes to the topic filter with provided maximal QoS
+func (c *Client) Subscribe(ctx context.Context, filter string, qos byte) error {
+	if qos > 1 {
+		return ErrNotSupported
+	}
+	p, err := c.request(ctx, func(packetID uint16) *Packet {
+		return SubscribePacket(c.options.Version, packetID, []string{filter}, qos)
+	})
+	if err != nil {
+		return err
+	}
+	_, codes, err := ParseSuback(c.options.Version, p)
+	if err != nil {
+		return err
+	}
+	if len(codes) != 1 || codes[0] > 2 {
+		code := byte(0x80)
+		if len(codes) > 0 {
+			code = codes[0]
+		}
+		return &SubscribeError{Filter: filter, Code: code}
+	}
+	return nil
+}
+
+// Publish publishes the message, the QoS 1 message is acknowledged by the broker when the function returns
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte, qos byte, retain bool) error {
+	select {
+	case <-c.done:
+		return c.Err()
+	default:
+	}
+	switch qos {
+	case 0:
+		pb := &Publish{Topic: topic, Payload: payload, Retain: retain}
+		if err := c.write(pb.Packet(c.options.Version)); err != nil {
+			c.fail(err)
+			return err
+		}
+		return nil
+	case 1:
+		p, err := c.request(ctx, func(packetID uint16) *Packet {
+			pb := &Publish{Topic: topic, Payload: payload, QoS: 1, Retain: retain, PacketID: packetID}
+			return pb.Packet(c.options.Version)
+		})
+		if err != nil {
+			return err
+		}
+		if _, code, err := ParseAck(p); err != nil {
+			return err
+		} else if code >= 0x80 {
+			return fmt.Errorf("the message is rejected by the broker with code 0x%02x", code)
+		}
+		return nil
+	}
+	return ErrNotSupported
+}
+
+func (c *Client) readLoop(r *bufio.Reader) {
+	defer c.wg.Done()
+	defer close(c.messages)
+	for {
+		p, err := ReadPacket(r, maxPacketSize)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		switch p.Type {
+		case PUBLISH:
+			pb, err := ParsePublish(c.options.Version, p)
+			if err != nil {
+				c.fail(err)
+				return
+			}
+			if pb.QoS == 2 {
+				c.fail(ErrNotSupported) // never granted by the subscriptions
+				return
+			}
+			select {
+			case c.messages <- &Message{Topic: pb.Topic, Payload: pb.Payload}:
+			case <-c.done:
+				return
+			}
+			if pb.QoS == 1 {
+				c.write(PubackPacket(pb.PacketID))
+			}
+		case PUBACK, SUBACK, UNSUBACK:
+			if len(p.Body) < 2 {
+				c.fail(ErrMalformedPacket)
+				return
+			}
+			packetID := uint16(p.Body[0])<<8 | uint16(p.Body[1])
+			c.lock.Lock()
+			response := c.pending[packetID]
+			c.lock.Unlock()
+			if response != nil {
+				select {
+				case response <- p:
+				default: // the duplicate response
+				}
+			}
+		case PINGRESP:
+			select {
+			case c.pong <- struct{}{}:
+			default:
+			}
+		case DISCONNECT:
+			c.fail(errors.New("the connection is closed by the broker"))
+			return
+		}
+	}
+}
+
+func (c *Client) keepAliveLoop() {
+	defer c.wg.Done()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(c.options.KeepAlive):
+		}
+		if err := c.write(&Packet{Type: PINGREQ}); err != nil {
+			c.fail(err)
+			return
+		}
+		select {
+		case <-c.done:
+			return
+		case <-time.After(c.options.Timeout):
+			c.fail(ErrNoResponse)
+			return
+		case <-c.pong:
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestMatchTopic(t *testing.T) {
+	for _, test := range []struct {
+		filter, topic string
+		match         bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"a/+/c", "a/b/c", true},
+		{"a/#", "a/b/c", true},
+		{"a/#", "a", true},
+		{"#", "a/b", true},
+		{"+/+", "a/b", true},
+		{"+", "a/b", false},
+		{"a/b/c", "a/b", false},
+		{"tele/+/SENSOR", "tele/plug/SENSOR", true},
+	} {
+		if m := MatchTopic(test.filter, test.topic); m != test.match {
+			t.Errorf("Unexpected match %v of %s and %s", m, test.filter, test.topic)
+		}
+	}
+}
+
+func TestPackets(t *testing.T) {
+	connect := &Connect{Version: Version311, ClientID: "howeve", KeepAlive: 60, CleanSession: true}
+	if s := hex.EncodeToString(connect.Packet().Encode()); s != "101200044d5154540402003c0006686f77657665" {
+		t.Errorf("Unexpected CONNECT packet %s", s)
+	}
+
+	for _, version := range []byte{Version311, Version5} {
+		connect := &Connect{Version: version, ClientID: "c", Username: "user", Password: "secret", KeepAlive: 30}
+		p, err := ReadPacket(bufio.NewReader(bytes.NewReader(connect.Packet().Encode())), maxPacketSize)
+		if err != nil {
+			t.Fatalf("Unable to read the packet: %v", err)
+		}
+		if parsed, err := ParseConnect(p); err != nil || *parsed != *connect {
+			t.Errorf("Unexpected CONNECT %+v, error %v", parsed, err)
+		}
+
+		publish := &Publish{Topic: "a/b", Payload: bytes.Repeat([]byte{1}, 200), QoS: 1, PacketID: 7}
+		p, _ = ReadPacket(bufio.NewReader(bytes.NewReader(publish.Packet(version).Encode())), maxPacketSize)
+		if parsed, err := ParsePublish(version, p); err != nil || parsed.Topic != "a/b" || parsed.PacketID != 7 || !bytes.Equal(parsed.Payload, publish.Payload) {
+			t.Errorf("Unexpected PUBLISH %+v, error %v", parsed, err)
+		}
+
+		packetID, filters, qos, err := ParseSubscribe(version, SubscribePacket(version, 3, []string{"a/#"}, 1))
+		if err != nil || packetID != 3 || len(filters) != 1 || filters[0] != "a/#" || qos[0] != 1 {
+			t.Errorf("Unexpected SUBSCRIBE %d %v %v, error %v", packetID, filters, qos, err)
+		}
+	}
+
+	if _, err := ReadPacket(bufio.NewReader(bytes.NewReader([]byte{0x30, 0x80, 0x80, 0x80, 0x80, 0x01})), maxPacketSize); err != ErrMalformedPacket {
+		t.Errorf("The malformed remaining length must be rejected, got %v", err)
+	}
+	if _, err := ReadPacket(bufio.NewReader(bytes.NewReader([]byte{0x30, 0x80, 0x01})), 100); err != ErrPacketTooLarge {
+		t.Errorf("The large packet must be rejected, got %v", err)
+	}
+}
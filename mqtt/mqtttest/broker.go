@@ -0,0 +1,199 @@
+package mqtttest
+
+import (
+	"bufio"
+	"net"
+	"sync"
+
+	"github.com/stas-makutin/howeve/mqtt"
+)
+
+// maxPacketSize the maximal size of the packet the broker accepts
+const maxPacketSize = 1 << 20
+
+// Broker is the minimal in-process MQTT broker for the tests. It supports MQTT 3.1.1 and 5, QoS 0 and 1,
+// and the wildcard subscriptions. The retained messages, the sessions, and the will messages are not supported
+type Broker struct {
+	listener net.Listener
+
+	lock     sync.Mutex
+	sessions map[*brokerSession]struct{}
+	wg       sync.WaitGroup
+}
+
+type brokerSession struct {
+	conn     net.Conn
+	version  byte
+	clientID string
+
+	lock          sync.Mutex
+	subscriptions map[string]byte // the topic filters and their maximal QoS
+	packetID      uint16
+}
+
+// NewBroker starts the broker listening on provided address (host:port)
+func NewBroker(address string) (*Broker, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	b := &Broker{listener: listener, sessions: make(map[*brokerSession]struct{})}
+	b.wg.Add(1)
+	go b.acceptLoop()
+	return b, nil
+}
+
+// Addr returns the address the broker is listening on
+func (b *Broker) Addr() string {
+	return b.listener.Addr().String()
+}
+
+// Clients returns the identifiers of the connected clients
+func (b *Broker) Clients() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var clients []string
+	for s := range b.sessions {
+		clients = append(clients, s.clientID)
+	}
+	return clients
+}
+
+// Disconnect closes the connections of all clients
+func (b *Broker) Disconnect() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for s := range b.sessions {
+		s.conn.Close()
+	}
+}
+
+// Close stops the broker and closes all connections
+func (b *Broker) Close() error {
+	err := b.listener.Close()
+	b.Disconnect()
+	b.wg.Wait()
+	return err
+}
+
+func (b *Broker) acceptLoop() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		b.wg.Add(1)
+		go b.serve(conn)
+	}
+}
+
+func (b *Broker) serve(conn net.Conn) {
+	defer b.wg.Done()
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	p, err := mqtt.ReadPacket(r, maxPacketSize)
+	if err != nil || p.Type != mqtt.CONNECT {
+		return
+	}
+	connect, err := mqtt.ParseConnect(p)
+	if err != nil || (connect.Version != mqtt.Version311 && connect.Version != mqtt.Version5) {
+		return
+	}
+	s := &brokerSession{conn: conn, version: connect.Version, clientID: connect.ClientID, subscriptions: make(map[string]byte)}
+	if s.write(mqtt.ConnackPacket(s.version, 0)) != nil {
+		return
+	}
+
+	b.lock.Lock()
+	b.sessions[s] = struct{}{}
+	b.lock.Unlock()
+	defer func() {
+		b.lock.Lock()
+		delete(b.sessions, s)
+		b.lock.Unlock()
+	}()
+
+	for {
+		p, err := mqtt.ReadPacket(r, maxPacketSize)
+		if err != nil {
+			return
+		}
+		switch p.Type {
+		case mqtt.PUBLISH:
+			pb, err := mqtt.ParsePublish(s.version, p)
+			if err != nil || pb.QoS > 1 {
+				return
+			}
+			if pb.QoS == 1 {
+				s.write(mqtt.PubackPacket(pb.PacketID))
+			}
+			b.deliver(pb)
+		case mqtt.SUBSCRIBE:
+			packetID, filters, qos, err := mqtt.ParseSubscribe(s.version, p)
+			if err != nil {
+				return
+			}
+			codes := make([]byte, len(filters))
+			s.lock.Lock()
+			for i, filter := range filters {
+				if codes[i] = qos[i]; codes[i] > 1 {
+					codes[i] = 1
+				}
+				s.subscriptions[filter] = codes[i]
+			}
+			s.lock.Unlock()
+			s.write(mqtt.SubackPacket(s.version, packetID, codes))
+		case mqtt.PINGREQ:
+			s.write(&mqtt.Packet{Type: mqtt.PINGRESP})
+		case mqtt.DISCONNECT:
+			return
+		}
+	}
+}
+
+// deliver sends the message to the matching subscribers
+func (b *Broker) deliver(pb *mqtt.Publish) {
+	b.lock.Lock()
+	sessions := make([]*brokerSession, 0, len(b.sessions))
+	for s := range b.sessions {
+		sessions = append(sessions, s)
+	}
+	b.lock.Unlock()
+
+	for _, s := range sessions {
+		s.lock.Lock()
+		matched, qos := false, byte(0)
+		for filter, q := range s.subscriptions {
+			if mqtt.MatchTopic(filter, pb.Topic) {
+				matched = true
+				if q > qos {
+					qos = q
+				}
+			}
+		}
+		if qos > pb.QoS {
+			qos = pb.QoS
+		}
+		var packetID uint16
+		if qos > 0 {
+			if s.packetID++; s.packetID == 0 {
+				s.packetID++
+			}
+			packetID = s.packetID
+		}
+		s.lock.Unlock()
+		if matched {
+			out := &mqtt.Publish{Topic: pb.Topic, Payload: pb.Payload, QoS: qos, PacketID: packetID}
+			s.write(out.Packet(s.version))
+		}
+	}
+}
+
+func (s *brokerSession) write(p *mqtt.Packet) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err := s.conn.Write(p.Encode())
+	return err
+}
@@ -0,0 +1,64 @@
+package mqtttest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/mqtt"
+)
+
+func TestClient(t *testing.T) {
+	broker, err := NewBroker("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to start the broker: %v", err)
+	}
+	defer broker.Close()
+
+	ctx := context.Background()
+	for _, version := range []byte{mqtt.Version311, mqtt.Version5} {
+		subscriber, err := mqtt.Dial(ctx, broker.Addr(), mqtt.Options{Version: version, ClientID: "subscriber", KeepAlive: time.Second, Timeout: time.Second})
+		if err != nil {
+			t.Fatalf("Unable to connect: %v", err)
+		}
+		if err := subscriber.Subscribe(ctx, "tele/+/SENSOR", 1); err != nil {
+			t.Fatalf("Unable to subscribe: %v", err)
+		}
+		publisher, err := mqtt.Dial(ctx, broker.Addr(), mqtt.Options{Version: mqtt.Version5 + mqtt.Version311 - version, ClientID: "publisher"})
+		if err != nil {
+			t.Fatalf("Unable to connect: %v", err)
+		}
+
+		if err := publisher.Publish(ctx, "tele/plug/STATE", []byte("skipped"), 0, false); err != nil {
+			t.Errorf("Unable to publish: %v", err)
+		}
+		for qos := byte(0); qos <= 1; qos++ {
+			if err := publisher.Publish(ctx, "tele/plug/SENSOR", []byte{'0' + qos}, qos, false); err != nil {
+				t.Errorf("Unable to publish with QoS %d: %v", qos, err)
+			}
+			select {
+			case m := <-subscriber.Messages():
+				if m.Topic != "tele/plug/SENSOR" || string(m.Payload) != string([]byte{'0' + qos}) {
+					t.Errorf("Unexpected message %s %s", m.Topic, m.Payload)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("The message is not received")
+			}
+		}
+		if err := publisher.Publish(ctx, "a", nil, 2, false); err != mqtt.ErrNotSupported {
+			t.Errorf("QoS 2 must not be supported, got %v", err)
+		}
+
+		publisher.Close()
+		broker.Disconnect()
+		select {
+		case <-subscriber.Done():
+		case <-time.After(time.Second):
+			t.Fatal("The connection loss must be detected")
+		}
+		if err := subscriber.Publish(ctx, "a", nil, 0, false); err == nil {
+			t.Error("Publish must fail after the connection loss")
+		}
+		subscriber.Close()
+	}
+}
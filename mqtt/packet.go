@@ -0,0 +1,386 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// MQTT control packet structure:
+//   Packet type (bits 7-4) and flags (bits 3-0)
+//   Remaining length, variable byte integer (1 to 4 bytes)
+//   ... Variable header and payload
+// MQTT 5 adds the properties (the variable byte integer length followed by the properties) to most of the variable headers
+
+// Protocol versions (levels)
+const (
+	Version311 = 4
+	Version5   = 5
+)
+
+// Control packet types
+const (
+	CONNECT     = 1
+	CONNACK     = 2
+	PUBLISH     = 3
+	PUBACK      = 4
+	PUBREC      = 5
+	PUBREL      = 6
+	PUBCOMP     = 7
+	SUBSCRIBE   = 8
+	SUBACK      = 9
+	UNSUBSCRIBE = 10
+	UNSUBACK    = 11
+	PINGREQ     = 12
+	PINGRESP    = 13
+	DISCONNECT  = 14
+)
+
+// MaxRemainingLength the maximal remaining length of the packet
+const MaxRemainingLength = 268435455
+
+// packet errors
+var (
+	ErrMalformedPacket = errors.New("the packet is malformed")
+	ErrPacketTooLarge  = errors.New("the packet is too large")
+)
+
+// Packet is MQTT control packet
+type Packet struct {
+	Type  byte
+	Flags byte
+	Body  []byte // the variable header and the payload
+}
+
+// Encode encodes the packet
+func (p *Packet) Encode() []byte {
+	data := make([]byte, 0, len(p.Body)+5)
+	data = append(data, p.Type<<4|p.Flags&0x0f)
+	data = appendVarint(data, len(p.Body))
+	return append(data, p.Body...)
+}
+
+// ReadPacket reads the packet, the packets with the remaining length above the limit are rejected
+func ReadPacket(r *bufio.Reader, limit int) (*Packet, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := readVarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > limit {
+		return nil, ErrPacketTooLarge
+	}
+	p := &Packet{Type: b >> 4, Flags: b & 0x0f, Body: make([]byte, length)}
+	if _, err := io.ReadFull(r, p.Body); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func appendVarint(data []byte, v int) []byte {
+	for {
+		b := byte(v & 0x7f)
+		if v >>= 7; v > 0 {
+			b |= 0x80
+		}
+		data = append(data, b)
+		if v == 0 {
+			return data
+		}
+	}
+}
+
+func readVarint(r io.ByteReader) (int, error) {
+	v := 0
+	for i := 0; i < 4; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v |= int(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, ErrMalformedPacket
+}
+
+func appendString(data []byte, s string) []byte {
+	data = binary.BigEndian.AppendUint16(data, uint16(len(s)))
+	return append(data, s...)
+}
+
+// decoder reads the fields of the packet body
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil || n > len(d.data) {
+		d.err = ErrMalformedPacket
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) byte() byte {
+	if b := d.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint16() uint16 {
+	if b := d.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(int(d.uint16())))
+}
+
+func (d *decoder) varint() int {
+	if d.err != nil {
+		return 0
+	}
+	r := &sliceReader{data: d.data}
+	v, err := readVarint(r)
+	if err != nil {
+		d.err = ErrMalformedPacket
+		return 0
+	}
+	d.data = r.data
+	return v
+}
+
+// properties skips MQTT 5 properties
+func (d *decoder) properties(version byte) {
+	if version >= Version5 {
+		d.bytes(d.varint())
+	}
+}
+
+type sliceReader struct {
+	data []byte
+}
+
+func (r *sliceReader) ReadByte() (byte, error) {
+	if len(r.data) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b, nil
+}
+
+// appendProperties appends the empty properties for MQTT 5
+func appendProperties(data []byte, version byte) []byte {
+	if version >= Version5 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+// Connect is the content of CONNECT packet
+type Connect struct {
+	Version      byte
+	ClientID     string
+	Username     string
+	Password     string
+	KeepAlive    uint16 // seconds
+	CleanSession bool   // clean start in MQTT 5
+}
+
+// Packet creates CONNECT packet
+func (c *Connect) Packet() *Packet {
+	flags := byte(0)
+	if c.CleanSession {
+		flags |= 0x02
+	}
+	if c.Username != "" {
+		flags |= 0x80
+	}
+	if c.Password != "" {
+		flags |= 0x40
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, c.Version, flags)
+	body = binary.BigEndian.AppendUint16(body, c.KeepAlive)
+	body = appendProperties(body, c.Version)
+	body = appendString(body, c.ClientID)
+	if c.Username != "" {
+		body = appendString(body, c.Username)
+	}
+	if c.Password != "" {
+		body = appendString(body, c.Password)
+	}
+	return &Packet{Type: CONNECT, Body: body}
+}
+
+// ParseConnect parses CONNECT packet, the will message is not supported
+func ParseConnect(p *Packet) (*Connect, error) {
+	d := &decoder{data: p.Body}
+	if d.string() != "MQTT" {
+		return nil, ErrMalformedPacket
+	}
+	c := &Connect{Version: d.byte()}
+	flags := d.byte()
+	c.KeepAlive = d.uint16()
+	c.CleanSession = flags&0x02 != 0
+	d.properties(c.Version)
+	c.ClientID = d.string()
+	if flags&0x04 != 0 {
+		return nil, ErrMalformedPacket
+	}
+	if flags&0x80 != 0 {
+		c.Username = d.string()
+	}
+	if flags&0x40 != 0 {
+		c.Password = d.string()
+	}
+	return c, d.err
+}
+
+// ConnackPacket creates CONNACK packet
+func ConnackPacket(version byte, code byte) *Packet {
+	return &Packet{Type: CONNACK, Body: appendProperties([]byte{0, code}, version)}
+}
+
+// ParseConnack parses CONNACK packet, returns the return (reason) code
+func ParseConnack(p *Packet) (byte, error) {
+	d := &decoder{data: p.Body}
+	d.byte()
+	code := d.byte()
+	return code, d.err
+}
+
+// Publish is the content of PUBLISH packet
+type Publish struct {
+	Topic    string
+	Payload  []byte
+	QoS      byte
+	Retain   bool
+	Dup      bool
+	PacketID uint16 // QoS 1 and 2 only
+}
+
+// Packet creates PUBLISH packet
+func (pb *Publish) Packet(version byte) *Packet {
+	flags := pb.QoS << 1
+	if pb.Retain {
+		flags |= 0x01
+	}
+	if pb.Dup {
+		flags |= 0x08
+	}
+	body := appendString(nil, pb.Topic)
+	if pb.QoS > 0 {
+		body = binary.BigEndian.AppendUint16(body, pb.PacketID)
+	}
+	body = appendProperties(body, version)
+	return &Packet{Type: PUBLISH, Flags: flags, Body: append(body, pb.Payload...)}
+}
+
+// ParsePublish parses PUBLISH packet
+func ParsePublish(version byte, p *Packet) (*Publish, error) {
+	pb := &Publish{QoS: (p.Flags >> 1) & 0x03, Retain: p.Flags&0x01 != 0, Dup: p.Flags&0x08 != 0}
+	if pb.QoS > 2 {
+		return nil, ErrMalformedPacket
+	}
+	d := &decoder{data: p.Body}
+	pb.Topic = d.string()
+	if pb.QoS > 0 {
+		pb.PacketID = d.uint16()
+	}
+	d.properties(version)
+	if d.err != nil {
+		return nil, d.err
+	}
+	pb.Payload = d.data
+	return pb, nil
+}
+
+// PubackPacket creates PUBACK packet
+func PubackPacket(packetID uint16) *Packet {
+	return &Packet{Type: PUBACK, Body: binary.BigEndian.AppendUint16(nil, packetID)}
+}
+
+// ParseAck parses the packet identifier and the reason code of PUBACK or UNSUBACK packet, the reason code is 0 if it is omitted
+func ParseAck(p *Packet) (packetID uint16, code byte, err error) {
+	d := &decoder{data: p.Body}
+	packetID = d.uint16()
+	if len(d.data) > 0 {
+		code = d.byte()
+	}
+	return packetID, code, d.err
+}
+
+// SubscribePacket creates SUBSCRIBE packet of the topic filters with provided maximal QoS
+func SubscribePacket(version byte, packetID uint16, filters []string, qos byte) *Packet {
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+	body = appendProperties(body, version)
+	for _, filter := range filters {
+		body = append(appendString(body, filter), qos)
+	}
+	return &Packet{Type: SUBSCRIBE, Flags: 0x02, Body: body}
+}
+
+// ParseSubscribe parses SUBSCRIBE packet, returns the packet identifier, the topic filters and their maximal QoS
+func ParseSubscribe(version byte, p *Packet) (packetID uint16, filters []string, qos []byte, err error) {
+	d := &decoder{data: p.Body}
+	packetID = d.uint16()
+	d.properties(version)
+	for d.err == nil && len(d.data) > 0 {
+		filters = append(filters, d.string())
+		qos = append(qos, d.byte()&0x03)
+	}
+	if d.err == nil && len(filters) == 0 {
+		d.err = ErrMalformedPacket
+	}
+	return packetID, filters, qos, d.err
+}
+
+// SubackPacket creates SUBACK packet
+func SubackPacket(version byte, packetID uint16, codes []byte) *Packet {
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+	body = appendProperties(body, version)
+	return &Packet{Type: SUBACK, Body: append(body, codes...)}
+}
+
+// ParseSuback parses SUBACK packet, returns the packet identifier and the return (reason) codes
+func ParseSuback(version byte, p *Packet) (packetID uint16, codes []byte, err error) {
+	d := &decoder{data: p.Body}
+	packetID = d.uint16()
+	d.properties(version)
+	return packetID, d.data, d.err
+}
+
+// MatchTopic verifies if the topic name matches the topic filter with + (single level) and # (multi-level) wildcards
+func MatchTopic(filter, topic string) bool {
+	for {
+		var f, t string
+		var fMore, tMore bool
+		f, filter, fMore = strings.Cut(filter, "/")
+		if f == "#" {
+			return true
+		}
+		t, topic, tMore = strings.Cut(topic, "/")
+		if f != "+" && f != t {
+			return false
+		}
+		if !fMore || !tMore {
+			// "a/#" matches "a" too
+			return fMore == tMore || (fMore && filter == "#")
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package netdisco
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MDNSAddress is the IPv4 mDNS multicast address.
+// The query is sent from the ephemeral port, so the responders answer with unicast (legacy unicast, RFC 6762 section 6.7)
+const MDNSAddress = "224.0.0.251:5353"
+
+// DNS record types and class
+const (
+	dnsTypeA   = 1
+	dnsTypePTR = 12
+	dnsTypeTXT = 16
+	dnsTypeSRV = 33
+
+	dnsClassIN = 1
+)
+
+// the limit of compression pointers to follow while reading the name
+const maxNamePointers = 16
+
+// Service is the service instance announced over mDNS (DNS-SD)
+type Service struct {
+	Instance string            // the instance name, like "Kitchen Stick"
+	Type     string            // the service type, like "_modbus._tcp"
+	Host     string            // the host name, like "stick.local."
+	IP       net.IP            // the IPv4 address of the host
+	Port     uint16            // the service port
+	Text     map[string]string // the TXT record key/value pairs
+}
+
+// Address returns the service address as ip:port
+func (s *Service) Address() string {
+	return net.JoinHostPort(s.IP.String(), strconv.Itoa(int(s.Port)))
+}
+
+// dnsRecord is the resource record of the received DNS message
+type dnsRecord struct {
+	name   string
+	rtype  uint16
+	target string   // PTR or SRV target
+	port   uint16   // SRV port
+	ip     net.IP   // A address
+	text   []string // TXT strings
+	from   net.IP   // the address of the responder
+}
+
+// Browse queries the service instances of provided types, like "_modbus._tcp", and collects the responses until the timeout expires
+func Browse(ctx context.Context, address string, serviceTypes []string, timeout time.Duration) ([]*Service, error) {
+	var types []string
+	for _, t := range serviceTypes {
+		if t = strings.TrimSuffix(strings.TrimSpace(t), "."); t != "" {
+			if !strings.HasSuffix(strings.ToLower(t), ".local") {
+				t += ".local"
+			}
+			types = append(types, t+".")
+		}
+	}
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	var records []*dnsRecord
+	err := Probe(ctx, address, dnsQuery(types, dnsTypePTR), timeout, func(b []byte, from *net.UDPAddr) {
+		if rs, ok := parseDNSMessage(b); ok {
+			for _, r := range rs {
+				r.from = from.IP
+			}
+			records = append(records, rs...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collectServices(types, records), nil
+}
+
+// collectServices assembles the service instances from the PTR, SRV, TXT and A records
+func collectServices(types []string, records []*dnsRecord) []*Service {
+	find := func(name string, rtype uint16) *dnsRecord {
+		for _, r := range records {
+			if r.rtype == rtype && strings.EqualFold(r.name, name) {
+				return r
+			}
+		}
+		return nil
+	}
+
+	var rv []*Service
+	found := make(map[string]bool)
+	for _, r := range records {
+		if r.rtype != dnsTypePTR {
+			continue
+		}
+		var serviceType string
+		for _, t := range types {
+			if strings.EqualFold(r.name, t) {
+				serviceType = t
+				break
+			}
+		}
+		key := strings.ToLower(r.target)
+		if serviceType == "" || found[key] {
+			continue
+		}
+		srv := find(r.target, dnsTypeSRV)
+		if srv == nil {
+			continue
+		}
+		found[key] = true
+
+		s := &Service{
+			Instance: r.target,
+			Type:     strings.TrimSuffix(serviceType, ".local."),
+			Host:     srv.target,
+			IP:       srv.from,
+			Port:     srv.port,
+		}
+		if len(r.target) > len(serviceType)+1 && strings.EqualFold(r.target[len(r.target)-len(serviceType):], serviceType) {
+			s.Instance = r.target[:len(r.target)-len(serviceType)-1]
+		}
+		if a := find(srv.target, dnsTypeA); a != nil {
+			s.IP = a.ip
+		}
+		if txt := find(r.target, dnsTypeTXT); txt != nil {
+			s.Text = make(map[string]string)
+			for _, t := range txt.text {
+				if k, v, _ := strings.Cut(t, "="); k != "" {
+					s.Text[k] = v
+				}
+			}
+		}
+		rv = append(rv, s)
+	}
+	return rv
+}
+
+// dnsQuery creates DNS query message with the questions of provided names and type
+func dnsQuery(names []string, qtype uint16) []byte {
+	msg := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(msg[4:], uint16(len(names)))
+	for _, name := range names {
+		msg = appendName(msg, name)
+		msg = binary.BigEndian.AppendUint16(msg, qtype)
+		msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+	}
+	return msg
+}
+
+// appendName appends the uncompressed domain name
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(name, ".") {
+		if label != "" {
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	return append(b, 0)
+}
+
+// parseDNSMessage parses the response DNS message, returns the records of answer, authority and additional sections
+func parseDNSMessage(msg []byte) ([]*dnsRecord, bool) {
+	if len(msg) < 12 || msg[2]&0x80 == 0 {
+		return nil, false // not a response
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	count := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+
+	pos := 12
+	for i := 0; i < questions; i++ {
+		_, next, ok := readName(msg, pos)
+		if !ok || next+4 > len(msg) {
+			return nil, false
+		}
+		pos = next + 4
+	}
+
+	var rv []*dnsRecord
+	for i := 0; i < count; i++ {
+		name, next, ok := readName(msg, pos)
+		if !ok || next+10 > len(msg) {
+			return nil, false
+		}
+		r := &dnsRecord{name: name, rtype: binary.BigEndian.Uint16(msg[next:])}
+		class := binary.BigEndian.Uint16(msg[next+2:]) & 0x7fff // the top bit is the cache flush flag
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		pos = start + length
+		if pos > len(msg) {
+			return nil, false
+		}
+		if class != dnsClassIN {
+			continue
+		}
+		data := msg[start:pos]
+		switch r.rtype {
+		case dnsTypePTR:
+			if r.target, _, ok = readName(msg, start); !ok {
+				continue
+			}
+		case dnsTypeSRV:
+			if length < 7 {
+				continue
+			}
+			r.port = binary.BigEndian.Uint16(data[4:])
+			if r.target, _, ok = readName(msg, start+6); !ok {
+				continue
+			}
+		case dnsTypeTXT:
+			for len(data) > 0 && int(data[0]) < len(data) {
+				r.text = append(r.text, string(data[1:1+data[0]]))
+				data = data[1+data[0]:]
+			}
+		case dnsTypeA:
+			if length != 4 {
+				continue
+			}
+			r.ip = net.IP(append([]byte(nil), data...))
+		default:
+			continue
+		}
+		rv = append(rv, r)
+	}
+	return rv, true
+}
+
+// readName reads the possibly compressed domain name at provided position, returns the name and the position after it
+func readName(msg []byte, pos int) (string, int, bool) {
+	var sb strings.Builder
+	end := -1
+	for pointers := 0; ; {
+		if pos >= len(msg) {
+			return "", 0, false
+		}
+		length := int(msg[pos])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = pos + 1
+			}
+			if sb.Len() == 0 {
+				sb.WriteByte('.')
+			}
+			return sb.String(), end, true
+		case length&0xc0 == 0xc0:
+			if pos+1 >= len(msg) || pointers >= maxNamePointers {
+				return "", 0, false
+			}
+			if end < 0 {
+				end = pos + 2
+			}
+			pointers++
+			pos = int(binary.BigEndian.Uint16(msg[pos:]) & 0x3fff)
+		case length&0xc0 != 0:
+			return "", 0, false
+		default:
+			if pos+1+length > len(msg) {
+				return "", 0, false
+			}
+			sb.Write(msg[pos+1 : pos+1+length])
+			sb.WriteByte('.')
+			pos += 1 + length
+		}
+	}
+}
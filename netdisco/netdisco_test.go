@@ -0,0 +1,151 @@
+package netdisco
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// responder starts UDP server which answers every request by the result of provided function, returns its address
+func responder(t *testing.T, answer func(request []byte) [][]byte) string {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				return
+			}
+			for _, b := range answer(buffer[:n]) {
+				conn.WriteToUDP(b, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// appendRecord appends the resource record, the name is compressed if the pointer is not 0
+func appendRecord(b []byte, name string, pointer uint16, rtype uint16, data []byte) []byte {
+	if pointer != 0 {
+		b = binary.BigEndian.AppendUint16(b, 0xc000|pointer)
+	} else {
+		b = appendName(b, name)
+	}
+	b = binary.BigEndian.AppendUint16(b, rtype)
+	b = binary.BigEndian.AppendUint16(b, 0x8000|dnsClassIN) // with the cache flush bit
+	b = binary.BigEndian.AppendUint32(b, 120)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+func TestReadName(t *testing.T) {
+	msg := appendName(make([]byte, 12), "_modbus._tcp.local.")
+	msg = append(msg, 3, 'a', 'b', 'c', 0xc0, 12)
+	if name, next, ok := readName(msg, 12); !ok || name != "_modbus._tcp.local." || next != 32 {
+		t.Errorf("Unexpected name '%s', position %d", name, next)
+	}
+	if name, next, ok := readName(msg, 32); !ok || name != "abc._modbus._tcp.local." || next != len(msg) {
+		t.Errorf("Unexpected compressed name '%s', position %d", name, next)
+	}
+	loop := append(make([]byte, 12), 0xc0, 12)
+	if _, _, ok := readName(loop, 12); ok {
+		t.Error("Pointer loop is accepted")
+	}
+	if _, _, ok := readName(msg[:20], 12); ok {
+		t.Error("Truncated name is accepted")
+	}
+}
+
+func TestBrowse(t *testing.T) {
+	address := responder(t, func(request []byte) [][]byte {
+		if name, _, ok := readName(request, 12); !ok || name != "_modbus._tcp.local." || binary.BigEndian.Uint16(request[4:]) != 2 {
+			return nil
+		}
+		// PTR answer in the first message and SRV, TXT and A records in the second one
+		first := []byte{0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 0}
+		instance := appendName(nil, "Meter._modbus._tcp.local.")
+		first = appendRecord(first, "_modbus._tcp.local.", 0, dnsTypePTR, instance)
+
+		second := []byte{0, 0, 0x84, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+		second = appendRecord(second, "Meter._modbus._tcp.local.", 0, dnsTypeSRV, appendName([]byte{0, 0, 0, 0, 0x01, 0xf6}, "meter.local."))
+		second = appendRecord(second, "", 12, dnsTypeTXT, []byte{10, 'm', 'o', 'd', 'e', 'l', '=', 'M', '1', '0', '0', 4, 'r', 'e', 'v', '=', 0})
+		second = appendRecord(second, "meter.local.", 0, dnsTypeA, []byte{192, 0, 2, 7})
+		second = appendRecord(second, "meter.local.", 0, 0xff, []byte{1, 2}) // unknown record type
+		binary.BigEndian.PutUint16(second[10:], 4)
+		return [][]byte{first, second, {1, 2, 3}}
+	})
+
+	services, err := Browse(context.Background(), address, []string{"_modbus._tcp", " _other._udp.local. "}, 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("Unexpected number of services %d", len(services))
+	}
+	s := services[0]
+	if s.Instance != "Meter" || s.Type != "_modbus._tcp" || s.Host != "meter.local." || s.Port != 502 || s.Address() != "192.0.2.7:502" {
+		t.Errorf("Unexpected service %+v", s)
+	}
+	if v, ok := s.Text["rev"]; !ok || v != "" || len(s.Text) != 2 || s.Text["model"] != "M100" {
+		t.Errorf("Unexpected text %v", s.Text)
+	}
+}
+
+func TestBrowseNoAddress(t *testing.T) {
+	// without A record the address of the responder is used
+	address := responder(t, func(request []byte) [][]byte {
+		msg := []byte{0, 0, 0x84, 0, 0, 0, 0, 2, 0, 0, 0, 0}
+		msg = appendRecord(msg, "_iostream._tcp.local.", 0, dnsTypePTR, appendName(nil, "stick._iostream._tcp.local."))
+		msg = appendRecord(msg, "stick._iostream._tcp.local.", 0, dnsTypeSRV, appendName([]byte{0, 0, 0, 0, 0x19, 0xf3}, "stick.local."))
+		return [][]byte{msg, msg}
+	})
+	services, err := Browse(context.Background(), address, []string{"_iostream._tcp"}, 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].Address() != "127.0.0.1:6643" || services[0].Text != nil {
+		t.Errorf("Unexpected services %+v", services)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	address := responder(t, func(request []byte) [][]byte {
+		r := string(request)
+		if !strings.HasPrefix(r, "M-SEARCH * HTTP/1.1\r\n") || !strings.Contains(r, "\r\nST: urn:test:device:Bridge:1\r\n") || !strings.Contains(r, "\r\nMX: 1\r\n") {
+			return nil
+		}
+		response := []byte("HTTP/1.1 200 OK\r\nCACHE-CONTROL: max-age=1800\r\nLOCATION: http://192.0.2.9:80/desc.xml\r\nSERVER: Linux/5.10 UPnP/1.0 Bridge/1.2\r\nST: urn:test:device:Bridge:1\r\nUSN: uuid:1234::urn:test:device:Bridge:1\r\n\r\n")
+		return [][]byte{response, response, []byte("HTTP/1.1 404 Not Found\r\n\r\n"), []byte("garbage")}
+	})
+	devices, err := Search(context.Background(), address, "urn:test:device:Bridge:1", 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("Unexpected number of devices %d", len(devices))
+	}
+	d := devices[0]
+	if d.Location != "http://192.0.2.9:80/desc.xml" || d.Server != "Linux/5.10 UPnP/1.0 Bridge/1.2" || d.USN != "uuid:1234::urn:test:device:Bridge:1" || !d.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("Unexpected device %+v", d)
+	}
+}
+
+func TestProbeCancel(t *testing.T) {
+	address := responder(t, func(request []byte) [][]byte { return nil })
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if err := Probe(ctx, address, []byte{1}, 5*time.Second, func(b []byte, from *net.UDPAddr) {}); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("Probe is not cancelled")
+	}
+}
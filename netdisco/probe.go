@@ -0,0 +1,50 @@
+package netdisco
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Probe sends the request datagram to the address (multicast, broadcast or unicast host:port) and passes every received
+// datagram to the handler until the timeout expires or the context is cancelled
+func Probe(ctx context.Context, address string, request []byte, timeout time.Duration, handler func(b []byte, from *net.UDPAddr)) error {
+	remote, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.WriteToUDP(request, remote); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// unblock the reading if the discovery is cancelled
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	buffer := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			return nil // the timeout or the cancellation
+		}
+		handler(buffer[:n], from)
+	}
+}
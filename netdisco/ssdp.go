@@ -0,0 +1,79 @@
+package netdisco
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// SSDPAddress is the IPv4 SSDP multicast address
+const SSDPAddress = "239.255.255.250:1900"
+
+// the limits of MX header value, seconds
+const (
+	minSSDPWait = 1
+	maxSSDPWait = 5
+)
+
+// Device is the device responded to SSDP search request
+type Device struct {
+	Target   string // the search target of the response (ST header)
+	USN      string // the unique service name
+	Location string // the URL of the device description
+	Server   string // the operating system and product
+	IP       net.IP // the address of the responder
+}
+
+// Search sends SSDP search request of provided target, like "ssdp:all" or "urn:schemas-upnp-org:device:Basic:1",
+// and collects the responses until the timeout expires
+func Search(ctx context.Context, address string, target string, timeout time.Duration) ([]*Device, error) {
+	wait := int(timeout / time.Second)
+	if wait < minSSDPWait {
+		wait = minSSDPWait
+	} else if wait > maxSSDPWait {
+		wait = maxSSDPWait
+	}
+	request := fmt.Sprintf("M-SEARCH * HTTP/1.1\r\nHOST: %s\r\nMAN: \"ssdp:discover\"\r\nMX: %d\r\nST: %s\r\n\r\n", address, wait, target)
+
+	var rv []*Device
+	found := make(map[string]bool)
+	err := Probe(ctx, address, []byte(request), timeout, func(b []byte, from *net.UDPAddr) {
+		d, ok := parseSearchResponse(b)
+		if !ok {
+			return
+		}
+		key := d.USN + " " + d.Location
+		if found[key] {
+			return
+		}
+		found[key] = true
+		d.IP = from.IP
+		rv = append(rv, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// parseSearchResponse parses SSDP search response
+func parseSearchResponse(b []byte) (*Device, bool) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), nil)
+	if err != nil {
+		return nil, false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+	return &Device{
+		Target:   resp.Header.Get("ST"),
+		USN:      resp.Header.Get("USN"),
+		Location: resp.Header.Get("LOCATION"),
+		Server:   resp.Header.Get("SERVER"),
+	}, true
+}
@@ -76,6 +76,14 @@ func (s *DiscoveryViewStore) action(event interface{}) {
 			if p, ok := e.Payload.(*api.ProtocolDiscoveryResult); ok {
 				s.findOrAddDiscovery(p.ID).Result = p
 			}
+		case api.QueryProtocolDiscoveryProgress:
+			if p, ok := e.Payload.(*api.ProtocolDiscoveryProgress); ok {
+				data := s.findOrAddDiscovery(p.ID)
+				data.Percent = p.Percent
+				if p.Found != nil {
+					data.Found = append(data.Found, p.Found)
+				}
+			}
 		default:
 			return
 		}
@@ -103,18 +111,21 @@ func (s *DiscoveryViewStore) action(event interface{}) {
 type DiscoveryLoad struct{}
 
 type DiscoveryData struct {
-	Input  *api.ProtocolDiscover
-	Result *api.ProtocolDiscoveryResult
+	Input   *api.ProtocolDiscover
+	Result  *api.ProtocolDiscoveryResult
+	Found   []*api.DiscoveryEntry // the entries found while the discovery is running
+	Percent uint8
 }
 
 const localStorageDiscoveryKey = "hw-discoveries"
 
-func (s *DiscoveryViewStore) findOrAddDiscovery(id uuid.UUID) (r *DiscoveryData) {
-	if r, ok := s.Discoveries[id]; !ok {
+func (s *DiscoveryViewStore) findOrAddDiscovery(id uuid.UUID) *DiscoveryData {
+	r, ok := s.Discoveries[id]
+	if !ok {
 		r = &DiscoveryData{}
 		s.Discoveries[id] = r
 	}
-	return
+	return r
 }
 
 func (s *DiscoveryViewStore) loadDiscoveries() {
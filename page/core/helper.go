@@ -3,7 +3,6 @@ package core
 import (
 	"fmt"
 	"sort"
-	"strconv"
 	"strings"
 
 	"github.com/stas-makutin/howeve/api"
@@ -31,6 +30,8 @@ var errorCodeToName map[api.ErrorCode]string = map[api.ErrorCode]string{
 	api.ErrorServiceStatusBad:         "Service Status Bad",
 	api.ErrorServiceBadPayload:        "Service Bad Payload",
 	api.ErrorServiceSendBusy:          "Service Send Busy",
+	api.ErrorServiceBadNode:           "Service Bad Node",
+	api.ErrorServiceNotSupported:      "Service Not Supported",
 	api.ErrorOtherError:               "Other Error",
 }
 
@@ -90,8 +91,8 @@ func (pw *ProtocolsWrapper) ProtocolAndTransportNames(protocolID api.ProtocolIde
 }
 
 func (pw *ProtocolsWrapper) ProtocolAndTransportFullNames(protocolID api.ProtocolIdentifier, transportID api.TransportIdentifier) (protocolName, transportName string) {
-	protocolName = strconv.Itoa(int(protocolID))
-	transportName = strconv.Itoa(int(transportID))
+	protocolName = string(protocolID)
+	transportName = string(transportID)
 	pi, ti := pw.ProtocolAndTransport(protocolID, transportID)
 	if pi != nil && pi.Name != "" {
 		protocolName = pi.Name + " (" + protocolName + ")"
@@ -28,6 +28,13 @@ var queryTypeToPath = map[api.QueryType]string{
 	api.QueryServiceStatus:      "/service/status",
 	api.QueryListServices:       "/service/list",
 	api.QuerySendToService:      "/service/send",
+	api.QuerySendMulticast:      "/service/multicast",
+	api.QueryServiceNodes:       "/service/nodes",
+	api.QueryServiceStatistics:  "/service/statistics",
+	api.QueryReadRegisters:      "/service/readRegisters",
+	api.QueryWriteRegisters:     "/service/writeRegisters",
+	api.QueryDeviceCommand:      "/service/deviceCommand",
+	api.QueryDeviceLinks:        "/service/deviceLinks",
 	api.QueryGetMessage:         "/messages/get",
 	api.QueryListMessages:       "/messages/list",
 }
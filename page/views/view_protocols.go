@@ -1,7 +1,6 @@
 package views
 
 import (
-	"strconv"
 	"strings"
 
 	"github.com/hexops/vecty"
@@ -111,9 +110,9 @@ func (ch *protocolsTable) Render() vecty.ComponentOrHTML {
 							vecty.Class("mdc-data-table__header-row"),
 						),
 						ch.headerColumn("Protocol"),
-						ch.headerColumn("Protocol ID", "mdc-data-table__header-cell--numeric"),
+						ch.headerColumn("Protocol ID"),
 						ch.headerColumn("Transport"),
-						ch.headerColumn("Transport ID", "mdc-data-table__header-cell--numeric"),
+						ch.headerColumn("Transport ID"),
 						ch.headerColumn("Parameters"),
 						ch.headerColumn("Discoverable"),
 						ch.headerColumn("Discovery Parameters"),
@@ -168,9 +167,9 @@ func (ch *protocolsTable) tableRow(protocol *api.ProtocolInfoEntry, transport *a
 			vecty.Class("mdc-data-table__row"),
 		),
 		ch.tableColumn(vecty.Text(protocol.Name)),
-		ch.tableColumn(vecty.Text(strconv.Itoa(int(protocol.ID))), "mdc-data-table__cell--numeric"),
+		ch.tableColumn(vecty.Text(string(protocol.ID))),
 		ch.tableColumn(vecty.Text(transport.Name)),
-		ch.tableColumn(vecty.Text(strconv.Itoa(int(transport.ID))), "mdc-data-table__cell--numeric"),
+		ch.tableColumn(vecty.Text(string(transport.ID))),
 		ch.tableColumn(ch.parametersTable(transport.Params)),
 		ch.tableColumn(vecty.Text(dicoverable)),
 		ch.tableColumn(ch.parametersTable(transport.Params)),
@@ -2,7 +2,6 @@ package views
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/hexops/vecty"
 	"github.com/hexops/vecty/elem"
@@ -302,6 +301,11 @@ func (ch *servicesTable) tableRow(service *api.ListServicesEntry) vecty.Componen
 		)
 	}
 
+	entry := service.Entry
+	if service.ResolvedEntry != "" && service.ResolvedEntry != service.Entry {
+		entry += " (" + service.ResolvedEntry + ")"
+	}
+
 	return elem.TableRow(
 		vecty.Markup(
 			vecty.Class("mdc-data-table__row"),
@@ -309,7 +313,7 @@ func (ch *servicesTable) tableRow(service *api.ListServicesEntry) vecty.Componen
 		ch.tableColumn(vecty.Text(service.Alias)),
 		ch.tableColumn(vecty.Text(protocolName)),
 		ch.tableColumn(vecty.Text(transportName)),
-		ch.tableColumn(vecty.Text(service.Entry), "sv-service-table-entry-cell"),
+		ch.tableColumn(vecty.Text(entry), "sv-service-table-entry-cell"),
 		ch.tableColumn(vecty.List{
 			elem.Anchor(
 				vecty.Markup(
@@ -517,7 +521,7 @@ func (ch *addServiceDialog) RenderProtocols(protocol *api.ProtocolInfoEntry) vec
 	var options vecty.List
 	for _, p := range ch.Protocols.Protocols {
 		options = append(options, &components.MdcSelectOption{
-			Name:     fmt.Sprintf("%s (%d)", p.Name, p.ID),
+			Name:     fmt.Sprintf("%s (%s)", p.Name, p.ID),
 			Selected: protocol.ID == p.ID,
 		})
 	}
@@ -531,8 +535,8 @@ func (ch *addServiceDialog) RenderTransports(protocol *api.ProtocolInfoEntry, tr
 	if protocol != nil {
 		for _, t := range protocol.Transports {
 			options = append(options, &components.MdcSelectOption{
-				Name:     fmt.Sprintf("%s (%d)", t.Name, t.ID),
-				Value:    strconv.Itoa(int(t.ID)),
+				Name:     fmt.Sprintf("%s (%s)", t.Name, t.ID),
+				Value:    string(t.ID),
 				Selected: transport.ID == t.ID,
 			})
 		}
@@ -689,7 +693,7 @@ func (ch *changeServiceAliasDialog) Render() vecty.ComponentOrHTML {
 		}
 	}
 	disableChange := ch.Service.Alias == ch.NewAlias || aliasMessage != ""
-	key := fmt.Sprintf("sv-change-alias-%d-%d-%s-%s", ch.Service.Protocol, ch.Service.Transport, ch.Service.Entry, ch.NewAlias)
+	key := fmt.Sprintf("sv-change-alias-%s-%s-%s-%s", ch.Service.Protocol, ch.Service.Transport, ch.Service.Entry, ch.NewAlias)
 
 	return components.NewMdcDialog(
 		"sv-change-service-alias-dialog", "Change Service Alias", false, false, ch.closeDialog, nil,
@@ -731,7 +735,7 @@ func (ch *viewServiceParametersDialog) Copy() vecty.Component {
 func (ch *viewServiceParametersDialog) Render() vecty.ComponentOrHTML {
 	protocolName, transportName := ch.Protocols.ProtocolAndTransportFullNames(ch.Service.Protocol, ch.Service.Transport)
 	names := core.ArrangeParams(ch.Service.Params)
-	key := fmt.Sprintf("sv-view-service-param-%d-%d-%s", ch.Service.Protocol, ch.Service.Transport, ch.Service.Entry)
+	key := fmt.Sprintf("sv-view-service-param-%s-%s-%s", ch.Service.Protocol, ch.Service.Transport, ch.Service.Entry)
 
 	return components.NewMdcDialog(
 		"sv-view-service-params-dialog", "Service Parameters", false, false, ch.closeDialog, nil,
@@ -775,7 +779,7 @@ func (ch *viewServiceStatusDialog) Copy() vecty.Component {
 
 func (ch *viewServiceStatusDialog) Render() vecty.ComponentOrHTML {
 	protocolName, transportName := ch.Protocols.ProtocolAndTransportFullNames(ch.Service.Protocol, ch.Service.Transport)
-	key := fmt.Sprintf("sv-view-service-status-%d-%d-%s", ch.Service.Protocol, ch.Service.Transport, ch.Service.Entry)
+	key := fmt.Sprintf("sv-view-service-status-%s-%s-%s", ch.Service.Protocol, ch.Service.Transport, ch.Service.Entry)
 
 	return components.NewMdcDialog(
 		"sv-view-service-status-dialog", "Service Error", false, false, ch.closeDialog, nil,
@@ -835,7 +839,7 @@ func (ch *removeServiceDialog) Copy() vecty.Component {
 
 func (ch *removeServiceDialog) Render() vecty.ComponentOrHTML {
 	protocolName, transportName := ch.Protocols.ProtocolAndTransportFullNames(ch.Service.Protocol, ch.Service.Transport)
-	key := fmt.Sprintf("sv-remove-service-%d-%d-%s", ch.Service.Protocol, ch.Service.Transport, ch.Service.Entry)
+	key := fmt.Sprintf("sv-remove-service-%s-%s-%s", ch.Service.Protocol, ch.Service.Transport, ch.Service.Entry)
 
 	return components.NewMdcDialog(
 		"sv-remove-service-dialog", "Remove the Service?", false, false, ch.closeDialog, nil,
@@ -0,0 +1,166 @@
+package backoff
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/events/handlers"
+	"github.com/stas-makutin/howeve/utils/syncutil"
+)
+
+// default values of the backoff policy, the same as the defaults of the service parameters
+const (
+	defaultInterval    = 5000 * time.Millisecond
+	defaultMaxInterval = 60000 * time.Millisecond
+	defaultMultiplier  = 200
+	defaultJitter      = 10
+)
+
+// Params are the service parameters of the backoff policy common for all protocols,
+// the interval between attempts depends on the transport so it is defined along with the transport options
+var Params = defs.Params{
+	defs.ParamNameOpenMaxInterval: {
+		Description:  "The maximal time interval between attempts to open the transport, milliseconds",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "60000",
+	},
+	defs.ParamNameOpenBackoffMultiplier: {
+		Description:  "The multiplier of the time interval after each failed attempt to open the transport, percents, 100 for the fixed interval",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "200",
+	},
+	defs.ParamNameOpenJitter: {
+		Description:  "The random deviation of the time interval between attempts to open the transport, percents",
+		Type:         defs.ParamTypeUint8,
+		DefaultValue: "10",
+	},
+	defs.ParamNameOpenMaxAttempts: {
+		Description:  "The number of failed attempts to open the transport after which the service gives up, 0 to never give up",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "0",
+	},
+}
+
+// Policy describes the intervals between the attempts to open the transport
+type Policy struct {
+	Interval    time.Duration // the interval before the second attempt
+	MaxInterval time.Duration // the upper limit of the interval
+	Multiplier  uint32        // the interval multiplier after each failed attempt, percents
+	Jitter      uint8         // the random deviation of the interval, percents
+	MaxAttempts uint32        // the number of failed attempts after which the service gives up, 0 - never give up
+}
+
+// NewPolicy creates the backoff policy from the service parameters
+func NewPolicy(params api.ParamValues) Policy {
+	p := Policy{
+		Interval:    defaultInterval,
+		MaxInterval: defaultMaxInterval,
+		Multiplier:  defaultMultiplier,
+		Jitter:      defaultJitter,
+	}
+	if v, ok := params[defs.ParamNameOpenAttemptsInterval]; ok {
+		p.Interval = time.Duration(v.(uint32)) * time.Millisecond
+	}
+	if p.Interval < 100*time.Millisecond {
+		p.Interval = 100 * time.Millisecond
+	}
+	if v, ok := params[defs.ParamNameOpenMaxInterval]; ok {
+		p.MaxInterval = time.Duration(v.(uint32)) * time.Millisecond
+	}
+	if p.MaxInterval < p.Interval {
+		p.MaxInterval = p.Interval
+	}
+	if v, ok := params[defs.ParamNameOpenBackoffMultiplier]; ok {
+		p.Multiplier = v.(uint32)
+	}
+	if p.Multiplier < 100 {
+		p.Multiplier = 100
+	}
+	if v, ok := params[defs.ParamNameOpenJitter]; ok {
+		p.Jitter = v.(uint8)
+	}
+	if p.Jitter > 100 {
+		p.Jitter = 100
+	}
+	if v, ok := params[defs.ParamNameOpenMaxAttempts]; ok {
+		p.MaxAttempts = v.(uint32)
+	}
+	return p
+}
+
+// Backoff tracks the failed attempts according to the policy
+type Backoff struct {
+	policy   Policy
+	attempts uint32
+	interval time.Duration
+}
+
+// New creates the backoff of provided policy
+func New(policy Policy) *Backoff {
+	return &Backoff{policy: policy}
+}
+
+// Reset starts counting the failed attempts from the beginning, it is called after the successful attempt
+func (b *Backoff) Reset() {
+	b.attempts = 0
+	b.interval = 0
+}
+
+// Attempts returns the number of failed attempts since the last reset
+func (b *Backoff) Attempts() uint32 {
+	return b.attempts
+}
+
+// Failed registers the failed attempt and returns the interval before the next one, or false if the attempts should be stopped
+func (b *Backoff) Failed() (time.Duration, bool) {
+	b.attempts++
+	if b.policy.MaxAttempts > 0 && b.attempts >= b.policy.MaxAttempts {
+		return 0, false
+	}
+
+	if b.interval <= 0 {
+		b.interval = b.policy.Interval
+	} else {
+		b.interval = time.Duration(int64(b.interval) * int64(b.policy.Multiplier) / 100)
+		if b.interval > b.policy.MaxInterval || b.interval <= 0 {
+			b.interval = b.policy.MaxInterval
+		}
+	}
+
+	interval := b.interval
+	if b.policy.Jitter > 0 {
+		deviation := int64(interval) * int64(b.policy.Jitter) / 100
+		if deviation > 0 {
+			interval += time.Duration(rand.Int63n(2*deviation+1) - deviation)
+		}
+	}
+	return interval, true
+}
+
+// Retry handles the failed attempt of the service to open its transport: the failure is logged by calling log, the service status is
+// set to the failure and Retry waits for the interval before the next attempt. If the service gives up, the status is set to
+// ErrOpenGaveUp error, the service gave up event is sent and Retry waits until the service is stopped.
+// Returns false if the context is done
+func (b *Backoff) Retry(ctx context.Context, key *api.ServiceKey, status *syncutil.RLocked[error], failure string, err error, log func(gaveUp bool)) bool {
+	interval, retry := b.Failed()
+	if !retry {
+		// stop the attempts until the service is restarted
+		status.Store(fmt.Errorf("%w after %d attempts: %s", defs.ErrOpenGaveUp, b.attempts, err.Error()))
+		log(true)
+		handlers.SendServiceGaveUp(key, b.attempts, err)
+		<-ctx.Done()
+		return false
+	}
+	status.Store(fmt.Errorf("%s: %s", failure, err.Error()))
+	log(false)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(interval):
+		return true
+	}
+}
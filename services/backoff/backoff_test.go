@@ -0,0 +1,86 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/events"
+	"github.com/stas-makutin/howeve/events/handlers"
+	"github.com/stas-makutin/howeve/utils/syncutil"
+)
+
+func TestBackoff(t *testing.T) {
+	b := New(NewPolicy(api.ParamValues{
+		defs.ParamNameOpenAttemptsInterval:  uint32(1000),
+		defs.ParamNameOpenMaxInterval:       uint32(5000),
+		defs.ParamNameOpenBackoffMultiplier: uint32(300),
+		defs.ParamNameOpenJitter:            uint8(0),
+		defs.ParamNameOpenMaxAttempts:       uint32(5),
+	}))
+
+	for i, expected := range []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if interval, ok := b.Failed(); !ok || interval != expected {
+			t.Errorf("attempt %d: unexpected interval %v, %v", i+1, interval, ok)
+		}
+	}
+	if _, ok := b.Failed(); ok || b.Attempts() != 5 {
+		t.Errorf("the attempts must be stopped after 5 failures, %d failures", b.Attempts())
+	}
+
+	b.Reset()
+	if interval, ok := b.Failed(); !ok || interval != time.Second || b.Attempts() != 1 {
+		t.Errorf("unexpected interval %v, %v after reset", interval, ok)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := New(NewPolicy(api.ParamValues{
+		defs.ParamNameOpenAttemptsInterval:  uint32(1000),
+		defs.ParamNameOpenBackoffMultiplier: uint32(100),
+		defs.ParamNameOpenJitter:            uint8(20),
+	}))
+	for i := 0; i < 100; i++ {
+		if interval, ok := b.Failed(); !ok || interval < 800*time.Millisecond || interval > 1200*time.Millisecond {
+			t.Fatalf("unexpected interval %v, %v", interval, ok)
+		}
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	handlers.Dispatcher = events.NewAsyncDispatcher(1)
+	defer handlers.Dispatcher.Close()
+
+	b := New(NewPolicy(api.ParamValues{
+		defs.ParamNameOpenAttemptsInterval: uint32(100),
+		defs.ParamNameOpenJitter:           uint8(0),
+		defs.ParamNameOpenMaxAttempts:      uint32(2),
+	}))
+	key := &api.ServiceKey{Protocol: api.ProtocolZWave, Transport: api.TransportSerial, Entry: "test"}
+	var status syncutil.RLocked[error]
+	var logged []bool
+	log := func(gaveUp bool) { logged = append(logged, gaveUp) }
+	openErr := errors.New("no device")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if !b.Retry(ctx, key, &status, "unable to open transport", openErr, log) {
+		t.Fatal("the first failure must be retried")
+	}
+	if err := status.Load(); err == nil || err.Error() != "unable to open transport: no device" {
+		t.Errorf("unexpected status %v", err)
+	}
+
+	cancel()
+	if b.Retry(ctx, key, &status, "unable to open transport", openErr, log) {
+		t.Fatal("the attempts must be stopped after 2 failures")
+	}
+	if err := status.Load(); !errors.Is(err, defs.ErrOpenGaveUp) {
+		t.Errorf("unexpected status %v", err)
+	}
+	if len(logged) != 2 || logged[0] || !logged[1] {
+		t.Errorf("unexpected log calls %v", logged)
+	}
+}
@@ -0,0 +1,151 @@
+package bridge
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+)
+
+// Arbiter struct - the transport wrapper which shares the raw byte stream of the wrapped transport with the remote side.
+// The data read from the transport is copied to the taps, and the writes of the owner and the remote side are arbitrated:
+// the owner's writes are queued while the remote side is active, and flushed when the hold time passes after the last remote write.
+// The owner's single byte writes are not held, they are the acknowledgements (ACK, NAK, CAN) of the received frames which
+// the device expects in time
+type Arbiter struct {
+	transport defs.Transport
+
+	writeLock  sync.Mutex
+	holdUntil  time.Time
+	pending    [][]byte    // the owner's writes queued while the transport is held
+	flushTimer *time.Timer // flushes the pending writes after the hold time
+
+	tapLock sync.RWMutex
+	taps    map[uint64]func(data []byte)
+	tapID   uint64
+}
+
+// the limit of the owner's writes queued while the remote side holds the transport, the owner's write fails with
+// defs.ErrTransportHeld error if the limit is reached
+const maxPendingWrites = 64
+
+// NewArbiter creates the sharing wrapper of provided transport
+func NewArbiter(transport defs.Transport) *Arbiter {
+	return &Arbiter{transport: transport, taps: make(map[uint64]func(data []byte))}
+}
+
+func (a *Arbiter) ID() api.TransportIdentifier {
+	return a.transport.ID()
+}
+
+// Open func
+func (a *Arbiter) Open(entry string, params api.ParamValues) error {
+	a.dropPending()
+	return a.transport.Open(entry, params)
+}
+
+// Close func, the queued owner's writes are dropped
+func (a *Arbiter) Close() error {
+	a.dropPending()
+	return a.transport.Close()
+}
+
+// ReadyToRead func
+func (a *Arbiter) ReadyToRead() <-chan struct{} {
+	return a.transport.ReadyToRead()
+}
+
+// Read func, the data read is copied to the taps
+func (a *Arbiter) Read(p []byte) (int, error) {
+	n, err := a.transport.Read(p)
+	if n > 0 {
+		a.tapLock.RLock()
+		for _, fn := range a.taps {
+			fn(append([]byte(nil), p[:n]...))
+		}
+		a.tapLock.RUnlock()
+	}
+	return n, err
+}
+
+// Write func, the owner's write is queued while the remote side holds the transport, so the caller (and its reading) is not blocked.
+// The queued writes are flushed in order when the hold time passes, their errors are not reported
+func (a *Arbiter) Write(p []byte) (int, error) {
+	a.writeLock.Lock()
+	defer a.writeLock.Unlock()
+	if len(p) == 1 || (len(a.pending) == 0 && !time.Now().Before(a.holdUntil)) {
+		return a.transport.Write(p)
+	}
+	if len(a.pending) >= maxPendingWrites {
+		return 0, defs.ErrTransportHeld
+	}
+	a.pending = append(a.pending, append([]byte(nil), p...))
+	if a.flushTimer == nil {
+		a.flushTimer = time.AfterFunc(time.Until(a.holdUntil), a.flush)
+	}
+	return len(p), nil
+}
+
+// flush writes the queued owner's writes if the hold time has passed, otherwise reschedules itself
+func (a *Arbiter) flush() {
+	a.writeLock.Lock()
+	defer a.writeLock.Unlock()
+	a.flushTimer = nil
+	if len(a.pending) == 0 {
+		return
+	}
+	if wait := time.Until(a.holdUntil); wait > 0 {
+		a.flushTimer = time.AfterFunc(wait, a.flush)
+		return
+	}
+	for _, p := range a.pending {
+		if _, err := a.transport.Write(p); err != nil {
+			break // the transport failure is reported to the owner by its next read or write
+		}
+	}
+	a.pending = nil
+}
+
+// dropPending drops the queued owner's writes
+func (a *Arbiter) dropPending() {
+	a.writeLock.Lock()
+	defer a.writeLock.Unlock()
+	if a.flushTimer != nil {
+		a.flushTimer.Stop()
+		a.flushTimer = nil
+	}
+	a.pending = nil
+}
+
+// ResolvedEntry returns the resolved entry of the wrapped transport
+func (a *Arbiter) ResolvedEntry() string {
+	if r, ok := a.transport.(defs.EntryResolver); ok {
+		return r.ResolvedEntry()
+	}
+	return ""
+}
+
+// Tap registers the function which receives the copy of all the data read from the transport
+func (a *Arbiter) Tap(fn func(data []byte)) (untap func()) {
+	a.tapLock.Lock()
+	defer a.tapLock.Unlock()
+	a.tapID++
+	id := a.tapID
+	a.taps[id] = fn
+	return func() {
+		a.tapLock.Lock()
+		defer a.tapLock.Unlock()
+		delete(a.taps, id)
+	}
+}
+
+// WriteShared writes the data on behalf of the remote side and holds the owner's writes for provided time
+func (a *Arbiter) WriteShared(p []byte, hold time.Duration) (int, error) {
+	a.writeLock.Lock()
+	defer a.writeLock.Unlock()
+	if until := time.Now().Add(hold); until.After(a.holdUntil) {
+		a.holdUntil = until
+	}
+	return a.transport.Write(p)
+}
@@ -0,0 +1,264 @@
+package bridge
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/log"
+	"github.com/stas-makutin/howeve/services/serial"
+)
+
+// log constants
+const (
+	brOpAccept = "A"
+	brOpAuth   = "U"
+	brOpOpen   = "O"
+	brOpClose  = "C"
+
+	brOsSuccess  = "0"
+	brOsFailure  = "F"
+	brOsBusy     = "B"
+	brOsOverflow = "V"
+)
+
+const (
+	defaultHoldTime = 1000 * time.Millisecond
+	authTimeout     = 5 * time.Second
+	maxTokenLength  = 1024
+	outQueueSize    = 256
+)
+
+var errOverflow = errors.New("the client is not reading the data")
+
+// bridge shares the local serial port, or the raw byte stream of the service, with single TCP client at a time
+type bridge struct {
+	cfg      *api.BridgeConfig
+	params   api.ParamValues // the serial port parameters
+	holdTime time.Duration
+
+	listener net.Listener
+	lock     sync.Mutex
+	conn     net.Conn // the active client connection
+	wg       sync.WaitGroup
+}
+
+func newBridge(cfg *api.BridgeConfig, params api.ParamValues) *bridge {
+	b := &bridge{cfg: cfg, params: params, holdTime: cfg.HoldTime.Value()}
+	if b.holdTime <= 0 {
+		b.holdTime = defaultHoldTime
+	}
+	return b
+}
+
+func (b *bridge) log(op string, fields ...string) {
+	log.Report(append([]string{log.SrcBridge, op, b.cfg.Listen}, fields...)...)
+}
+
+func (b *bridge) start() (err error) {
+	if b.listener, err = net.Listen("tcp", b.cfg.Listen); err != nil {
+		return
+	}
+	b.wg.Add(1)
+	go b.acceptLoop()
+	return
+}
+
+func (b *bridge) stop() {
+	b.listener.Close()
+	b.lock.Lock()
+	if b.conn != nil {
+		b.conn.Close()
+	}
+	b.lock.Unlock()
+	b.wg.Wait()
+}
+
+func (b *bridge) acceptLoop() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return
+		}
+
+		b.lock.Lock()
+		busy := b.conn != nil
+		if !busy {
+			b.conn = conn
+		}
+		b.lock.Unlock()
+
+		if busy {
+			b.log(brOpAccept, brOsBusy, conn.RemoteAddr().String())
+			conn.Close()
+			continue
+		}
+
+		b.log(brOpAccept, brOsSuccess, conn.RemoteAddr().String())
+		b.wg.Add(1)
+		go b.serve(conn)
+	}
+}
+
+func (b *bridge) serve(conn net.Conn) {
+	defer b.wg.Done()
+	defer func() {
+		conn.Close()
+		b.lock.Lock()
+		b.conn = nil
+		b.lock.Unlock()
+	}()
+
+	reader := bufio.NewReader(conn)
+	if !b.authenticate(conn, reader) {
+		return
+	}
+
+	stream, closeStream, err := b.openStream(conn)
+	if err != nil {
+		b.log(brOpOpen, brOsFailure, err.Error())
+		return
+	}
+	defer closeStream()
+
+	err = b.pipe(conn, reader, stream)
+	if err == errOverflow {
+		b.log(brOpClose, brOsOverflow, conn.RemoteAddr().String())
+	} else {
+		b.log(brOpClose, brOsSuccess, conn.RemoteAddr().String())
+	}
+}
+
+// authenticate verifies the token the client sends first, followed by the new line
+func (b *bridge) authenticate(conn net.Conn, reader *bufio.Reader) bool {
+	if b.cfg.Token == "" {
+		return true
+	}
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var token []byte
+	for len(token) <= maxTokenLength {
+		c, err := reader.ReadByte()
+		if err != nil {
+			b.log(brOpAuth, brOsFailure, conn.RemoteAddr().String(), err.Error())
+			return false
+		}
+		if c == '\n' {
+			if subtle.ConstantTimeCompare([]byte(strings.TrimSuffix(string(token), "\r")), []byte(b.cfg.Token)) == 1 {
+				b.log(brOpAuth, brOsSuccess, conn.RemoteAddr().String())
+				return true
+			}
+			break
+		}
+		token = append(token, c)
+	}
+	b.log(brOpAuth, brOsFailure, conn.RemoteAddr().String())
+	return false
+}
+
+// openStream returns the shared stream of the service, or opens the serial port and shares its stream
+func (b *bridge) openStream(conn net.Conn) (defs.StreamSharer, func(), error) {
+	if b.cfg.Service != "" {
+		if defs.Services == nil {
+			return nil, nil, defs.ErrServiceNotExists
+		}
+		stream, err := defs.Services.SharedStream(nil, b.cfg.Service)
+		if err != nil {
+			return nil, nil, err
+		}
+		return stream, func() {}, nil
+	}
+
+	port := NewArbiter(&serial.Transport{})
+	if err := port.Open(b.cfg.Port, b.params); err != nil {
+		return nil, nil, err
+	}
+	// nobody else reads the port, the data read goes to the taps
+	stopCh := make(chan struct{})
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		buffer := make([]byte, 4096)
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-port.ReadyToRead():
+			}
+			if _, err := port.Read(buffer); err != nil {
+				b.log(brOpOpen, brOsFailure, err.Error())
+				conn.Close() // disconnect the client, the port is opened again on the next connection
+				return
+			}
+		}
+	}()
+	return port, func() {
+		close(stopCh)
+		port.Close()
+		<-readDone
+	}, nil
+}
+
+// pipe passes the data between the client and the stream until the client disconnects
+func (b *bridge) pipe(conn net.Conn, reader *bufio.Reader, stream defs.StreamSharer) error {
+	var overflow bool
+	var overflowOnce sync.Once
+	out := make(chan []byte, outQueueSize)
+	untap := stream.Tap(func(data []byte) {
+		select {
+		case out <- data:
+		default:
+			overflowOnce.Do(func() {
+				overflow = true
+				conn.Close()
+			})
+		}
+	})
+
+	writeDone := make(chan struct{})
+	go func() {
+		defer close(writeDone)
+		for data := range out {
+			if _, err := conn.Write(data); err != nil {
+				conn.Close()
+				for range out {
+				}
+				return
+			}
+		}
+	}()
+
+	buffer := make([]byte, 4096)
+	for {
+		n, err := reader.Read(buffer)
+		if n > 0 {
+			if _, werr := stream.WriteShared(buffer[:n], b.holdTime); werr != nil {
+				b.log(brOpOpen, brOsFailure, werr.Error())
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	untap()
+	close(out)
+	<-writeDone
+
+	if overflow {
+		return errOverflow
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package bridge
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/mock"
+)
+
+// testRegistry is the services registry with the single shared service
+type testRegistry struct {
+	defs.ServiceRegistry
+	alias  string
+	stream defs.StreamSharer
+}
+
+func (r *testRegistry) SharedStream(key *api.ServiceKey, alias string) (defs.StreamSharer, error) {
+	if alias != r.alias {
+		return nil, defs.ErrServiceNotExists
+	}
+	return r.stream, nil
+}
+
+// readLoop reads the transport the same way as the service does
+func readLoop(a *Arbiter, stopCh chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	buffer := make([]byte, 64)
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-a.ReadyToRead():
+		}
+		if _, err := a.Read(buffer); err != nil {
+			return
+		}
+	}
+}
+
+func receive(t *testing.T, conn net.Conn, length int) []byte {
+	data := make([]byte, length)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(conn, data); err != nil {
+		t.Fatalf("Unable to receive: %v", err)
+	}
+	return data
+}
+
+func TestArbiter(t *testing.T) {
+	m := mock.New()
+	m.Expect([]byte{0x01}).Reply([]byte{0x10})
+	m.Expect([]byte{0x06})
+	m.Expect([]byte{0x02, 0x03})
+	a := NewArbiter(m)
+	if err := a.Open("", nil); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer a.Close()
+
+	tapped := make(chan []byte, 1)
+	untap := a.Tap(func(data []byte) { tapped <- data })
+
+	if _, err := a.WriteShared([]byte{0x01}, 100*time.Millisecond); err != nil {
+		t.Fatalf("WriteShared failed: %v", err)
+	}
+	start := time.Now()
+	if _, err := a.Write([]byte{0x02, 0x03}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Errorf("The held write must not block the caller, returned after %v", d)
+	}
+	// the acknowledgement is not held
+	if _, err := a.Write([]byte{0x06}); err != nil || len(m.Writes()) != 2 {
+		t.Errorf("The single byte write must be written immediately: %v", err)
+	}
+
+	<-a.ReadyToRead()
+	data := make([]byte, 4)
+	if n, err := a.Read(data); n != 1 || err != nil {
+		t.Fatalf("Read result is %d, %v", n, err)
+	}
+	select {
+	case d := <-tapped:
+		if !bytes.Equal(d, []byte{0x10}) {
+			t.Errorf("Tapped data is %x", d)
+		}
+	default:
+		t.Error("The data read must be tapped")
+	}
+	untap()
+
+	if err := m.Wait(time.Second); err != nil {
+		t.Error(err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("The write must be held after the shared write, written after %v", d)
+	}
+}
+
+func TestBridgeService(t *testing.T) {
+	m := mock.New()
+	m.Expect([]byte{0x01})
+	m.Expect([]byte{0x02}).Reply([]byte{0x06}, []byte{0x03})
+	a := NewArbiter(m)
+	if err := a.Open("", nil); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer a.Close()
+	stopCh := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go readLoop(a, stopCh, &wg)
+	defer func() {
+		close(stopCh)
+		wg.Wait()
+	}()
+
+	services := defs.Services
+	defs.Services = &testRegistry{alias: "zw", stream: a}
+	defer func() { defs.Services = services }()
+
+	b := newBridge(&api.BridgeConfig{Listen: "127.0.0.1:0", Service: "zw", Token: "secret"}, nil)
+	if err := b.start(); err != nil {
+		t.Fatalf("Unable to start the bridge: %v", err)
+	}
+	defer b.stop()
+	addr := b.listener.Addr().String()
+
+	// wrong token
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+	conn.Write([]byte("wrong\n\x01"))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("The connection with wrong token must be closed")
+	}
+	conn.Close()
+
+	conn, err = net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("secret\r\n\x01"))
+
+	// the second client is rejected while the first one is connected
+	time.Sleep(50 * time.Millisecond)
+	if second, err := net.Dial("tcp", addr); err == nil {
+		second.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := second.Read(make([]byte, 1)); err == nil {
+			t.Error("The second connection must be closed")
+		}
+		second.Close()
+	}
+
+	conn.Write([]byte{0x02})
+	if data := receive(t, conn, 2); !bytes.Equal(data, []byte{0x06, 0x03}) {
+		t.Errorf("Received %x", data)
+	}
+	if err := m.Wait(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestArbiterHeld(t *testing.T) {
+	m := mock.New()
+	m.Expect([]byte{0x01})
+	a := NewArbiter(m)
+	if err := a.Open("", nil); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer a.Close()
+
+	if _, err := a.WriteShared([]byte{0x01}, time.Hour); err != nil {
+		t.Fatalf("WriteShared failed: %v", err)
+	}
+	for i := 0; i < maxPendingWrites; i++ {
+		if _, err := a.Write([]byte{0x02, 0x03}); err != nil {
+			t.Fatalf("Write %d failed: %v", i+1, err)
+		}
+	}
+	if _, err := a.Write([]byte{0x02, 0x03}); err != defs.ErrTransportHeld {
+		t.Errorf("The write over the limit must fail with ErrTransportHeld, got %v", err)
+	}
+	if len(m.Writes()) != 1 {
+		t.Errorf("The held writes must not be written, %d writes", len(m.Writes()))
+	}
+}
@@ -0,0 +1,82 @@
+package bridge
+
+import (
+	"fmt"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/config"
+	"github.com/stas-makutin/howeve/services/serial"
+	"github.com/stas-makutin/howeve/tasks"
+)
+
+// Task struct - network bridges task implementation
+type Task struct {
+	cfg     []api.BridgeConfig
+	bridges []*bridge
+}
+
+// NewTask func
+func NewTask() *Task {
+	t := &Task{}
+	config.AddReader(t.readConfig)
+	config.AddWriter(t.writeConfig)
+	return t
+}
+
+func (t *Task) readConfig(cfg *api.Config, cfgError config.Error) {
+	t.cfg = cfg.Bridges
+	t.bridges = nil
+	for i := range t.cfg {
+		bc := &t.cfg[i]
+		if bc.Listen == "" {
+			cfgError(fmt.Sprintf("bridges[%d].listen is required", i))
+		}
+		if (bc.Port == "") == (bc.Service == "") {
+			cfgError(fmt.Sprintf("bridges[%d] requires either port or service", i))
+			continue
+		}
+		var params api.ParamValues
+		if bc.Port != "" {
+			var err error
+			if params, err = serial.TransportInfo.Params.ParseValues(bc.Params); err != nil {
+				cfgError(fmt.Sprintf("bridges[%d].params are not valid: %v", i, err))
+				continue
+			}
+			// the port is read by the background reader, the timeouts must be 0 for non-blocking read/write operations
+			params[serial.ParamNameReadTimeout] = uint32(0)
+			params[serial.ParamNameWriteTimeout] = uint32(0)
+		} else if len(bc.Params) > 0 {
+			cfgError(fmt.Sprintf("bridges[%d].params are applicable to the port only", i))
+		}
+		t.bridges = append(t.bridges, newBridge(bc, params))
+	}
+}
+
+func (t *Task) writeConfig(cfg *api.Config) {
+	cfg.Bridges = t.cfg
+}
+
+// Open func
+func (t *Task) Open(ctx *tasks.ServiceTaskContext) error {
+	for i, b := range t.bridges {
+		if err := b.start(); err != nil {
+			for _, started := range t.bridges[:i] {
+				started.stop()
+			}
+			return fmt.Errorf("bridge listen on %s failed: %v", b.cfg.Listen, err)
+		}
+	}
+	return nil
+}
+
+// Close func
+func (t *Task) Close(ctx *tasks.ServiceTaskContext) error {
+	return nil
+}
+
+// Stop func
+func (t *Task) Stop(ctx *tasks.ServiceTaskContext) {
+	for _, b := range t.bridges {
+		b.stop()
+	}
+}
@@ -0,0 +1,106 @@
+package capture
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+)
+
+// Transport struct - the transport wrapper which captures all the operations of the wrapped transport into the file
+type Transport struct {
+	transport defs.Transport
+	fileName  string
+	lock      sync.Mutex
+	file      *os.File
+}
+
+// NewTransport creates the capturing wrapper of provided transport, the records are appended to the file
+func NewTransport(transport defs.Transport, fileName string) *Transport {
+	return &Transport{transport: transport, fileName: fileName}
+}
+
+func (t *Transport) ID() api.TransportIdentifier {
+	return t.transport.ID()
+}
+
+// capture appends the record to the capture file, the capture failures do not affect the transport
+func (t *Transport) capture(kind byte, data []byte) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.file == nil {
+		file, err := os.OpenFile(t.fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return
+		}
+		if info, err := file.Stat(); err == nil && info.Size() == 0 {
+			file.WriteString(captureHeader + "\n")
+		}
+		t.file = file
+	}
+	r := &record{time: time.Now(), kind: kind, data: data}
+	t.file.WriteString(r.String() + "\n")
+}
+
+// Open func
+func (t *Transport) Open(entry string, params api.ParamValues) error {
+	err := t.transport.Open(entry, params)
+	if err != nil {
+		t.capture(recordOpenError, []byte(err.Error()))
+	} else {
+		t.capture(recordOpen, []byte(entry))
+	}
+	return err
+}
+
+// Close func, closes the capture file as well
+func (t *Transport) Close() error {
+	err := t.transport.Close()
+	t.capture(recordClose, nil)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+	return err
+}
+
+// ResolvedEntry returns the resolved entry of the wrapped transport
+func (t *Transport) ResolvedEntry() string {
+	if r, ok := t.transport.(defs.EntryResolver); ok {
+		return r.ResolvedEntry()
+	}
+	return ""
+}
+
+// ReadyToRead function
+func (t *Transport) ReadyToRead() <-chan struct{} {
+	return t.transport.ReadyToRead()
+}
+
+// Read func
+func (t *Transport) Read(p []byte) (int, error) {
+	n, err := t.transport.Read(p)
+	if n > 0 {
+		t.capture(recordRead, p[:n])
+	}
+	if err != nil {
+		t.capture(recordReadError, []byte(err.Error()))
+	}
+	return n, err
+}
+
+// Write func
+func (t *Transport) Write(p []byte) (int, error) {
+	n, err := t.transport.Write(p)
+	if err != nil {
+		t.capture(recordWriteError, []byte(err.Error()))
+	} else {
+		t.capture(recordWrite, p[:n])
+	}
+	return n, err
+}
@@ -0,0 +1,129 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/services/mock"
+)
+
+func readChunk(t *testing.T, tr interface {
+	ReadyToRead() <-chan struct{}
+	Read(p []byte) (int, error)
+}) ([]byte, error) {
+	select {
+	case <-tr.ReadyToRead():
+	case <-time.After(time.Second):
+		t.Fatal("ReadyToRead is not signaled")
+	}
+	data := make([]byte, 64)
+	n, err := tr.Read(data)
+	return data[:n], err
+}
+
+func TestRecord(t *testing.T) {
+	ts := time.Date(2026, 10, 19, 11, 49, 44, 123456789, time.UTC)
+	for _, r := range []*record{
+		{time: ts, kind: recordOpen, data: []byte("/dev/ttyACM0")},
+		{time: ts, kind: recordRead, data: []byte{0x06, 0x01, 0x03}},
+		{time: ts, kind: recordWriteError, data: []byte("write failed")},
+		{time: ts, kind: recordClose},
+	} {
+		line := r.String()
+		pr, err := parseRecord(line)
+		if err != nil || !pr.time.Equal(r.time) || pr.kind != r.kind || !bytes.Equal(pr.data, r.data) {
+			t.Errorf("The record '%s' is parsed as %v, %v", line, pr, err)
+		}
+	}
+	for _, line := range []string{"2026-10-19 R 00", "2026-10-19T11:49:44Z Q", "2026-10-19T11:49:44Z W 0x"} {
+		if _, err := parseRecord(line); err == nil {
+			t.Errorf("The line '%s' must not be valid", line)
+		}
+	}
+}
+
+func TestCaptureReplay(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "capture.log")
+
+	m := mock.New()
+	m.FailOpen(errors.New("no device"))
+	m.Emit([]byte{0x01, 0x02})
+	m.Expect([]byte{0x10}).ReplyAfter(50*time.Millisecond, []byte{0x20})
+	m.Expect([]byte{0x30}).WriteError(errors.New("write failed"))
+
+	ct := NewTransport(m, fileName)
+	if ct.ID() != api.TransportMock {
+		t.Errorf("The capturing transport must keep the wrapped transport identifier")
+	}
+	if err := ct.Open("entry", nil); err == nil {
+		t.Fatal("Open must fail")
+	}
+	if err := ct.Open("entry", nil); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if data, _ := readChunk(t, ct); !bytes.Equal(data, []byte{0x01, 0x02}) {
+		t.Errorf("Unexpected data %x", data)
+	}
+	ct.Write([]byte{0x10})
+	if data, _ := readChunk(t, ct); !bytes.Equal(data, []byte{0x20}) {
+		t.Errorf("Unexpected data %x", data)
+	}
+	ct.Write([]byte{0x30})
+	ct.Close()
+
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("unable to read the capture file: %v", err)
+	}
+	var kinds string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n")[1:] {
+		kinds += strings.Fields(line)[1]
+	}
+	if kinds != "FORWRXC" {
+		t.Fatalf("Unexpected capture records %s:\n%s", kinds, content)
+	}
+
+	for _, timing := range []string{TimingNone, TimingOriginal} {
+		t.Run("Replay with "+timing+" timing", func(t *testing.T) {
+			rt := &Replay{}
+			defer rt.Close()
+			params := api.ParamValues{ParamNameTiming: timing}
+			if err := rt.Open(fileName, params); err == nil || err.Error() != "no device" {
+				t.Errorf("Open must fail with the captured error, got %v", err)
+			}
+			if err := rt.Open(fileName, params); err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			if data, _ := readChunk(t, rt); !bytes.Equal(data, []byte{0x01, 0x02}) {
+				t.Errorf("Unexpected data %x", data)
+			}
+			select {
+			case <-rt.ReadyToRead():
+				t.Error("The replay must wait for the write")
+			case <-time.After(20 * time.Millisecond):
+			}
+			start := time.Now()
+			if n, err := rt.Write([]byte{0x10}); n != 1 || err != nil {
+				t.Errorf("Write result is %d, %v", n, err)
+			}
+			if data, _ := readChunk(t, rt); !bytes.Equal(data, []byte{0x20}) {
+				t.Errorf("Unexpected data %x", data)
+			}
+			if d := time.Since(start); timing == TimingOriginal && d < 40*time.Millisecond {
+				t.Errorf("The captured delay is not kept, the data is replayed after %v", d)
+			}
+			if _, err := rt.Write([]byte{0x30}); err == nil || err.Error() != "write failed" {
+				t.Errorf("Write must fail with the captured error, got %v", err)
+			}
+			if err := rt.Open(fileName, params); err != ErrReplayCompleted {
+				t.Errorf("Open must fail when the capture is replayed, got %v", err)
+			}
+		})
+	}
+}
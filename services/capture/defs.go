@@ -0,0 +1,33 @@
+package capture
+
+import "github.com/stas-makutin/howeve/defs"
+
+// ParamNameTiming replay transport parameter name for the timing of the replayed data
+const ParamNameTiming = "timing"
+
+// timing parameter values
+const (
+	TimingOriginal = "original"
+	TimingNone     = "none"
+)
+
+// Params are the service parameters of the transport capture common for all protocols
+var Params = defs.Params{
+	defs.ParamNameCaptureFile: {
+		Description:  "The file to capture the transport data into, the file could be played back using Replay transport, empty to disable",
+		Type:         defs.ParamTypeString,
+		DefaultValue: "",
+	},
+}
+
+var ReplayTransportInfo *defs.TransportInfo = &defs.TransportInfo{
+	Name: "Replay",
+	Params: defs.Params{
+		ParamNameTiming: {
+			Description:  "The timing of the replayed data: original to keep the captured delays between the chunks, or none to replay without delays",
+			Type:         defs.ParamTypeEnum,
+			DefaultValue: TimingOriginal,
+			EnumValues:   []string{TimingOriginal, TimingNone},
+		},
+	},
+}
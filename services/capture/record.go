@@ -0,0 +1,91 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// The capture file is the text file, one record per line:
+//   timestamp (RFC 3339 with nanoseconds, UTC) kind [data]
+// The data of read and write records is hex encoded, the data of other records is the entry or the error message.
+// The lines starting with # are comments
+
+// record kinds
+const (
+	recordOpen       = 'O' // the transport is opened, the data is the entry
+	recordOpenError  = 'F' // the transport open failed, the data is the error message
+	recordRead       = 'R' // the chunk is read
+	recordReadError  = 'E' // the reading failed, the data is the error message
+	recordWrite      = 'W' // the chunk is written
+	recordWriteError = 'X' // the writing failed, the data is the error message
+	recordClose      = 'C' // the transport is closed
+)
+
+// the header of the capture file
+const captureHeader = "# howeve transport capture v1"
+
+// record is the single captured transport operation
+type record struct {
+	time time.Time
+	kind byte
+	data []byte // the chunk for read and write records, the text otherwise
+}
+
+// isChunk verifies if the record's data is the chunk of bytes
+func (r *record) isChunk() bool {
+	return r.kind == recordRead || r.kind == recordWrite
+}
+
+// String returns the capture file line of the record, without the line break
+func (r *record) String() string {
+	line := r.time.UTC().Format(time.RFC3339Nano) + " " + string(r.kind)
+	if r.isChunk() {
+		line += " " + hex.EncodeToString(r.data)
+	} else if len(r.data) > 0 {
+		line += " " + strings.ReplaceAll(string(r.data), "\n", " ")
+	}
+	return line
+}
+
+// parseRecord parses the capture file line
+func parseRecord(line string) (*record, error) {
+	ts, rest, _ := strings.Cut(line, " ")
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp '%s'", ts)
+	}
+	kind, data, _ := strings.Cut(rest, " ")
+	if len(kind) != 1 || !strings.Contains("OFREWXC", kind) {
+		return nil, fmt.Errorf("invalid record kind '%s'", kind)
+	}
+	r := &record{time: t, kind: kind[0], data: []byte(data)}
+	if r.isChunk() {
+		if r.data, err = hex.DecodeString(data); err != nil {
+			return nil, fmt.Errorf("invalid data: %s", err.Error())
+		}
+	}
+	return r, nil
+}
+
+// readRecords reads the capture file content
+func readRecords(source io.Reader) ([]*record, error) {
+	var records []*record
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		r, err := parseRecord(line)
+		if err != nil {
+			return nil, fmt.Errorf("the capture file line %d: %s", n, err.Error())
+		}
+		records = append(records, r)
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,197 @@
+package capture
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/stream"
+)
+
+// ErrReplayCompleted returned by Open when all captured sessions are replayed
+var ErrReplayCompleted = errors.New("the capture is completely replayed")
+
+// replaySession is the captured records between two Open calls
+type replaySession struct {
+	records []*record // the records after the open record
+	writes  []*record // the write and write error records
+	written int       // the number of writes happened during the replay
+	changed chan struct{}
+}
+
+// Replay struct - the transport which plays the capture file back, the entry is the capture file name.
+// Every Open call replays the next captured session, the read chunks are delivered in the captured order and,
+// optionally, with the captured delays. The replay waits for the writes where they were captured
+type Replay struct {
+	lock     sync.Mutex
+	fileName string
+	sessions [][]*record
+	next     int
+	session  *replaySession
+	stopCh   chan struct{}
+	pr       *io.PipeReader
+	reader   stream.Reader
+	playWg   sync.WaitGroup
+}
+
+func (t *Replay) ID() api.TransportIdentifier {
+	return api.TransportReplay
+}
+
+// loadSessions reads the capture file and splits its records into sessions
+func loadSessions(fileName string) ([][]*record, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records, err := readRecords(file)
+	if err != nil {
+		return nil, err
+	}
+	var sessions [][]*record
+	for _, r := range records {
+		if r.kind == recordOpen || r.kind == recordOpenError {
+			sessions = append(sessions, nil)
+		}
+		if len(sessions) > 0 {
+			sessions[len(sessions)-1] = append(sessions[len(sessions)-1], r)
+		}
+	}
+	return sessions, nil
+}
+
+// Open func
+func (t *Replay) Open(entry string, params api.ParamValues) error {
+	timing := true
+	if v, ok := params[ParamNameTiming]; ok {
+		timing = v.(string) != TimingNone
+	}
+
+	t.Close()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.sessions == nil || t.fileName != entry {
+		sessions, err := loadSessions(entry)
+		if err != nil {
+			return err
+		}
+		t.fileName, t.sessions, t.next = entry, sessions, 0
+	}
+	if t.next >= len(t.sessions) {
+		return ErrReplayCompleted
+	}
+	records := t.sessions[t.next]
+	t.next++
+	if records[0].kind == recordOpenError {
+		return errors.New(string(records[0].data))
+	}
+
+	s := &replaySession{records: records, changed: make(chan struct{})}
+	for _, r := range records {
+		if r.kind == recordWrite || r.kind == recordWriteError {
+			s.writes = append(s.writes, r)
+		}
+	}
+	var pw *io.PipeWriter
+	t.pr, pw = io.Pipe()
+	t.session = s
+	t.stopCh = make(chan struct{})
+	t.reader.Start(t.pr)
+	t.playWg.Add(1)
+	go t.play(s, pw, timing, t.stopCh)
+	return nil
+}
+
+// play delivers the session's read chunks, it waits for the replayed writes where the writes were captured
+func (t *Replay) play(s *replaySession, pw *io.PipeWriter, timing bool, stopCh chan struct{}) {
+	defer t.playWg.Done()
+	prev := s.records[0].time
+	writes := 0
+	for _, r := range s.records[1:] {
+		switch r.kind {
+		case recordRead, recordReadError:
+			if delay := r.time.Sub(prev); timing && delay > 0 {
+				select {
+				case <-stopCh:
+					return
+				case <-time.After(delay):
+				}
+			}
+			if r.kind == recordReadError {
+				pw.CloseWithError(errors.New(string(r.data)))
+				return
+			}
+			if _, err := pw.Write(r.data); err != nil {
+				return
+			}
+		case recordWrite, recordWriteError:
+			writes++
+			for {
+				t.lock.Lock()
+				written, changed := s.written, s.changed
+				t.lock.Unlock()
+				if written >= writes {
+					break
+				}
+				select {
+				case <-stopCh:
+					return
+				case <-changed:
+				}
+			}
+		default:
+			continue
+		}
+		prev = r.time
+	}
+}
+
+// Close func
+func (t *Replay) Close() error {
+	t.lock.Lock()
+	if t.session == nil {
+		t.lock.Unlock()
+		return nil
+	}
+	t.session = nil
+	close(t.stopCh)
+	pr := t.pr
+	t.lock.Unlock()
+
+	err := t.reader.Stop(pr.Close)
+	t.playWg.Wait()
+	return err
+}
+
+// ReadyToRead function, singal in the channel if something could be read or the transport state has changed
+func (t *Replay) ReadyToRead() <-chan struct{} {
+	return t.reader.ReadyToRead()
+}
+
+// Read func, returns immediately with the replayed data so far
+func (t *Replay) Read(p []byte) (int, error) {
+	return t.reader.Read(p)
+}
+
+// Write func, the written data is not verified, the captured write errors are replayed
+func (t *Replay) Write(p []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	s := t.session
+	if s == nil {
+		return 0, defs.ErrNotOpen
+	}
+	s.written++
+	close(s.changed)
+	s.changed = make(chan struct{})
+	if s.written <= len(s.writes) && s.writes[s.written-1].kind == recordWriteError {
+		return 0, errors.New(string(s.writes[s.written-1].data))
+	}
+	return len(p), nil
+}
@@ -3,21 +3,271 @@ package services
 import (
 	"github.com/stas-makutin/howeve/api"
 	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/backoff"
+	"github.com/stas-makutin/howeve/services/bridge"
+	"github.com/stas-makutin/howeve/services/capture"
+	"github.com/stas-makutin/howeve/services/insteon"
+	"github.com/stas-makutin/howeve/services/knx"
+	"github.com/stas-makutin/howeve/services/modbus"
+	"github.com/stas-makutin/howeve/services/mqtt"
+	"github.com/stas-makutin/howeve/services/pty"
+	"github.com/stas-makutin/howeve/services/raw"
+	"github.com/stas-makutin/howeve/services/rfc2217"
 	"github.com/stas-makutin/howeve/services/serial"
+	"github.com/stas-makutin/howeve/services/simulator"
+	"github.com/stas-makutin/howeve/services/tcp"
+	"github.com/stas-makutin/howeve/services/udp"
+	"github.com/stas-makutin/howeve/services/unixsock"
+	"github.com/stas-makutin/howeve/services/zigbee"
 	"github.com/stas-makutin/howeve/services/zwave"
 )
 
 var transports = map[api.TransportIdentifier]*defs.TransportInfo{
-	api.TransportSerial: serial.TransportInfo,
+	api.TransportSerial:    serial.TransportInfo,
+	api.TransportTCP:       tcp.TransportInfo,
+	api.TransportRFC2217:   rfc2217.TransportInfo,
+	api.TransportSimulator: simulator.TransportInfo,
+	api.TransportReplay:    capture.ReplayTransportInfo,
+	api.TransportUnix:      unixsock.TransportInfo,
+	api.TransportPTY:       pty.TransportInfo,
+	api.TransportUDP:       udp.TransportInfo,
+	api.TransportMQTT:      mqtt.TransportInfo,
 }
 
+// tcpDiscoveryParams returns the parameters of TCP endpoints discovery with provided default list of mDNS service types
+func tcpDiscoveryParams(serviceTypes string) defs.Params {
+	return defs.Params{
+		tcp.ParamNameMDNSServices: {
+			Description:  "The comma-separated list of DNS-SD service types to query over mDNS, empty to disable",
+			Type:         defs.ParamTypeString,
+			DefaultValue: serviceTypes,
+		},
+		tcp.ParamNameMDNSAddress: {
+			Description:  "The address (host:port) to send mDNS query to",
+			Type:         defs.ParamTypeString,
+			DefaultValue: "224.0.0.251:5353",
+		},
+		tcp.ParamNameSSDPTarget: {
+			Description:  "The SSDP search target, empty to disable",
+			Type:         defs.ParamTypeString,
+			DefaultValue: "",
+		},
+		tcp.ParamNameSSDPAddress: {
+			Description:  "The address (host:port) to send SSDP search request to",
+			Type:         defs.ParamTypeString,
+			DefaultValue: "239.255.255.250:1900",
+		},
+		tcp.ParamNameSSDPPort: {
+			Description:  "The TCP port of the devices found by SSDP search, SSDP search is disabled if 0",
+			Type:         defs.ParamTypeUint16,
+			DefaultValue: "0",
+		},
+		tcp.ParamNameSearchTimeout: {
+			Description:  "The time to wait for mDNS and SSDP responses, milliseconds",
+			Type:         defs.ParamTypeUint32,
+			DefaultValue: "3000",
+		},
+	}
+}
+
+// sharedService struct - the service with the raw byte stream of its transport, the registry unwraps it when the service is added
+type sharedService struct {
+	defs.Service
+	stream defs.StreamSharer
+}
+
+// newService creates the service of the protocol on provided transport, the transport data is captured into the file
+// if the capture file parameter is set. The transport is shared with the remote side (see bridge.Arbiter)
+func newService(transport defs.Transport, entry string, params api.ParamValues, create func(transport defs.Transport, entry string, params api.ParamValues) (defs.Service, error)) (defs.Service, error) {
+	if fileName, _ := params[defs.ParamNameCaptureFile].(string); fileName != "" {
+		transport = capture.NewTransport(transport, fileName)
+	}
+	arbiter := bridge.NewArbiter(transport)
+	service, err := create(arbiter, entry, params)
+	if err != nil {
+		return nil, err
+	}
+	return &sharedService{Service: service, stream: arbiter}, nil
+}
+
+// Z-Wave parameters common for all transports
+var zwaveParams = defs.Params{
+	defs.ParamNameOutgoingMaxTTL: {
+		Description:  "The time to live of outgoing message before it will be sent, milliseconds",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "10000",
+	},
+	zwave.ParamNameRSSIInterval: {
+		Description:  "The interval of background RSSI polling, milliseconds, 0 to disable",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "60000",
+	},
+	zwave.ParamNameBatteryThreshold: {
+		Description:  "The battery level, in percents, at or below which the low battery event is sent",
+		Type:         defs.ParamTypeUint8,
+		DefaultValue: "20",
+	},
+	zwave.ParamNameBatteryTimeout: {
+		Description:  "The time after the last battery report when the node is considered as not reporting, milliseconds, 0 to disable",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "172800000",
+	},
+	zwave.ParamNameBatteryPollInterval: {
+		Description:  "The interval of battery level polling, milliseconds, 0 to disable",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "21600000",
+	},
+	zwave.ParamNameClockSyncInterval: {
+		Description:  "The interval of setting the clock of the nodes, milliseconds, 0 to disable",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "86400000",
+	},
+	zwave.ParamNameNodeIDType: {
+		Description:  "The node identifiers type: 8bit for Z-Wave classic only, 16bit to support Z-Wave Long Range nodes, or auto to detect",
+		Type:         defs.ParamTypeEnum,
+		DefaultValue: zwave.NodeIDTypeAuto,
+		EnumValues:   []string{zwave.NodeIDTypeAuto, zwave.NodeIDType8Bit, zwave.NodeIDType16Bit},
+	},
+}.Merge(backoff.Params).Merge(capture.Params)
+
+// Modbus parameters common for all transports
+var modbusParams = defs.Params{
+	modbus.ParamNameResponseTimeout: {
+		Description:  "The time to wait for the response of the device, milliseconds",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "1000",
+	},
+	modbus.ParamNamePoll: {
+		Description:  "The comma-separated list of unit:table:address:count@interval entries to poll periodically, the interval is in milliseconds, empty to disable",
+		Type:         defs.ParamTypeString,
+		DefaultValue: "",
+	},
+}.Merge(backoff.Params).Merge(capture.Params)
+
+// Insteon parameters common for all transports
+var insteonParams = defs.Params{
+	insteon.ParamNameResponseTimeout: {
+		Description:  "The time to wait for the acknowledgement or the response of the device, milliseconds",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "3000",
+	},
+}.Merge(backoff.Params).Merge(capture.Params)
+
+// raw protocol parameters common for all transports
+var rawParams = defs.Params{
+	raw.ParamNameFraming: {
+		Description:  "The framing of the data: delimiter-terminated, fixed-length, length-prefixed, or enclosed by STX and ETX bytes",
+		Type:         defs.ParamTypeEnum,
+		DefaultValue: raw.FramingDelimiter,
+		EnumValues:   []string{raw.FramingDelimiter, raw.FramingFixed, raw.FramingLength, raw.FramingSTXETX},
+	},
+	raw.ParamNameDelimiter: {
+		Description:  "The hexadecimal bytes which end the frame, delimiter framing",
+		Type:         defs.ParamTypeString,
+		DefaultValue: "0d",
+	},
+	raw.ParamNameFrameLength: {
+		Description:  "The length of the frame, fixed-length framing",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "0",
+	},
+	raw.ParamNameLengthSize: {
+		Description:  "The size of the big-endian length field, 1 or 2 bytes, length-prefixed framing",
+		Type:         defs.ParamTypeUint8,
+		DefaultValue: "1",
+	},
+	raw.ParamNameLengthAdjust: {
+		Description:  "The value added to the length field to get the length of the data which follows it, length-prefixed framing",
+		Type:         defs.ParamTypeInt32,
+		DefaultValue: "0",
+	},
+	raw.ParamNameSTX: {
+		Description:  "The byte which starts the frame, STX/ETX framing",
+		Type:         defs.ParamTypeUint8,
+		DefaultValue: "2",
+	},
+	raw.ParamNameETX: {
+		Description:  "The byte which ends the frame data, STX/ETX framing",
+		Type:         defs.ParamTypeUint8,
+		DefaultValue: "3",
+	},
+	raw.ParamNameChecksum: {
+		Description:  "The checksum of the data and ETX byte which follows ETX byte, STX/ETX framing",
+		Type:         defs.ParamTypeEnum,
+		DefaultValue: raw.ChecksumNone,
+		EnumValues:   []string{raw.ChecksumNone, raw.ChecksumXOR, raw.ChecksumSum, raw.ChecksumCRC16CCITT, raw.ChecksumCRC16Modbus},
+	},
+	raw.ParamNameMaxFrameLength: {
+		Description:  "The maximal length of the frame data",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "256",
+	},
+	raw.ParamNameFrameTimeout: {
+		Description:  "The time after which the incomplete frame is discarded, milliseconds, 0 to wait forever",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "1000",
+	},
+}.Merge(backoff.Params).Merge(capture.Params)
+
+// KNX parameters common for all transports
+var knxParams = defs.Params{
+	knx.ParamNameGroups: {
+		Description:  "The comma-separated list of group:dpt entries, like 1/2/3:9.001, the values of the listed groups are decoded and logged",
+		Type:         defs.ParamTypeString,
+		DefaultValue: "",
+	},
+	knx.ParamNameHeartbeatInterval: {
+		Description:  "The time interval between the connection state requests, milliseconds",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "60000",
+	},
+}.Merge(backoff.Params).Merge(capture.Params)
+
+// Zigbee parameters common for all transports
+var zigbeeParams = defs.Params{
+	zigbee.ParamNameNetworkMode: {
+		Description:  "What to do if NCP is not joined to any network: coordinator to form the new network, router to join the existing one, existing to do nothing",
+		Type:         defs.ParamTypeEnum,
+		DefaultValue: zigbee.NetworkModeCoordinator,
+		EnumValues:   []string{zigbee.NetworkModeCoordinator, zigbee.NetworkModeRouter, zigbee.NetworkModeExisting},
+	},
+	zigbee.ParamNameChannel: {
+		Description:  "The radio channel of the network to form or join, 11 - 26",
+		Type:         defs.ParamTypeUint8,
+		DefaultValue: "15",
+	},
+	zigbee.ParamNamePanID: {
+		Description:  "The PAN ID of the network to form, 0 for the random one",
+		Type:         defs.ParamTypeUint16,
+		DefaultValue: "0",
+	},
+	zigbee.ParamNameExtendedPanID: {
+		Description:  "The extended PAN ID of the network to form or join, 16 hexadecimal digits, empty for the random one or to join any network",
+		Type:         defs.ParamTypeString,
+		DefaultValue: "",
+	},
+	zigbee.ParamNameNetworkKey: {
+		Description:  "The network key of the network to form, 32 hexadecimal digits, empty for the random one",
+		Type:         defs.ParamTypeString,
+		DefaultValue: "",
+	},
+	zigbee.ParamNamePermitJoin: {
+		Description:  "The time the new nodes are permitted to join the network after it is up, seconds, 0 to disable, 255 to permit until restart",
+		Type:         defs.ParamTypeUint8,
+		DefaultValue: "0",
+	},
+}.Merge(backoff.Params).Merge(capture.Params)
+
+// MQTT protocol parameters common for all transports
+var mqttParams = backoff.Params.Merge(capture.Params)
+
 var protocols = map[api.ProtocolIdentifier]*defs.ProtocolInfo{
 	api.ProtocolZWave: {
 		Name: "Z-Wave",
 		Transports: map[api.TransportIdentifier]*defs.ProtocolTransportOptions{
 			api.TransportSerial: {
 				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
-					return zwave.NewService(&serial.Transport{}, entry, params)
+					return newService(&serial.Transport{}, entry, params, zwave.NewService)
 				},
 				DiscoveryFunc: zwave.DiscoverSerial,
 				Params: defs.Params{
@@ -41,12 +291,331 @@ var protocols = map[api.ProtocolIdentifier]*defs.ProtocolInfo{
 						Type:         defs.ParamTypeUint32,
 						DefaultValue: "3000",
 					},
-					defs.ParamNameOutgoingMaxTTL: {
-						Description:  "The time to live of outgoing message before it will be sent, milliseconds",
+				}.Merge(zwaveParams),
+			},
+			api.TransportTCP: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&tcp.Transport{}, entry, params, zwave.NewService)
+				},
+				DiscoveryFunc:   zwave.DiscoverTCP,
+				DiscoveryParams: tcpDiscoveryParams("_iostream._tcp"),
+				Params: defs.Params{
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to connect, milliseconds",
 						Type:         defs.ParamTypeUint32,
-						DefaultValue: "10000",
+						DefaultValue: "3000",
 					},
+				}.Merge(zwaveParams),
+			},
+			api.TransportRFC2217: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&rfc2217.Transport{}, entry, params, zwave.NewService)
 				},
+				Params: defs.Params{
+					serial.ParamNameDataBits: &defs.ParamInfo{
+						Type:         defs.ParamTypeString,
+						DefaultValue: "8",
+						Flags:        defs.ParamFlagConst,
+					},
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to connect, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(zwaveParams),
+			},
+			api.TransportUnix: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&unixsock.Transport{}, entry, params, zwave.NewService)
+				},
+				Params: defs.Params{
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to connect, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(zwaveParams),
+			},
+			api.TransportPTY: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&pty.Transport{}, entry, params, zwave.NewService)
+				},
+				Params: defs.Params{
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to open the terminal, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(zwaveParams),
+			},
+			api.TransportSimulator: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&simulator.Transport{}, entry, params, zwave.NewService)
+				},
+				DiscoveryFunc: zwave.DiscoverSimulator,
+				Params:        zwaveParams,
+			},
+			api.TransportReplay: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&capture.Replay{}, entry, params, zwave.NewService)
+				},
+				Params: zwaveParams,
+			},
+		},
+	},
+	api.ProtocolModbus: {
+		Name: "Modbus",
+		Transports: map[api.TransportIdentifier]*defs.ProtocolTransportOptions{
+			api.TransportSerial: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&serial.Transport{}, entry, params, func(transport defs.Transport, entry string, params api.ParamValues) (defs.Service, error) {
+						return modbus.NewService(transport, modbus.FramingRTU, entry, params)
+					})
+				},
+				Params: defs.Params{
+					serial.ParamNameBaudRate: &defs.ParamInfo{
+						Description:  "The serial port bitrate",
+						Type:         defs.ParamTypeInt32,
+						DefaultValue: "19200",
+					},
+					serial.ParamNameParity: &defs.ParamInfo{
+						Description:  "The parity",
+						Type:         defs.ParamTypeEnum,
+						DefaultValue: "even",
+						EnumValues:   []string{"none", "odd", "even", "mark", "space"},
+					},
+					serial.ParamNameReadTimeout: &defs.ParamInfo{
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "0",
+						Flags:        defs.ParamFlagConst,
+					},
+					serial.ParamNameWriteTimeout: &defs.ParamInfo{
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "0",
+						Flags:        defs.ParamFlagConst,
+					},
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to open serial port, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(modbusParams),
+			},
+			api.TransportTCP: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&tcp.Transport{}, entry, params, func(transport defs.Transport, entry string, params api.ParamValues) (defs.Service, error) {
+						return modbus.NewService(transport, modbus.FramingTCP, entry, params)
+					})
+				},
+				DiscoveryFunc: modbus.DiscoverTCP,
+				DiscoveryParams: defs.Params{
+					modbus.ParamNameScanNetwork: {
+						Description:  "The IPv4 network (CIDR) which hosts are probed for Modbus TCP devices, up to 1024 addresses, empty to disable",
+						Type:         defs.ParamTypeString,
+						DefaultValue: "",
+					},
+					modbus.ParamNameScanPort: {
+						Description:  "The TCP port to probe while scanning the network",
+						Type:         defs.ParamTypeUint16,
+						DefaultValue: "502",
+					},
+					modbus.ParamNameProbeUnit: {
+						Description:  "The unit identifier of the probe request",
+						Type:         defs.ParamTypeUint8,
+						DefaultValue: "1",
+					},
+					modbus.ParamNameProbeTimeout: {
+						Description:  "The time to wait for the connection and the response of the probed device, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "1000",
+					},
+				}.Merge(tcpDiscoveryParams("_modbus._tcp")),
+				Params: defs.Params{
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to connect, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(modbusParams),
+			},
+		},
+	},
+	api.ProtocolZigbee: {
+		Name: "Zigbee",
+		Transports: map[api.TransportIdentifier]*defs.ProtocolTransportOptions{
+			api.TransportSerial: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&serial.Transport{}, entry, params, zigbee.NewService)
+				},
+				DiscoveryFunc: zigbee.DiscoverSerial,
+				Params: defs.Params{
+					serial.ParamNameDataBits: &defs.ParamInfo{
+						Type:         defs.ParamTypeString,
+						DefaultValue: "8",
+						Flags:        defs.ParamFlagConst,
+					},
+					serial.ParamNameReadTimeout: &defs.ParamInfo{
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "0",
+						Flags:        defs.ParamFlagConst,
+					},
+					serial.ParamNameWriteTimeout: &defs.ParamInfo{
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "0",
+						Flags:        defs.ParamFlagConst,
+					},
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to open serial port, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(zigbeeParams),
+			},
+			api.TransportTCP: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&tcp.Transport{}, entry, params, zigbee.NewService)
+				},
+				DiscoveryFunc:   zigbee.DiscoverTCP,
+				DiscoveryParams: tcpDiscoveryParams("_iostream._tcp,_zigstar_gw._tcp,_uzg-01._tcp,_slzb-06._tcp,_xzg._tcp,_czc._tcp"),
+				Params: defs.Params{
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to connect, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(zigbeeParams),
+			},
+		},
+	},
+	api.ProtocolInsteon: {
+		Name: "Insteon",
+		Transports: map[api.TransportIdentifier]*defs.ProtocolTransportOptions{
+			api.TransportSerial: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&serial.Transport{}, entry, params, insteon.NewService)
+				},
+				Params: defs.Params{
+					serial.ParamNameBaudRate: &defs.ParamInfo{
+						Description:  "The serial port bitrate",
+						Type:         defs.ParamTypeInt32,
+						DefaultValue: "19200",
+					},
+					serial.ParamNameReadTimeout: &defs.ParamInfo{
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "0",
+						Flags:        defs.ParamFlagConst,
+					},
+					serial.ParamNameWriteTimeout: &defs.ParamInfo{
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "0",
+						Flags:        defs.ParamFlagConst,
+					},
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to open serial port, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(insteonParams),
+			},
+		},
+	},
+	api.ProtocolRaw: {
+		Name: "Raw",
+		Transports: map[api.TransportIdentifier]*defs.ProtocolTransportOptions{
+			api.TransportSerial: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&serial.Transport{}, entry, params, raw.NewService)
+				},
+				Params: defs.Params{
+					serial.ParamNameReadTimeout: &defs.ParamInfo{
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "0",
+						Flags:        defs.ParamFlagConst,
+					},
+					serial.ParamNameWriteTimeout: &defs.ParamInfo{
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "0",
+						Flags:        defs.ParamFlagConst,
+					},
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to open serial port, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(rawParams),
+			},
+			api.TransportTCP: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&tcp.Transport{}, entry, params, raw.NewService)
+				},
+				DiscoveryFunc:   raw.DiscoverTCP,
+				DiscoveryParams: tcpDiscoveryParams("_iostream._tcp"),
+				Params: defs.Params{
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to connect, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(rawParams),
+			},
+			api.TransportMQTT: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&mqtt.Transport{}, entry, params, raw.NewService)
+				},
+				Params: defs.Params{
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to connect, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(rawParams),
+			},
+		},
+	},
+	api.ProtocolKNX: {
+		Name: "KNX",
+		Transports: map[api.TransportIdentifier]*defs.ProtocolTransportOptions{
+			api.TransportUDP: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&udp.Transport{}, entry, params, knx.NewService)
+				},
+				DiscoveryFunc: knx.DiscoverUDP,
+				DiscoveryParams: defs.Params{
+					knx.ParamNameSearchAddress: {
+						Description:  "The address (host:port) to send the search request to",
+						Type:         defs.ParamTypeString,
+						DefaultValue: "224.0.23.12:3671",
+					},
+					knx.ParamNameSearchTimeout: {
+						Description:  "The time to wait for the search responses, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				},
+				Params: defs.Params{
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to connect, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(knxParams),
+			},
+		},
+	},
+	api.ProtocolMQTT: {
+		Name: "MQTT",
+		Transports: map[api.TransportIdentifier]*defs.ProtocolTransportOptions{
+			api.TransportMQTT: {
+				ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+					return newService(&mqtt.Transport{Records: true}, entry, params, mqtt.NewService)
+				},
+				Params: defs.Params{
+					defs.ParamNameOpenAttemptsInterval: {
+						Description:  "The time interval between attempts to connect, milliseconds",
+						Type:         defs.ParamTypeUint32,
+						DefaultValue: "3000",
+					},
+				}.Merge(mqttParams),
 			},
 		},
 	},
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/mock"
+	"github.com/stas-makutin/howeve/services/raw"
+)
+
+func TestNewServiceCapture(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "capture.log")
+	m := mock.New()
+	m.Emit([]byte("hello\n"))
+
+	svc := mock.NewService[defs.Service](t, api.ParamValues{defs.ParamNameCaptureFile: fileName}, func(params api.ParamValues) (defs.Service, error) {
+		return newService(m, "test", params, raw.NewService)
+	})
+	svc.Start()
+
+	mock.WaitFor(t, "the captured data", func() bool {
+		data, _ := os.ReadFile(fileName)
+		return strings.Contains(string(data), " R "+hex.EncodeToString([]byte("hello\n")))
+	})
+}
+
+func TestNewServiceShared(t *testing.T) {
+	m := mock.New()
+	m.Emit([]byte("hello\n"))
+
+	svc := mock.NewService[*sharedService](t, nil, func(params api.ParamValues) (defs.Service, error) {
+		return newService(m, "test", params, raw.NewService)
+	})
+	tapped := make(chan []byte, 10)
+	untap := svc.stream.Tap(func(data []byte) { tapped <- data })
+	defer untap()
+	svc.Start()
+
+	select {
+	case data := <-tapped:
+		if !bytes.Equal(data, []byte("hello\n")) {
+			t.Errorf("Unexpected tapped data %q", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the tapped data")
+	}
+}
@@ -18,6 +18,10 @@ type discoveryEntry struct {
 	results []*api.DiscoveryEntry
 	err     error
 
+	progressLock sync.Mutex
+	found        []*api.DiscoveryEntry // the entries found so far, the partial results
+	percent      uint8
+
 	completed uint32
 	ctx       context.Context
 	cancel    context.CancelFunc
@@ -27,9 +31,41 @@ func newDiscoveryEntry(ctx context.Context) *discoveryEntry {
 	de := &discoveryEntry{}
 	de.id = uuid.New()
 	de.ctx, de.cancel = context.WithCancel(ctx)
+	de.ctx = defs.WithDiscoveryProgress(de.ctx, de)
 	return de
 }
 
+// Probed is the part of defs.DiscoveryProgress implementation
+func (de *discoveryEntry) Probed(entry string, done, total int) {
+	de.progressLock.Lock()
+	if total > 0 && done <= total {
+		if percent := uint8(done * 100 / total); percent > de.percent {
+			de.percent = percent
+		}
+	}
+	percent := de.percent
+	de.progressLock.Unlock()
+
+	handlers.SendDiscoveryProgress(de.id, entry, nil, percent)
+}
+
+// Found is the part of defs.DiscoveryProgress implementation
+func (de *discoveryEntry) Found(entry *api.DiscoveryEntry) {
+	de.progressLock.Lock()
+	de.found = append(de.found, entry)
+	percent := de.percent
+	de.progressLock.Unlock()
+
+	handlers.SendDiscoveryProgress(de.id, "", entry, percent)
+}
+
+// partialResults returns the entries found so far
+func (de *discoveryEntry) partialResults() []*api.DiscoveryEntry {
+	de.progressLock.Lock()
+	defer de.progressLock.Unlock()
+	return append([]*api.DiscoveryEntry(nil), de.found...)
+}
+
 // discoveryRegistry is the controlling strucuture to uexecute limited number of discovery queries
 // maxActive - max number of goroutines allocated for discovery queries
 // maxEntries - max number of discovery queries allowed, must be >= maxActive
@@ -110,7 +146,8 @@ func (d *discoveryRegistry) Discover(protocol api.ProtocolIdentifier, transport
 	return de.id, nil
 }
 
-// Discovery method returns the state or results of discovery query, identifying by its id
+// Discovery method returns the state or results of discovery query, identifying by its id.
+// The pending discovery query returns the entries found so far along with ErrDiscoveryPending error
 func (d *discoveryRegistry) Discovery(id uuid.UUID, stop bool) ([]*api.DiscoveryEntry, error) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
@@ -123,7 +160,7 @@ func (d *discoveryRegistry) Discovery(id uuid.UUID, stop bool) ([]*api.Discovery
 		de.cancel()
 	}
 	if atomic.LoadUint32(&de.completed) != 1 {
-		return nil, defs.ErrDiscoveryPending
+		return de.partialResults(), defs.ErrDiscoveryPending
 	}
 	return de.results, de.err
 }
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/events/handlers"
+	"github.com/stas-makutin/howeve/services/mock"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(mock.RunTests(m))
+}
+
+func TestDiscoveryProgress(t *testing.T) {
+	first := &api.DiscoveryEntry{ServiceKey: api.ServiceKey{Protocol: "test", Transport: api.TransportTCP, Entry: "first:1"}}
+	second := &api.DiscoveryEntry{ServiceKey: api.ServiceKey{Protocol: "test", Transport: api.TransportTCP, Entry: "second:2"}}
+	release := make(chan struct{})
+
+	protocols, transports := defs.Protocols, defs.Transports
+	defer func() { defs.Protocols, defs.Transports = protocols, transports }()
+	defs.Transports = map[api.TransportIdentifier]*defs.TransportInfo{api.TransportTCP: {Name: "TCP"}}
+	defs.Protocols = map[api.ProtocolIdentifier]*defs.ProtocolInfo{
+		"test": {
+			Name: "Test",
+			Transports: map[api.TransportIdentifier]*defs.ProtocolTransportOptions{
+				api.TransportTCP: {
+					DiscoveryFunc: func(ctx context.Context, params api.ParamValues) ([]*api.DiscoveryEntry, error) {
+						defs.DiscoveryFound(ctx, first)
+						defs.DiscoveryProbed(ctx, first.Entry, 1, 2)
+						<-release
+						defs.DiscoveryFound(ctx, second)
+						defs.DiscoveryProbed(ctx, second.Entry, 2, 2)
+						return []*api.DiscoveryEntry{first, second}, nil
+					},
+				},
+			},
+		},
+	}
+
+	progress := make(chan *api.ProtocolDiscoveryProgress, 10)
+	sid := handlers.Dispatcher.Subscribe(func(event interface{}) {
+		if e, ok := event.(*handlers.ProtocolDiscoveryProgress); ok {
+			progress <- e.ProtocolDiscoveryProgress
+		}
+	})
+	defer handlers.Dispatcher.Unsubscribe(sid)
+
+	d := newDiscoveryRegistry(2, 1)
+	defer d.stop()
+	id, err := d.Discover("test", api.TransportTCP, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() *api.ProtocolDiscoveryProgress {
+		select {
+		case p := <-progress:
+			if p.ID != id {
+				t.Fatalf("Unexpected discovery id %v", p.ID)
+			}
+			return p
+		case <-time.After(2 * time.Second):
+			t.Fatal("No progress event")
+		}
+		return nil
+	}
+	if p := next(); p.Found != first || p.Percent != 0 {
+		t.Errorf("Unexpected found event %+v", p)
+	}
+	if p := next(); p.Probed != first.Entry || p.Found != nil || p.Percent != 50 {
+		t.Errorf("Unexpected probed event %+v", p)
+	}
+
+	entries, err := d.Discovery(id, false)
+	if err != defs.ErrDiscoveryPending || len(entries) != 1 || entries[0] != first {
+		t.Errorf("Unexpected partial results %v, error %v", entries, err)
+	}
+
+	close(release)
+	if p := next(); p.Found != second || p.Percent != 50 {
+		t.Errorf("Unexpected found event %+v", p)
+	}
+	if p := next(); p.Probed != second.Entry || p.Percent != 100 {
+		t.Errorf("Unexpected probed event %+v", p)
+	}
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if entries, err = d.Discovery(id, false); err != defs.ErrDiscoveryPending || time.Now().After(deadline) {
+			break
+		}
+	}
+	if err != nil || len(entries) != 2 {
+		t.Errorf("Unexpected results %v, error %v", entries, err)
+	}
+}
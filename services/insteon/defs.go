@@ -0,0 +1,6 @@
+package insteon
+
+// Insteon parameters names
+const (
+	ParamNameResponseTimeout = "responseTimeout"
+)
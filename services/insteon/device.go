@@ -0,0 +1,143 @@
+package insteon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	in "github.com/stas-makutin/howeve/insteon"
+)
+
+// errDeviceNak is returned if the device did not accept the command
+var errDeviceNak = errors.New("the command is not accepted by the device")
+
+// waiter is the frame received from PLM someone waits for
+type waiter struct {
+	match  func(frame []byte) bool
+	frames chan []byte
+}
+
+// waiters is the list of the frames waiting to be received from PLM
+type waiters struct {
+	lock    sync.Mutex
+	entries []*waiter
+}
+
+func (ws *waiters) add(match func(frame []byte) bool) *waiter {
+	w := &waiter{match: match, frames: make(chan []byte, 1)}
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	ws.entries = append(ws.entries, w)
+	return w
+}
+
+func (ws *waiters) remove(w *waiter) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	for i, entry := range ws.entries {
+		if entry == w {
+			ws.entries = append(ws.entries[:i], ws.entries[i+1:]...)
+			break
+		}
+	}
+}
+
+// deliver delivers the frame to all matching waiters
+func (ws *waiters) deliver(frame []byte) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	for _, entry := range ws.entries {
+		if entry.match(frame) {
+			select {
+			case entry.frames <- frame:
+			default:
+			}
+		}
+	}
+}
+
+// DeviceCommand sends the command to the device and waits for its acknowledgement, returns the device's level reported by the acknowledgement
+func (svc *Service) DeviceCommand(ctx context.Context, device string, command string, level uint8) (uint8, error) {
+	address, err := in.ParseAddress(device)
+	if err != nil {
+		return 0, defs.ErrBadNodeID
+	}
+
+	var cmd1, cmd2 byte
+	switch command {
+	case api.DeviceCommandOn:
+		if cmd1, cmd2 = in.CmdOn, level; cmd2 == 0 {
+			cmd2 = 0xff
+		}
+	case api.DeviceCommandOff:
+		cmd1 = in.CmdOff
+	case api.DeviceCommandLevel:
+		cmd1, cmd2 = in.CmdOn, level
+	case api.DeviceCommandStatus:
+		cmd1 = in.CmdStatusRequest
+	default:
+		return 0, defs.ErrBadPayload
+	}
+
+	w := svc.waiters.add(func(frame []byte) bool {
+		m, ok := in.ParseMessage(frame)
+		if !ok || m.From != address {
+			return false
+		}
+		t := m.Flags.Type()
+		return t == in.MessageDirectAck || t == in.MessageDirectNak
+	})
+	defer svc.waiters.remove(w)
+
+	if err := svc.do(ctx, in.StandardMessage(address, in.DirectFlags, cmd1, cmd2)); err != nil {
+		return 0, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-time.After(svc.responseTimeout):
+		return 0, defs.ErrNoResponse
+	case frame := <-w.frames:
+		m, _ := in.ParseMessage(frame)
+		if m.Flags.Type() == in.MessageDirectNak {
+			return 0, errDeviceNak
+		}
+		return m.Cmd2, nil
+	}
+}
+
+// DeviceLinks reads PLM ALL-Link database, returns the records in use
+func (svc *Service) DeviceLinks(ctx context.Context) ([]*api.DeviceLink, error) {
+	w := svc.waiters.add(func(frame []byte) bool {
+		return frame[1] == in.AllLinkRecordResponse
+	})
+	defer svc.waiters.remove(w)
+
+	links := []*api.DeviceLink{}
+	command := byte(in.GetFirstAllLinkRecord)
+	for {
+		if err := svc.do(ctx, []byte{in.STX, command}); err == errNak {
+			return links, nil // no more records
+		} else if err != nil {
+			return nil, err
+		}
+		command = in.GetNextAllLinkRecord
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(svc.responseTimeout):
+			return nil, defs.ErrNoResponse
+		case frame := <-w.frames:
+			r, ok := in.ParseAllLinkRecord(frame)
+			if !ok || !r.InUse() {
+				continue
+			}
+			links = append(links, &api.DeviceLink{Device: r.Address.String(), Group: r.Group, Controller: r.Controller(), Data: r.Data})
+		}
+	}
+}
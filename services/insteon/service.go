@@ -0,0 +1,373 @@
+package insteon
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	in "github.com/stas-makutin/howeve/insteon"
+	"github.com/stas-makutin/howeve/log"
+	"github.com/stas-makutin/howeve/services/backoff"
+	"github.com/stas-makutin/howeve/services/serial"
+	"github.com/stas-makutin/howeve/utils/syncutil"
+)
+
+// log constants
+const (
+	// operation
+	inOpService = "IN"
+
+	inOcTransportOpen  = "O"
+	inOcTransportRead  = "R"
+	inOcTransportWrite = "W"
+	inOcEchoTimeout    = "T"
+	inOcBadFrame       = "B"
+	inOcNak            = "N"
+
+	inOsSuccess = "0"
+	inOsFailure = "F"
+	inOsGaveUp  = "G"
+)
+
+// PLM timings
+const (
+	echoTimeout = time.Second            // the time to wait for the command echo
+	nakDelay    = 150 * time.Millisecond // the delay before resending the message if PLM is busy
+	nakRetries  = 3                      // the number of attempts to resend the message if PLM is busy
+)
+
+// errNak is returned if PLM did not accept the command
+var errNak = errors.New("the command is not accepted by PLM")
+
+// request is the command to send to PLM and the channel to deliver the echo result to
+type request struct {
+	frame   []byte
+	message *api.Message // the registered outgoing message
+	result  chan error
+}
+
+func (r *request) complete(err error) {
+	if r.result != nil {
+		r.result <- err
+	}
+}
+
+// Service Insteon PowerLinc Modem service implementation
+type Service struct {
+	transport defs.Transport
+	key       *api.ServiceKey
+	params    api.ParamValues
+
+	responseTimeout time.Duration
+
+	requests chan *request
+	waiters  waiters
+
+	status syncutil.RLocked[error]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stopWg sync.WaitGroup
+}
+
+// NewService creates new Insteon PLM service implementation using provided transport
+func NewService(transport defs.Transport, entry string, params api.ParamValues) (defs.Service, error) {
+	pv := serial.ServiceParams(transport, params)
+
+	svc := &Service{
+		transport:       transport,
+		key:             &api.ServiceKey{Protocol: api.ProtocolInsteon, Transport: transport.ID(), Entry: entry},
+		params:          pv,
+		responseTimeout: 3 * time.Second,
+		requests:        make(chan *request, 10),
+	}
+	if v, ok := pv[ParamNameResponseTimeout]; ok {
+		if svc.responseTimeout = time.Duration(v.(uint32)) * time.Millisecond; svc.responseTimeout < 10*time.Millisecond {
+			svc.responseTimeout = 10 * time.Millisecond
+		}
+	}
+	return svc, nil
+}
+
+func (svc *Service) Start() {
+	svc.Stop()
+
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+
+	svc.stopWg.Add(1)
+	go svc.serviceLoop()
+}
+
+func (svc *Service) Stop() {
+	if svc.ctx == nil {
+		return // already stopped
+	}
+
+	svc.cancel()
+	svc.stopWg.Wait()
+
+PurgeLoop:
+	for {
+		select {
+		default:
+			break PurgeLoop
+		case req := <-svc.requests:
+			if req.message != nil {
+				defs.Messages.UpdateState(req.message.ID, api.OutgoingRejected)
+			}
+			req.complete(defs.ErrNotOpen)
+		}
+	}
+
+	svc.ctx, svc.cancel = nil, nil
+
+	svc.status.Store(defs.ErrStatusGood)
+}
+
+func (svc *Service) Status() defs.ServiceStatus {
+	err := svc.status.Load()
+	if err == nil {
+		return defs.ServiceStatus(defs.ErrStatusGood)
+	}
+	return err.(defs.ServiceStatus)
+}
+
+// Send sends the raw PLM command (starting from STX byte) as is, the frames received from PLM are registered in the message log
+func (svc *Service) Send(payload []byte) (*api.Message, error) {
+	if in.CommandLength(payload) != len(payload) {
+		return nil, defs.ErrBadPayload
+	}
+	req := &request{frame: append([]byte(nil), payload...)}
+	req.message = defs.Messages.Register(svc.key, payload, api.OutgoingPending)
+	select {
+	case svc.requests <- req:
+	default:
+		defs.Messages.UpdateState(req.message.ID, api.OutgoingRejected)
+		return req.message, defs.ErrSendBusy
+	}
+	return req.message, nil
+}
+
+// ResolvedEntry returns the actual entry the transport is opened with, if the transport resolves the service entry
+func (svc *Service) ResolvedEntry() string {
+	if r, ok := svc.transport.(defs.EntryResolver); ok {
+		return r.ResolvedEntry()
+	}
+	return ""
+}
+
+// do sends the command to PLM and waits for its echo, returns errNak if PLM did not accept the command
+func (svc *Service) do(ctx context.Context, frame []byte) error {
+	if status := svc.Status(); status != defs.ErrStatusGood {
+		return status
+	}
+
+	req := &request{frame: frame, result: make(chan error, 1)}
+	req.message = defs.Messages.Register(svc.key, frame, api.OutgoingPending)
+
+	select {
+	case <-ctx.Done():
+		defs.Messages.UpdateState(req.message.ID, api.OutgoingRejected)
+		return ctx.Err()
+	case svc.requests <- req:
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-req.result:
+		return err
+	}
+}
+
+func (svc *Service) log(op string, fields ...string) {
+	log.Report(append([]string{
+		log.SrcSVC,
+		inOpService,
+		op,
+		defs.ProtocolName(svc.key.Protocol),
+		defs.TransportName(svc.key.Transport),
+		svc.key.Entry,
+	}, fields...)...)
+}
+
+// reader accumulates the data read from PLM and splits it to the frames
+type reader struct {
+	buffer []byte
+	length int
+}
+
+func (svc *Service) serviceLoop() {
+	defer svc.transport.Close()
+	defer svc.stopWg.Done()
+
+	openBackoff := backoff.New(backoff.NewPolicy(svc.params))
+	r := &reader{buffer: make([]byte, 256)}
+	open := true
+
+ServiceLoop:
+	for {
+		if open {
+			open = false
+			r.length = 0
+			if err := svc.transport.Open(svc.key.Entry, svc.params); err != nil {
+				if !openBackoff.Retry(svc.ctx, svc.key, &svc.status, "unable to open transport", err, func(gaveUp bool) {
+					if gaveUp {
+						svc.log(inOcTransportOpen, inOsGaveUp, err.Error())
+					} else {
+						svc.log(inOcTransportOpen, inOsFailure, err.Error())
+					}
+				}) {
+					break ServiceLoop
+				}
+				open = true
+				continue
+			}
+			openBackoff.Reset()
+			svc.log(inOcTransportOpen, inOsSuccess)
+			svc.status.Store(defs.ErrStatusGood)
+		}
+
+		select {
+		case <-svc.ctx.Done():
+			break ServiceLoop
+		case req := <-svc.requests:
+			if !svc.perform(req, r) {
+				open = true
+			}
+		case <-svc.transport.ReadyToRead():
+			if _, err := svc.read(r, nil); err != nil {
+				open = true
+			}
+		}
+	}
+}
+
+// perform writes the command and waits for its echo, resending the message if PLM is busy. Returns false if the transport needs to be reopened
+func (svc *Service) perform(req *request, r *reader) bool {
+	for attempt := 0; ; attempt++ {
+		if n, err := svc.transport.Write(req.frame); err != nil || n != len(req.frame) {
+			if err == nil {
+				err = fmt.Errorf("%d bytes of %d written", n, len(req.frame))
+			}
+			svc.log(inOcTransportWrite, inOsFailure, err.Error())
+			if req.message != nil {
+				defs.Messages.UpdateState(req.message.ID, api.OutgoingFailed)
+			}
+			req.complete(err)
+			if errors.Is(err, defs.ErrTransportHeld) {
+				// the held transport is operational, only the message fails
+				return true
+			}
+			svc.status.Store(fmt.Errorf("unable to write using transport: %s", err.Error()))
+			return false
+		}
+		if attempt == 0 && req.message != nil {
+			defs.Messages.UpdateState(req.message.ID, api.Outgoing)
+		}
+
+		echo, err := svc.waitEcho(req, r)
+		if err != nil {
+			req.complete(err)
+			return err == defs.ErrNoResponse || err == svc.ctx.Err()
+		}
+		if echo[len(echo)-1] == in.ACK {
+			req.complete(nil)
+			return true
+		}
+
+		svc.log(inOcNak, hex.EncodeToString(req.frame))
+		// PLM is busy, only the messages are resent since NAK is the regular response of some commands, like the end of ALL-Link database
+		if req.frame[1] != in.SendMessage || attempt+1 >= nakRetries {
+			if req.message != nil {
+				defs.Messages.UpdateState(req.message.ID, api.OutgoingFailed)
+			}
+			req.complete(errNak)
+			return true
+		}
+		select {
+		case <-svc.ctx.Done():
+			req.complete(svc.ctx.Err())
+			return true
+		case <-time.After(nakDelay):
+		}
+	}
+}
+
+// waitEcho reads the frames until the echo of the request is received
+func (svc *Service) waitEcho(req *request, r *reader) ([]byte, error) {
+	deadline := time.Now().Add(echoTimeout)
+	for {
+		select {
+		case <-svc.ctx.Done():
+			return nil, svc.ctx.Err()
+		case <-time.After(time.Until(deadline)):
+			svc.log(inOcEchoTimeout, hex.EncodeToString(req.frame))
+			return nil, defs.ErrNoResponse
+		case <-svc.transport.ReadyToRead():
+		}
+		echo, err := svc.read(r, req.frame)
+		if err != nil {
+			return nil, err
+		}
+		if echo != nil {
+			return echo, nil
+		}
+	}
+}
+
+// read reads the available data and handles the complete frames, returns the echo of the command if it is provided and received
+func (svc *Service) read(r *reader, command []byte) (echo []byte, err error) {
+	n, err := svc.transport.Read(r.buffer[r.length:])
+	if err != nil {
+		svc.status.Store(fmt.Errorf("unable to read using transport: %s", err.Error()))
+		svc.log(inOcTransportRead, inOsFailure, err.Error())
+		return nil, err
+	}
+	r.length += n
+
+	for r.length > 0 {
+		frameLength := in.FrameLength(r.buffer[:r.length])
+		if frameLength < 0 {
+			// skip to the next STX byte, the lone NAK byte is sent by PLM if it is not ready
+			skip := 1
+			for skip < r.length && r.buffer[skip] != in.STX {
+				skip++
+			}
+			if skip > 1 || r.buffer[0] != in.NAK {
+				svc.log(inOcBadFrame, hex.EncodeToString(r.buffer[:skip]))
+			}
+			copy(r.buffer, r.buffer[skip:r.length])
+			r.length -= skip
+			continue
+		}
+		if frameLength == 0 || frameLength > r.length {
+			break // continue to read
+		}
+
+		frame := append([]byte(nil), r.buffer[:frameLength]...)
+		copy(r.buffer, r.buffer[frameLength:r.length])
+		r.length -= frameLength
+
+		if frame[1] >= in.GetIMInfo {
+			// the echo of the command sent to PLM
+			if echo == nil && command != nil && len(frame) == len(command)+1 && string(frame[:len(command)]) == string(command) {
+				echo = frame
+			} else if frame[1] == in.GetIMInfo && command != nil && command[1] == in.GetIMInfo {
+				echo = frame // the echo of get IM info command contains the information
+			} else {
+				svc.log(inOcBadFrame, hex.EncodeToString(frame))
+			}
+			continue
+		}
+
+		defs.Messages.Register(svc.key, frame, api.Incoming)
+		svc.waiters.deliver(frame)
+	}
+	return echo, nil
+}
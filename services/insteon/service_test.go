@@ -0,0 +1,175 @@
+package insteon
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	in "github.com/stas-makutin/howeve/insteon"
+	"github.com/stas-makutin/howeve/services/mock"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(mock.RunTests(m))
+}
+
+func newTestService(t *testing.T, m *mock.Transport) *Service {
+	svc := mock.NewService[*Service](t, api.ParamValues{ParamNameResponseTimeout: uint32(200)}, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(m, "test", params)
+	})
+	svc.Start()
+	return svc
+}
+
+var (
+	plmAddress    = in.Address{0x11, 0x22, 0x33}
+	deviceAddress = in.Address{0x1a, 0x2b, 0x3c}
+)
+
+// deviceMessage creates the standard message received frame sent by the device to PLM
+func deviceMessage(from in.Address, flags byte, cmd1, cmd2 byte) []byte {
+	return []byte{in.STX, in.StandardMessageReceived, from[0], from[1], from[2], plmAddress[0], plmAddress[1], plmAddress[2], flags, cmd1, cmd2}
+}
+
+func TestDeviceCommand(t *testing.T) {
+	ml := defs.Messages.(*mock.MessageLog)
+	ml.Reset()
+
+	command := in.StandardMessage(deviceAddress, in.DirectFlags, in.CmdOn, 0x80)
+	broadcast := deviceMessage(in.Address{0x44, 0x55, 0x66}, 0x8f, in.CmdOn, 0x01)
+	ack := deviceMessage(deviceAddress, 0x2b, in.CmdOn, 0x80)
+
+	echo := append(append([]byte(nil), command...), in.ACK)
+
+	m := mock.New()
+	// the broadcast of another device must be skipped, the echo is split to test the reassembly
+	m.Expect(command).Reply(echo[:4], echo[4:], broadcast, ack)
+
+	svc := newTestService(t, m)
+	defer svc.Stop()
+
+	level, err := svc.DeviceCommand(context.Background(), "1A.2B.3C", api.DeviceCommandLevel, 0x80)
+	if err != nil {
+		t.Fatalf("DeviceCommand failed: %v", err)
+	}
+	if level != 0x80 {
+		t.Errorf("Unexpected level %02x", level)
+	}
+	if err := m.Wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if incoming := ml.Incoming(); len(incoming) != 2 || string(incoming[0]) != string(broadcast) || string(incoming[1]) != string(ack) {
+		t.Errorf("Unexpected incoming messages %x", incoming)
+	}
+
+	if _, err := svc.DeviceCommand(context.Background(), "1A.2B", api.DeviceCommandOn, 0); err != defs.ErrBadNodeID {
+		t.Errorf("Bad node error expected, got %v", err)
+	}
+	if _, err := svc.DeviceCommand(context.Background(), "1A.2B.3C", "toggle", 0); err != defs.ErrBadPayload {
+		t.Errorf("Bad payload error expected, got %v", err)
+	}
+}
+
+func TestDeviceCommandNak(t *testing.T) {
+	command := in.StandardMessage(deviceAddress, in.DirectFlags, in.CmdOff, 0)
+	status := in.StandardMessage(deviceAddress, in.DirectFlags, in.CmdStatusRequest, 0)
+
+	m := mock.New()
+	// PLM is busy, the message must be resent
+	m.Expect(command).Reply(append(append([]byte(nil), command...), in.NAK))
+	m.Expect(command).Reply(append(append([]byte(nil), command...), in.ACK), deviceMessage(deviceAddress, 0xab, in.CmdOff, 0))
+	m.Expect(status).Reply(append(append([]byte(nil), status...), in.ACK))
+
+	svc := newTestService(t, m)
+	defer svc.Stop()
+
+	if _, err := svc.DeviceCommand(context.Background(), "1a2b3c", api.DeviceCommandOff, 0); err != errDeviceNak {
+		t.Errorf("Device NAK error expected, got %v", err)
+	}
+	start := time.Now()
+	if _, err := svc.DeviceCommand(context.Background(), "1a2b3c", api.DeviceCommandStatus, 0); err != defs.ErrNoResponse {
+		t.Errorf("No response error expected, got %v", err)
+	}
+	if d := time.Since(start); d < 200*time.Millisecond {
+		t.Errorf("The response must be awaited for 200 milliseconds, failed after %v", d)
+	}
+	if err := m.Wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeviceLinks(t *testing.T) {
+	m := mock.New()
+	m.Expect([]byte{in.STX, in.GetFirstAllLinkRecord}).Reply(
+		[]byte{in.STX, in.GetFirstAllLinkRecord, in.ACK},
+		[]byte{in.STX, in.AllLinkRecordResponse, 0xe2, 0x01, 0x1a, 0x2b, 0x3c, 0x01, 0x20, 0x41},
+	)
+	m.Expect([]byte{in.STX, in.GetNextAllLinkRecord}).Reply(
+		[]byte{in.STX, in.GetNextAllLinkRecord, in.ACK},
+		[]byte{in.STX, in.AllLinkRecordResponse, 0x22, 0x00, 0x44, 0x55, 0x66, 0x00, 0x00, 0x00},
+	)
+	m.Expect([]byte{in.STX, in.GetNextAllLinkRecord}).Reply(
+		[]byte{in.STX, in.GetNextAllLinkRecord, in.ACK},
+		[]byte{in.STX, in.AllLinkRecordResponse, 0xa2, 0x00, 0x1a, 0x2b, 0x3c, 0x01, 0x20, 0x41},
+	)
+	m.Expect([]byte{in.STX, in.GetNextAllLinkRecord}).Reply([]byte{in.STX, in.GetNextAllLinkRecord, in.NAK})
+
+	svc := newTestService(t, m)
+	defer svc.Stop()
+
+	links, err := svc.DeviceLinks(context.Background())
+	if err != nil {
+		t.Fatalf("DeviceLinks failed: %v", err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("Only the records in use expected, got %d", len(links))
+	}
+	if l := links[0]; l.Device != "1A.2B.3C" || l.Group != 1 || !l.Controller || l.Data != [3]uint8{0x01, 0x20, 0x41} {
+		t.Errorf("Unexpected link %+v", l)
+	}
+	if l := links[1]; l.Device != "1A.2B.3C" || l.Group != 0 || l.Controller {
+		t.Errorf("Unexpected link %+v", l)
+	}
+	if err := m.Wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSend(t *testing.T) {
+	ml := defs.Messages.(*mock.MessageLog)
+	ml.Reset()
+
+	command := in.ExtendedMessage(deviceAddress, in.DirectExtendedFlags, 0x2e, 0x00, [13]byte{0x01})
+	reply := append([]byte{in.STX, in.ExtendedMessageReceived, 0x1a, 0x2b, 0x3c, 0x11, 0x22, 0x33, 0x1b, 0x2e, 0x00}, make([]byte, 14)...)
+
+	m := mock.New()
+	m.Expect(command).Reply(append(append([]byte(nil), command...), in.ACK), []byte{in.NAK}, reply)
+
+	svc := newTestService(t, m)
+	defer svc.Stop()
+
+	if _, err := svc.Send([]byte{in.STX, in.SendMessage, 0x1a}); err != defs.ErrBadPayload {
+		t.Errorf("Bad payload error expected, got %v", err)
+	}
+	message, err := svc.Send(command)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := m.Wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(time.Second); len(ml.Incoming()) < 1; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+	if incoming := ml.Incoming(); len(incoming) != 1 || string(incoming[0]) != string(reply) {
+		t.Errorf("Unexpected incoming messages %x", incoming)
+	}
+	if _, m := ml.Get(message.ID); m == nil || m.State != api.Outgoing {
+		t.Errorf("The message must be sent, got %+v", m)
+	}
+}
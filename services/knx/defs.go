@@ -0,0 +1,13 @@
+package knx
+
+// KNX parameters names
+const (
+	ParamNameGroups            = "groups"
+	ParamNameHeartbeatInterval = "heartbeatInterval"
+)
+
+// KNX discovery parameters names
+const (
+	ParamNameSearchAddress = "searchAddress"
+	ParamNameSearchTimeout = "searchTimeout"
+)
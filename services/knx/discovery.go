@@ -0,0 +1,61 @@
+package knx
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	kx "github.com/stas-makutin/howeve/knx"
+	"github.com/stas-makutin/howeve/netdisco"
+)
+
+// DiscoverUDP - discover KNXnet/IP gateways which support the tunneling by sending the search request to the multicast (or provided) address
+func DiscoverUDP(ctx context.Context, params api.ParamValues) ([]*api.DiscoveryEntry, error) {
+	searchAddress := kx.MulticastAddress
+	if v, _ := params[ParamNameSearchAddress].(string); v != "" {
+		searchAddress = v
+	}
+	timeout := 3 * time.Second
+	if v, ok := params[ParamNameSearchTimeout]; ok {
+		timeout = time.Duration(v.(uint32)) * time.Millisecond
+	}
+
+	var rv []*api.DiscoveryEntry
+	found := make(map[string]bool)
+	// the route back (NAT) mode: the gateways respond to the address the request came from
+	err := netdisco.Probe(ctx, searchAddress, kx.SearchRequest(kx.HPAI{}), timeout, func(b []byte, from *net.UDPAddr) {
+		f, ok := kx.ParseFrame(b)
+		if !ok || f.ServiceType != kx.SEARCH_RESPONSE {
+			return
+		}
+		r, ok := kx.ParseSearchResponse(f.Body)
+		if !ok || !r.Tunneling {
+			return
+		}
+		if r.Control.IP.IsUnspecified() || r.Control.Port == 0 {
+			r.Control = kx.HPAI{IP: from.IP, Port: uint16(from.Port)}
+		}
+		entry := r.Control.String()
+		if found[entry] {
+			return
+		}
+		found[entry] = true
+		de := &api.DiscoveryEntry{
+			ServiceKey: api.ServiceKey{
+				Protocol:  api.ProtocolKNX,
+				Transport: api.TransportUDP,
+				Entry:     entry,
+			},
+			Description: fmt.Sprintf("%s [%s, %s]", r.Name, r.Address, r.MAC),
+		}
+		rv = append(rv, de)
+		defs.DiscoveryFound(ctx, de)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
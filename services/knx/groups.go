@@ -0,0 +1,53 @@
+package knx
+
+import (
+	"fmt"
+	"strings"
+
+	kx "github.com/stas-makutin/howeve/knx"
+)
+
+// parseGroups parses the comma-separated list of group:dpt entries, like 1/2/3:9.001
+func parseGroups(s string) (map[kx.GroupAddress]string, error) {
+	groups := make(map[kx.GroupAddress]string)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		group, dpt, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("the group '%s' is not valid, expected group:dpt", item)
+		}
+		address, err := kx.ParseGroupAddress(group)
+		if err != nil {
+			return nil, fmt.Errorf("the group address '%s' is not valid", group)
+		}
+		if _, err := kx.DPTMain(dpt); err != nil {
+			return nil, fmt.Errorf("the datapoint type '%s' of the group %s is not valid", dpt, group)
+		}
+		groups[address] = dpt
+	}
+	return groups, nil
+}
+
+// handleGroupValue logs the decoded value of the group value service if the datapoint type of the group is known
+func (svc *Service) handleGroupValue(cemi []byte) {
+	f, ok := kx.ParseLData(cemi)
+	if !ok || f.MessageCode != kx.L_DATA_IND {
+		return
+	}
+	service, ok := f.GroupService()
+	if !ok || service == kx.GroupValueRead {
+		return
+	}
+	group := kx.GroupAddress(f.Destination)
+	dpt, ok := svc.groups[group]
+	if !ok {
+		return
+	}
+	if value, err := kx.FormatDPT(dpt, f.Data); err == nil {
+		svc.log(kxOcGroupValue, group.String(), f.Source.String(), dpt, value)
+	} else {
+		svc.log(kxOcGroupValue, group.String(), f.Source.String(), dpt, kxOsFailure, err.Error())
+	}
+}
@@ -0,0 +1,491 @@
+package knx
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	kx "github.com/stas-makutin/howeve/knx"
+	"github.com/stas-makutin/howeve/log"
+	"github.com/stas-makutin/howeve/services/backoff"
+	"github.com/stas-makutin/howeve/utils/syncutil"
+)
+
+// log constants
+const (
+	// operation
+	kxOpService = "KX"
+
+	kxOcTransportOpen  = "O"
+	kxOcTransportRead  = "R"
+	kxOcTransportWrite = "W"
+	kxOcConnect        = "C"
+	kxOcHeartbeat      = "H"
+	kxOcDisconnect     = "D"
+	kxOcAckTimeout     = "T"
+	kxOcBadFrame       = "B"
+	kxOcGroupValue     = "V"
+
+	kxOsSuccess = "0"
+	kxOsFailure = "F"
+	kxOsGaveUp  = "G"
+)
+
+// KNXnet/IP timings
+const (
+	connectTimeout           = 10 * time.Second // CONNECT_REQUEST_TIMEOUT
+	connectionStateTimeout   = 10 * time.Second // CONNECTIONSTATE_REQUEST_TIMEOUT
+	tunnelingTimeout         = time.Second      // TUNNELING_REQUEST_TIMEOUT
+	defaultHeartbeatInterval = 60 * time.Second
+	heartbeatAttempts        = 3 // the connection is lost if the heartbeat fails this number of times in a row
+	tunnelingAttempts        = 2 // the request is sent again once if it is not acknowledged
+)
+
+// errConnectionLost is returned if the gateway closed the connection
+var errConnectionLost = errors.New("the gateway closed the connection")
+
+// outgoing is cEMI frame to send and its registered message
+type outgoing struct {
+	cemi    []byte
+	message *api.Message
+}
+
+// tunnel is the state of the tunneling connection, it is owned by the service loop
+type tunnel struct {
+	channel     uint8
+	sendSeq     uint8
+	recvSeq     uint8
+	pending     *outgoing // the request waiting for the acknowledgement
+	attempts    int
+	ackDeadline time.Time
+
+	heartbeatDue      time.Time
+	heartbeatDeadline time.Time // zero if no heartbeat is pending
+	heartbeatFailures int
+}
+
+// Service KNXnet/IP tunneling service implementation
+type Service struct {
+	transport defs.Transport
+	key       *api.ServiceKey
+	params    api.ParamValues
+
+	groups            map[kx.GroupAddress]string // the datapoint types of the groups
+	heartbeatInterval time.Duration
+
+	// the timeouts, the constants are overridden by the tests only
+	connectTimeout         time.Duration
+	connectionStateTimeout time.Duration
+	tunnelingTimeout       time.Duration
+
+	sendQueue chan *outgoing
+
+	status syncutil.RLocked[error]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stopWg sync.WaitGroup
+}
+
+// NewService creates new KNXnet/IP tunneling service implementation using provided transport
+func NewService(transport defs.Transport, entry string, params api.ParamValues) (defs.Service, error) {
+	svc := &Service{
+		transport:              transport,
+		key:                    &api.ServiceKey{Protocol: api.ProtocolKNX, Transport: transport.ID(), Entry: entry},
+		params:                 params.Copy(),
+		heartbeatInterval:      defaultHeartbeatInterval,
+		connectTimeout:         connectTimeout,
+		connectionStateTimeout: connectionStateTimeout,
+		tunnelingTimeout:       tunnelingTimeout,
+		sendQueue:              make(chan *outgoing, 10),
+	}
+	if v, ok := params[ParamNameHeartbeatInterval]; ok {
+		if svc.heartbeatInterval = time.Duration(v.(uint32)) * time.Millisecond; svc.heartbeatInterval < time.Second {
+			svc.heartbeatInterval = time.Second
+		}
+	}
+	var err error
+	groups, _ := params[ParamNameGroups].(string)
+	if svc.groups, err = parseGroups(groups); err != nil {
+		return nil, err
+	}
+	return svc, nil
+}
+
+func (svc *Service) Start() {
+	svc.Stop()
+
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+
+	svc.stopWg.Add(1)
+	go svc.serviceLoop()
+}
+
+func (svc *Service) Stop() {
+	if svc.ctx == nil {
+		return // already stopped
+	}
+
+	svc.cancel()
+	svc.stopWg.Wait()
+
+	svc.purgeQueue()
+
+	svc.ctx, svc.cancel = nil, nil
+
+	svc.status.Store(defs.ErrStatusGood)
+}
+
+func (svc *Service) purgeQueue() {
+	for {
+		select {
+		default:
+			return
+		case out := <-svc.sendQueue:
+			defs.Messages.UpdateState(out.message.ID, api.OutgoingRejected)
+		}
+	}
+}
+
+func (svc *Service) Status() defs.ServiceStatus {
+	err := svc.status.Load()
+	if err == nil {
+		return defs.ServiceStatus(defs.ErrStatusGood)
+	}
+	return err.(defs.ServiceStatus)
+}
+
+// Send sends cEMI L_Data.req frame through the tunnel. The payload of the incoming messages is cEMI frame too
+func (svc *Service) Send(payload []byte) (*api.Message, error) {
+	if f, ok := kx.ParseLData(payload); !ok || f.MessageCode != kx.L_DATA_REQ {
+		return nil, defs.ErrBadPayload
+	}
+	out := &outgoing{cemi: append([]byte(nil), payload...), message: defs.Messages.Register(svc.key, payload, api.OutgoingPending)}
+	select {
+	case svc.sendQueue <- out:
+	default:
+		defs.Messages.UpdateState(out.message.ID, api.OutgoingRejected)
+		return out.message, defs.ErrSendBusy
+	}
+	return out.message, nil
+}
+
+// ResolvedEntry returns the actual entry the transport is opened with, if the transport resolves the service entry
+func (svc *Service) ResolvedEntry() string {
+	if r, ok := svc.transport.(defs.EntryResolver); ok {
+		return r.ResolvedEntry()
+	}
+	return ""
+}
+
+func (svc *Service) log(op string, fields ...string) {
+	log.Report(append([]string{
+		log.SrcSVC,
+		kxOpService,
+		op,
+		defs.ProtocolName(svc.key.Protocol),
+		defs.TransportName(svc.key.Transport),
+		svc.key.Entry,
+	}, fields...)...)
+}
+
+// reader accumulates the received data and splits it to KNXnet/IP frames
+type reader struct {
+	buffer []byte
+	length int
+}
+
+// read reads the available data, returns the complete frames
+func (svc *Service) read(r *reader) ([]*kx.Frame, error) {
+	n, err := svc.transport.Read(r.buffer[r.length:])
+	if err != nil {
+		svc.status.Store(fmt.Errorf("unable to read using transport: %s", err.Error()))
+		svc.log(kxOcTransportRead, kxOsFailure, err.Error())
+		return nil, err
+	}
+	r.length += n
+
+	var frames []*kx.Frame
+	for r.length > 0 {
+		length := kx.FrameLength(r.buffer[:r.length])
+		if length < 0 || length > len(r.buffer) {
+			// the datagram boundaries are lost, drop everything received
+			svc.log(kxOcBadFrame, hex.EncodeToString(r.buffer[:r.length]))
+			r.length = 0
+			break
+		}
+		if length == 0 || length > r.length {
+			break
+		}
+		data := append([]byte(nil), r.buffer[:length]...)
+		copy(r.buffer, r.buffer[length:r.length])
+		r.length -= length
+		if f, ok := kx.ParseFrame(data); ok {
+			frames = append(frames, f)
+		}
+	}
+	return frames, nil
+}
+
+// write writes the frame, returns false if the transport failed. The frame dropped while the transport is held by the
+// remote side is recovered by the tunneling retransmission, the transport is not failed
+func (svc *Service) write(frame []byte) bool {
+	if n, err := svc.transport.Write(frame); err != nil || n != len(frame) {
+		if err == nil {
+			err = fmt.Errorf("%d bytes of %d written", n, len(frame))
+		}
+		svc.log(kxOcTransportWrite, kxOsFailure, err.Error())
+		if errors.Is(err, defs.ErrTransportHeld) {
+			return true
+		}
+		svc.status.Store(fmt.Errorf("unable to write using transport: %s", err.Error()))
+		return false
+	}
+	return true
+}
+
+// connect establishes the tunneling connection
+func (svc *Service) connect(r *reader) (*tunnel, error) {
+	if !svc.write(kx.ConnectRequest(kx.HPAI{}, kx.HPAI{})) {
+		return nil, fmt.Errorf("unable to send the connect request")
+	}
+	deadline := time.After(svc.connectTimeout)
+	for {
+		select {
+		case <-svc.ctx.Done():
+			return nil, svc.ctx.Err()
+		case <-deadline:
+			return nil, fmt.Errorf("no response to the connect request")
+		case <-svc.transport.ReadyToRead():
+		}
+		frames, err := svc.read(r)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range frames {
+			if f.ServiceType != kx.CONNECT_RESPONSE {
+				continue
+			}
+			cr, ok := kx.ParseConnectResponse(f.Body)
+			if !ok {
+				return nil, fmt.Errorf("the connect response is not valid")
+			}
+			if cr.Status != kx.E_NO_ERROR {
+				return nil, fmt.Errorf("the connection is rejected with status 0x%02x", cr.Status)
+			}
+			svc.log(kxOcConnect, kxOsSuccess, strconv.Itoa(int(cr.Channel)), cr.Address.String())
+			return &tunnel{channel: cr.Channel, heartbeatDue: time.Now().Add(svc.heartbeatInterval)}, nil
+		}
+	}
+}
+
+func (svc *Service) serviceLoop() {
+	defer svc.transport.Close()
+	defer svc.stopWg.Done()
+
+	openBackoff := backoff.New(backoff.NewPolicy(svc.params))
+	r := &reader{buffer: make([]byte, 1024)}
+	var t *tunnel
+
+ServiceLoop:
+	for {
+		if t == nil {
+			r.length = 0
+			err := svc.transport.Open(svc.key.Entry, svc.params)
+			if err == nil {
+				if t, err = svc.connect(r); err != nil {
+					svc.transport.Close()
+					svc.log(kxOcConnect, kxOsFailure, err.Error())
+				}
+			}
+			if err != nil {
+				if svc.ctx.Err() != nil {
+					break ServiceLoop
+				}
+				svc.purgeQueue()
+				if !openBackoff.Retry(svc.ctx, svc.key, &svc.status, "unable to connect", err, func(gaveUp bool) {
+					if gaveUp {
+						svc.log(kxOcTransportOpen, kxOsGaveUp, err.Error())
+					} else {
+						svc.log(kxOcTransportOpen, kxOsFailure, err.Error())
+					}
+				}) {
+					break ServiceLoop
+				}
+				continue
+			}
+			openBackoff.Reset()
+			svc.log(kxOcTransportOpen, kxOsSuccess)
+			svc.status.Store(defs.ErrStatusGood)
+		}
+
+		// the queue is not read while the request is waiting for the acknowledgement
+		sendQueue := svc.sendQueue
+		if t.pending != nil {
+			sendQueue = nil
+		}
+
+		var err error
+		select {
+		case <-svc.ctx.Done():
+			svc.write(kx.DisconnectRequest(t.channel, kx.HPAI{}))
+			svc.failPending(t)
+			break ServiceLoop
+		case out := <-sendQueue:
+			t.pending, t.attempts = out, 0
+			err = svc.sendPending(t)
+		case <-svc.transport.ReadyToRead():
+			var frames []*kx.Frame
+			if frames, err = svc.read(r); err == nil {
+				for _, f := range frames {
+					if err = svc.handleFrame(t, f); err != nil {
+						break
+					}
+				}
+			}
+		case <-time.After(time.Until(t.nextDeadline())):
+			err = svc.handleTimeouts(t)
+		}
+		if err != nil {
+			svc.failPending(t)
+			if err != errConnectionLost {
+				svc.write(kx.DisconnectRequest(t.channel, kx.HPAI{}))
+			}
+			svc.status.Store(fmt.Errorf("the connection is lost: %s", err.Error()))
+			svc.log(kxOcDisconnect, err.Error())
+			t = nil
+		}
+	}
+}
+
+// nextDeadline returns the time of the closest timeout
+func (t *tunnel) nextDeadline() time.Time {
+	next := t.heartbeatDue
+	if !t.heartbeatDeadline.IsZero() && t.heartbeatDeadline.Before(next) {
+		next = t.heartbeatDeadline
+	}
+	if t.pending != nil && t.ackDeadline.Before(next) {
+		next = t.ackDeadline
+	}
+	return next
+}
+
+// sendPending sends the pending request
+func (svc *Service) sendPending(t *tunnel) error {
+	if !svc.write(kx.TunnelingRequest(t.channel, t.sendSeq, t.pending.cemi)) {
+		return fmt.Errorf("unable to send the tunneling request")
+	}
+	if t.attempts == 0 {
+		defs.Messages.UpdateState(t.pending.message.ID, api.Outgoing)
+	}
+	t.attempts++
+	t.ackDeadline = time.Now().Add(svc.tunnelingTimeout)
+	return nil
+}
+
+// failPending marks the pending request as failed
+func (svc *Service) failPending(t *tunnel) {
+	if t.pending != nil {
+		defs.Messages.UpdateState(t.pending.message.ID, api.OutgoingFailed)
+		t.pending = nil
+	}
+}
+
+// handleTimeouts resends the not acknowledged request and sends the heartbeat, returns error if the connection is lost
+func (svc *Service) handleTimeouts(t *tunnel) error {
+	now := time.Now()
+	if t.pending != nil && !now.Before(t.ackDeadline) {
+		svc.log(kxOcAckTimeout, strconv.Itoa(int(t.sendSeq)), hex.EncodeToString(t.pending.cemi))
+		if t.attempts >= tunnelingAttempts {
+			return fmt.Errorf("the tunneling request %d is not acknowledged", t.sendSeq)
+		}
+		if err := svc.sendPending(t); err != nil {
+			return err
+		}
+	}
+	if !t.heartbeatDeadline.IsZero() && !now.Before(t.heartbeatDeadline) {
+		t.heartbeatDeadline = time.Time{}
+		t.heartbeatFailures++
+		svc.log(kxOcHeartbeat, kxOsFailure, strconv.Itoa(t.heartbeatFailures))
+		if t.heartbeatFailures >= heartbeatAttempts {
+			return fmt.Errorf("no response to %d heartbeats", t.heartbeatFailures)
+		}
+		t.heartbeatDue = now // send the next one immediately
+	}
+	if t.heartbeatDeadline.IsZero() && !now.Before(t.heartbeatDue) {
+		if !svc.write(kx.ConnectionStateRequest(t.channel, kx.HPAI{})) {
+			return fmt.Errorf("unable to send the heartbeat")
+		}
+		t.heartbeatDeadline = now.Add(svc.connectionStateTimeout)
+		t.heartbeatDue = now.Add(svc.heartbeatInterval)
+	}
+	return nil
+}
+
+// handleFrame handles the frame received from the gateway, returns error if the connection is lost
+func (svc *Service) handleFrame(t *tunnel, f *kx.Frame) error {
+	switch f.ServiceType {
+	case kx.TUNNELING_REQUEST:
+		channel, sequence, _, cemi, ok := kx.ParseTunneling(f.Body)
+		if !ok || channel != t.channel {
+			break
+		}
+		switch sequence {
+		case t.recvSeq:
+			t.recvSeq++
+		case t.recvSeq - 1:
+			// the repeated request, the acknowledgement is lost
+			svc.write(kx.TunnelingAck(t.channel, sequence, kx.E_NO_ERROR))
+			return nil
+		default:
+			return nil // out of sequence, it is discarded without the acknowledgement
+		}
+		if !svc.write(kx.TunnelingAck(t.channel, sequence, kx.E_NO_ERROR)) {
+			return fmt.Errorf("unable to send the acknowledgement")
+		}
+		defs.Messages.Register(svc.key, append([]byte(nil), cemi...), api.Incoming)
+		svc.handleGroupValue(cemi)
+		return nil
+	case kx.TUNNELING_ACK:
+		channel, sequence, status, _, ok := kx.ParseTunneling(f.Body)
+		if !ok || channel != t.channel || t.pending == nil || sequence != t.sendSeq {
+			break
+		}
+		if status == kx.E_NO_ERROR {
+			t.pending = nil
+		} else {
+			svc.log(kxOcTransportWrite, kxOsFailure, fmt.Sprintf("status 0x%02x", status))
+			svc.failPending(t)
+		}
+		t.sendSeq++
+		return nil
+	case kx.CONNECTIONSTATE_RESPONSE:
+		channel, status, ok := kx.ParseChannelStatus(f.Body)
+		if !ok || channel != t.channel || t.heartbeatDeadline.IsZero() {
+			break
+		}
+		t.heartbeatDeadline = time.Time{}
+		if status != kx.E_NO_ERROR {
+			return fmt.Errorf("the heartbeat is rejected with status 0x%02x", status)
+		}
+		t.heartbeatFailures = 0
+		return nil
+	case kx.DISCONNECT_REQUEST:
+		channel, _, ok := kx.ParseChannelStatus(f.Body)
+		if !ok || channel != t.channel {
+			break
+		}
+		svc.write(kx.DisconnectResponse(t.channel, kx.E_NO_ERROR))
+		return errConnectionLost
+	case kx.DISCONNECT_RESPONSE, kx.CONNECT_RESPONSE:
+		return nil // late response
+	}
+	svc.log(kxOcBadFrame, fmt.Sprintf("%04x", f.ServiceType), hex.EncodeToString(f.Body))
+	return nil
+}
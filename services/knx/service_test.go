@@ -0,0 +1,278 @@
+package knx
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	kx "github.com/stas-makutin/howeve/knx"
+	"github.com/stas-makutin/howeve/services/mock"
+	"github.com/stas-makutin/howeve/services/udp"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(mock.RunTests(m))
+}
+
+// gateway is the stand-in KNXnet/IP gateway
+type gateway struct {
+	t       *testing.T
+	conn    *net.UDPConn
+	channel uint8
+
+	lock        sync.Mutex
+	client      *net.UDPAddr
+	connects    int
+	heartbeats  int
+	disconnects int
+	silent      bool // do not respond to the heartbeats
+	drop        int  // the number of tunneling requests to ignore
+
+	requests chan []byte // cEMI frames of the tunneling requests
+	acks     chan uint8  // the sequence numbers of the acknowledgements
+	wg       sync.WaitGroup
+}
+
+func newGateway(t *testing.T) *gateway {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	g := &gateway{t: t, conn: conn, channel: 0x15, requests: make(chan []byte, 10), acks: make(chan uint8, 10)}
+	g.wg.Add(1)
+	go g.loop()
+	return g
+}
+
+func (g *gateway) close() {
+	g.conn.Close()
+	g.wg.Wait()
+}
+
+func (g *gateway) address() string {
+	return g.conn.LocalAddr().String()
+}
+
+func (g *gateway) control() kx.HPAI {
+	a := g.conn.LocalAddr().(*net.UDPAddr)
+	return kx.HPAI{IP: a.IP, Port: uint16(a.Port)}
+}
+
+func (g *gateway) send(frame []byte) {
+	g.lock.Lock()
+	client := g.client
+	g.lock.Unlock()
+	if _, err := g.conn.WriteToUDP(frame, client); err != nil {
+		g.t.Errorf("Unable to send: %v", err)
+	}
+}
+
+func (g *gateway) counters() (connects, heartbeats, disconnects int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.connects, g.heartbeats, g.disconnects
+}
+
+func (g *gateway) loop() {
+	defer g.wg.Done()
+	buffer := make([]byte, 512)
+	for {
+		n, from, err := g.conn.ReadFromUDP(buffer)
+		if err != nil {
+			return
+		}
+		f, ok := kx.ParseFrame(append([]byte(nil), buffer[:n]...))
+		if !ok {
+			g.t.Errorf("Unexpected datagram %x", buffer[:n])
+			continue
+		}
+
+		g.lock.Lock()
+		if f.ServiceType != kx.SEARCH_REQUEST {
+			g.client = from
+		}
+		var reply []byte
+		switch f.ServiceType {
+		case kx.SEARCH_REQUEST:
+			reply = kx.SearchResponseFrame(&kx.SearchResponse{
+				Medium: 0x20, Address: 0x11ff, MAC: net.HardwareAddr{0, 1, 2, 3, 4, 5}, Name: "Stand-in", Tunneling: true,
+			})
+		case kx.CONNECT_REQUEST:
+			g.connects++
+			body := append([]byte{g.channel, kx.E_NO_ERROR}, 8, kx.IPV4_UDP, 127, 0, 0, 1, 0x0e, 0x57)
+			reply = kx.NewFrame(kx.CONNECT_RESPONSE, append(body, 4, kx.TUNNEL_CONNECTION, 0x11, 0xff))
+		case kx.CONNECTIONSTATE_REQUEST:
+			g.heartbeats++
+			if !g.silent {
+				reply = kx.NewFrame(kx.CONNECTIONSTATE_RESPONSE, []byte{g.channel, kx.E_NO_ERROR})
+			}
+		case kx.DISCONNECT_REQUEST:
+			g.disconnects++
+			reply = kx.DisconnectResponse(g.channel, kx.E_NO_ERROR)
+		case kx.TUNNELING_REQUEST:
+			if g.drop > 0 {
+				g.drop--
+				break
+			}
+			_, sequence, _, cemi, _ := kx.ParseTunneling(f.Body)
+			g.requests <- cemi
+			reply = kx.TunnelingAck(g.channel, sequence, kx.E_NO_ERROR)
+		case kx.TUNNELING_ACK:
+			_, sequence, _, _, _ := kx.ParseTunneling(f.Body)
+			g.acks <- sequence
+		case kx.DISCONNECT_RESPONSE:
+		default:
+			g.t.Errorf("Unexpected frame %+v", f)
+		}
+		g.lock.Unlock()
+
+		if reply != nil {
+			g.conn.WriteToUDP(reply, from)
+		}
+	}
+}
+
+func newTestService(t *testing.T, g *gateway, params api.ParamValues, setup func(svc *Service)) *Service {
+	svc := mock.NewService[*Service](t, params, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(&udp.Transport{}, g.address(), params)
+	})
+	if setup != nil {
+		setup(svc)
+	}
+	svc.Start()
+	return svc
+}
+
+func receive[T any](t *testing.T, ch chan T, what string) T {
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timeout waiting for %s", what)
+	}
+	var v T
+	return v
+}
+
+func TestTunneling(t *testing.T) {
+	ml := defs.Messages.(*mock.MessageLog)
+	ml.Reset()
+
+	g := newGateway(t)
+	defer g.close()
+	svc := newTestService(t, g, api.ParamValues{ParamNameGroups: "1/2/3:9.001"}, nil)
+	defer svc.Stop()
+
+	mock.WaitFor(t, "the connection", func() bool { connects, _, _ := g.counters(); return connects == 1 })
+
+	if _, err := svc.Send([]byte{kx.L_DATA_IND, 0}); err != defs.ErrBadPayload {
+		t.Errorf("Bad payload error expected, got %v", err)
+	}
+	write := kx.GroupData(0x0a04, kx.GroupValueWrite, []byte{1}, true)
+	message, err := svc.Send(write)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if cemi := receive(t, g.requests, "the tunneling request"); string(cemi) != string(write) {
+		t.Errorf("Unexpected cEMI frame %x", cemi)
+	}
+	mock.WaitFor(t, "the acknowledgement", func() bool {
+		_, m := ml.Get(message.ID)
+		return m.State == api.Outgoing
+	})
+
+	// the indication of 21.5 °C temperature, it is repeated since the acknowledgement is "lost"
+	data, _, _ := kx.EncodeDPT("9.001", 21.5)
+	indication := kx.GroupData(0x0a03, kx.GroupValueWrite, data, false)
+	indication[0] = kx.L_DATA_IND
+	g.send(kx.TunnelingRequest(g.channel, 0, indication))
+	if seq := receive(t, g.acks, "the acknowledgement"); seq != 0 {
+		t.Errorf("Unexpected acknowledgement %d", seq)
+	}
+	g.send(kx.TunnelingRequest(g.channel, 0, indication))
+	if seq := receive(t, g.acks, "the acknowledgement"); seq != 0 {
+		t.Errorf("Unexpected acknowledgement %d", seq)
+	}
+	g.send(kx.TunnelingRequest(g.channel, 1, indication))
+	if seq := receive(t, g.acks, "the acknowledgement"); seq != 1 {
+		t.Errorf("Unexpected acknowledgement %d", seq)
+	}
+	mock.WaitFor(t, "the incoming messages", func() bool { return len(ml.Incoming()) >= 2 })
+	if incoming := ml.Incoming(); len(incoming) != 2 || string(incoming[0]) != string(indication) {
+		t.Errorf("The repeated request must be registered once, got %x", incoming)
+	}
+
+	// the request is sent again if it is not acknowledged
+	g.lock.Lock()
+	g.drop = 1
+	g.lock.Unlock()
+	if _, err := svc.Send(write); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if cemi := receive(t, g.requests, "the repeated tunneling request"); string(cemi) != string(write) {
+		t.Errorf("Unexpected cEMI frame %x", cemi)
+	}
+
+	svc.Stop()
+	mock.WaitFor(t, "the disconnection", func() bool { _, _, disconnects := g.counters(); return disconnects == 1 })
+}
+
+func TestHeartbeat(t *testing.T) {
+	g := newGateway(t)
+	defer g.close()
+	svc := newTestService(t, g, nil, func(svc *Service) {
+		svc.heartbeatInterval = 50 * time.Millisecond
+		svc.connectionStateTimeout = 50 * time.Millisecond
+	})
+	defer svc.Stop()
+
+	mock.WaitFor(t, "the heartbeats", func() bool { _, heartbeats, _ := g.counters(); return heartbeats >= 2 })
+	if connects, _, _ := g.counters(); connects != 1 {
+		t.Errorf("The connection must be kept, got %d connects", connects)
+	}
+
+	// the gateway does not respond, the service must reconnect after 3 heartbeats
+	g.lock.Lock()
+	g.silent = true
+	heartbeats := g.heartbeats
+	g.lock.Unlock()
+	mock.WaitFor(t, "the reconnection", func() bool { connects, _, _ := g.counters(); return connects == 2 })
+	if _, h, disconnects := g.counters(); h < heartbeats+heartbeatAttempts || disconnects != 1 {
+		t.Errorf("Unexpected number of heartbeats %d and disconnects %d", h-heartbeats, disconnects)
+	}
+
+	// the gateway closes the connection
+	g.send(kx.DisconnectRequest(g.channel, kx.HPAI{}))
+	mock.WaitFor(t, "the reconnection", func() bool { connects, _, _ := g.counters(); return connects == 3 })
+}
+
+func TestDiscoverUDP(t *testing.T) {
+	g := newGateway(t)
+	defer g.close()
+
+	entries, err := DiscoverUDP(context.Background(), api.ParamValues{ParamNameSearchAddress: g.address(), ParamNameSearchTimeout: uint32(300)})
+	if err != nil {
+		t.Fatalf("DiscoverUDP failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("One gateway expected, got %d", len(entries))
+	}
+	if e := entries[0]; e.Protocol != api.ProtocolKNX || e.Transport != api.TransportUDP || e.Entry != g.control().String() ||
+		e.Description != "Stand-in [1.1.255, 00:01:02:03:04:05]" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+
+	if _, err := parseGroups("1/2/3:9.001, 1/2/4:1.001"); err != nil {
+		t.Errorf("The groups must be valid: %v", err)
+	}
+	for _, groups := range []string{"1/2/3", "1/2/300:9.001", "1/2/3:x"} {
+		if _, err := parseGroups(groups); err == nil {
+			t.Errorf("The groups %s must not be valid", groups)
+		}
+	}
+}
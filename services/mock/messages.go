@@ -0,0 +1,115 @@
+package mock
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/events"
+	"github.com/stas-makutin/howeve/events/handlers"
+)
+
+// MessageLog is the minimal in-memory message log for the service tests
+type MessageLog struct {
+	lock     sync.Mutex
+	messages []*api.Message
+}
+
+func (ml *MessageLog) Persist() {}
+
+func (ml *MessageLog) Register(key *api.ServiceKey, payload []byte, state api.MessageState) *api.Message {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	message := &api.Message{Time: time.Now().UTC(), ID: uuid.New(), State: state, Payload: payload}
+	ml.messages = append(ml.messages, message)
+	return message
+}
+
+func (ml *MessageLog) UpdateState(id uuid.UUID, state api.MessageState) (*api.ServiceKey, *api.Message) {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	for _, message := range ml.messages {
+		if message.ID == id {
+			message.State = state
+			return nil, message
+		}
+	}
+	return nil, nil
+}
+
+func (ml *MessageLog) Get(id uuid.UUID) (*api.ServiceKey, *api.Message) {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	for _, message := range ml.messages {
+		if message.ID == id {
+			return nil, &api.Message{Time: message.Time, ID: message.ID, State: message.State, Payload: message.Payload}
+		}
+	}
+	return nil, nil
+}
+
+func (ml *MessageLog) List(find defs.MessageFindFunc, filter defs.MessageFunc) int { return 0 }
+func (ml *MessageLog) FromIndex(index int, exclusive bool) defs.MessageFindFunc    { return nil }
+func (ml *MessageLog) FromID(id uuid.UUID, exclusive bool) defs.MessageFindFunc    { return nil }
+func (ml *MessageLog) FromTime(time time.Time, exclusive bool) defs.MessageFindFunc {
+	return nil
+}
+
+// Incoming returns the payloads of the registered incoming messages
+func (ml *MessageLog) Incoming() [][]byte {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	var result [][]byte
+	for _, message := range ml.messages {
+		if message.State == api.Incoming {
+			result = append(result, message.Payload)
+		}
+	}
+	return result
+}
+
+// Reset removes all registered messages
+func (ml *MessageLog) Reset() {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	ml.messages = nil
+}
+
+// RunTests runs the tests of the service package with the in-memory message log and the asynchronous events dispatcher,
+// it is called from TestMain and returns the exit code
+func RunTests(m *testing.M) int {
+	defs.Messages = &MessageLog{}
+	handlers.Dispatcher = events.NewAsyncDispatcher(1)
+	defer handlers.Dispatcher.Close()
+	return m.Run()
+}
+
+// NewService creates the service under the test by calling provided function with the test parameters: the transport is reopened
+// after 100 milliseconds, provided parameters are added to them. The test fails if the service is not created, the service is stopped
+// when the test ends
+func NewService[S defs.Service](t testing.TB, params api.ParamValues, create func(params api.ParamValues) (defs.Service, error)) S {
+	t.Helper()
+	pv := api.ParamValues{defs.ParamNameOpenAttemptsInterval: uint32(100)}
+	for name, value := range params {
+		pv[name] = value
+	}
+	svc, err := create(pv)
+	if err != nil {
+		t.Fatalf("unable to create the service: %v", err)
+	}
+	t.Cleanup(svc.Stop)
+	return svc.(S)
+}
+
+// WaitFor waits until the condition is met, the test fails if it is not met in 5 seconds
+func WaitFor(t testing.TB, what string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !condition(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timeout waiting for %s", what)
+		}
+	}
+}
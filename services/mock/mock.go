@@ -0,0 +1,275 @@
+package mock
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/stream"
+)
+
+// the maximal number of replies waiting to be delivered to the reading side
+const maxPendingReplies = 256
+
+// reply is the data (or the reading error) the device side sends after the delay
+type reply struct {
+	delay time.Duration
+	data  []byte
+	err   error
+}
+
+// Step is the step of the device side script: the expected write and the reaction to it.
+// The step without expected write is performed as soon as the previous step is completed and the transport is open.
+// The reaction must be defined before the expected write happens
+type Step struct {
+	t        *Transport
+	expect   bool
+	match    func(p []byte) bool
+	data     []byte
+	writeErr error
+	replies  []reply
+}
+
+// Transport struct - in-memory Transport implementation with the scripted device side, intended for tests
+type Transport struct {
+	lock       sync.Mutex
+	open       bool
+	opens      int
+	openErrors []error
+	steps      []*Step
+	writes     [][]byte
+	errs       []error
+	changed    chan struct{} // closed and replaced when the script state changes
+
+	reader stream.Reader
+	feed   *stream.Feed
+}
+
+// New creates new mock transport with the empty script
+func New() *Transport {
+	return &Transport{changed: make(chan struct{})}
+}
+
+func (t *Transport) ID() api.TransportIdentifier {
+	return api.TransportMock
+}
+
+// Expect adds the step which expects the write of provided data
+func (t *Transport) Expect(data []byte) *Step {
+	data = append([]byte(nil), data...)
+	return t.ExpectFunc(func(p []byte) bool { return bytes.Equal(p, data) }, data)
+}
+
+// ExpectFunc adds the step which expects the write matching provided function, the data is used in error messages only
+func (t *Transport) ExpectFunc(match func(p []byte) bool, data []byte) *Step {
+	return t.add(&Step{t: t, expect: true, match: match, data: data})
+}
+
+// Emit adds the step which sends provided data without the preceding write
+func (t *Transport) Emit(data ...[]byte) {
+	s := &Step{t: t}
+	for _, d := range data {
+		s.replies = append(s.replies, reply{data: append([]byte(nil), d...)})
+	}
+	t.add(s)
+}
+
+// FailOpen makes the next Open calls fail with provided errors, one error per call
+func (t *Transport) FailOpen(errs ...error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.openErrors = append(t.openErrors, errs...)
+}
+
+// Reply adds the data to send once the step is performed
+func (s *Step) Reply(data ...[]byte) *Step {
+	for _, d := range data {
+		s.ReplyAfter(0, d)
+	}
+	return s
+}
+
+// ReplyAfter adds the data to send with provided delay once the step is performed
+func (s *Step) ReplyAfter(delay time.Duration, data []byte) *Step {
+	s.t.lock.Lock()
+	defer s.t.lock.Unlock()
+	s.replies = append(s.replies, reply{delay: delay, data: append([]byte(nil), data...)})
+	return s
+}
+
+// ReplyError makes the reading side fail with provided error (the connection is lost) with provided delay once the step is performed
+func (s *Step) ReplyError(delay time.Duration, err error) *Step {
+	s.t.lock.Lock()
+	defer s.t.lock.Unlock()
+	s.replies = append(s.replies, reply{delay: delay, err: err})
+	return s
+}
+
+// WriteError makes the expected write fail with provided error, the replies are not sent
+func (s *Step) WriteError(err error) *Step {
+	s.t.lock.Lock()
+	defer s.t.lock.Unlock()
+	s.writeErr = err
+	return s
+}
+
+func (t *Transport) add(s *Step) *Step {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.steps = append(t.steps, s)
+	t.performUnexpected()
+	return s
+}
+
+// performUnexpected performs the steps without expected write at the head of the script, the lock must be held
+func (t *Transport) performUnexpected() {
+	if !t.open {
+		return
+	}
+	for len(t.steps) > 0 && !t.steps[0].expect {
+		t.enqueue(t.steps[0].replies)
+		t.steps = t.steps[1:]
+		t.notify()
+	}
+}
+
+func (t *Transport) enqueue(replies []reply) {
+	for _, r := range replies {
+		if r.err != nil {
+			t.feed.Fail(r.delay, r.err)
+		} else {
+			t.feed.Send(r.delay, r.data)
+		}
+	}
+}
+
+func (t *Transport) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+func (t *Transport) fail(err error) {
+	t.errs = append(t.errs, err)
+	t.notify()
+}
+
+// Open func
+func (t *Transport) Open(entry string, params api.ParamValues) error {
+	t.Close()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.opens++
+	if len(t.openErrors) > 0 {
+		err := t.openErrors[0]
+		t.openErrors = t.openErrors[1:]
+		t.notify()
+		return err
+	}
+
+	t.feed = stream.NewFeed(maxPendingReplies)
+	t.open = true
+	t.reader.Start(t.feed.Source())
+
+	t.notify()
+	t.performUnexpected()
+	return nil
+}
+
+// Close func
+func (t *Transport) Close() error {
+	t.lock.Lock()
+	if !t.open {
+		t.lock.Unlock()
+		return nil
+	}
+	t.open = false
+	feed := t.feed
+	t.notify()
+	t.lock.Unlock()
+
+	return t.reader.Stop(feed.Close)
+}
+
+// ReadyToRead function, singal in the channel if something could be read or the transport state has changed
+func (t *Transport) ReadyToRead() <-chan struct{} {
+	return t.reader.ReadyToRead()
+}
+
+// Read func, returns immediately with the data sent by the device side so far
+func (t *Transport) Read(p []byte) (int, error) {
+	return t.reader.Read(p)
+}
+
+// Write func, matches the data with the next step of the script
+func (t *Transport) Write(p []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if !t.open {
+		return 0, defs.ErrNotOpen
+	}
+	t.writes = append(t.writes, append([]byte(nil), p...))
+
+	if len(t.steps) == 0 {
+		t.fail(fmt.Errorf("unexpected write %x", p))
+		return len(p), nil
+	}
+	s := t.steps[0]
+	t.steps = t.steps[1:]
+	t.notify()
+	if !s.match(p) {
+		t.fail(fmt.Errorf("write %x, expected %x", p, s.data))
+	}
+	if s.writeErr != nil {
+		return 0, s.writeErr
+	}
+	t.enqueue(s.replies)
+	t.performUnexpected()
+	return len(p), nil
+}
+
+// Writes returns all the data written so far
+func (t *Transport) Writes() [][]byte {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([][]byte(nil), t.writes...)
+}
+
+// Opens returns the number of Open calls so far
+func (t *Transport) Opens() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.opens
+}
+
+// Wait waits until all the steps of the script are performed. Returns the first script violation or the timeout error
+func (t *Transport) Wait(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		t.lock.Lock()
+		if len(t.errs) > 0 {
+			err := t.errs[0]
+			t.lock.Unlock()
+			return err
+		}
+		var err error
+		if len(t.steps) > 0 {
+			err = fmt.Errorf("%d steps are not performed, the next expected write is %x", len(t.steps), t.steps[0].data)
+		}
+		changed := t.changed
+		t.lock.Unlock()
+
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return err
+		}
+	}
+}
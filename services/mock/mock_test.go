@@ -0,0 +1,128 @@
+package mock
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+)
+
+func readAll(t *testing.T, m *Transport, length int) []byte {
+	var rv []byte
+	buffer := make([]byte, 64)
+	for len(rv) < length {
+		select {
+		case <-m.ReadyToRead():
+		case <-time.After(time.Second):
+			t.Fatalf("no data to read, received %x", rv)
+		}
+		n, err := m.Read(buffer)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		rv = append(rv, buffer[:n]...)
+	}
+	return rv
+}
+
+func TestMockTransport(t *testing.T) {
+	m := New()
+	openErr := errors.New("no device")
+	m.FailOpen(openErr)
+	m.Emit([]byte{0x01})
+	m.Expect([]byte{0x10, 0x11}).Reply([]byte{0x20}).ReplyAfter(20*time.Millisecond, []byte{0x21, 0x22})
+	m.Expect([]byte{0x30}).WriteError(errors.New("write failed"))
+	m.Expect([]byte{0x40}).ReplyError(0, errors.New("connection lost"))
+
+	if _, err := m.Write([]byte{0x10}); err != defs.ErrNotOpen {
+		t.Errorf("Write to not open transport must fail with ErrNotOpen, got %v", err)
+	}
+	if err := m.Open("", nil); err != openErr {
+		t.Errorf("Open must fail with the scripted error, got %v", err)
+	}
+	if err := m.Open("", nil); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer m.Close()
+
+	if data := readAll(t, m, 1); !bytes.Equal(data, []byte{0x01}) {
+		t.Errorf("Emitted data is %x", data)
+	}
+
+	start := time.Now()
+	if n, err := m.Write([]byte{0x10, 0x11}); n != 2 || err != nil {
+		t.Errorf("Write result is %d, %v", n, err)
+	}
+	if data := readAll(t, m, 3); !bytes.Equal(data, []byte{0x20, 0x21, 0x22}) {
+		t.Errorf("Replied data is %x", data)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("The reply must be delayed, received after %v", d)
+	}
+
+	if _, err := m.Write([]byte{0x30}); err == nil || err.Error() != "write failed" {
+		t.Errorf("Write must fail with the scripted error, got %v", err)
+	}
+
+	if _, err := m.Write([]byte{0x40}); err != nil {
+		t.Errorf("Write failed: %v", err)
+	}
+	select {
+	case <-m.ReadyToRead():
+	case <-time.After(time.Second):
+		t.Fatal("ReadyToRead must signal the reading error")
+	}
+	if _, err := m.Read(make([]byte, 1)); err == nil || err.Error() != "connection lost" {
+		t.Errorf("Read must fail with the scripted error, got %v", err)
+	}
+
+	if err := m.Wait(time.Second); err != nil {
+		t.Errorf("The script is not completed: %v", err)
+	}
+	if m.Opens() != 2 || len(m.Writes()) != 3 {
+		t.Errorf("Unexpected number of opens %d or writes %d", m.Opens(), len(m.Writes()))
+	}
+
+	m.Write([]byte{0x50})
+	if err := m.Wait(time.Second); err == nil {
+		t.Error("Unexpected write must be reported")
+	}
+}
+
+func TestMockTransportMismatch(t *testing.T) {
+	m := New()
+	m.Expect([]byte{0x10})
+	m.Expect([]byte{0x20})
+	if err := m.Open("", nil); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer m.Close()
+
+	if err := m.Wait(10 * time.Millisecond); err == nil {
+		t.Error("Not performed steps must be reported")
+	}
+	m.Write([]byte{0x11})
+	if err := m.Wait(time.Second); err == nil || err.Error() != "write 11, expected 10" {
+		t.Errorf("Unexpected mismatch error: %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	create := func(entry string, params api.ParamValues) (defs.Service, error) { return nil, defs.ErrNotSupported }
+	t.Run("registered", func(t *testing.T) {
+		Register(t, "mocked", &defs.ProtocolTransportOptions{ServiceFunc: create})
+		to, ti, err := defs.ResolveProtocolAndTransport("mocked", api.TransportMock)
+		if err != nil || ti != TransportInfo || to.ServiceFunc == nil {
+			t.Errorf("The mock transport of the protocol is not resolved: %v", err)
+		}
+	})
+	if _, _, err := defs.ResolveProtocolAndTransport("mocked", api.TransportMock); err != defs.ErrProtocolNotSupported {
+		t.Errorf("The protocol must be unregistered when the test ends, got %v", err)
+	}
+	if _, ok := defs.Transports[api.TransportMock]; ok {
+		t.Error("The mock transport must be unregistered when the test ends")
+	}
+}
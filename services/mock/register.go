@@ -0,0 +1,40 @@
+package mock
+
+import (
+	"testing"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+)
+
+// TransportInfo is the definition of the mock transport, it is registered by the tests only
+var TransportInfo = &defs.TransportInfo{
+	Name:   "Mock",
+	Params: defs.Params{},
+}
+
+// Register registers the mock transport and the protocol which uses it with provided options, both are unregistered when
+// the test ends. The services of the protocol are resolved and created the same way as the services of the built-in protocols
+func Register(t testing.TB, protocol api.ProtocolIdentifier, options *defs.ProtocolTransportOptions) {
+	t.Helper()
+	if defs.Transports == nil {
+		defs.Transports = make(map[api.TransportIdentifier]*defs.TransportInfo)
+	}
+	if defs.Protocols == nil {
+		defs.Protocols = make(map[api.ProtocolIdentifier]*defs.ProtocolInfo)
+	}
+	if _, ok := defs.Transports[api.TransportMock]; ok {
+		t.Fatal("the mock transport is registered already")
+	}
+	defs.Transports[api.TransportMock] = TransportInfo
+	t.Cleanup(func() { delete(defs.Transports, api.TransportMock) })
+
+	pi := &defs.ProtocolInfo{
+		Name:       "Mock " + string(protocol),
+		Transports: map[api.TransportIdentifier]*defs.ProtocolTransportOptions{api.TransportMock: options},
+	}
+	if err := defs.RegisterProtocol(protocol, pi); err != nil {
+		t.Fatalf("unable to register the protocol %s: %v", protocol, err)
+	}
+	t.Cleanup(func() { defs.UnregisterProtocol(protocol) })
+}
@@ -0,0 +1,24 @@
+package modbus
+
+// Modbus parameters names
+const (
+	ParamNameResponseTimeout = "responseTimeout"
+	ParamNamePoll            = "poll"
+)
+
+// Modbus discovery parameters names
+const (
+	ParamNameScanNetwork  = "scanNetwork"
+	ParamNameScanPort     = "scanPort"
+	ParamNameProbeUnit    = "probeUnit"
+	ParamNameProbeTimeout = "probeTimeout"
+)
+
+// Framing defines how the Modbus PDU is framed on the wire
+type Framing uint8
+
+// Modbus framings
+const (
+	FramingRTU Framing = iota // unit address, PDU and CRC-16, the frames are separated by the silent interval
+	FramingTCP                // MBAP header followed by PDU
+)
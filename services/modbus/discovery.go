@@ -0,0 +1,169 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	mb "github.com/stas-makutin/howeve/modbus"
+	"github.com/stas-makutin/howeve/services/tcp"
+)
+
+// the limits of the network scan
+const (
+	maxScanHosts   = 1024
+	maxScanWorkers = 32
+)
+
+// the transaction identifier of the probe request
+const probeTransaction = 0x4d42
+
+var errScanNetwork = errors.New("the network to scan must be IPv4 CIDR of no more than 1024 addresses")
+
+// DiscoverTCP - discover Modbus TCP devices announced over mDNS or SSDP and, optionally, by probing every host of provided network.
+// Every candidate is verified by the read device identification request, any valid Modbus response, including the exception, is accepted
+func DiscoverTCP(ctx context.Context, params api.ParamValues) ([]*api.DiscoveryEntry, error) {
+	port := uint16(502)
+	if v, ok := params[ParamNameScanPort]; ok {
+		port = v.(uint16)
+	}
+	unit := uint8(1)
+	if v, ok := params[ParamNameProbeUnit]; ok {
+		unit = v.(uint8)
+	}
+	timeout := time.Second
+	if v, ok := params[ParamNameProbeTimeout]; ok {
+		timeout = time.Duration(v.(uint32)) * time.Millisecond
+	}
+
+	var candidates []*tcp.Endpoint
+	if v, _ := params[ParamNameScanNetwork].(string); v != "" {
+		hosts, err := scanHosts(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range hosts {
+			candidates = append(candidates, &tcp.Endpoint{Entry: net.JoinHostPort(host, strconv.Itoa(int(port)))})
+		}
+	}
+	endpoints, err := tcp.DiscoverEndpoints(ctx, params)
+	if err != nil && len(candidates) == 0 {
+		return nil, err
+	}
+	for _, e := range endpoints {
+		// the announced endpoint replaces the scanned one to keep its description
+		replaced := false
+		for i, c := range candidates {
+			if c.Entry == e.Entry {
+				candidates[i], replaced = e, true
+				break
+			}
+		}
+		if !replaced {
+			candidates = append(candidates, e)
+		}
+	}
+
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.Entry
+	}
+	return defs.DiscoverConcurrently(ctx, names, maxScanWorkers, func(i int) *api.DiscoveryEntry {
+		c := candidates[i]
+		ok, info := probeDevice(ctx, c.Entry, unit, timeout)
+		if !ok {
+			return nil
+		}
+		description := c.Description
+		if info != "" {
+			if description != "" {
+				info = " [" + info + "]"
+			}
+			description += info
+		}
+		if description == "" {
+			description = "Modbus TCP device"
+		}
+		return &api.DiscoveryEntry{
+			ServiceKey: api.ServiceKey{
+				Protocol:  api.ProtocolModbus,
+				Transport: api.TransportTCP,
+				Entry:     c.Entry,
+			},
+			Description: description,
+		}
+	}), nil
+}
+
+// scanHosts returns the host addresses of IPv4 network, excluding the network and the broadcast addresses
+func scanHosts(network string) ([]string, error) {
+	_, ipNet, err := net.ParseCIDR(network)
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := ipNet.Mask.Size()
+	if bits != 32 || bits-ones > 10 {
+		return nil, errScanNetwork
+	}
+	base := ipNet.IP.To4()
+	count := 1 << (bits - ones)
+	first, last := 0, count-1
+	if count > 2 {
+		first, last = 1, count-2
+	}
+	var rv []string
+	for i := first; i <= last; i++ {
+		v := uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3]) + uint32(i)
+		rv = append(rv, net.IPv4(byte(v>>24), byte(v>>16), byte(v>>8), byte(v)).String())
+	}
+	return rv, nil
+}
+
+// probeDevice verifies if there is Modbus TCP device behind the entry by reading its basic identification.
+// Returns true if the device responds, along with its vendor name, product code and revision if the device supports the identification
+func probeDevice(ctx context.Context, entry string, unit uint8, timeout time.Duration) (bool, string) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", entry)
+	if err != nil {
+		return false, ""
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(mb.TCPFrame(probeTransaction, unit, mb.ReadDeviceIdentification(mb.DeviceIDBasic, mb.DeviceObjectVendorName))); err != nil {
+		return false, ""
+	}
+	frame := make([]byte, mb.TCPMaxLength)
+	if _, err := io.ReadFull(conn, frame[:6]); err != nil {
+		return false, ""
+	}
+	length := mb.TCPFrameLength(frame[:6])
+	if length <= 0 {
+		return false, ""
+	}
+	if _, err := io.ReadFull(conn, frame[6:length]); err != nil {
+		return false, ""
+	}
+	transaction, _, pdu := mb.UnpackTCPFrame(frame[:length])
+	if transaction != probeTransaction || len(pdu) == 0 || pdu[0]&^mb.FuncException != mb.FuncEncapsulatedInterface {
+		return false, ""
+	}
+
+	objects, err := mb.DeviceIdentification(pdu)
+	if err != nil {
+		return true, "" // the identification is optional
+	}
+	var info []string
+	for _, object := range []byte{mb.DeviceObjectVendorName, mb.DeviceObjectProductCode, mb.DeviceObjectRevision} {
+		if v := strings.TrimSpace(objects[object]); v != "" {
+			info = append(info, v)
+		}
+	}
+	return true, strings.Join(info, " ")
+}
@@ -0,0 +1,125 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+)
+
+// the minimal polling interval
+const minPollInterval = 100 * time.Millisecond
+
+// poll is the item of the polling schedule
+type poll struct {
+	unit     uint8
+	table    string
+	address  uint16
+	count    uint16
+	interval time.Duration
+	values   []uint16 // the last read values, nil if not read yet
+}
+
+// parsePolls parses the polling schedule: comma-separated list of unit:table:address:count@interval items, the interval is in milliseconds
+func parsePolls(schedule string) ([]*poll, error) {
+	var polls []*poll
+	for _, item := range strings.Split(schedule, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		spec, interval, ok := strings.Cut(item, "@")
+		parts := strings.Split(spec, ":")
+		if !ok || len(parts) != 4 {
+			return nil, fmt.Errorf("invalid polling item '%s', expected unit:table:address:count@interval", item)
+		}
+		unit, err := strconv.ParseUint(parts[0], 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unit of polling item '%s'", item)
+		}
+		address, err := strconv.ParseUint(parts[2], 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address of polling item '%s'", item)
+		}
+		count, err := strconv.ParseUint(parts[3], 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid count of polling item '%s'", item)
+		}
+		if _, err := readRequest(parts[1], uint16(address), uint16(count)); err != nil {
+			return nil, fmt.Errorf("invalid table or count of polling item '%s'", item)
+		}
+		ms, err := strconv.ParseUint(interval, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval of polling item '%s'", item)
+		}
+		p := &poll{
+			unit: uint8(unit), table: parts[1], address: uint16(address), count: uint16(count),
+			interval: time.Duration(ms) * time.Millisecond,
+		}
+		if p.interval < minPollInterval {
+			p.interval = minPollInterval
+		}
+		polls = append(polls, p)
+	}
+	return polls, nil
+}
+
+// pollLoop reads the items of the polling schedule, the response is registered in the message log when the values change
+func (svc *Service) pollLoop(ctx context.Context) {
+	defer svc.stopWg.Done()
+	if len(svc.polls) == 0 {
+		return
+	}
+
+	next := make([]time.Time, len(svc.polls))
+	for {
+		now := time.Now()
+		wait := time.Duration(-1)
+		for i, p := range svc.polls {
+			if !next[i].After(now) {
+				svc.pollOnce(ctx, p)
+				next[i] = time.Now().Add(p.interval)
+			}
+			if d := time.Until(next[i]); wait < 0 || d < wait {
+				wait = d
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (svc *Service) pollOnce(ctx context.Context, p *poll) {
+	if svc.Status() != defs.ErrStatusGood {
+		return
+	}
+	values, frame, err := svc.read(ctx, p.unit, p.table, p.address, p.count, false)
+	if err != nil {
+		if ctx.Err() == nil {
+			svc.log(mbOcPoll, mbOsFailure, strconv.Itoa(int(p.unit)), p.table, strconv.Itoa(int(p.address)), err.Error())
+		}
+		return
+	}
+	if !equalValues(p.values, values) {
+		defs.Messages.Register(svc.key, frame, api.Incoming)
+		p.values = values
+	}
+}
+
+func equalValues(a, b []uint16) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,461 @@
+package modbus
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/log"
+	mb "github.com/stas-makutin/howeve/modbus"
+	"github.com/stas-makutin/howeve/services/backoff"
+	"github.com/stas-makutin/howeve/services/serial"
+	"github.com/stas-makutin/howeve/utils/syncutil"
+)
+
+// log constants
+const (
+	// operation
+	mbOpService = "MB"
+
+	mbOcTransportOpen  = "O"
+	mbOcTransportRead  = "R"
+	mbOcTransportWrite = "W"
+	mbOcReplyTimeout   = "T"
+	mbOcBadFrame       = "B"
+	mbOcPoll           = "P"
+
+	mbOsSuccess = "0"
+	mbOsFailure = "F"
+	mbOsGaveUp  = "G"
+)
+
+// request is the frame to send to the device and the channel to deliver the response to
+type request struct {
+	frame       []byte
+	unit        uint8
+	transaction uint16       // Modbus TCP transaction identifier
+	message     *api.Message // the registered outgoing message, the response is registered too if it is not nil
+	result      chan *response
+}
+
+type response struct {
+	frame []byte
+	pdu   []byte
+	err   error
+}
+
+func (r *request) complete(frame, pdu []byte, err error) {
+	if r.result != nil {
+		r.result <- &response{frame: frame, pdu: pdu, err: err}
+	}
+}
+
+// Service Modbus service implementation, it is the client (master) of the devices available through the transport
+type Service struct {
+	transport defs.Transport
+	framing   Framing
+	key       *api.ServiceKey
+	params    api.ParamValues
+
+	frameDelay      time.Duration
+	responseTimeout time.Duration
+	polls           []*poll
+
+	requests    chan *request
+	transaction uint32
+
+	status syncutil.RLocked[error]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stopWg sync.WaitGroup
+}
+
+// NewService creates new Modbus service implementation using provided transport and framing
+func NewService(transport defs.Transport, framing Framing, entry string, params api.ParamValues) (defs.Service, error) {
+	pv := serial.ServiceParams(transport, params)
+
+	svc := &Service{
+		transport:       transport,
+		framing:         framing,
+		key:             &api.ServiceKey{Protocol: api.ProtocolModbus, Transport: transport.ID(), Entry: entry},
+		params:          pv,
+		responseTimeout: time.Second,
+		requests:        make(chan *request, 10),
+	}
+	if framing == FramingRTU {
+		baudRate := 0
+		if v, ok := pv[serial.ParamNameBaudRate]; ok {
+			baudRate = int(v.(int32))
+		}
+		svc.frameDelay = mb.RTUFrameDelay(baudRate)
+	}
+	if v, ok := pv[ParamNameResponseTimeout]; ok {
+		if svc.responseTimeout = time.Duration(v.(uint32)) * time.Millisecond; svc.responseTimeout < 10*time.Millisecond {
+			svc.responseTimeout = 10 * time.Millisecond
+		}
+	}
+	if v, ok := pv[ParamNamePoll]; ok {
+		var err error
+		if svc.polls, err = parsePolls(v.(string)); err != nil {
+			return nil, err
+		}
+	}
+	return svc, nil
+}
+
+func (svc *Service) Start() {
+	svc.Stop()
+
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+
+	svc.stopWg.Add(2)
+	go svc.serviceLoop()
+	go svc.pollLoop(svc.ctx)
+}
+
+func (svc *Service) Stop() {
+	if svc.ctx == nil {
+		return // already stopped
+	}
+
+	svc.cancel()
+	svc.stopWg.Wait()
+
+PurgeLoop:
+	for {
+		select {
+		default:
+			break PurgeLoop
+		case req := <-svc.requests:
+			if req.message != nil {
+				defs.Messages.UpdateState(req.message.ID, api.OutgoingRejected)
+			}
+			req.complete(nil, nil, defs.ErrNotOpen)
+		}
+	}
+
+	svc.ctx, svc.cancel = nil, nil
+
+	svc.status.Store(defs.ErrStatusGood)
+}
+
+func (svc *Service) Status() defs.ServiceStatus {
+	err := svc.status.Load()
+	if err == nil {
+		return defs.ServiceStatus(defs.ErrStatusGood)
+	}
+	return err.(defs.ServiceStatus)
+}
+
+// Send sends the raw frame (RTU frame with CRC or Modbus TCP frame with MBAP header) to the device, the response is registered in the message log
+func (svc *Service) Send(payload []byte) (*api.Message, error) {
+	req := &request{frame: append([]byte(nil), payload...)}
+	switch svc.framing {
+	case FramingRTU:
+		if !mb.ValidateRTUFrame(payload) {
+			return nil, defs.ErrBadPayload
+		}
+		req.unit = payload[0]
+	case FramingTCP:
+		if mb.TCPFrameLength(payload) != len(payload) {
+			return nil, defs.ErrBadPayload
+		}
+		req.transaction, req.unit, _ = mb.UnpackTCPFrame(payload)
+	}
+
+	req.message = defs.Messages.Register(svc.key, payload, api.OutgoingPending)
+	select {
+	case svc.requests <- req:
+	default:
+		defs.Messages.UpdateState(req.message.ID, api.OutgoingRejected)
+		return req.message, defs.ErrSendBusy
+	}
+	return req.message, nil
+}
+
+// ResolvedEntry returns the actual entry the transport is opened with, if the transport resolves the service entry
+func (svc *Service) ResolvedEntry() string {
+	if r, ok := svc.transport.(defs.EntryResolver); ok {
+		return r.ResolvedEntry()
+	}
+	return ""
+}
+
+// ReadRegisters reads the coils or registers of the device
+func (svc *Service) ReadRegisters(ctx context.Context, unit uint8, table string, address uint16, count uint16) ([]uint16, error) {
+	values, _, err := svc.read(ctx, unit, table, address, count, true)
+	return values, err
+}
+
+// WriteRegisters writes the coils or holding registers of the device
+func (svc *Service) WriteRegisters(ctx context.Context, unit uint8, table string, address uint16, values []uint16) error {
+	var pdu []byte
+	switch {
+	case len(values) == 0:
+		return defs.ErrBadPayload
+	case table == api.RegisterTableCoil && len(values) == 1:
+		pdu = mb.WriteSingleCoil(address, values[0] != 0)
+	case table == api.RegisterTableCoil && len(values) <= mb.MaxWriteBits:
+		pdu = mb.WriteMultipleCoils(address, values)
+	case table == api.RegisterTableHolding && len(values) == 1:
+		pdu = mb.WriteSingleRegister(address, values[0])
+	case table == api.RegisterTableHolding && len(values) <= mb.MaxWriteRegisters:
+		pdu = mb.WriteMultipleRegisters(address, values)
+	default:
+		return defs.ErrBadPayload
+	}
+	response, _, err := svc.do(ctx, unit, pdu, true)
+	if err != nil {
+		return err
+	}
+	return mb.CheckResponse(pdu, response)
+}
+
+// readRequest returns the request PDU which reads the table items
+func readRequest(table string, address, count uint16) ([]byte, error) {
+	var function byte
+	maxCount := uint16(mb.MaxReadRegisters)
+	switch table {
+	case api.RegisterTableCoil:
+		function, maxCount = mb.FuncReadCoils, mb.MaxReadBits
+	case api.RegisterTableDiscreteInput:
+		function, maxCount = mb.FuncReadDiscreteInputs, mb.MaxReadBits
+	case api.RegisterTableHolding:
+		function = mb.FuncReadHoldingRegisters
+	case api.RegisterTableInput:
+		function = mb.FuncReadInputRegisters
+	default:
+		return nil, defs.ErrBadPayload
+	}
+	if count == 0 || count > maxCount {
+		return nil, defs.ErrBadPayload
+	}
+	return mb.ReadRequest(function, address, count), nil
+}
+
+// read reads the table items, returns the values and the response frame
+func (svc *Service) read(ctx context.Context, unit uint8, table string, address uint16, count uint16, logMessages bool) ([]uint16, []byte, error) {
+	pdu, err := readRequest(table, address, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	response, frame, err := svc.do(ctx, unit, pdu, logMessages)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := mb.CheckResponse(pdu, response); err != nil {
+		return nil, nil, err
+	}
+	return mb.ReadValues(pdu, response), frame, nil
+}
+
+// do sends the request PDU to the device and waits for the response, returns the response PDU and frame
+func (svc *Service) do(ctx context.Context, unit uint8, pdu []byte, logMessages bool) ([]byte, []byte, error) {
+	if status := svc.Status(); status != defs.ErrStatusGood {
+		return nil, nil, status
+	}
+
+	req := &request{unit: unit, result: make(chan *response, 1)}
+	switch svc.framing {
+	case FramingRTU:
+		req.frame = mb.RTUFrame(unit, pdu)
+	case FramingTCP:
+		req.transaction = uint16(atomic.AddUint32(&svc.transaction, 1))
+		req.frame = mb.TCPFrame(req.transaction, unit, pdu)
+	}
+	if logMessages {
+		req.message = defs.Messages.Register(svc.key, req.frame, api.OutgoingPending)
+	}
+
+	select {
+	case <-ctx.Done():
+		if req.message != nil {
+			defs.Messages.UpdateState(req.message.ID, api.OutgoingRejected)
+		}
+		return nil, nil, ctx.Err()
+	case svc.requests <- req:
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case r := <-req.result:
+		return r.pdu, r.frame, r.err
+	}
+}
+
+func (svc *Service) log(op string, fields ...string) {
+	log.Report(append([]string{
+		log.SrcSVC,
+		mbOpService,
+		op,
+		defs.ProtocolName(svc.key.Protocol),
+		defs.TransportName(svc.key.Transport),
+		svc.key.Entry,
+	}, fields...)...)
+}
+
+func (svc *Service) serviceLoop() {
+	defer svc.transport.Close()
+	defer svc.stopWg.Done()
+
+	openBackoff := backoff.New(backoff.NewPolicy(svc.params))
+	buffer := make([]byte, 512)
+	var lastFrame time.Time
+	open := true
+
+ServiceLoop:
+	for {
+		if open {
+			open = false
+			if err := svc.transport.Open(svc.key.Entry, svc.params); err != nil {
+				if !openBackoff.Retry(svc.ctx, svc.key, &svc.status, "unable to open transport", err, func(gaveUp bool) {
+					if gaveUp {
+						svc.log(mbOcTransportOpen, mbOsGaveUp, err.Error())
+					} else {
+						svc.log(mbOcTransportOpen, mbOsFailure, err.Error())
+					}
+				}) {
+					break ServiceLoop
+				}
+				open = true
+				continue
+			}
+			openBackoff.Reset()
+			svc.log(mbOcTransportOpen, mbOsSuccess)
+			svc.status.Store(defs.ErrStatusGood)
+		}
+
+		select {
+		case <-svc.ctx.Done():
+			break ServiceLoop
+		case req := <-svc.requests:
+			if !svc.perform(req, buffer, &lastFrame) {
+				open = true
+			}
+		case <-svc.transport.ReadyToRead():
+			// unsolicited data, there is no request pending
+			if n, err := svc.transport.Read(buffer); err != nil {
+				svc.status.Store(fmt.Errorf("unable to read using transport: %s", err.Error()))
+				svc.log(mbOcTransportRead, mbOsFailure, err.Error())
+				open = true
+			} else if n > 0 {
+				svc.log(mbOcBadFrame, hex.EncodeToString(buffer[:n]))
+			}
+		}
+	}
+}
+
+// perform writes the request and reads the response, returns false if the transport needs to be reopened
+func (svc *Service) perform(req *request, buffer []byte, lastFrame *time.Time) bool {
+	// RTU frames must be separated by the silent interval
+	if wait := time.Until(lastFrame.Add(svc.frameDelay)); svc.framing == FramingRTU && wait > 0 {
+		time.Sleep(wait)
+	}
+
+	if n, err := svc.transport.Write(req.frame); err != nil || n != len(req.frame) {
+		if err == nil {
+			err = fmt.Errorf("%d bytes of %d written", n, len(req.frame))
+		}
+		svc.log(mbOcTransportWrite, mbOsFailure, err.Error())
+		if req.message != nil {
+			defs.Messages.UpdateState(req.message.ID, api.OutgoingFailed)
+		}
+		req.complete(nil, nil, err)
+		if errors.Is(err, defs.ErrTransportHeld) {
+			// the held transport is operational, only the request fails
+			return true
+		}
+		svc.status.Store(fmt.Errorf("unable to write using transport: %s", err.Error()))
+		return false
+	}
+	if req.message != nil {
+		defs.Messages.UpdateState(req.message.ID, api.Outgoing)
+	}
+
+	frame, pdu, err, reopen := svc.readResponse(req, buffer)
+	*lastFrame = time.Now()
+	if err != nil {
+		req.complete(nil, nil, err)
+		return !reopen
+	}
+	if req.message != nil {
+		defs.Messages.Register(svc.key, frame, api.Incoming)
+	}
+	req.complete(frame, pdu, nil)
+	return true
+}
+
+// readResponse reads the response frame of the request, reopen is true if the transport is failed or the stream can't be synchronized
+func (svc *Service) readResponse(req *request, buffer []byte) (frame []byte, pdu []byte, err error, reopen bool) {
+	deadline := time.Now().Add(svc.responseTimeout)
+	length := 0
+	for {
+		select {
+		case <-svc.ctx.Done():
+			return nil, nil, svc.ctx.Err(), false
+		case <-time.After(time.Until(deadline)):
+			if length > 0 {
+				svc.log(mbOcReplyTimeout, hex.EncodeToString(buffer[:length]))
+			} else {
+				svc.log(mbOcReplyTimeout)
+			}
+			return nil, nil, defs.ErrNoResponse, false
+		case <-svc.transport.ReadyToRead():
+		}
+
+		n, err := svc.transport.Read(buffer[length:])
+		if err != nil {
+			svc.status.Store(fmt.Errorf("unable to read using transport: %s", err.Error()))
+			svc.log(mbOcTransportRead, mbOsFailure, err.Error())
+			return nil, nil, err, true
+		}
+		length += n
+
+		for length > 0 {
+			var frameLength int
+			if svc.framing == FramingRTU {
+				frameLength = mb.RTUResponseLength(buffer[:length])
+			} else {
+				frameLength = mb.TCPFrameLength(buffer[:length])
+			}
+			if frameLength < 0 || frameLength > len(buffer) {
+				svc.log(mbOcBadFrame, hex.EncodeToString(buffer[:length]))
+				return nil, nil, mb.ErrBadResponse, svc.framing == FramingTCP
+			}
+			if frameLength == 0 || frameLength > length {
+				break // continue to read
+			}
+
+			frame = append([]byte(nil), buffer[:frameLength]...)
+			copy(buffer, buffer[frameLength:length])
+			length -= frameLength
+
+			if svc.framing == FramingRTU {
+				if !mb.ValidateRTUFrame(frame) {
+					svc.log(mbOcBadFrame, hex.EncodeToString(frame))
+					return nil, nil, mb.ErrBadResponse, false
+				}
+				var unit uint8
+				if unit, pdu = mb.UnpackRTUFrame(frame); unit == req.unit {
+					return frame, pdu, nil, false
+				}
+			} else {
+				var transaction uint16
+				var unit uint8
+				if transaction, unit, pdu = mb.UnpackTCPFrame(frame); transaction == req.transaction && unit == req.unit {
+					return frame, pdu, nil, false
+				}
+			}
+			// the response to another request, like the late response to the timed out one
+			svc.log(mbOcBadFrame, hex.EncodeToString(frame))
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	mb "github.com/stas-makutin/howeve/modbus"
+	"github.com/stas-makutin/howeve/services/mock"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(mock.RunTests(m))
+}
+
+func newTestService(t *testing.T, m *mock.Transport, framing Framing, params api.ParamValues) *Service {
+	pv := api.ParamValues{ParamNameResponseTimeout: uint32(200)}
+	for name, value := range params {
+		pv[name] = value
+	}
+	svc := mock.NewService[*Service](t, pv, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(m, framing, "test", params)
+	})
+	svc.Start()
+	return svc
+}
+
+func TestServiceReadRTU(t *testing.T) {
+	request := mb.RTUFrame(17, mb.ReadRequest(mb.FuncReadHoldingRegisters, 0x6b, 3))
+	response := mb.RTUFrame(17, []byte{mb.FuncReadHoldingRegisters, 6, 0x02, 0x2b, 0x00, 0x00, 0x00, 0x64})
+
+	m := mock.New()
+	// the response of another unit must be skipped, the response is split to test the reassembly
+	m.Expect(request).Reply(mb.RTUFrame(5, []byte{mb.FuncReadHoldingRegisters, 2, 0, 1}), response[:4], response[4:])
+
+	svc := newTestService(t, m, FramingRTU, nil)
+	defer svc.Stop()
+
+	values, err := svc.ReadRegisters(context.Background(), 17, api.RegisterTableHolding, 0x6b, 3)
+	if err != nil {
+		t.Fatalf("ReadRegisters failed: %v", err)
+	}
+	if len(values) != 3 || values[0] != 0x022b || values[1] != 0 || values[2] != 0x64 {
+		t.Errorf("Unexpected values: %v", values)
+	}
+	if err := m.Wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceException(t *testing.T) {
+	request := mb.RTUFrame(1, mb.ReadRequest(mb.FuncReadInputRegisters, 8, 1))
+
+	m := mock.New()
+	m.Expect(request).Reply(mb.RTUFrame(1, []byte{mb.FuncReadInputRegisters | mb.FuncException, mb.ExceptionIllegalAddress}))
+
+	svc := newTestService(t, m, FramingRTU, nil)
+	defer svc.Stop()
+
+	_, err := svc.ReadRegisters(context.Background(), 1, api.RegisterTableInput, 8, 1)
+	var exception *mb.ExceptionError
+	if !errors.As(err, &exception) || exception.Code != mb.ExceptionIllegalAddress {
+		t.Errorf("Exception expected, got %v", err)
+	}
+	if _, err := svc.ReadRegisters(context.Background(), 1, "unknown", 8, 1); err != defs.ErrBadPayload {
+		t.Errorf("Bad payload error expected for unknown table, got %v", err)
+	}
+	if err := svc.WriteRegisters(context.Background(), 1, api.RegisterTableInput, 8, []uint16{1}); err != defs.ErrBadPayload {
+		t.Errorf("Bad payload error expected for read-only table, got %v", err)
+	}
+}
+
+func TestServiceWriteTCP(t *testing.T) {
+	m := mock.New()
+	m.ExpectFunc(func(p []byte) bool {
+		_, unit, pdu := mb.UnpackTCPFrame(p)
+		return unit == 3 && string(pdu) == string(mb.WriteSingleCoil(10, true))
+	}, []byte("write single coil")).ReplyAfter(0, mb.TCPFrame(1, 3, mb.WriteSingleCoil(10, true)))
+
+	svc := newTestService(t, m, FramingTCP, nil)
+	defer svc.Stop()
+
+	if err := svc.WriteRegisters(context.Background(), 3, api.RegisterTableCoil, 10, []uint16{1}); err != nil {
+		t.Fatalf("WriteRegisters failed: %v", err)
+	}
+	if err := m.Wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceTimeout(t *testing.T) {
+	request := mb.RTUFrame(2, mb.ReadRequest(mb.FuncReadCoils, 0, 8))
+	response := mb.RTUFrame(2, []byte{mb.FuncReadCoils, 1, 0x05})
+
+	m := mock.New()
+	m.Expect(request)
+	m.Expect(request).Reply(response)
+
+	svc := newTestService(t, m, FramingRTU, nil)
+	defer svc.Stop()
+
+	start := time.Now()
+	if _, err := svc.ReadRegisters(context.Background(), 2, api.RegisterTableCoil, 0, 8); err != defs.ErrNoResponse {
+		t.Errorf("No response error expected, got %v", err)
+	}
+	if d := time.Since(start); d < 200*time.Millisecond {
+		t.Errorf("The response must be awaited for 200 milliseconds, failed after %v", d)
+	}
+
+	values, err := svc.ReadRegisters(context.Background(), 2, api.RegisterTableCoil, 0, 8)
+	if err != nil {
+		t.Fatalf("ReadRegisters failed: %v", err)
+	}
+	if len(values) != 8 || values[0] != 1 || values[1] != 0 || values[2] != 1 {
+		t.Errorf("Unexpected values: %v", values)
+	}
+}
+
+func TestServicePoll(t *testing.T) {
+	ml := defs.Messages.(*mock.MessageLog)
+	ml.Reset()
+
+	request := mb.RTUFrame(1, mb.ReadRequest(mb.FuncReadDiscreteInputs, 0, 2))
+	first := mb.RTUFrame(1, []byte{mb.FuncReadDiscreteInputs, 1, 0x01})
+	second := mb.RTUFrame(1, []byte{mb.FuncReadDiscreteInputs, 1, 0x03})
+
+	m := mock.New()
+	m.Expect(request).Reply(first)
+	m.Expect(request).Reply(first)
+	m.Expect(request).Reply(second)
+
+	svc := newTestService(t, m, FramingRTU, api.ParamValues{ParamNamePoll: "1:discreteInput:0:2@100"})
+	defer svc.Stop()
+
+	if err := m.Wait(2 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(time.Second); len(ml.Incoming()) < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+	incoming := ml.Incoming()
+	if len(incoming) != 2 || string(incoming[0]) != string(first) || string(incoming[1]) != string(second) {
+		t.Errorf("Only the changed values must be registered, got %x", incoming)
+	}
+
+	if _, err := parsePolls("1:holding:0:200@100"); err == nil {
+		t.Error("The count over the limit must be rejected")
+	}
+	if _, err := parsePolls("1:holding:0@100"); err == nil {
+		t.Error("The invalid item must be rejected")
+	}
+}
+
+func TestDiscoverTCP(t *testing.T) {
+	// the device which responds with the identification and the one which responds with the exception
+	device := func(response []byte) uint16 {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				frame := make([]byte, 11)
+				if _, err := io.ReadFull(conn, frame); err == nil {
+					transaction, unit, _ := mb.UnpackTCPFrame(frame)
+					conn.Write(mb.TCPFrame(transaction, unit, response))
+				}
+				conn.Close()
+			}
+		}()
+		return uint16(l.Addr().(*net.TCPAddr).Port)
+	}
+	identified := device([]byte{0x2b, 0x0e, 0x01, 0x01, 0x00, 0x00, 0x02, 0x00, 0x04, 'A', 'c', 'm', 'e', 0x01, 0x02, 'P', '1'})
+	exception := device([]byte{0xab, mb.ExceptionIllegalFunction})
+
+	params := api.ParamValues{ParamNameScanNetwork: "127.0.0.1/32", ParamNameProbeTimeout: uint32(500)}
+	for _, port := range []uint16{identified, exception} {
+		params[ParamNameScanPort] = port
+		entries, err := DiscoverTCP(context.Background(), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := "127.0.0.1:" + strconv.Itoa(int(port))
+		if len(entries) != 1 || entries[0].Protocol != api.ProtocolModbus || entries[0].Transport != api.TransportTCP || entries[0].Entry != entry {
+			t.Fatalf("Unexpected entries %+v", entries)
+		}
+		if description := entries[0].Description; (port == identified && description != "Acme P1") || (port == exception && description != "Modbus TCP device") {
+			t.Errorf("Unexpected description '%s'", description)
+		}
+	}
+
+	if hosts, err := scanHosts("192.0.2.0/30"); err != nil || len(hosts) != 2 || hosts[0] != "192.0.2.1" || hosts[1] != "192.0.2.2" {
+		t.Errorf("Unexpected hosts %v, error %v", hosts, err)
+	}
+	if _, err := scanHosts("10.0.0.0/16"); !errors.Is(err, errScanNetwork) {
+		t.Errorf("The network over the limit must be rejected, error %v", err)
+	}
+}
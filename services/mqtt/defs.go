@@ -0,0 +1,82 @@
+package mqtt
+
+import "github.com/stas-makutin/howeve/defs"
+
+// MQTT transport parameters names
+const (
+	ParamNameClientID        = "clientId"
+	ParamNameUsername        = "username"
+	ParamNamePassword        = "password"
+	ParamNameProtocolVersion = "protocolVersion"
+	ParamNameKeepAlive       = "keepAlive"
+	ParamNameConnectTimeout  = "connectTimeout"
+	ParamNamePublishTopic    = "publishTopic"
+	ParamNameSubscribeTopic  = "subscribeTopic"
+	ParamNameQoS             = "qos"
+	ParamNameRetain          = "retain"
+)
+
+// protocolVersion parameter values
+const (
+	ProtocolVersion311 = "3.1.1"
+	ProtocolVersion5   = "5"
+)
+
+var TransportInfo *defs.TransportInfo = &defs.TransportInfo{
+	Name: "MQTT",
+	Params: defs.Params{
+		ParamNameClientID: {
+			Description:  "The client identifier, empty to let the broker assign it",
+			Type:         defs.ParamTypeString,
+			DefaultValue: "",
+		},
+		ParamNameUsername: {
+			Description:  "The user name, empty to connect anonymously",
+			Type:         defs.ParamTypeString,
+			DefaultValue: "",
+		},
+		ParamNamePassword: {
+			Description:  "The password",
+			Type:         defs.ParamTypeString,
+			Flags:        defs.ParamFlagSecret,
+			DefaultValue: "",
+		},
+		ParamNameProtocolVersion: {
+			Description:  "The version of MQTT protocol",
+			Type:         defs.ParamTypeEnum,
+			DefaultValue: ProtocolVersion311,
+			EnumValues:   []string{ProtocolVersion311, ProtocolVersion5},
+		},
+		ParamNameKeepAlive: {
+			Description:  "The keep alive interval, milliseconds, rounded down to seconds, 0 to disable",
+			Type:         defs.ParamTypeUint32,
+			DefaultValue: "60000",
+		},
+		ParamNameConnectTimeout: {
+			Description:  "The timeout of the connection and the broker responses, milliseconds",
+			Type:         defs.ParamTypeUint32,
+			DefaultValue: "5000",
+		},
+		ParamNamePublishTopic: {
+			Description:  "The topic to publish the sent data to",
+			Type:         defs.ParamTypeString,
+			DefaultValue: "",
+		},
+		ParamNameSubscribeTopic: {
+			Description:  "The topic filter to receive the data from, the wildcards + and # are allowed, empty to not subscribe",
+			Type:         defs.ParamTypeString,
+			DefaultValue: "",
+		},
+		ParamNameQoS: {
+			Description:  "The quality of service of the published messages and of the subscription",
+			Type:         defs.ParamTypeEnum,
+			DefaultValue: "0",
+			EnumValues:   []string{"0", "1"},
+		},
+		ParamNameRetain: {
+			Description:  "Whether the broker should retain the published messages",
+			Type:         defs.ParamTypeBool,
+			DefaultValue: "false",
+		},
+	},
+}
@@ -0,0 +1,201 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	mq "github.com/stas-makutin/howeve/mqtt"
+	"github.com/stas-makutin/howeve/services/stream"
+)
+
+// errNoPublishTopic is returned on write if the publish topic is not configured
+var errNoPublishTopic = errors.New("the publish topic is not configured")
+
+// Transport struct - MQTT Transport implementation, the entry is host:port of the broker.
+// The payloads of the messages received from the subscribed topics are read as the byte stream, and the written data
+// is published to the publish topic, so the transport could be used like the serial line.
+// In the records mode the messages are read and written as the records which keep the topic, see AppendRecord
+type Transport struct {
+	Records bool
+
+	client       *mq.Client
+	lock         sync.RWMutex
+	reader       stream.Reader
+	publishTopic string
+	qos          byte
+	retain       bool
+	timeout      time.Duration
+}
+
+func (t *Transport) ID() api.TransportIdentifier {
+	return api.TransportMQTT
+}
+
+// Open func, connects to the broker and subscribes to the subscribe topic
+func (t *Transport) Open(entry string, params api.ParamValues) error {
+	options := mq.Options{Version: mq.Version311, CleanSession: true, Timeout: 5 * time.Second}
+	if v, ok := params[ParamNameProtocolVersion]; ok && v.(string) == ProtocolVersion5 {
+		options.Version = mq.Version5
+	}
+	if v, ok := params[ParamNameClientID]; ok {
+		options.ClientID = v.(string)
+	}
+	if v, ok := params[ParamNameUsername]; ok {
+		options.Username = v.(string)
+	}
+	if v, ok := params[ParamNamePassword]; ok {
+		options.Password = v.(string)
+	}
+	if v, ok := params[ParamNameKeepAlive]; ok {
+		options.KeepAlive = time.Duration(v.(uint32)) * time.Millisecond
+	}
+	if v, ok := params[ParamNameConnectTimeout]; ok && v.(uint32) > 0 {
+		options.Timeout = time.Duration(v.(uint32)) * time.Millisecond
+	}
+	var qos byte
+	if v, ok := params[ParamNameQoS]; ok && v.(string) == "1" {
+		qos = 1
+	}
+	publishTopic, subscribeTopic := "", ""
+	if v, ok := params[ParamNamePublishTopic]; ok {
+		publishTopic = v.(string)
+	}
+	if v, ok := params[ParamNameSubscribeTopic]; ok {
+		subscribeTopic = v.(string)
+	}
+	retain := false
+	if v, ok := params[ParamNameRetain]; ok {
+		retain = v.(bool)
+	}
+
+	t.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout)
+	defer cancel()
+	client, err := mq.Dial(ctx, entry, options)
+	if err != nil {
+		return err
+	}
+	if subscribeTopic != "" {
+		if err := client.Subscribe(ctx, subscribeTopic, qos); err != nil {
+			client.Close()
+			return err
+		}
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.client = client
+	t.publishTopic, t.qos, t.retain, t.timeout = publishTopic, qos, retain, options.Timeout
+	t.reader.Start(&messageSource{client: client, records: t.Records})
+	return nil
+}
+
+// Close func
+func (t *Transport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	client := t.client
+	t.client = nil
+	return t.reader.Stop(func() error {
+		return client.Close()
+	})
+}
+
+// ReadyToRead function, singal in the channel if something could be read or the connection state has changed
+func (t *Transport) ReadyToRead() <-chan struct{} {
+	return t.reader.ReadyToRead()
+}
+
+// Read func, returns immediately with the data received so far
+func (t *Transport) Read(p []byte) (int, error) {
+	return t.reader.Read(p)
+}
+
+// Write func, publishes the data as one message to the publish topic, or one record in the records mode
+func (t *Transport) Write(p []byte) (int, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.client == nil {
+		return 0, defs.ErrNotOpen
+	}
+	topic, payload := t.publishTopic, p
+	if t.Records {
+		n, recordTopic, recordPayload := NextRecord(p)
+		if n != len(p) {
+			return 0, errors.New("the data is not a record")
+		}
+		if recordTopic != "" {
+			topic = recordTopic
+		}
+		payload = recordPayload
+	}
+	if topic == "" {
+		return 0, errNoPublishTopic
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	if err := t.client.Publish(ctx, topic, payload, t.qos, t.retain); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// AppendRecord appends the record of the message to the buffer: 2-byte length of the topic followed by the topic,
+// and 4-byte length of the payload followed by the payload, the lengths are big-endian
+func AppendRecord(b []byte, topic string, payload []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(topic)))
+	b = append(b, topic...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	return append(b, payload...)
+}
+
+// NextRecord parses the record at the start of the buffer, returns the record length or 0 if the record is incomplete
+func NextRecord(b []byte) (n int, topic string, payload []byte) {
+	if len(b) < 2 {
+		return 0, "", nil
+	}
+	end := 2 + int(binary.BigEndian.Uint16(b))
+	if len(b) < end+4 {
+		return 0, "", nil
+	}
+	topic = string(b[2:end])
+	n = end + 4 + int(binary.BigEndian.Uint32(b[end:]))
+	if n < end+4 || len(b) < n {
+		return 0, "", nil
+	}
+	return n, topic, b[end+4 : n]
+}
+
+// messageSource is the blocking reader of the received messages
+type messageSource struct {
+	client  *mq.Client
+	records bool
+	pending []byte
+}
+
+func (s *messageSource) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		m, ok := <-s.client.Messages()
+		if !ok {
+			if err := s.client.Err(); err != nil && err != mq.ErrClosed {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		if s.records {
+			s.pending = AppendRecord(nil, m.Topic, m.Payload)
+		} else {
+			s.pending = m.Payload
+		}
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,224 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/log"
+	"github.com/stas-makutin/howeve/services/backoff"
+	"github.com/stas-makutin/howeve/utils/syncutil"
+)
+
+// log constants
+const (
+	// operation
+	mqOpService = "MQ"
+
+	mqOcTransportOpen  = "O"
+	mqOcTransportRead  = "R"
+	mqOcTransportWrite = "W"
+
+	mqOsSuccess = "0"
+	mqOsFailure = "F"
+	mqOsGaveUp  = "G"
+)
+
+// TopicSeparator separates the topic and the payload in the message payload
+const TopicSeparator = 0
+
+// outgoing is the record to publish and its registered message
+type outgoing struct {
+	record  []byte
+	message *api.Message
+}
+
+// Service the MQTT protocol service implementation, each message is published to or received from the broker.
+// The payload of the messages is the topic followed by TopicSeparator and the MQTT message payload
+type Service struct {
+	transport defs.Transport
+	key       *api.ServiceKey
+	params    api.ParamValues
+
+	sendQueue chan *outgoing
+
+	status syncutil.RLocked[error]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stopWg sync.WaitGroup
+}
+
+// NewService creates new MQTT protocol service implementation using provided transport, the transport must be MQTT transport
+// in the records mode (possibly wrapped)
+func NewService(transport defs.Transport, entry string, params api.ParamValues) (defs.Service, error) {
+	return &Service{
+		transport: transport,
+		key:       &api.ServiceKey{Protocol: api.ProtocolMQTT, Transport: transport.ID(), Entry: entry},
+		params:    params.Copy(),
+		sendQueue: make(chan *outgoing, 10),
+	}, nil
+}
+
+func (svc *Service) Start() {
+	svc.Stop()
+
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+
+	svc.stopWg.Add(1)
+	go svc.serviceLoop()
+}
+
+func (svc *Service) Stop() {
+	if svc.ctx == nil {
+		return // already stopped
+	}
+
+	svc.cancel()
+	svc.stopWg.Wait()
+
+	svc.purgeQueue()
+
+	svc.ctx, svc.cancel = nil, nil
+
+	svc.status.Store(defs.ErrStatusGood)
+}
+
+func (svc *Service) purgeQueue() {
+	for {
+		select {
+		default:
+			return
+		case out := <-svc.sendQueue:
+			defs.Messages.UpdateState(out.message.ID, api.OutgoingRejected)
+		}
+	}
+}
+
+func (svc *Service) Status() defs.ServiceStatus {
+	err := svc.status.Load()
+	if err == nil {
+		return defs.ServiceStatus(defs.ErrStatusGood)
+	}
+	return err.(defs.ServiceStatus)
+}
+
+// Send publishes the message. The payload is the topic followed by TopicSeparator and the message payload,
+// or just the message payload to publish it to the publish topic of the service
+func (svc *Service) Send(payload []byte) (*api.Message, error) {
+	topic, data := "", payload
+	if i := bytes.IndexByte(payload, TopicSeparator); i >= 0 {
+		topic, data = string(payload[:i]), payload[i+1:]
+	}
+	out := &outgoing{record: AppendRecord(nil, topic, data), message: defs.Messages.Register(svc.key, payload, api.OutgoingPending)}
+	select {
+	case svc.sendQueue <- out:
+	default:
+		defs.Messages.UpdateState(out.message.ID, api.OutgoingRejected)
+		return out.message, defs.ErrSendBusy
+	}
+	return out.message, nil
+}
+
+// ResolvedEntry returns the actual entry the transport is opened with, MQTT transport does not resolve the service entry
+func (svc *Service) ResolvedEntry() string {
+	return ""
+}
+
+func (svc *Service) log(op string, fields ...string) {
+	log.Report(append([]string{
+		log.SrcSVC,
+		mqOpService,
+		op,
+		defs.ProtocolName(svc.key.Protocol),
+		defs.TransportName(svc.key.Transport),
+		svc.key.Entry,
+	}, fields...)...)
+}
+
+func (svc *Service) serviceLoop() {
+	defer svc.transport.Close()
+	defer svc.stopWg.Done()
+
+	openBackoff := backoff.New(backoff.NewPolicy(svc.params))
+	buffer := make([]byte, 4096)
+	length := 0
+	open := true
+
+ServiceLoop:
+	for {
+		if open {
+			open = false
+			length = 0
+			if err := svc.transport.Open(svc.key.Entry, svc.params); err != nil {
+				svc.purgeQueue()
+				if !openBackoff.Retry(svc.ctx, svc.key, &svc.status, "unable to open transport", err, func(gaveUp bool) {
+					if gaveUp {
+						svc.log(mqOcTransportOpen, mqOsGaveUp, err.Error())
+					} else {
+						svc.log(mqOcTransportOpen, mqOsFailure, err.Error())
+					}
+				}) {
+					break ServiceLoop
+				}
+				open = true
+				continue
+			}
+			openBackoff.Reset()
+			svc.log(mqOcTransportOpen, mqOsSuccess)
+			svc.status.Store(defs.ErrStatusGood)
+		}
+
+		select {
+		case <-svc.ctx.Done():
+			break ServiceLoop
+		case out := <-svc.sendQueue:
+			if _, err := svc.transport.Write(out.record); err != nil {
+				svc.log(mqOcTransportWrite, mqOsFailure, err.Error())
+				defs.Messages.UpdateState(out.message.ID, api.OutgoingFailed)
+				// the held transport is operational, only the message fails
+				if !errors.Is(err, defs.ErrTransportHeld) {
+					svc.status.Store(fmt.Errorf("unable to write using transport: %s", err.Error()))
+					if err != errNoPublishTopic {
+						open = true
+					}
+				}
+			} else {
+				defs.Messages.UpdateState(out.message.ID, api.Outgoing)
+			}
+		case <-svc.transport.ReadyToRead():
+			if length == len(buffer) {
+				buffer = append(buffer, make([]byte, len(buffer))...)
+			}
+			n, err := svc.transport.Read(buffer[length:])
+			if err != nil {
+				svc.status.Store(fmt.Errorf("unable to read using transport: %s", err.Error()))
+				svc.log(mqOcTransportRead, mqOsFailure, err.Error())
+				open = true
+				continue
+			}
+			length = svc.handleRecords(buffer, length+n)
+		}
+	}
+}
+
+// handleRecords registers the messages of the complete records in the buffer, returns the length of the remaining data
+func (svc *Service) handleRecords(buffer []byte, length int) int {
+	offset := 0
+	for {
+		n, topic, payload := NextRecord(buffer[offset:length])
+		if n == 0 {
+			break
+		}
+		message := make([]byte, 0, len(topic)+1+len(payload))
+		message = append(append(append(message, topic...), TopicSeparator), payload...)
+		defs.Messages.Register(svc.key, message, api.Incoming)
+		offset += n
+	}
+	copy(buffer, buffer[offset:length])
+	return length - offset
+}
@@ -0,0 +1,170 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	mq "github.com/stas-makutin/howeve/mqtt"
+	"github.com/stas-makutin/howeve/mqtt/mqtttest"
+	"github.com/stas-makutin/howeve/services/mock"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(mock.RunTests(m))
+}
+
+func newTestBroker(t *testing.T) (*mqtttest.Broker, *mq.Client) {
+	broker, err := mqtttest.NewBroker("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to start the broker: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+	client, err := mq.Dial(context.Background(), broker.Addr(), mq.Options{ClientID: "device"})
+	if err != nil {
+		t.Fatalf("Unable to connect to the broker: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if err := client.Subscribe(context.Background(), "cmnd/#", 1); err != nil {
+		t.Fatalf("Unable to subscribe: %v", err)
+	}
+	return broker, client
+}
+
+func receive(t *testing.T, client *mq.Client) *mq.Message {
+	t.Helper()
+	select {
+	case m := <-client.Messages():
+		return m
+	case <-time.After(2 * time.Second):
+		t.Fatal("The message is not received")
+	}
+	return nil
+}
+
+func TestRecords(t *testing.T) {
+	record := AppendRecord(nil, "a/b", []byte{1, 2})
+	if !bytes.Equal(record, []byte{0, 3, 'a', '/', 'b', 0, 0, 0, 2, 1, 2}) {
+		t.Fatalf("Unexpected record %v", record)
+	}
+	for i := 0; i < len(record); i++ {
+		if n, _, _ := NextRecord(record[:i]); n != 0 {
+			t.Errorf("The incomplete record of length %d is parsed", i)
+		}
+	}
+	if n, topic, payload := NextRecord(append(record, 0xff)); n != len(record) || topic != "a/b" || !bytes.Equal(payload, []byte{1, 2}) {
+		t.Errorf("Unexpected record %d %s %v", n, topic, payload)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	broker, device := newTestBroker(t)
+
+	transport := &Transport{}
+	if _, err := transport.Write([]byte{1}); err != defs.ErrNotOpen {
+		t.Errorf("Write must fail if the transport is not open, got %v", err)
+	}
+	params := api.ParamValues{
+		ParamNameProtocolVersion: ProtocolVersion5,
+		ParamNamePublishTopic:    "cmnd/plug/POWER",
+		ParamNameSubscribeTopic:  "stat/plug/#",
+		ParamNameQoS:             "1",
+	}
+	if err := transport.Open(broker.Addr(), params); err != nil {
+		t.Fatalf("Unable to open the transport: %v", err)
+	}
+	defer transport.Close()
+
+	if n, err := transport.Write([]byte("ON")); err != nil || n != 2 {
+		t.Fatalf("Unable to write: %d, %v", n, err)
+	}
+	if m := receive(t, device); m.Topic != "cmnd/plug/POWER" || string(m.Payload) != "ON" {
+		t.Errorf("Unexpected message %s %s", m.Topic, m.Payload)
+	}
+
+	ctx := context.Background()
+	device.Publish(ctx, "stat/plug/POWER", []byte("ON\r"), 1, false)
+	device.Publish(ctx, "stat/plug/RESULT", []byte("OK\r"), 1, false)
+	var data []byte
+	buffer := make([]byte, 16)
+	mock.WaitFor(t, "the data", func() bool {
+		n, err := transport.Read(buffer)
+		if err != nil {
+			t.Fatalf("Unable to read: %v", err)
+		}
+		data = append(data, buffer[:n]...)
+		return len(data) >= 6
+	})
+	if string(data) != "ON\rOK\r" {
+		t.Errorf("Unexpected data %q", data)
+	}
+
+	broker.Disconnect()
+	mock.WaitFor(t, "the connection loss", func() bool {
+		_, err := transport.Read(buffer)
+		return err != nil
+	})
+}
+
+func TestService(t *testing.T) {
+	log := defs.Messages.(*mock.MessageLog)
+	log.Reset()
+	broker, device := newTestBroker(t)
+
+	svc := mock.NewService[defs.Service](t, api.ParamValues{
+		ParamNameClientID:       "howeve",
+		ParamNamePublishTopic:   "cmnd/plug/POWER",
+		ParamNameSubscribeTopic: "tele/+/SENSOR",
+	}, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(&Transport{Records: true}, broker.Addr(), params)
+	})
+	svc.Start()
+	defer svc.Stop()
+	mock.WaitFor(t, "the connection", func() bool {
+		for _, id := range broker.Clients() {
+			if id == "howeve" {
+				return true
+			}
+		}
+		return false
+	})
+
+	device.Publish(context.Background(), "tele/plug/SENSOR", []byte(`{"ENERGY":{"Power":12}}`), 0, false)
+	mock.WaitFor(t, "the incoming message", func() bool { return len(log.Incoming()) == 1 })
+	if p := log.Incoming()[0]; string(p) != "tele/plug/SENSOR\x00{\"ENERGY\":{\"Power\":12}}" {
+		t.Errorf("Unexpected incoming message %q", p)
+	}
+
+	message, err := svc.Send([]byte("cmnd/plug/Dimmer\x0050"))
+	if err != nil {
+		t.Fatalf("Unable to send: %v", err)
+	}
+	if m := receive(t, device); m.Topic != "cmnd/plug/Dimmer" || string(m.Payload) != "50" {
+		t.Errorf("Unexpected message %s %s", m.Topic, m.Payload)
+	}
+	mock.WaitFor(t, "the outgoing state", func() bool {
+		_, m := defs.Messages.Get(message.ID)
+		return m.State == api.Outgoing
+	})
+
+	// the connection is restored after it is lost
+	broker.Disconnect()
+	device, err = mq.Dial(context.Background(), broker.Addr(), mq.Options{ClientID: "device"})
+	if err != nil {
+		t.Fatalf("Unable to connect to the broker: %v", err)
+	}
+	defer device.Close()
+	device.Subscribe(context.Background(), "cmnd/#", 0)
+	mock.WaitFor(t, "the reconnection", func() bool { return len(broker.Clients()) == 2 })
+
+	if _, err := svc.Send([]byte("OFF")); err != nil {
+		t.Fatalf("Unable to send: %v", err)
+	}
+	if m := receive(t, device); m.Topic != "cmnd/plug/POWER" || string(m.Payload) != "OFF" {
+		t.Errorf("Unexpected message %s %s", m.Topic, m.Payload)
+	}
+}
@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/backoff"
+)
+
+// The plugin is the executable which implements the protocol. It reads the requests from the standard input and
+// writes the responses and the events to the standard output, each one is JSON object on the separate line.
+// The standard error output of the plugin is logged.
+//
+// The request is {"id": 1, "method": "describe", "params": {...}}, the response is {"id": 1, "result": {...}} or
+// {"id": 1, "error": "message"}. The event is {"event": "message", "service": 1, "payload": "base64"}.
+//
+// The plugin is asked to describe itself first, then the services are started and stopped by the requests.
+// Each service is identified by the number assigned by howeve. The plugin opens the service transport (the serial port,
+// the TCP connection, etc.) by itself using the service entry and the parameters.
+
+// plugin methods, the requests of howeve
+const (
+	methodDescribe = "describe" // no params, the result is describeResult
+	methodStart    = "start"    // startParams, starts the service
+	methodStop     = "stop"     // serviceParams, stops the service
+	methodSend     = "send"     // sendParams, sends the message
+	methodDiscover = "discover" // discoverParams, the result is the array of discoveryEntry
+)
+
+// plugin events
+const (
+	eventStatus  = "status"  // the service status, the error is empty if the service is functioning normally
+	eventMessage = "message" // the service received the message
+)
+
+// plugin timeouts
+const (
+	defaultStartTimeout    = 10 * time.Second
+	defaultRestartInterval = 1 * time.Second
+	maxRestartInterval     = 60 * time.Second
+	stableRunTime          = 60 * time.Second // the plugin running that long is not counted as failed one on exit
+	stopTimeout            = 5 * time.Second
+)
+
+// maxLineSize the maximal length of the line the plugin writes
+const maxLineSize = 1 << 20
+
+// request is the request to the plugin
+type request struct {
+	ID     uint32      `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+// frame is the line written by the plugin: the response if the identifier is set, the event otherwise
+type frame struct {
+	ID      uint32          `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Service uint32          `json:"service,omitempty"`
+	Payload []byte          `json:"payload,omitempty"`
+}
+
+// describeResult is the description of the protocol implemented by the plugin
+type describeResult struct {
+	Name       string                           `json:"name"`
+	Transports map[string]*transportDescription `json:"transports"` // the transport identifier, like serial or tcp, to its options
+}
+
+// transportDescription describes the transport the plugin is able to use
+type transportDescription struct {
+	Params          map[string]*api.ParamInfoEntry `json:"params,omitempty"`
+	Discoverable    bool                           `json:"discoverable,omitempty"`
+	DiscoveryParams map[string]*api.ParamInfoEntry `json:"discoveryParams,omitempty"`
+}
+
+type serviceParams struct {
+	Service uint32 `json:"service"`
+}
+
+type startParams struct {
+	Service   uint32          `json:"service"`
+	Transport string          `json:"transport"`
+	Entry     string          `json:"entry"`
+	Params    api.ParamValues `json:"params,omitempty"`
+}
+
+type sendParams struct {
+	Service uint32 `json:"service"`
+	Payload []byte `json:"payload"`
+}
+
+type discoverParams struct {
+	Transport string          `json:"transport"`
+	Params    api.ParamValues `json:"params,omitempty"`
+}
+
+// discoveryEntry is the service entry found by the plugin
+type discoveryEntry struct {
+	Entry       string            `json:"entry"`
+	Params      map[string]string `json:"params,omitempty"`
+	Description string            `json:"description,omitempty"`
+}
+
+// the parameters of all plugin protocols, the plugin parameters with the same names take precedence
+var commonParams = defs.Params{
+	defs.ParamNameOpenAttemptsInterval: {
+		Description:  "The time interval between attempts to start the service, milliseconds",
+		Type:         defs.ParamTypeUint32,
+		DefaultValue: "3000",
+	},
+}.Merge(backoff.Params)
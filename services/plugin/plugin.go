@@ -0,0 +1,280 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/log"
+	"github.com/stas-makutin/howeve/services/backoff"
+)
+
+// log constants
+const (
+	plOpStart  = "S"
+	plOpExit   = "X"
+	plOpOutput = "E"
+
+	plOsSuccess = "0"
+	plOsFailure = "F"
+)
+
+// Plugin supervises the plugin process, restarts it if it exits, and routes its events to the services
+type Plugin struct {
+	cfg  *api.PluginConfig
+	info *describeResult
+
+	lock      sync.Mutex
+	proc      *process
+	changed   chan struct{} // closed and replaced when the process is started or exited
+	services  map[uint32]*Service
+	serviceID uint32
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newPlugin(cfg *api.PluginConfig) *Plugin {
+	return &Plugin{
+		cfg:      cfg,
+		changed:  make(chan struct{}),
+		services: make(map[uint32]*Service),
+	}
+}
+
+func (p *Plugin) log(op string, fields ...string) {
+	log.Report(append([]string{log.SrcPlugin, op, string(p.cfg.ID), p.cfg.Path}, fields...)...)
+}
+
+// start starts the plugin, reads its description and begins the supervision
+func (p *Plugin) start() error {
+	proc, info, err := p.launch()
+	if err != nil {
+		p.log(plOpStart, plOsFailure, err.Error())
+		return err
+	}
+	p.info = info
+	p.log(plOpStart, plOsSuccess, info.Name)
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.setProcess(proc)
+	p.wg.Add(1)
+	go p.superviseLoop(proc)
+	return nil
+}
+
+// stop stops the plugin and the supervision
+func (p *Plugin) stop() {
+	if p.cancel == nil {
+		return // not started
+	}
+	p.cancel()
+	p.wg.Wait()
+	p.ctx, p.cancel = nil, nil
+}
+
+// launch starts the process and waits for its description
+func (p *Plugin) launch() (*process, *describeResult, error) {
+	proc, err := startProcess(p.cfg, p.handleEvent, func(line string) {
+		p.log(plOpOutput, line)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timeout := p.cfg.StartTimeout.Value()
+	if timeout <= 0 {
+		timeout = defaultStartTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	info := &describeResult{}
+	if err = proc.call(ctx, methodDescribe, nil, info); err == nil && (info.Name == "" || len(info.Transports) == 0) {
+		err = fmt.Errorf("the name or the transports are missing")
+	}
+	if err != nil {
+		proc.stop()
+		return nil, nil, fmt.Errorf("unable to describe the plugin: %w", err)
+	}
+	return proc, info, nil
+}
+
+func (p *Plugin) superviseLoop(proc *process) {
+	defer p.wg.Done()
+
+	policy := backoff.Policy{Interval: p.cfg.RestartInterval.Value(), MaxInterval: maxRestartInterval, Multiplier: 200, Jitter: 10}
+	if policy.Interval <= 0 {
+		policy.Interval = defaultRestartInterval
+	}
+	if policy.MaxInterval < policy.Interval {
+		policy.MaxInterval = policy.Interval
+	}
+	restartBackoff := backoff.New(policy)
+
+	for {
+		started := time.Now()
+		select {
+		case <-p.ctx.Done():
+			p.setProcess(nil)
+			proc.stop()
+			return
+		case <-proc.done:
+		}
+
+		p.setProcess(nil)
+		reason := "exited"
+		if proc.err != nil {
+			reason = proc.err.Error()
+		}
+		p.log(plOpExit, reason)
+		if time.Since(started) >= stableRunTime {
+			restartBackoff.Reset()
+		}
+
+		for proc = nil; proc == nil; {
+			interval, _ := restartBackoff.Failed()
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+			var err error
+			if proc, _, err = p.launch(); err != nil {
+				p.log(plOpStart, plOsFailure, err.Error())
+			}
+		}
+		p.log(plOpStart, plOsSuccess, p.info.Name)
+		p.setProcess(proc)
+	}
+}
+
+func (p *Plugin) setProcess(proc *process) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.proc = proc
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// current returns the running process, nil if the plugin is not running, and the channel which is closed when it changes
+func (p *Plugin) current() (*process, <-chan struct{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.proc, p.changed
+}
+
+// register returns the identifier of the service the plugin events are routed to
+func (p *Plugin) register(svc *Service) uint32 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.serviceID++
+	p.services[p.serviceID] = svc
+	return p.serviceID
+}
+
+func (p *Plugin) unregister(id uint32) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.services, id)
+}
+
+func (p *Plugin) handleEvent(f *frame) {
+	p.lock.Lock()
+	svc := p.services[f.Service]
+	p.lock.Unlock()
+	if svc == nil {
+		return // the service is stopped
+	}
+	switch f.Event {
+	case eventStatus:
+		svc.setStatus(f.Error)
+	case eventMessage:
+		defs.Messages.Register(svc.key, f.Payload, api.Incoming)
+	}
+}
+
+// protocolInfo returns the definition of the protocol implemented by the plugin
+func (p *Plugin) protocolInfo() (*defs.ProtocolInfo, error) {
+	pi := &defs.ProtocolInfo{Name: p.info.Name, Transports: make(map[api.TransportIdentifier]*defs.ProtocolTransportOptions)}
+	for name, td := range p.info.Transports {
+		tid, ok := defs.TransportByName(name)
+		if !ok {
+			return nil, fmt.Errorf("the transport '%s' is not supported", name)
+		}
+		params, err := convertParams(td.Params)
+		if err != nil {
+			return nil, fmt.Errorf("the transport '%s' parameter %w", name, err)
+		}
+		to := &defs.ProtocolTransportOptions{
+			ServiceFunc: func(entry string, params api.ParamValues) (defs.Service, error) {
+				return newService(p, tid, entry, params), nil
+			},
+			Params: params.Merge(commonParams),
+		}
+		if td.Discoverable {
+			if to.DiscoveryParams, err = convertParams(td.DiscoveryParams); err != nil {
+				return nil, fmt.Errorf("the transport '%s' discovery parameter %w", name, err)
+			}
+			to.DiscoveryFunc = p.discoveryFunc(tid, to.Params.Merge(defs.Transports[tid].Params))
+		}
+		pi.Transports[tid] = to
+	}
+	return pi, nil
+}
+
+func (p *Plugin) discoveryFunc(tid api.TransportIdentifier, params defs.Params) defs.DiscoveryFunc {
+	return func(ctx context.Context, values api.ParamValues) ([]*api.DiscoveryEntry, error) {
+		proc, _ := p.current()
+		if proc == nil {
+			return nil, errNotRunning
+		}
+		var entries []*discoveryEntry
+		if err := proc.call(ctx, methodDiscover, &discoverParams{Transport: string(tid), Params: values}, &entries); err != nil {
+			return nil, err
+		}
+		var result []*api.DiscoveryEntry
+		for _, e := range entries {
+			de := &api.DiscoveryEntry{
+				ServiceKey:  api.ServiceKey{Protocol: p.cfg.ID, Transport: tid, Entry: e.Entry},
+				Description: e.Description,
+			}
+			for name, value := range e.Params {
+				if pi, ok := params[name]; ok {
+					if v, err := pi.Parse(name, value); err == nil {
+						if de.ParamValues == nil {
+							de.ParamValues = make(api.ParamValues)
+						}
+						de.ParamValues[name] = v
+					}
+				}
+			}
+			result = append(result, de)
+		}
+		return result, nil
+	}
+}
+
+// convertParams converts the parameters description of the plugin
+func convertParams(entries map[string]*api.ParamInfoEntry) (defs.Params, error) {
+	params := make(defs.Params)
+	for name, e := range entries {
+		if e == nil {
+			return nil, fmt.Errorf("'%s' has no description", name)
+		}
+		pt, ok := defs.ParamTypeByName(e.Type)
+		if !ok {
+			return nil, fmt.Errorf("'%s' has unknown type '%s'", name, e.Type)
+		}
+		pi := &defs.ParamInfo{Description: e.Description, Type: pt, DefaultValue: e.DefaultValue, EnumValues: e.EnumValues}
+		if _, err := pi.Parse(name, pi.DefaultValue); err != nil {
+			return nil, fmt.Errorf("'%s' has invalid default value '%s'", name, e.DefaultValue)
+		}
+		params[name] = pi
+	}
+	return params, nil
+}
@@ -0,0 +1,199 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/mock"
+	"github.com/stas-makutin/howeve/services/tcp"
+)
+
+// testPluginEnv is set to run the test executable as the plugin
+const testPluginEnv = "HOWEVE_TEST_PLUGIN=1"
+
+func TestMain(m *testing.M) {
+	if os.Getenv("HOWEVE_TEST_PLUGIN") == "1" {
+		testPlugin()
+		os.Exit(0)
+	}
+	defs.Transports = map[api.TransportIdentifier]*defs.TransportInfo{api.TransportTCP: tcp.TransportInfo}
+	defs.Protocols = make(map[api.ProtocolIdentifier]*defs.ProtocolInfo)
+	os.Exit(mock.RunTests(m))
+}
+
+// testPlugin is the echo protocol plugin: the sent payload is received back with the prefix
+func testPlugin() {
+	out := json.NewEncoder(os.Stdout)
+	prefixes := make(map[uint32]string)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     uint32          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		json.Unmarshal(scanner.Bytes(), &req)
+		response := &frame{ID: req.ID, Result: json.RawMessage("{}")}
+		switch req.Method {
+		case methodDescribe:
+			os.Stderr.WriteString("describing\n")
+			response.Result, _ = json.Marshal(&describeResult{
+				Name: "Echo",
+				Transports: map[string]*transportDescription{
+					"tcp": {
+						Params:       map[string]*api.ParamInfoEntry{"prefix": {Description: "The prefix", Type: api.ParamTypeString, DefaultValue: ">"}},
+						Discoverable: true,
+					},
+				},
+			})
+		case methodStart:
+			var start startParams
+			json.Unmarshal(req.Params, &start)
+			if start.Entry == "fail" {
+				response.Error = "unable to connect"
+				break
+			}
+			prefixes[start.Service] = start.Params["prefix"].(string)
+			out.Encode(&frame{Event: eventStatus, Service: start.Service, Error: "connecting"})
+		case methodStop:
+			var stop serviceParams
+			json.Unmarshal(req.Params, &stop)
+			delete(prefixes, stop.Service)
+		case methodSend:
+			var send sendParams
+			json.Unmarshal(req.Params, &send)
+			if string(send.Payload) == "exit" {
+				os.Exit(1)
+			}
+			out.Encode(&frame{Event: eventStatus, Service: send.Service})
+			out.Encode(&frame{Event: eventMessage, Service: send.Service, Payload: append([]byte(prefixes[send.Service]), send.Payload...)})
+		case methodDiscover:
+			response.Result = json.RawMessage(`[{"entry":"localhost:1000","params":{"prefix":"#","unknown":"1"},"description":"Echo"}]`)
+		default:
+			response.Error = "unknown method"
+		}
+		out.Encode(response)
+	}
+}
+
+func startTestPlugin(t *testing.T) *Plugin {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Unable to get the test executable: %v", err)
+	}
+	p := newPlugin(&api.PluginConfig{ID: "echo", Path: executable, Env: []string{testPluginEnv}, RestartInterval: api.DurationType(100 * time.Millisecond)})
+	if err := p.start(); err != nil {
+		t.Fatalf("Unable to start the plugin: %v", err)
+	}
+	t.Cleanup(p.stop)
+	if err := register(p); err != nil {
+		t.Fatalf("Unable to register the plugin: %v", err)
+	}
+	t.Cleanup(func() { defs.UnregisterProtocol(p.cfg.ID) })
+	return p
+}
+
+func newTestService(t *testing.T, entry string, raw api.RawParamValues) *Service {
+	to, ti, err := defs.ResolveProtocolAndTransport("echo", api.TransportTCP)
+	if err != nil {
+		t.Fatalf("Unable to resolve the protocol: %v", err)
+	}
+	params, err := ti.Params.Merge(to.Params).ParseValues(raw)
+	if err != nil {
+		t.Fatalf("Unable to parse the parameters: %v", err)
+	}
+	svc, err := to.ServiceFunc(entry, params)
+	if err != nil {
+		t.Fatalf("Unable to create the service: %v", err)
+	}
+	svc.Start()
+	t.Cleanup(svc.Stop)
+	return svc.(*Service)
+}
+
+func TestConvertParams(t *testing.T) {
+	params, err := convertParams(map[string]*api.ParamInfoEntry{
+		"mode": {Type: api.ParamTypeEnum, DefaultValue: "a", EnumValues: []string{"a", "b"}},
+		"size": {Type: api.ParamTypeUint16, DefaultValue: "10"},
+	})
+	if err != nil || params["mode"].Type != defs.ParamTypeEnum || params["size"].Type != defs.ParamTypeUint16 {
+		t.Errorf("Unexpected parameters %v, error %v", params, err)
+	}
+	for _, e := range []*api.ParamInfoEntry{
+		nil,
+		{Type: "float"},
+		{Type: api.ParamTypeUint8, DefaultValue: "300"},
+	} {
+		if _, err := convertParams(map[string]*api.ParamInfoEntry{"p": e}); err == nil {
+			t.Errorf("The parameter %v must be rejected", e)
+		}
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	log := defs.Messages.(*mock.MessageLog)
+	log.Reset()
+	p := startTestPlugin(t)
+
+	if name := defs.ProtocolName("echo"); name != "Echo" {
+		t.Fatalf("Unexpected protocol name %s", name)
+	}
+	to, _, _ := defs.ResolveProtocolAndTransport("echo", api.TransportTCP)
+	if _, ok := to.Params[defs.ParamNameOpenMaxAttempts]; !ok {
+		t.Error("The common parameters are missing")
+	}
+	entries, err := to.DiscoveryFunc(context.Background(), nil)
+	if err != nil || len(entries) != 1 || entries[0].Entry != "localhost:1000" || entries[0].Protocol != "echo" ||
+		entries[0].ParamValues["prefix"] != "#" || len(entries[0].ParamValues) != 1 {
+		t.Errorf("Unexpected discovery result %v, error %v", entries, err)
+	}
+
+	svc := newTestService(t, "localhost:1000", api.RawParamValues{"prefix": "echo:"})
+	mock.WaitFor(t, "the status", func() bool { return svc.Status() != nil && svc.Status().Error() == "connecting" })
+
+	message, _ := svc.Send([]byte("hello"))
+	mock.WaitFor(t, "the incoming message", func() bool { return len(log.Incoming()) == 1 })
+	if s := string(log.Incoming()[0]); s != "echo:hello" {
+		t.Errorf("Unexpected incoming message %s", s)
+	}
+	if _, m := defs.Messages.Get(message.ID); m.State != api.Outgoing {
+		t.Errorf("Unexpected outgoing message state %v", m.State)
+	}
+	if svc.Status() != defs.ErrStatusGood {
+		t.Errorf("Unexpected status %v", svc.Status())
+	}
+
+	// the plugin is restarted and the service is started again
+	proc, _ := p.current()
+	svc.Send([]byte("exit"))
+	<-proc.done
+	mock.WaitFor(t, "the restart", func() bool {
+		restarted, _ := p.current()
+		return restarted != nil && restarted != proc
+	})
+	mock.WaitFor(t, "the incoming message", func() bool {
+		svc.Send([]byte("again"))
+		time.Sleep(50 * time.Millisecond)
+		return len(log.Incoming()) > 1
+	})
+	if s := string(log.Incoming()[1]); s != "echo:again" {
+		t.Errorf("Unexpected incoming message %s", s)
+	}
+}
+
+func TestServiceGaveUp(t *testing.T) {
+	startTestPlugin(t)
+
+	svc := newTestService(t, "fail", api.RawParamValues{
+		defs.ParamNameOpenAttemptsInterval: "100",
+		defs.ParamNameOpenMaxAttempts:      "2",
+	})
+	mock.WaitFor(t, "the service to give up", func() bool { return errors.Is(svc.Status(), defs.ErrOpenGaveUp) })
+}
@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+)
+
+// errors
+var (
+	errNotRunning = errors.New("the plugin is not running")
+	errExited     = errors.New("the plugin exited")
+)
+
+// process is the running plugin executable
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeLock sync.Mutex
+	lock      sync.Mutex
+	lastID    uint32
+	pending   map[uint32]chan *frame
+
+	events func(f *frame)
+	stderr func(line string)
+
+	done chan struct{}
+	err  error
+	wg   sync.WaitGroup
+}
+
+// startProcess starts the plugin executable, the events and the lines of the standard error output are passed to provided functions
+func startProcess(cfg *api.PluginConfig, events func(f *frame), stderr func(line string)) (*process, error) {
+	cmd := exec.Command(cfg.Path, cfg.Args...)
+	if len(cfg.Env) > 0 {
+		cmd.Env = append(os.Environ(), cfg.Env...)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	errout, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &process{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[uint32]chan *frame),
+		events:  events,
+		stderr:  stderr,
+		done:    make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.stderrLoop(errout)
+	go p.readLoop(stdout)
+	return p, nil
+}
+
+// call sends the request and waits for the response, the result is decoded into provided value if it is not nil
+func (p *process) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	p.lock.Lock()
+	p.lastID++
+	if p.lastID == 0 {
+		p.lastID = 1
+	}
+	id := p.lastID
+	response := make(chan *frame, 1)
+	p.pending[id] = response
+	p.lock.Unlock()
+
+	defer func() {
+		p.lock.Lock()
+		delete(p.pending, id)
+		p.lock.Unlock()
+	}()
+
+	line, err := json.Marshal(&request{ID: id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	p.writeLock.Lock()
+	_, err = p.stdin.Write(append(line, '\n'))
+	p.writeLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return errExited
+	case f := <-response:
+		if f.Error != "" {
+			return errors.New(f.Error)
+		}
+		if result != nil {
+			if err := json.Unmarshal(f.Result, result); err != nil {
+				return fmt.Errorf("invalid result of '%s': %w", method, err)
+			}
+		}
+		return nil
+	}
+}
+
+// stop closes the standard input of the plugin, and kills it if it does not exit in time
+func (p *process) stop() {
+	p.stdin.Close()
+	select {
+	case <-p.done:
+	case <-time.After(stopTimeout):
+		p.cmd.Process.Kill()
+		<-p.done
+	}
+}
+
+func (p *process) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	for scanner.Scan() {
+		f := &frame{}
+		if err := json.Unmarshal(scanner.Bytes(), f); err != nil {
+			p.stderr(fmt.Sprintf("invalid output: %v", err))
+			continue
+		}
+		if f.ID == 0 {
+			p.events(f)
+			continue
+		}
+		p.lock.Lock()
+		response := p.pending[f.ID]
+		p.lock.Unlock()
+		if response != nil {
+			select {
+			case response <- f:
+			default: // the duplicate response
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		p.stderr(fmt.Sprintf("unable to read the output: %v", err))
+		p.cmd.Process.Kill()
+	}
+
+	p.wg.Wait()
+	p.err = p.cmd.Wait()
+	close(p.done)
+}
+
+func (p *process) stderrLoop(errout io.Reader) {
+	defer p.wg.Done()
+	scanner := bufio.NewScanner(errout)
+	for scanner.Scan() {
+		p.stderr(scanner.Text())
+	}
+	io.Copy(io.Discard, errout) // drain the rest if the line is too long
+}
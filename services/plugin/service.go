@@ -0,0 +1,214 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/log"
+	"github.com/stas-makutin/howeve/services/backoff"
+	"github.com/stas-makutin/howeve/utils/syncutil"
+)
+
+// log constants
+const (
+	// operation
+	plOpService = "PL"
+
+	plOcServiceStart = "O"
+	plOcServiceSend  = "W"
+
+	plOsGaveUp = "G"
+)
+
+// outgoing is the payload to send and its registered message
+type outgoing struct {
+	payload []byte
+	message *api.Message
+}
+
+// Service is the service of the protocol implemented by the plugin, it forwards the requests to the plugin process
+type Service struct {
+	plugin *Plugin
+	id     uint32
+	key    *api.ServiceKey
+	params api.ParamValues
+
+	sendQueue chan *outgoing
+
+	status syncutil.RLocked[error]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stopWg sync.WaitGroup
+}
+
+func newService(p *Plugin, transport api.TransportIdentifier, entry string, params api.ParamValues) *Service {
+	return &Service{
+		plugin:    p,
+		key:       &api.ServiceKey{Protocol: p.cfg.ID, Transport: transport, Entry: entry},
+		params:    params.Copy(),
+		sendQueue: make(chan *outgoing, 10),
+	}
+}
+
+func (svc *Service) Start() {
+	svc.Stop()
+
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+	svc.id = svc.plugin.register(svc)
+
+	svc.stopWg.Add(1)
+	go svc.serviceLoop()
+}
+
+func (svc *Service) Stop() {
+	if svc.ctx == nil {
+		return // already stopped
+	}
+
+	svc.cancel()
+	svc.stopWg.Wait()
+	svc.plugin.unregister(svc.id)
+
+	svc.purgeQueue()
+
+	svc.ctx, svc.cancel = nil, nil
+
+	svc.status.Store(defs.ErrStatusGood)
+}
+
+func (svc *Service) purgeQueue() {
+	for {
+		select {
+		default:
+			return
+		case out := <-svc.sendQueue:
+			defs.Messages.UpdateState(out.message.ID, api.OutgoingRejected)
+		}
+	}
+}
+
+func (svc *Service) Status() defs.ServiceStatus {
+	err := svc.status.Load()
+	if err == nil {
+		return defs.ServiceStatus(defs.ErrStatusGood)
+	}
+	return err.(defs.ServiceStatus)
+}
+
+// setStatus sets the status reported by the plugin, empty if the service is functioning normally
+func (svc *Service) setStatus(status string) {
+	if status == "" {
+		svc.status.Store(defs.ErrStatusGood)
+	} else {
+		svc.status.Store(errors.New(status))
+	}
+}
+
+// Send passes the payload to the plugin as is
+func (svc *Service) Send(payload []byte) (*api.Message, error) {
+	out := &outgoing{payload: payload, message: defs.Messages.Register(svc.key, payload, api.OutgoingPending)}
+	select {
+	case svc.sendQueue <- out:
+	default:
+		defs.Messages.UpdateState(out.message.ID, api.OutgoingRejected)
+		return out.message, defs.ErrSendBusy
+	}
+	return out.message, nil
+}
+
+// ResolvedEntry returns the actual entry the transport is opened with, the plugin does not resolve the service entry
+func (svc *Service) ResolvedEntry() string {
+	return ""
+}
+
+func (svc *Service) log(op string, fields ...string) {
+	log.Report(append([]string{
+		log.SrcSVC,
+		plOpService,
+		op,
+		defs.ProtocolName(svc.key.Protocol),
+		defs.TransportName(svc.key.Transport),
+		svc.key.Entry,
+	}, fields...)...)
+}
+
+func (svc *Service) serviceLoop() {
+	defer svc.stopWg.Done()
+
+	startBackoff := backoff.New(backoff.NewPolicy(svc.params))
+
+	for {
+		proc, changed := svc.plugin.current()
+		if proc == nil {
+			svc.purgeQueue()
+			svc.status.Store(errNotRunning)
+			select {
+			case <-svc.ctx.Done():
+				return
+			case <-changed:
+				continue
+			}
+		}
+
+		// the plugin could report the status as soon as it receives the request
+		svc.status.Store(defs.ErrStatusGood)
+		start := &startParams{Service: svc.id, Transport: string(svc.key.Transport), Entry: svc.key.Entry, Params: svc.params}
+		if err := proc.call(svc.ctx, methodStart, start, nil); err != nil {
+			if svc.ctx.Err() != nil {
+				return
+			}
+			svc.purgeQueue()
+			if err == errExited {
+				// the plugin is being restarted, wait for the supervisor to replace the process
+				select {
+				case <-svc.ctx.Done():
+					return
+				case <-changed:
+				}
+				continue
+			}
+			if !startBackoff.Retry(svc.ctx, svc.key, &svc.status, "unable to start the service", err, func(gaveUp bool) {
+				if gaveUp {
+					svc.log(plOcServiceStart, plOsGaveUp, err.Error())
+				} else {
+					svc.log(plOcServiceStart, plOsFailure, err.Error())
+				}
+			}) {
+				return
+			}
+			continue
+		}
+		startBackoff.Reset()
+		svc.log(plOcServiceStart, plOsSuccess)
+
+		if !svc.running(proc) {
+			return
+		}
+	}
+}
+
+// running forwards the messages to the running plugin, returns false if the service is stopped, true if the plugin exited
+func (svc *Service) running(proc *process) bool {
+	for {
+		select {
+		case <-svc.ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+			defer cancel()
+			proc.call(ctx, methodStop, &serviceParams{Service: svc.id}, nil)
+			return false
+		case <-proc.done:
+			return true
+		case out := <-svc.sendQueue:
+			if err := proc.call(svc.ctx, methodSend, &sendParams{Service: svc.id, Payload: out.payload}, nil); err != nil {
+				svc.log(plOcServiceSend, plOsFailure, err.Error())
+				defs.Messages.UpdateState(out.message.ID, api.OutgoingFailed)
+			} else {
+				defs.Messages.UpdateState(out.message.ID, api.Outgoing)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/config"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/tasks"
+)
+
+// Task struct - protocol plugins task implementation, registers the protocols of the plugins
+type Task struct {
+	cfg     []api.PluginConfig
+	plugins []*Plugin
+}
+
+// NewTask func
+func NewTask() *Task {
+	t := &Task{}
+	config.AddReader(t.readConfig)
+	config.AddWriter(t.writeConfig)
+	return t
+}
+
+func (t *Task) readConfig(cfg *api.Config, cfgError config.Error) {
+	t.cfg = cfg.Plugins
+	t.plugins = nil
+	ids := make(map[api.ProtocolIdentifier]struct{})
+	for i := range t.cfg {
+		pc := &t.cfg[i]
+		if pc.Path == "" {
+			cfgError(fmt.Sprintf("plugins[%d].path is required", i))
+			continue
+		}
+		if !pc.ID.IsValid() {
+			cfgError(fmt.Sprintf("plugins[%d].id must consist of lower case latin letters, digits, '.', '-', or '_'", i))
+			continue
+		}
+		if _, ok := ids[pc.ID]; ok {
+			cfgError(fmt.Sprintf("plugins[%d].id %s is used by another plugin", i, pc.ID))
+			continue
+		}
+		ids[pc.ID] = struct{}{}
+		t.plugins = append(t.plugins, newPlugin(pc))
+	}
+}
+
+func (t *Task) writeConfig(cfg *api.Config) {
+	cfg.Plugins = t.cfg
+}
+
+// Open func, starts the plugins and registers their protocols
+func (t *Task) Open(ctx *tasks.ServiceTaskContext) error {
+	for i, p := range t.plugins {
+		err := p.start()
+		if err == nil {
+			err = register(p)
+		}
+		if err != nil {
+			p.stop()
+			for _, started := range t.plugins[:i] {
+				started.stop()
+				defs.UnregisterProtocol(started.cfg.ID)
+			}
+			return fmt.Errorf("plugin %s failed: %v", p.cfg.Path, err)
+		}
+	}
+	return nil
+}
+
+// register adds the protocol of the started plugin to the protocols definitions
+func register(p *Plugin) error {
+	pi, err := p.protocolInfo()
+	if err != nil {
+		return err
+	}
+	if err = defs.RegisterProtocol(p.cfg.ID, pi); err != nil {
+		return fmt.Errorf("%w: %s (%s)", err, pi.Name, p.cfg.ID)
+	}
+	return nil
+}
+
+// Close func
+func (t *Task) Close(ctx *tasks.ServiceTaskContext) error {
+	for _, p := range t.plugins {
+		defs.UnregisterProtocol(p.cfg.ID)
+	}
+	return nil
+}
+
+// Stop func
+func (t *Task) Stop(ctx *tasks.ServiceTaskContext) {
+	for _, p := range t.plugins {
+		p.stop()
+	}
+}
@@ -0,0 +1,19 @@
+package pty
+
+import "github.com/stas-makutin/howeve/defs"
+
+// pseudo-terminal transport parameters names
+const (
+	ParamNameRaw = "raw"
+)
+
+var TransportInfo *defs.TransportInfo = &defs.TransportInfo{
+	Name: "PTY",
+	Params: defs.Params{
+		ParamNameRaw: {
+			Description:  "Switch the terminal into raw mode, without echo and any processing of the input and output",
+			Type:         defs.ParamTypeBool,
+			DefaultValue: "true",
+		},
+	},
+}
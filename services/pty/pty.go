@@ -0,0 +1,74 @@
+package pty
+
+import (
+	"os"
+	"sync"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/stream"
+)
+
+// Transport struct - pseudo-terminal Transport implementation, the entry is the path of the terminal device,
+// like the one created by socat or by the device simulator
+type Transport struct {
+	file   *os.File
+	lock   sync.RWMutex
+	reader stream.Reader
+}
+
+func (t *Transport) ID() api.TransportIdentifier {
+	return api.TransportPTY
+}
+
+// Open func
+func (t *Transport) Open(entry string, params api.ParamValues) error {
+	raw := true
+	if v, ok := params[ParamNameRaw]; ok {
+		raw = v.(bool)
+	}
+
+	t.Close()
+
+	file, err := openTerminal(entry, raw)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.file = file
+	t.reader.Start(file)
+	return nil
+}
+
+// Close func
+func (t *Transport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	file := t.file
+	t.file = nil
+	return t.reader.Stop(func() error {
+		return file.Close()
+	})
+}
+
+// ReadyToRead function, singal in the channel if something could be read from the terminal or terminal state has changed
+func (t *Transport) ReadyToRead() <-chan struct{} {
+	return t.reader.ReadyToRead()
+}
+
+// Read func, returns immediately with the data received so far
+func (t *Transport) Read(p []byte) (int, error) {
+	return t.reader.Read(p)
+}
+
+// Write func
+func (t *Transport) Write(p []byte) (int, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.file != nil {
+		return t.file.Write(p)
+	}
+	return 0, defs.ErrNotOpen
+}
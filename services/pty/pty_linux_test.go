@@ -0,0 +1,98 @@
+package pty
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"golang.org/x/sys/unix"
+)
+
+// openMaster creates new pseudo-terminal and returns its master side and the path of the slave side
+func openMaster(t *testing.T) (*os.File, string) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("Pseudo-terminals are not available: %v", err)
+	}
+	if err := unix.IoctlSetPointerInt(int(master.Fd()), unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		t.Fatalf("Unable to unlock the pseudo-terminal: %v", err)
+	}
+	n, err := unix.IoctlGetInt(int(master.Fd()), unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		t.Fatalf("Unable to get the pseudo-terminal number: %v", err)
+	}
+	return master, "/dev/pts/" + strconv.Itoa(n)
+}
+
+func readAll(t *testing.T, tr *Transport, length int) []byte {
+	var rv []byte
+	data := make([]byte, 16)
+	for len(rv) < length {
+		select {
+		case <-tr.ReadyToRead():
+		case <-time.After(time.Second):
+			t.Fatalf("No data to read, received %x", rv)
+		}
+		n, err := tr.Read(data)
+		if err != nil {
+			t.Fatalf("Unable to read: %v", err)
+		}
+		rv = append(rv, data[:n]...)
+	}
+	return rv
+}
+
+func TestTransport(t *testing.T) {
+	master, path := openMaster(t)
+	defer master.Close()
+
+	tr := &Transport{}
+	if err := tr.Open(path, api.ParamValues{ParamNameRaw: true}); err != nil {
+		t.Fatalf("Unable to open the transport: %v", err)
+	}
+	defer tr.Close()
+
+	// the control characters must be passed as is in raw mode, and no line buffering
+	sent := []byte{0x01, 0x0d, 0x03, 0x7f, 0x0a}
+	if _, err := master.Write(sent); err != nil {
+		t.Fatalf("Unable to write: %v", err)
+	}
+	if data := readAll(t, tr, len(sent)); !bytes.Equal(data, sent) {
+		t.Errorf("Received %x", data)
+	}
+
+	if _, err := tr.Write([]byte{0x0a, 0x06}); err != nil {
+		t.Fatalf("Unable to write: %v", err)
+	}
+	data := make([]byte, 2)
+	master.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := master.Read(data); err != nil || !bytes.Equal(data[:n], []byte{0x0a, 0x06}) {
+		t.Errorf("Received %x, %v", data[:n], err)
+	}
+
+	// reopen the same terminal
+	if err := tr.Open(path, nil); err != nil {
+		t.Fatalf("Unable to reopen the transport: %v", err)
+	}
+	master.Write([]byte{0x15})
+	if data := readAll(t, tr, 1); !bytes.Equal(data, []byte{0x15}) {
+		t.Errorf("Received %x after reopen", data)
+	}
+
+	if err := tr.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestOpenFailure(t *testing.T) {
+	tr := &Transport{}
+	if err := tr.Open("/dev/pts/not-exists", nil); err == nil {
+		tr.Close()
+		t.Error("Open of not existing terminal must fail")
+	}
+}
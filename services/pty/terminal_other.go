@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package pty
+
+import (
+	"errors"
+	"os"
+)
+
+func openTerminal(path string, raw bool) (*os.File, error) {
+	return nil, errors.New("pseudo-terminals are not supported on this platform")
+}
@@ -0,0 +1,40 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package pty
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openTerminal opens the terminal device in non-blocking mode, so the pending read is interrupted when the file gets closed
+func openTerminal(path string, raw bool) (*os.File, error) {
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	if raw {
+		if err := makeRaw(fd); err != nil {
+			unix.Close(fd)
+			return nil, &os.PathError{Op: "raw mode", Path: path, Err: err}
+		}
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// makeRaw switches the terminal into raw mode, the same way as cfmakeraw does
+func makeRaw(fd int) error {
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return err
+	}
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+	return unix.IoctlSetTermios(fd, ioctlSetTermios, termios)
+}
@@ -0,0 +1,32 @@
+package raw
+
+// raw protocol parameters names
+const (
+	ParamNameFraming        = "framing"
+	ParamNameDelimiter      = "delimiter"
+	ParamNameFrameLength    = "frameLength"
+	ParamNameLengthSize     = "lengthSize"
+	ParamNameLengthAdjust   = "lengthAdjust"
+	ParamNameSTX            = "stx"
+	ParamNameETX            = "etx"
+	ParamNameChecksum       = "checksum"
+	ParamNameMaxFrameLength = "maxFrameLength"
+	ParamNameFrameTimeout   = "frameTimeout"
+)
+
+// framing parameter values
+const (
+	FramingDelimiter = "delimiter" // the frame ends with the delimiter bytes
+	FramingFixed     = "fixed"     // all frames have the same length
+	FramingLength    = "length"    // the frame starts with the big-endian length of the data
+	FramingSTXETX    = "stxEtx"    // the data is enclosed by STX and ETX bytes followed by the optional checksum
+)
+
+// checksum parameter values
+const (
+	ChecksumNone        = "none"
+	ChecksumXOR         = "xor"         // XOR of all bytes, 1 byte
+	ChecksumSum         = "sum"         // the sum of all bytes modulo 256, 1 byte
+	ChecksumCRC16CCITT  = "crc16ccitt"  // CRC-16/CCITT-FALSE (polynomial 0x1021, initial value 0xFFFF), high byte first
+	ChecksumCRC16Modbus = "crc16modbus" // CRC-16/MODBUS (polynomial 0xA001 reflected, initial value 0xFFFF), low byte first
+)
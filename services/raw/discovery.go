@@ -0,0 +1,32 @@
+package raw
+
+import (
+	"context"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/tcp"
+)
+
+// DiscoverTCP - discover TCP serial bridges announced over mDNS or SSDP, the raw protocol has nothing to verify so every bridge is returned
+func DiscoverTCP(ctx context.Context, params api.ParamValues) ([]*api.DiscoveryEntry, error) {
+	endpoints, err := tcp.DiscoverEndpoints(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rv []*api.DiscoveryEntry
+	for _, endpoint := range endpoints {
+		entry := &api.DiscoveryEntry{
+			ServiceKey: api.ServiceKey{
+				Protocol:  api.ProtocolRaw,
+				Transport: api.TransportTCP,
+				Entry:     endpoint.Entry,
+			},
+			Description: endpoint.Description,
+		}
+		rv = append(rv, entry)
+		defs.DiscoveryFound(ctx, entry)
+	}
+	return rv, nil
+}
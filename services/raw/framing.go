@@ -0,0 +1,225 @@
+package raw
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/modbus"
+	"github.com/stas-makutin/howeve/zigbee"
+)
+
+// framer encodes the outgoing data into the frames and splits the incoming stream into the frames, as configured by the service parameters
+type framer struct {
+	framing      string
+	delimiter    []byte
+	frameLength  int
+	lengthSize   int
+	lengthAdjust int
+	stx, etx     byte
+	checksum     string
+	maxLength    int // the maximal length of the frame data
+}
+
+func newFramer(params api.ParamValues) (*framer, error) {
+	f := &framer{framing: FramingDelimiter, delimiter: []byte{'\r'}, lengthSize: 1, stx: 0x02, etx: 0x03, checksum: ChecksumNone, maxLength: 256}
+	if v, ok := params[ParamNameFraming].(string); ok {
+		f.framing = v
+	}
+	if v, ok := params[ParamNameMaxFrameLength].(uint32); ok && v > 0 {
+		f.maxLength = int(v)
+	}
+
+	switch f.framing {
+	case FramingDelimiter:
+		if v, ok := params[ParamNameDelimiter].(string); ok {
+			b, err := hex.DecodeString(v)
+			if err != nil || len(b) == 0 {
+				return nil, fmt.Errorf("delimiter '%s' is not valid, expected hexadecimal digits", v)
+			}
+			f.delimiter = b
+		}
+	case FramingFixed:
+		v, _ := params[ParamNameFrameLength].(uint32)
+		if v == 0 {
+			return nil, fmt.Errorf("the frame length must be provided for fixed-length framing")
+		}
+		f.frameLength = int(v)
+		f.maxLength = f.frameLength
+	case FramingLength:
+		if v, ok := params[ParamNameLengthSize].(uint8); ok {
+			if v != 1 && v != 2 {
+				return nil, fmt.Errorf("the length size %d is not valid, expected 1 or 2", v)
+			}
+			f.lengthSize = int(v)
+		}
+		if v, ok := params[ParamNameLengthAdjust].(int32); ok {
+			f.lengthAdjust = int(v)
+		}
+	case FramingSTXETX:
+		if v, ok := params[ParamNameSTX].(uint8); ok {
+			f.stx = v
+		}
+		if v, ok := params[ParamNameETX].(uint8); ok {
+			f.etx = v
+		}
+		if f.stx == f.etx {
+			return nil, fmt.Errorf("STX and ETX bytes must be different")
+		}
+		if v, ok := params[ParamNameChecksum].(string); ok {
+			f.checksum = v
+		}
+		if checksumSize(f.checksum) < 0 {
+			return nil, fmt.Errorf("the checksum '%s' is not supported", f.checksum)
+		}
+	default:
+		return nil, fmt.Errorf("the framing '%s' is not supported", f.framing)
+	}
+	return f, nil
+}
+
+// checksumSize returns the size of the checksum in bytes, -1 if the checksum is not supported
+func checksumSize(checksum string) int {
+	switch checksum {
+	case ChecksumNone:
+		return 0
+	case ChecksumXOR, ChecksumSum:
+		return 1
+	case ChecksumCRC16CCITT, ChecksumCRC16Modbus:
+		return 2
+	}
+	return -1
+}
+
+// appendChecksum appends the checksum of the data
+func appendChecksum(checksum string, frame []byte, data []byte) []byte {
+	switch checksum {
+	case ChecksumXOR:
+		var c byte
+		for _, b := range data {
+			c ^= b
+		}
+		return append(frame, c)
+	case ChecksumSum:
+		var c byte
+		for _, b := range data {
+			c += b
+		}
+		return append(frame, c)
+	case ChecksumCRC16CCITT:
+		c := zigbee.ASHCRC(data)
+		return append(frame, byte(c>>8), byte(c))
+	case ChecksumCRC16Modbus:
+		c := modbus.CRC16(data)
+		return append(frame, byte(c), byte(c>>8))
+	}
+	return frame
+}
+
+// encode creates the frame of the data, returns defs.ErrBadPayload if the data can't be framed
+func (f *framer) encode(data []byte) ([]byte, error) {
+	if len(data) == 0 || len(data) > f.maxLength {
+		return nil, defs.ErrBadPayload
+	}
+	switch f.framing {
+	case FramingDelimiter:
+		if bytes.Contains(data, f.delimiter) {
+			return nil, defs.ErrBadPayload
+		}
+		return append(append([]byte(nil), data...), f.delimiter...), nil
+	case FramingFixed:
+		if len(data) != f.frameLength {
+			return nil, defs.ErrBadPayload
+		}
+		return append([]byte(nil), data...), nil
+	case FramingLength:
+		length := len(data) - f.lengthAdjust
+		if length < 0 || length >= 1<<(8*f.lengthSize) {
+			return nil, defs.ErrBadPayload
+		}
+		frame := make([]byte, 0, f.lengthSize+len(data))
+		if f.lengthSize == 2 {
+			frame = append(frame, byte(length>>8))
+		}
+		frame = append(frame, byte(length))
+		return append(frame, data...), nil
+	case FramingSTXETX:
+		if bytes.IndexByte(data, f.etx) >= 0 || bytes.IndexByte(data, f.stx) >= 0 {
+			return nil, defs.ErrBadPayload
+		}
+		frame := append([]byte{f.stx}, data...)
+		frame = append(frame, f.etx)
+		return appendChecksum(f.checksum, frame, frame[1:]), nil
+	}
+	return nil, defs.ErrBadPayload
+}
+
+// next returns the next frame in the buffer: the number of bytes the frame occupies (0 if more data is needed), the frame data,
+// and the validity flag. Not valid bytes must be skipped
+func (f *framer) next(buffer []byte) (n int, data []byte, valid bool) {
+	switch f.framing {
+	case FramingDelimiter:
+		if i := bytes.Index(buffer, f.delimiter); i >= 0 {
+			if i > f.maxLength {
+				return i + len(f.delimiter), nil, false
+			}
+			return i + len(f.delimiter), buffer[:i], true
+		}
+		if len(buffer) >= f.maxLength+len(f.delimiter) {
+			// the delimiter could be split, keep its possible beginning
+			return len(buffer) - len(f.delimiter) + 1, nil, false
+		}
+	case FramingFixed:
+		if len(buffer) >= f.frameLength {
+			return f.frameLength, buffer[:f.frameLength], true
+		}
+	case FramingLength:
+		if len(buffer) < f.lengthSize {
+			break
+		}
+		length := int(buffer[0])
+		if f.lengthSize == 2 {
+			length = length<<8 | int(buffer[1])
+		}
+		length += f.lengthAdjust
+		if length <= 0 || length > f.maxLength {
+			return 1, nil, false
+		}
+		if len(buffer) >= f.lengthSize+length {
+			return f.lengthSize + length, buffer[f.lengthSize : f.lengthSize+length], true
+		}
+	case FramingSTXETX:
+		if len(buffer) == 0 {
+			break
+		}
+		if buffer[0] != f.stx {
+			// skip to the next STX byte
+			if i := bytes.IndexByte(buffer, f.stx); i > 0 {
+				return i, nil, false
+			}
+			return len(buffer), nil, false
+		}
+		i := bytes.IndexByte(buffer[1:], f.etx)
+		if j := bytes.IndexByte(buffer[1:], f.stx); j >= 0 && (i < 0 || j < i) {
+			return j + 1, nil, false // the frame is interrupted by the next one
+		}
+		if i < 0 {
+			if len(buffer) > f.maxLength+1 {
+				return 1, nil, false
+			}
+			break
+		}
+		end := i + 2 // STX, data, and ETX
+		size := end + checksumSize(f.checksum)
+		if len(buffer) < size {
+			break
+		}
+		if string(appendChecksum(f.checksum, nil, buffer[1:end])) != string(buffer[end:size]) {
+			return size, nil, false
+		}
+		return size, buffer[1 : end-1], true
+	}
+	return 0, nil, false
+}
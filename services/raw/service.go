@@ -0,0 +1,252 @@
+package raw
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/log"
+	"github.com/stas-makutin/howeve/services/backoff"
+	"github.com/stas-makutin/howeve/services/serial"
+	"github.com/stas-makutin/howeve/utils/syncutil"
+)
+
+// log constants
+const (
+	// operation
+	rwOpService = "RW"
+
+	rwOcTransportOpen  = "O"
+	rwOcTransportRead  = "R"
+	rwOcTransportWrite = "W"
+	rwOcBadFrame       = "B"
+	rwOcFrameTimeout   = "T"
+
+	rwOsSuccess = "0"
+	rwOsFailure = "F"
+	rwOsGaveUp  = "G"
+)
+
+// outgoing is the frame to send and its registered message
+type outgoing struct {
+	frame   []byte
+	message *api.Message
+}
+
+// Service the generic framed protocol service implementation, the framing is configured by the service parameters
+type Service struct {
+	transport defs.Transport
+	key       *api.ServiceKey
+	params    api.ParamValues
+
+	framer       *framer
+	frameTimeout time.Duration
+
+	sendQueue chan *outgoing
+
+	status syncutil.RLocked[error]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stopWg sync.WaitGroup
+}
+
+// NewService creates new raw protocol service implementation using provided transport
+func NewService(transport defs.Transport, entry string, params api.ParamValues) (defs.Service, error) {
+	pv := serial.ServiceParams(transport, params)
+
+	f, err := newFramer(pv)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := &Service{
+		transport:    transport,
+		key:          &api.ServiceKey{Protocol: api.ProtocolRaw, Transport: transport.ID(), Entry: entry},
+		params:       pv,
+		framer:       f,
+		frameTimeout: time.Second,
+		sendQueue:    make(chan *outgoing, 10),
+	}
+	if v, ok := pv[ParamNameFrameTimeout]; ok {
+		svc.frameTimeout = time.Duration(v.(uint32)) * time.Millisecond
+	}
+	return svc, nil
+}
+
+func (svc *Service) Start() {
+	svc.Stop()
+
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+
+	svc.stopWg.Add(1)
+	go svc.serviceLoop()
+}
+
+func (svc *Service) Stop() {
+	if svc.ctx == nil {
+		return // already stopped
+	}
+
+	svc.cancel()
+	svc.stopWg.Wait()
+
+	svc.purgeQueue()
+
+	svc.ctx, svc.cancel = nil, nil
+
+	svc.status.Store(defs.ErrStatusGood)
+}
+
+func (svc *Service) purgeQueue() {
+	for {
+		select {
+		default:
+			return
+		case out := <-svc.sendQueue:
+			defs.Messages.UpdateState(out.message.ID, api.OutgoingRejected)
+		}
+	}
+}
+
+func (svc *Service) Status() defs.ServiceStatus {
+	err := svc.status.Load()
+	if err == nil {
+		return defs.ServiceStatus(defs.ErrStatusGood)
+	}
+	return err.(defs.ServiceStatus)
+}
+
+// Send frames the payload and sends it. The payload of the messages, outgoing and incoming, is the frame data without the framing bytes
+func (svc *Service) Send(payload []byte) (*api.Message, error) {
+	frame, err := svc.framer.encode(payload)
+	if err != nil {
+		return nil, err
+	}
+	out := &outgoing{frame: frame, message: defs.Messages.Register(svc.key, payload, api.OutgoingPending)}
+	select {
+	case svc.sendQueue <- out:
+	default:
+		defs.Messages.UpdateState(out.message.ID, api.OutgoingRejected)
+		return out.message, defs.ErrSendBusy
+	}
+	return out.message, nil
+}
+
+// ResolvedEntry returns the actual entry the transport is opened with, if the transport resolves the service entry
+func (svc *Service) ResolvedEntry() string {
+	if r, ok := svc.transport.(defs.EntryResolver); ok {
+		return r.ResolvedEntry()
+	}
+	return ""
+}
+
+func (svc *Service) log(op string, fields ...string) {
+	log.Report(append([]string{
+		log.SrcSVC,
+		rwOpService,
+		op,
+		defs.ProtocolName(svc.key.Protocol),
+		defs.TransportName(svc.key.Transport),
+		svc.key.Entry,
+	}, fields...)...)
+}
+
+func (svc *Service) serviceLoop() {
+	defer svc.transport.Close()
+	defer svc.stopWg.Done()
+
+	openBackoff := backoff.New(backoff.NewPolicy(svc.params))
+	buffer := make([]byte, 2*svc.framer.maxLength+64)
+	length := 0
+	var lastRead time.Time
+	open := true
+
+ServiceLoop:
+	for {
+		if open {
+			open = false
+			length = 0
+			if err := svc.transport.Open(svc.key.Entry, svc.params); err != nil {
+				svc.purgeQueue()
+				if !openBackoff.Retry(svc.ctx, svc.key, &svc.status, "unable to open transport", err, func(gaveUp bool) {
+					if gaveUp {
+						svc.log(rwOcTransportOpen, rwOsGaveUp, err.Error())
+					} else {
+						svc.log(rwOcTransportOpen, rwOsFailure, err.Error())
+					}
+				}) {
+					break ServiceLoop
+				}
+				open = true
+				continue
+			}
+			openBackoff.Reset()
+			svc.log(rwOcTransportOpen, rwOsSuccess)
+			svc.status.Store(defs.ErrStatusGood)
+		}
+
+		select {
+		case <-svc.ctx.Done():
+			break ServiceLoop
+		case out := <-svc.sendQueue:
+			if n, err := svc.transport.Write(out.frame); err != nil || n != len(out.frame) {
+				if err == nil {
+					err = fmt.Errorf("%d bytes of %d written", n, len(out.frame))
+				}
+				svc.log(rwOcTransportWrite, rwOsFailure, err.Error())
+				defs.Messages.UpdateState(out.message.ID, api.OutgoingFailed)
+				if !errors.Is(err, defs.ErrTransportHeld) {
+					// the held transport is operational, only the message fails
+					svc.status.Store(fmt.Errorf("unable to write using transport: %s", err.Error()))
+					open = true
+				}
+			} else {
+				defs.Messages.UpdateState(out.message.ID, api.Outgoing)
+			}
+		case <-svc.transport.ReadyToRead():
+			if length > 0 && svc.frameTimeout > 0 && time.Since(lastRead) > svc.frameTimeout {
+				// the incomplete frame is abandoned by the device
+				svc.log(rwOcFrameTimeout, hex.EncodeToString(buffer[:length]))
+				length = 0
+			}
+			n, err := svc.transport.Read(buffer[length:])
+			if err != nil {
+				svc.status.Store(fmt.Errorf("unable to read using transport: %s", err.Error()))
+				svc.log(rwOcTransportRead, rwOsFailure, err.Error())
+				open = true
+				continue
+			}
+			lastRead = time.Now()
+			length = svc.handleFrames(buffer, length+n)
+		}
+	}
+}
+
+// handleFrames registers the complete frames in the buffer, returns the length of the remaining data
+func (svc *Service) handleFrames(buffer []byte, length int) int {
+	for length > 0 {
+		n, data, valid := svc.framer.next(buffer[:length])
+		if n == 0 {
+			if length == len(buffer) {
+				// unable to find the frame in the full buffer
+				svc.log(rwOcBadFrame, hex.EncodeToString(buffer[:length]))
+				return 0
+			}
+			break
+		}
+		if !valid {
+			svc.log(rwOcBadFrame, hex.EncodeToString(buffer[:n]))
+		} else if len(data) > 0 {
+			defs.Messages.Register(svc.key, append([]byte(nil), data...), api.Incoming)
+		}
+		copy(buffer, buffer[n:length])
+		length -= n
+	}
+	return length
+}
@@ -0,0 +1,154 @@
+package raw
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/mock"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(mock.RunTests(m))
+}
+
+func newTestService(t *testing.T, m *mock.Transport, params api.ParamValues) *Service {
+	svc := mock.NewService[*Service](t, params, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(m, "test", params)
+	})
+	svc.Start()
+	return svc
+}
+
+func TestChecksums(t *testing.T) {
+	check := []byte("123456789")
+	for checksum, expected := range map[string]string{
+		ChecksumNone:        "",
+		ChecksumXOR:         "31",
+		ChecksumSum:         "dd",
+		ChecksumCRC16CCITT:  "29b1",
+		ChecksumCRC16Modbus: "374b",
+	} {
+		if c := hex.EncodeToString(appendChecksum(checksum, nil, check)); c != expected {
+			t.Errorf("Unexpected %s checksum %s, expected %s", checksum, c, expected)
+		}
+		if checksumSize(checksum) != len(expected)/2 {
+			t.Errorf("Unexpected %s checksum size %d", checksum, checksumSize(checksum))
+		}
+	}
+}
+
+func TestFraming(t *testing.T) {
+	tests := []struct {
+		name   string
+		params api.ParamValues
+		data   string // the frame data, hexadecimal
+		frame  string // the encoded frame, hexadecimal
+	}{
+		{"delimiter", api.ParamValues{ParamNameFraming: FramingDelimiter, ParamNameDelimiter: "0d0a"}, "505752", "5057520d0a"},
+		{"fixed", api.ParamValues{ParamNameFraming: FramingFixed, ParamNameFrameLength: uint32(4)}, "01020304", "01020304"},
+		{"length", api.ParamValues{ParamNameFraming: FramingLength}, "aabbcc", "03aabbcc"},
+		{"length2", api.ParamValues{ParamNameFraming: FramingLength, ParamNameLengthSize: uint8(2), ParamNameLengthAdjust: int32(-2)}, "aabb", "0004aabb"},
+		{"stxEtx", api.ParamValues{ParamNameFraming: FramingSTXETX, ParamNameChecksum: ChecksumXOR}, "3031", "0230310302"},
+		{"stxEtxCRC", api.ParamValues{ParamNameFraming: FramingSTXETX, ParamNameChecksum: ChecksumCRC16Modbus}, "41", "02410371e1"},
+	}
+	for _, test := range tests {
+		f, err := newFramer(test.params)
+		if err != nil {
+			t.Fatalf("%s: unable to create the framer: %v", test.name, err)
+		}
+		data, _ := hex.DecodeString(test.data)
+		frame, err := f.encode(data)
+		if err != nil || hex.EncodeToString(frame) != test.frame {
+			t.Errorf("%s: unexpected frame %x, error %v", test.name, frame, err)
+			continue
+		}
+		// the frame is preceded by the garbage byte (skipped for STX/ETX framing only) and followed by the beginning of the next frame
+		buffer := append(append([]byte(nil), frame...), frame[:1]...)
+		if test.params[ParamNameFraming] == FramingSTXETX {
+			buffer = append([]byte{0x03}, buffer...)
+			if n, _, valid := f.next(buffer); n != 1 || valid {
+				t.Errorf("%s: the garbage byte must be skipped, got %d %v", test.name, n, valid)
+			}
+			buffer = buffer[1:]
+		}
+		if n, _, _ := f.next(frame[:len(frame)-1]); n != 0 {
+			t.Errorf("%s: more data must be requested for the incomplete frame, got %d", test.name, n)
+		}
+		n, d, valid := f.next(buffer)
+		if n != len(frame) || !valid || !bytes.Equal(d, data) {
+			t.Errorf("%s: unexpected frame %d %x %v", test.name, n, d, valid)
+		}
+	}
+
+	f, _ := newFramer(api.ParamValues{ParamNameFraming: FramingSTXETX, ParamNameChecksum: ChecksumSum})
+	if n, _, valid := f.next([]byte{0x02, 0x30, 0x03, 0x00}); n != 4 || valid {
+		t.Errorf("The frame with bad checksum must not be valid, got %d %v", n, valid)
+	}
+	if n, _, valid := f.next([]byte{0x02, 0x30, 0x02, 0x31, 0x03, 0x34}); n != 2 || valid {
+		t.Errorf("The interrupted frame must not be valid, got %d %v", n, valid)
+	}
+	if _, err := f.encode([]byte{0x30, 0x03}); err != defs.ErrBadPayload {
+		t.Errorf("The data with ETX byte must not be encoded, got %v", err)
+	}
+
+	for _, params := range []api.ParamValues{
+		{ParamNameFraming: "unknown"},
+		{ParamNameFraming: FramingFixed},
+		{ParamNameFraming: FramingDelimiter, ParamNameDelimiter: "zz"},
+		{ParamNameFraming: FramingLength, ParamNameLengthSize: uint8(3)},
+		{ParamNameFraming: FramingSTXETX, ParamNameChecksum: "md5"},
+	} {
+		if _, err := newFramer(params); err == nil {
+			t.Errorf("The parameters %v must not be valid", params)
+		}
+	}
+}
+
+func TestService(t *testing.T) {
+	ml := defs.Messages.(*mock.MessageLog)
+	ml.Reset()
+
+	m := mock.New()
+	// the reply is split, the partial frame must be discarded after the frame timeout
+	m.Expect([]byte("PWR?\r")).Reply([]byte("PW"), []byte("ON\rMV5"))
+	m.Emit([]byte("0\rMU"))
+	m.Expect([]byte("MV?\r")).ReplyAfter(300*time.Millisecond, []byte("OFF\r"))
+
+	svc := newTestService(t, m, api.ParamValues{ParamNameFrameTimeout: uint32(200)})
+	defer svc.Stop()
+
+	if _, err := svc.Send([]byte("PWR\r?")); err != defs.ErrBadPayload {
+		t.Errorf("Bad payload error expected, got %v", err)
+	}
+	if _, err := svc.Send([]byte("PWR?")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	for deadline := time.Now().Add(time.Second); len(ml.Incoming()) < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+	message, err := svc.Send([]byte("MV?"))
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := m.Wait(2 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(time.Second); len(ml.Incoming()) < 3; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+	if incoming := ml.Incoming(); len(incoming) != 3 || string(incoming[0]) != "PWON" || string(incoming[1]) != "MV50" || string(incoming[2]) != "OFF" {
+		t.Errorf("Unexpected incoming messages %q", incoming)
+	}
+	if _, m := ml.Get(message.ID); m == nil || m.State != api.Outgoing || string(m.Payload) != "MV?" {
+		t.Errorf("The message must be sent, got %+v", m)
+	}
+}
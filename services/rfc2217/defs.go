@@ -0,0 +1,19 @@
+package rfc2217
+
+import (
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/serial"
+	"github.com/stas-makutin/howeve/services/tcp"
+)
+
+var TransportInfo *defs.TransportInfo = &defs.TransportInfo{
+	Name: "RFC2217",
+	Params: defs.Params{
+		serial.ParamNameBaudRate:    serial.TransportInfo.Params[serial.ParamNameBaudRate],
+		serial.ParamNameDataBits:    serial.TransportInfo.Params[serial.ParamNameDataBits],
+		serial.ParamNameParity:      serial.TransportInfo.Params[serial.ParamNameParity],
+		serial.ParamNameStopBits:    serial.TransportInfo.Params[serial.ParamNameStopBits],
+		tcp.ParamNameConnectTimeout: tcp.TransportInfo.Params[tcp.ParamNameConnectTimeout],
+		tcp.ParamNameKeepAlive:      tcp.TransportInfo.Params[tcp.ParamNameKeepAlive],
+	},
+}
@@ -0,0 +1,145 @@
+package rfc2217
+
+import (
+	"sync"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/services/serial"
+	"github.com/stas-makutin/howeve/services/tcp"
+)
+
+// Transport struct - RFC 2217 (Telnet COM port control) Transport implementation, the entry is host:port
+type Transport struct {
+	tcp.Transport
+	lock    sync.Mutex
+	decoder decoder
+	replied map[[2]byte]struct{}
+}
+
+func (t *Transport) ID() api.TransportIdentifier {
+	return api.TransportRFC2217
+}
+
+// Open func
+func (t *Transport) Open(entry string, params api.ParamValues) error {
+	if err := t.Transport.Open(entry, params); err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	t.decoder = decoder{}
+	t.replied = make(map[[2]byte]struct{})
+	t.lock.Unlock()
+
+	request := []byte{
+		telnetIAC, telnetWILL, optionComPort,
+		telnetIAC, telnetWILL, optionBinary,
+		telnetIAC, telnetDO, optionBinary,
+		telnetIAC, telnetWILL, optionSuppressGoAhead,
+		telnetIAC, telnetDO, optionSuppressGoAhead,
+	}
+	if v, ok := params[serial.ParamNameBaudRate]; ok {
+		baudRate := uint32(v.(int32))
+		request = append(request, subnegotiation(comPortSetBaudRate, byte(baudRate>>24), byte(baudRate>>16), byte(baudRate>>8), byte(baudRate))...)
+	}
+	if v, ok := params[serial.ParamNameDataBits]; ok {
+		switch v {
+		case "5":
+			request = append(request, subnegotiation(comPortSetDataSize, 5)...)
+		case "6":
+			request = append(request, subnegotiation(comPortSetDataSize, 6)...)
+		case "7":
+			request = append(request, subnegotiation(comPortSetDataSize, 7)...)
+		case "8":
+			request = append(request, subnegotiation(comPortSetDataSize, 8)...)
+		}
+	}
+	if v, ok := params[serial.ParamNameParity]; ok {
+		switch v {
+		case "none":
+			request = append(request, subnegotiation(comPortSetParity, parityNone)...)
+		case "odd":
+			request = append(request, subnegotiation(comPortSetParity, parityOdd)...)
+		case "even":
+			request = append(request, subnegotiation(comPortSetParity, parityEven)...)
+		case "mark":
+			request = append(request, subnegotiation(comPortSetParity, parityMark)...)
+		case "space":
+			request = append(request, subnegotiation(comPortSetParity, paritySpace)...)
+		}
+	}
+	if v, ok := params[serial.ParamNameStopBits]; ok {
+		switch v {
+		case "1":
+			request = append(request, subnegotiation(comPortSetStopSize, stopSize1)...)
+		case "1.5":
+			request = append(request, subnegotiation(comPortSetStopSize, stopSize1_5)...)
+		case "2":
+			request = append(request, subnegotiation(comPortSetStopSize, stopSize2)...)
+		}
+	}
+	request = append(request, subnegotiation(comPortSetControl, controlNoFlowControl)...)
+
+	if _, err := t.Transport.Write(request); err != nil {
+		t.Transport.Close()
+		return err
+	}
+	return nil
+}
+
+// negotiate answers the server's option negotiation, returns the reply or nil
+func (t *Transport) negotiate(command, option byte) []byte {
+	key := [2]byte{command, option}
+	if _, ok := t.replied[key]; ok {
+		return nil
+	}
+	t.replied[key] = struct{}{}
+
+	supported := option == optionBinary || option == optionSuppressGoAhead || option == optionComPort
+	switch command {
+	case telnetDO:
+		if supported {
+			return []byte{telnetIAC, telnetWILL, option}
+		}
+		return []byte{telnetIAC, telnetWONT, option}
+	case telnetWILL:
+		if supported && option != optionComPort {
+			return []byte{telnetIAC, telnetDO, option}
+		}
+		return []byte{telnetIAC, telnetDONT, option}
+	}
+	return nil
+}
+
+// Read func, returns the data without Telnet commands
+func (t *Transport) Read(p []byte) (int, error) {
+	n, err := t.Transport.Read(p)
+	if n <= 0 {
+		return n, err
+	}
+
+	var reply []byte
+	t.lock.Lock()
+	n = t.decoder.decode(p[:n], func(command, option byte) {
+		reply = append(reply, t.negotiate(command, option)...)
+	})
+	t.lock.Unlock()
+
+	if len(reply) > 0 {
+		if _, werr := t.Transport.Write(reply); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// Write func, escapes IAC bytes in the data
+func (t *Transport) Write(p []byte) (int, error) {
+	data := escape(p)
+	n, err := t.Transport.Write(data)
+	if n >= len(data) {
+		return len(p), err
+	}
+	// the number of original bytes written is not known precisely, report partial write
+	return 0, err
+}
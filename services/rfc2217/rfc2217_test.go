@@ -0,0 +1,130 @@
+package rfc2217
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/services/serial"
+)
+
+func TestDecoder(t *testing.T) {
+	var d decoder
+	var negotiated [][2]byte
+	negotiate := func(command, option byte) { negotiated = append(negotiated, [2]byte{command, option}) }
+
+	data := []byte{1, telnetIAC, telnetIAC, 2, telnetIAC, telnetDO, optionBinary, 3, telnetIAC}
+	n := d.decode(data, negotiate)
+	if !bytes.Equal(data[:n], []byte{1, telnetIAC, 2, 3}) {
+		t.Errorf("Decoded data is %v", data[:n])
+	}
+	// the command is split between reads
+	data = []byte{telnetSB, optionComPort, 101, 0, 1, telnetIAC, telnetIAC, telnetIAC, telnetSE, 4}
+	n = d.decode(data, negotiate)
+	if !bytes.Equal(data[:n], []byte{4}) {
+		t.Errorf("Decoded data is %v", data[:n])
+	}
+	if len(negotiated) != 1 || negotiated[0] != [2]byte{telnetDO, optionBinary} {
+		t.Errorf("Negotiated options are %v", negotiated)
+	}
+
+	if e := escape([]byte{1, telnetIAC, 2}); !bytes.Equal(e, []byte{1, telnetIAC, telnetIAC, 2}) {
+		t.Errorf("Escaped data is %v", e)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	tr := &Transport{}
+	err = tr.Open(listener.Addr().String(), api.ParamValues{
+		serial.ParamNameBaudRate: int32(115200),
+		serial.ParamNameDataBits: "8",
+		serial.ParamNameParity:   "none",
+		serial.ParamNameStopBits: "1",
+	})
+	if err != nil {
+		t.Fatalf("Unable to open the transport: %v", err)
+	}
+	defer tr.Close()
+
+	var peer net.Conn
+	select {
+	case peer = <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("The connection is not accepted")
+	}
+	defer peer.Close()
+
+	readPeer := func(expected []byte) []byte {
+		var received []byte
+		peer.SetReadDeadline(time.Now().Add(time.Second))
+		for !bytes.Contains(received, expected) {
+			data := make([]byte, 256)
+			n, err := peer.Read(data)
+			if err != nil {
+				break
+			}
+			received = append(received, data[:n]...)
+		}
+		return received
+	}
+
+	// 115200 = 0x0001C200
+	baudRate := []byte{telnetIAC, telnetSB, optionComPort, comPortSetBaudRate, 0x00, 0x01, 0xC2, 0x00, telnetIAC, telnetSE}
+	setControl := subnegotiation(comPortSetControl, controlNoFlowControl)
+	received := readPeer(setControl)
+	for _, expected := range [][]byte{
+		{telnetIAC, telnetWILL, optionComPort},
+		baudRate,
+		subnegotiation(comPortSetDataSize, 8),
+		subnegotiation(comPortSetParity, parityNone),
+		subnegotiation(comPortSetStopSize, stopSize1),
+	} {
+		if !bytes.Contains(received, expected) {
+			t.Errorf("The negotiation %v does not contain %v", received, expected)
+		}
+	}
+
+	peer.Write([]byte{0x06, telnetIAC, telnetDO, optionBinary, 0x01, telnetIAC, telnetIAC})
+	var data []byte
+	deadline := time.Now().Add(time.Second)
+	for len(data) < 3 && time.Now().Before(deadline) {
+		select {
+		case <-tr.ReadyToRead():
+		case <-time.After(100 * time.Millisecond):
+		}
+		buffer := make([]byte, 16)
+		n, err := tr.Read(buffer)
+		if err != nil {
+			t.Fatalf("Unable to read: %v", err)
+		}
+		data = append(data, buffer[:n]...)
+	}
+	if !bytes.Equal(data, []byte{0x06, 0x01, telnetIAC}) {
+		t.Errorf("Received data is %v", data)
+	}
+	if reply := []byte{telnetIAC, telnetWILL, optionBinary}; !bytes.Contains(readPeer(reply), reply) {
+		t.Error("The option negotiation is not answered")
+	}
+
+	if n, err := tr.Write([]byte{0x01, telnetIAC}); n != 2 || err != nil {
+		t.Errorf("Write result is %d, %v", n, err)
+	}
+	if expected := []byte{0x01, telnetIAC, telnetIAC}; !bytes.Equal(readPeer(expected), expected) {
+		t.Error("The data is not escaped")
+	}
+}
@@ -0,0 +1,135 @@
+package rfc2217
+
+// Telnet commands
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+)
+
+// Telnet options
+const (
+	optionBinary          = 0
+	optionSuppressGoAhead = 3
+	optionComPort         = 44
+)
+
+// COM-PORT-OPTION commands (client to server), server responses have the code increased by 100
+const (
+	comPortSetBaudRate = 1
+	comPortSetDataSize = 2
+	comPortSetParity   = 3
+	comPortSetStopSize = 4
+	comPortSetControl  = 5
+)
+
+// COM-PORT-OPTION values
+const (
+	parityNone  = 1
+	parityOdd   = 2
+	parityEven  = 3
+	parityMark  = 4
+	paritySpace = 5
+
+	stopSize1   = 1
+	stopSize2   = 2
+	stopSize1_5 = 3
+
+	controlNoFlowControl = 1
+)
+
+// decoder states
+const (
+	stateData = iota
+	stateIAC
+	stateOption
+	stateSB
+	stateSBIAC
+)
+
+// decoder separates the data from Telnet commands in the received stream
+type decoder struct {
+	state   int
+	command byte
+	sb      []byte
+}
+
+// decode removes Telnet commands from the data in place, returns the length of remaining data.
+// The option negotiation commands are passed to the callback
+func (d *decoder) decode(data []byte, negotiate func(command, option byte)) int {
+	n := 0
+	for _, b := range data {
+		switch d.state {
+		case stateData:
+			if b == telnetIAC {
+				d.state = stateIAC
+			} else {
+				data[n] = b
+				n++
+			}
+		case stateIAC:
+			switch b {
+			case telnetIAC:
+				data[n] = b
+				n++
+				d.state = stateData
+			case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+				d.command = b
+				d.state = stateOption
+			case telnetSB:
+				d.sb = d.sb[:0]
+				d.state = stateSB
+			default:
+				d.state = stateData
+			}
+		case stateOption:
+			negotiate(d.command, b)
+			d.state = stateData
+		case stateSB:
+			if b == telnetIAC {
+				d.state = stateSBIAC
+			} else {
+				d.sb = append(d.sb, b)
+			}
+		case stateSBIAC:
+			switch b {
+			case telnetIAC:
+				d.sb = append(d.sb, b)
+				d.state = stateSB
+			case telnetSE:
+				// the server's COM-PORT-OPTION responses are not used
+				d.state = stateData
+			default:
+				d.state = stateData
+			}
+		}
+	}
+	return n
+}
+
+// escape doubles IAC bytes in the data
+func escape(data []byte) []byte {
+	for i, b := range data {
+		if b == telnetIAC {
+			rv := make([]byte, 0, len(data)+1)
+			rv = append(rv, data[:i]...)
+			for _, b := range data[i:] {
+				if b == telnetIAC {
+					rv = append(rv, telnetIAC)
+				}
+				rv = append(rv, b)
+			}
+			return rv
+		}
+	}
+	return data
+}
+
+// subnegotiation creates COM-PORT-OPTION subnegotiation command
+func subnegotiation(command byte, value ...byte) []byte {
+	return append(append([]byte{telnetIAC, telnetSB, optionComPort, command}, escape(value)...), telnetIAC, telnetSE)
+}
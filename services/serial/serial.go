@@ -2,27 +2,40 @@ package serial
 
 import (
 	"sync"
-	"time"
 
 	serial "github.com/albenik/go-serial/v2"
 	"github.com/stas-makutin/howeve/api"
 	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/stream"
 )
 
-const readyToReadPoolInterval = 200 * time.Millisecond
+// the timeout of the blocking read in the background reader, the pending read is also interrupted when the port gets closed
+const blockingReadTimeout = 1000
 
 // Transport struct - serial Transport implementation
 type Transport struct {
-	port   *serial.Port
-	lock   sync.RWMutex
-	wg     sync.WaitGroup
-	stopCh chan struct{}
+	port     *serial.Port
+	portName string // the name of the opened port, it differs from the entry if the entry refers to USB device
+	lock     sync.RWMutex
+	reader   stream.Reader
 }
 
 func (t *Transport) ID() api.TransportIdentifier {
 	return api.TransportSerial
 }
 
+// ServiceParams returns the copy of the service parameters with the serial port timeouts overridden if the transport is serial:
+// the read timeout is not used since the port is read by the background reader, and the write timeout 0 makes the write
+// return after the single write call, so the service loop is never blocked on the port
+func ServiceParams(transport defs.Transport, params api.ParamValues) api.ParamValues {
+	pv := params.Copy()
+	if transport.ID() == api.TransportSerial {
+		pv[ParamNameReadTimeout] = uint32(0)
+		pv[ParamNameWriteTimeout] = uint32(0)
+	}
+	return pv
+}
+
 // Open func
 func (t *Transport) Open(entry string, params api.ParamValues) (err error) {
 	options := []serial.Option{}
@@ -75,10 +88,22 @@ func (t *Transport) Open(entry string, params api.ParamValues) (err error) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 	t.close()
-	t.port, err = serial.Open(entry, options...)
-	if t.port != nil {
-		t.stopCh = make(chan struct{}, 1)
+	// the USB device entry is resolved on every open attempt since the port name may change after the device is replugged
+	portName, err := resolvePort(entry)
+	if err != nil {
+		return
 	}
+	if t.port, err = serial.Open(portName, options...); err != nil {
+		return
+	}
+	// the port is read by the background reader, it blocks until the first byte received
+	if err = t.port.SetFirstByteReadTimeout(blockingReadTimeout); err != nil {
+		t.port.Close()
+		t.port = nil
+		return
+	}
+	t.portName = portName
+	t.reader.Start(t.port)
 	return
 }
 
@@ -89,80 +114,30 @@ func (t *Transport) Close() error {
 	return t.close()
 }
 
-func (t *Transport) close() (err error) {
-	defer t.wg.Wait()
-	if t.port != nil {
-		err = t.port.Close()
-		t.port = nil
-		close(t.stopCh)
-		t.stopCh = nil
-	}
-	return
+func (t *Transport) close() error {
+	port := t.port
+	t.port = nil
+	t.portName = ""
+	return t.reader.Stop(func() error {
+		return port.Close()
+	})
 }
 
-// ReadyToRead function, singal in the channel if something could be read from the port or port state has changed
-func (t *Transport) ReadyToRead() <-chan struct{} {
-	// stop previous if any
+// ResolvedEntry returns the name of the opened port
+func (t *Transport) ResolvedEntry() string {
 	t.lock.RLock()
-	if t.stopCh != nil {
-		select {
-		case t.stopCh <- struct{}{}:
-		default:
-		}
-	}
-	t.lock.RUnlock()
-	t.wg.Wait()
-	select {
-	case <-t.stopCh:
-	default:
-	}
-
-	rc := make(chan struct{})
-	t.wg.Add(1)
-	go func() {
-		defer t.wg.Done()
-		for {
-			stopCh := func() <-chan struct{} {
-				t.lock.RLock()
-				defer t.lock.RUnlock()
-
-				if t.port == nil {
-					return nil
-				}
-
-				if n, err := t.port.ReadyToRead(); n > 0 || err != nil {
-					return nil
-				}
-
-				return t.stopCh
-			}()
-
-			if stopCh == nil {
-				close(rc)
-				break
-			}
+	defer t.lock.RUnlock()
+	return t.portName
+}
 
-			select {
-			case <-rc:
-				return
-			case <-stopCh:
-				close(rc)
-				return
-			case <-time.After(readyToReadPoolInterval):
-			}
-		}
-	}()
-	return rc
+// ReadyToRead function, singal in the channel if something could be read from the port or port state has changed
+func (t *Transport) ReadyToRead() <-chan struct{} {
+	return t.reader.ReadyToRead()
 }
 
-// Read func
+// Read func, returns immediately with the data received so far
 func (t *Transport) Read(p []byte) (int, error) {
-	t.lock.RLock()
-	defer t.lock.RUnlock()
-	if t.port != nil {
-		return t.port.Read(p)
-	}
-	return 0, defs.ErrNotOpen
+	return t.reader.Read(p)
 }
 
 // Write func
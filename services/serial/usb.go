@@ -0,0 +1,94 @@
+package serial
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/albenik/go-serial/v2/enumerator"
+)
+
+// the prefix of the entry which refers to the USB device instead of the port name
+const usbEntryPrefix = "usb:"
+
+// usbDevice identifies USB serial device, the empty serial number matches any device with the same vendor and product IDs
+type usbDevice struct {
+	vid    string
+	pid    string
+	serial string
+}
+
+// USBEntry returns the entry which refers to the USB device by vendor ID, product ID and optional serial number
+func USBEntry(vid, pid, serialNumber string) string {
+	entry := usbEntryPrefix + strings.ToUpper(vid) + ":" + strings.ToUpper(pid)
+	if serialNumber != "" {
+		entry += ":" + serialNumber
+	}
+	return entry
+}
+
+// parseUSBEntry parses the entry in usb:VID:PID[:SERIAL] format, ok is false if the entry is the port name
+func parseUSBEntry(entry string) (device usbDevice, ok bool, err error) {
+	if len(entry) < len(usbEntryPrefix) || !strings.EqualFold(entry[:len(usbEntryPrefix)], usbEntryPrefix) {
+		return
+	}
+	ok = true
+	parts := strings.SplitN(entry[len(usbEntryPrefix):], ":", 3)
+	if len(parts) < 2 || !isHexID(parts[0]) || !isHexID(parts[1]) {
+		err = fmt.Errorf("invalid USB entry '%s', expected usb:VID:PID[:SERIAL]", entry)
+		return
+	}
+	device.vid, device.pid = parts[0], parts[1]
+	if len(parts) > 2 {
+		device.serial = parts[2]
+	}
+	return
+}
+
+func isHexID(s string) bool {
+	if len(s) == 0 || len(s) > 4 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func sameHexID(a, b string) bool {
+	return strings.EqualFold(strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0"))
+}
+
+// findUSBPort returns the name of the first (by name) port of the USB device
+func findUSBPort(ports []*enumerator.PortDetails, device usbDevice) (string, bool) {
+	var names []string
+	for _, port := range ports {
+		if port.IsUSB && sameHexID(port.VID, device.vid) && sameHexID(port.PID, device.pid) &&
+			(device.serial == "" || strings.EqualFold(port.SerialNumber, device.serial)) {
+			names = append(names, port.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+	return names[0], true
+}
+
+// resolvePort returns the port name of the entry, the USB device entry is resolved using the current list of ports
+func resolvePort(entry string) (string, error) {
+	device, ok, err := parseUSBEntry(entry)
+	if !ok || err != nil {
+		return entry, err
+	}
+	ports, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return "", fmt.Errorf("unable to list serial ports: %w", err)
+	}
+	if name, found := findUSBPort(ports, device); found {
+		return name, nil
+	}
+	return "", fmt.Errorf("USB device '%s' not found", entry)
+}
@@ -0,0 +1,55 @@
+package serial
+
+import (
+	"testing"
+
+	"github.com/albenik/go-serial/v2/enumerator"
+)
+
+func TestParseUSBEntry(t *testing.T) {
+	tests := []struct {
+		entry  string
+		device usbDevice
+		ok     bool
+		fail   bool
+	}{
+		{entry: "/dev/ttyACM0"},
+		{entry: "COM3"},
+		{entry: "usb:0658:0200", device: usbDevice{vid: "0658", pid: "0200"}, ok: true},
+		{entry: "USB:10c4:ea60:0001:A", device: usbDevice{vid: "10c4", pid: "ea60", serial: "0001:A"}, ok: true},
+		{entry: "usb:0658", ok: true, fail: true},
+		{entry: "usb:0658:xyz", ok: true, fail: true},
+		{entry: "usb:12345:0200", ok: true, fail: true},
+	}
+	for _, test := range tests {
+		device, ok, err := parseUSBEntry(test.entry)
+		if ok != test.ok || (err != nil) != test.fail || (!test.fail && device != test.device) {
+			t.Errorf("%s: unexpected result %v, %v, %v", test.entry, device, ok, err)
+		}
+	}
+}
+
+func TestFindUSBPort(t *testing.T) {
+	ports := []*enumerator.PortDetails{
+		{Name: "/dev/ttyS0"},
+		{Name: "/dev/ttyACM2", IsUSB: true, VID: "0658", PID: "0200", SerialNumber: "B"},
+		{Name: "/dev/ttyACM1", IsUSB: true, VID: "0658", PID: "0200", SerialNumber: "A"},
+		{Name: "/dev/ttyUSB0", IsUSB: true, VID: "10C4", PID: "EA60"},
+	}
+	tests := []struct {
+		device usbDevice
+		name   string
+	}{
+		{usbDevice{vid: "0658", pid: "0200"}, "/dev/ttyACM1"},
+		{usbDevice{vid: "658", pid: "200", serial: "b"}, "/dev/ttyACM2"},
+		{usbDevice{vid: "10c4", pid: "ea60"}, "/dev/ttyUSB0"},
+		{usbDevice{vid: "10c4", pid: "ea60", serial: "A"}, ""},
+		{usbDevice{vid: "0403", pid: "6001"}, ""},
+	}
+	for _, test := range tests {
+		name, ok := findUSBPort(ports, test.device)
+		if name != test.name || ok != (test.name != "") {
+			t.Errorf("%v: unexpected port %s, %v", test.device, name, ok)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package simulator
+
+import (
+	"sort"
+	"time"
+
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+// simulated controller properties
+const (
+	controllerNodeID = 1
+	libraryVersion   = "Z-Wave 7.18"
+	appVersion       = 1
+	appRevision      = 0
+	productType      = 0x0001
+	productID        = 0x0001
+	initDataVersion  = 0x08
+	chipType         = 0x07
+	chipVersion      = 0x00
+)
+
+// the Serial API functions supported by the simulated controller
+var supportedFunctions = []byte{
+	zw.FUNC_ID_SERIAL_API_GET_INIT_DATA,
+	zw.ZW_APPLICATION_COMMAND_HANDLER,
+	zw.FUNC_ID_SERIAL_API_GET_CAPABILITIES,
+	zw.ZW_SEND_DATA,
+	zw.ZW_SEND_DATA_MULTI,
+	zw.ZW_VERSION,
+	zw.ZW_APPLICATION_UPDATE,
+	zw.ZW_REQUEST_NODE_INFO,
+}
+
+// controller is the simulated Z-Wave controller speaking Serial API
+type controller struct {
+	nodes  map[uint16]*node
+	delay  time.Duration
+	buffer []byte
+	send   func(delay time.Duration, data []byte) // sends the data to the host
+}
+
+// receive processes the data received from the host
+func (c *controller) receive(p []byte) {
+	c.buffer = append(c.buffer, p...)
+	for len(c.buffer) > 0 {
+		switch c.buffer[0] {
+		case zw.FrameSOF:
+			switch vr, pos := zw.ValidateDataFrame(c.buffer); vr {
+			case zw.FrameOK:
+				frame := c.buffer[:pos]
+				c.buffer = c.buffer[pos:]
+				c.send(0, []byte{zw.FrameASK})
+				if payload := zw.UnpackRequest(frame); payload != nil {
+					c.handle(payload)
+				}
+			case zw.FrameIncomplete:
+				return
+			case zw.FrameWrongLength:
+				c.send(0, []byte{zw.FrameNAK})
+				c.buffer = nil
+			case zw.FrameWrongChecksum:
+				c.send(0, []byte{zw.FrameNAK})
+				c.buffer = c.buffer[pos:]
+			}
+		default:
+			// ACK, NAK, or CAN from the host, the simulated controller does not retransmit the frames
+			c.buffer = c.buffer[1:]
+		}
+	}
+}
+
+func (c *controller) respond(payload []byte) {
+	c.send(0, zw.DataResponse(payload))
+}
+
+func (c *controller) request(delay time.Duration, payload []byte) {
+	c.send(delay, zw.DataRequest(payload))
+}
+
+// handle processes the request received from the host, the unsupported requests are ignored
+func (c *controller) handle(payload []byte) {
+	switch payload[0] {
+	case zw.ZW_VERSION:
+		version := make([]byte, 12)
+		copy(version, libraryVersion)
+		c.respond(append(append([]byte{zw.ZW_VERSION}, version...), zw.ZW_LIB_CONTROLLER_STATIC))
+
+	case zw.FUNC_ID_SERIAL_API_GET_CAPABILITIES:
+		functions := make([]byte, 32)
+		for _, f := range supportedFunctions {
+			functions[(f-1)/8] |= 1 << ((f - 1) % 8)
+		}
+		c.respond(append([]byte{
+			zw.FUNC_ID_SERIAL_API_GET_CAPABILITIES, appVersion, appRevision,
+			0, 0, byte(productType >> 8), byte(productType), byte(productID >> 8), byte(productID),
+		}, functions...))
+
+	case zw.FUNC_ID_SERIAL_API_GET_INIT_DATA:
+		bitmask := make([]byte, zw.ZW_MAX_NODES/8)
+		for _, id := range c.nodeIDs() {
+			bitmask[(id-1)/8] |= 1 << ((id - 1) % 8)
+		}
+		response := append([]byte{zw.FUNC_ID_SERIAL_API_GET_INIT_DATA, initDataVersion, 0, byte(len(bitmask))}, bitmask...)
+		c.respond(append(response, chipType, chipVersion))
+
+	case zw.ZW_REQUEST_NODE_INFO:
+		if len(payload) < 2 {
+			return
+		}
+		c.respond([]byte{zw.ZW_REQUEST_NODE_INFO, 1})
+		var info []byte
+		if payload[1] == controllerNodeID {
+			info = []byte{zw.BASIC_TYPE_STATIC_CONTROLLER, zw.GENERIC_TYPE_STATIC_CONTROLLER, 0x01}
+		} else if n, ok := c.nodes[uint16(payload[1])]; ok {
+			info = n.info()
+		} else {
+			c.request(c.delay, []byte{zw.ZW_APPLICATION_UPDATE, zw.UPDATE_STATE_NODE_INFO_REQ_FAILED, 0, 0})
+			return
+		}
+		c.request(c.delay, append([]byte{zw.ZW_APPLICATION_UPDATE, zw.UPDATE_STATE_NODE_INFO_RECEIVED, payload[1], byte(len(info))}, info...))
+
+	case zw.ZW_SEND_DATA:
+		// ZW_SEND_DATA, node, data length, data, transmit options, function id
+		if len(payload) < 5 || len(payload) < 5+int(payload[2]) {
+			return
+		}
+		data := payload[3 : 3+int(payload[2])]
+		c.transmit(payload[0], []uint16{uint16(payload[1])}, data, payload[4+len(data)])
+
+	case zw.ZW_SEND_DATA_MULTI:
+		// ZW_SEND_DATA_MULTI, number of nodes, nodes, data length, data, transmit options, function id
+		if len(payload) < 2 || len(payload) < 5+int(payload[1]) {
+			return
+		}
+		nodes := make([]uint16, 0, payload[1])
+		for _, id := range payload[2 : 2+int(payload[1])] {
+			nodes = append(nodes, uint16(id))
+		}
+		rest := payload[2+len(nodes):]
+		if len(rest) < 3+int(rest[0]) {
+			return
+		}
+		data := rest[1 : 1+int(rest[0])]
+		c.transmit(payload[0], nodes, data, rest[2+len(data)])
+	}
+}
+
+// transmit delivers the command to the nodes, reports the transmit status using the callback and the nodes reports
+func (c *controller) transmit(command byte, nodes []uint16, data []byte, funcID byte) {
+	c.respond([]byte{command, 1})
+
+	status := byte(zw.TRANSMIT_COMPLETE_OK)
+	var targets []*node
+	if len(nodes) == 1 && nodes[0] == zw.NODE_BROADCAST {
+		for _, id := range c.nodeIDs() {
+			if n, ok := c.nodes[id]; ok {
+				targets = append(targets, n)
+			}
+		}
+	} else {
+		for _, id := range nodes {
+			if n, ok := c.nodes[id]; ok {
+				targets = append(targets, n)
+			} else {
+				status = zw.TRANSMIT_COMPLETE_NO_ACK
+			}
+		}
+	}
+
+	var reports [][]byte
+	for _, n := range targets {
+		if report := n.handle(data); report != nil {
+			reports = append(reports, append([]byte{zw.ZW_APPLICATION_COMMAND_HANDLER, 0, byte(n.id), byte(len(report))}, report...))
+		}
+	}
+
+	// the delays are counted from the previous frame delivery
+	if funcID != 0 {
+		c.request(c.delay, []byte{command, funcID, status})
+	}
+	for _, report := range reports {
+		c.request(c.delay, report)
+	}
+}
+
+// nodeIDs returns sorted identifiers of the network nodes including the controller
+func (c *controller) nodeIDs() []uint16 {
+	ids := []uint16{controllerNodeID}
+	for id := range c.nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,34 @@
+package simulator
+
+import "github.com/stas-makutin/howeve/defs"
+
+// simulator transport parameters names
+const (
+	ParamNameNodes = "nodes"
+	ParamNameDelay = "delay"
+)
+
+// virtual node types
+const (
+	NodeTypeSwitch = "switch"
+	NodeTypeDimmer = "dimmer"
+)
+
+// the default set of virtual nodes
+const defaultNodes = "2:" + NodeTypeSwitch + ",3:" + NodeTypeDimmer
+
+var TransportInfo *defs.TransportInfo = &defs.TransportInfo{
+	Name: "Simulator",
+	Params: defs.Params{
+		ParamNameNodes: {
+			Description:  "The virtual nodes, comma separated list of node id and type (switch or dimmer) pairs, like 2:switch,3:dimmer",
+			Type:         defs.ParamTypeString,
+			DefaultValue: defaultNodes,
+		},
+		ParamNameDelay: {
+			Description:  "The radio transmission time of the virtual nodes, milliseconds",
+			Type:         defs.ParamTypeUint32,
+			DefaultValue: "20",
+		},
+	},
+}
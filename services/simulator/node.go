@@ -0,0 +1,119 @@
+package simulator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+// the level reported by binary switch when it is on
+const switchOn = 0xFF
+
+// the maximal level of multilevel switch
+const dimmerMaxLevel = 99
+
+// node is the virtual node of the simulated Z-Wave network
+type node struct {
+	id        uint16
+	nodeType  string
+	level     byte // 0 - off, the level of the dimmer or switchOn for the switch otherwise
+	lastLevel byte // the last non-zero level of the dimmer
+}
+
+// parseNodes parses the nodes parameter value, like 2:switch,3:dimmer
+func parseNodes(value string) (map[uint16]*node, error) {
+	nodes := make(map[uint16]*node)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, nodeType, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("the node type is missing in '%s'", entry)
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(id), 10, 8)
+		if err != nil || n <= controllerNodeID || n > zw.ZW_MAX_NODES {
+			return nil, fmt.Errorf("invalid node id in '%s'", entry)
+		}
+		nodeType = strings.ToLower(strings.TrimSpace(nodeType))
+		if nodeType != NodeTypeSwitch && nodeType != NodeTypeDimmer {
+			return nil, fmt.Errorf("unknown node type in '%s'", entry)
+		}
+		if _, ok := nodes[uint16(n)]; ok {
+			return nil, fmt.Errorf("duplicate node id in '%s'", entry)
+		}
+		nodes[uint16(n)] = &node{id: uint16(n), nodeType: nodeType}
+	}
+	return nodes, nil
+}
+
+// info returns the node information frame: basic, generic, and specific device classes followed by supported command classes
+func (n *node) info() []byte {
+	if n.nodeType == NodeTypeDimmer {
+		return []byte{zw.BASIC_TYPE_ROUTING_SLAVE, zw.GENERIC_TYPE_SWITCH_MULTILEVEL, 0x01, zw.COMMAND_CLASS_SWITCH_MULTILEVEL}
+	}
+	return []byte{zw.BASIC_TYPE_ROUTING_SLAVE, zw.GENERIC_TYPE_SWITCH_BINARY, 0x01, zw.COMMAND_CLASS_SWITCH_BINARY}
+}
+
+// switchCommandClass returns the command class of the node's switch
+func (n *node) switchCommandClass() byte {
+	if n.nodeType == NodeTypeDimmer {
+		return zw.COMMAND_CLASS_SWITCH_MULTILEVEL
+	}
+	return zw.COMMAND_CLASS_SWITCH_BINARY
+}
+
+// set changes the switch state using Basic or Switch Set value
+func (n *node) set(value byte) {
+	switch {
+	case value == 0:
+		n.level = 0
+	case n.nodeType == NodeTypeSwitch:
+		n.level = switchOn
+	case value <= dimmerMaxLevel:
+		n.level, n.lastLevel = value, value
+	case value == 0xFF: // restore the last level
+		if n.level = n.lastLevel; n.level == 0 {
+			n.level = dimmerMaxLevel
+		}
+	}
+}
+
+// handle processes the command sent to the node, returns the report the node sends to the controller or nil
+func (n *node) handle(command []byte) []byte {
+	if len(command) < 2 {
+		return nil
+	}
+	switch command[0] {
+	case zw.COMMAND_CLASS_BASIC:
+		switch command[1] {
+		case zw.BASIC_SET:
+			if len(command) > 2 {
+				n.set(command[2])
+				return n.report() // the state change is reported to the controller (lifeline)
+			}
+		case zw.BASIC_GET:
+			return []byte{zw.COMMAND_CLASS_BASIC, zw.BASIC_REPORT, n.level}
+		}
+	case n.switchCommandClass():
+		// Binary and Multilevel Switch commands share the identifiers
+		switch command[1] {
+		case zw.SWITCH_BINARY_SET:
+			if len(command) > 2 {
+				n.set(command[2])
+				return n.report()
+			}
+		case zw.SWITCH_BINARY_GET:
+			return n.report()
+		}
+	}
+	return nil
+}
+
+// report returns the switch report of the node
+func (n *node) report() []byte {
+	return []byte{n.switchCommandClass(), zw.SWITCH_BINARY_REPORT, n.level}
+}
@@ -0,0 +1,82 @@
+package simulator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/stream"
+)
+
+// the maximal number of frames waiting to be delivered to the host
+const maxPendingFrames = 256
+
+// Transport struct - the simulated Z-Wave controller with virtual nodes, the entry is any name of the simulator
+type Transport struct {
+	lock       sync.Mutex
+	controller *controller
+	feed       *stream.Feed
+	reader     stream.Reader
+}
+
+func (t *Transport) ID() api.TransportIdentifier {
+	return api.TransportSimulator
+}
+
+// Open func
+func (t *Transport) Open(entry string, params api.ParamValues) error {
+	nodesValue := defaultNodes
+	if v, ok := params[ParamNameNodes]; ok {
+		nodesValue = v.(string)
+	}
+	nodes, err := parseNodes(nodesValue)
+	if err != nil {
+		return err
+	}
+	delay := 20 * time.Millisecond
+	if v, ok := params[ParamNameDelay]; ok {
+		delay = time.Duration(v.(uint32)) * time.Millisecond
+	}
+
+	t.Close()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.feed = stream.NewFeed(maxPendingFrames)
+	t.controller = &controller{nodes: nodes, delay: delay, send: t.feed.Send}
+	t.reader.Start(t.feed.Source())
+	return nil
+}
+
+// Close func
+func (t *Transport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.controller == nil {
+		return nil
+	}
+	t.controller = nil
+	return t.reader.Stop(t.feed.Close)
+}
+
+// ReadyToRead function, singal in the channel if something could be read or the transport state has changed
+func (t *Transport) ReadyToRead() <-chan struct{} {
+	return t.reader.ReadyToRead()
+}
+
+// Read func, returns immediately with the data sent by the simulated controller so far
+func (t *Transport) Read(p []byte) (int, error) {
+	return t.reader.Read(p)
+}
+
+// Write func, the simulated controller processes the data immediately
+func (t *Transport) Write(p []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.controller == nil {
+		return 0, defs.ErrNotOpen
+	}
+	t.controller.receive(p)
+	return len(p), nil
+}
@@ -0,0 +1,155 @@
+package simulator
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+// exchange writes the request data frame and reads the controller's frames until the expected number of data frames received
+func exchange(t *testing.T, st *Transport, frame []byte, frames int) (payloads [][]byte) {
+	if _, err := st.Write(frame); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var buffer []byte
+	data := make([]byte, 256)
+	for len(payloads) < frames {
+		select {
+		case <-st.ReadyToRead():
+		case <-time.After(time.Second):
+			t.Fatalf("no frames from the controller, received %d", len(payloads))
+		}
+		n, err := st.Read(data)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		buffer = append(buffer, data[:n]...)
+		for len(buffer) > 0 {
+			if buffer[0] != zw.FrameSOF {
+				buffer = buffer[1:]
+				continue
+			}
+			vr, pos := zw.ValidateDataFrame(buffer)
+			if vr == zw.FrameIncomplete {
+				break
+			}
+			if vr != zw.FrameOK {
+				t.Fatalf("Invalid frame received: %x", buffer)
+			}
+			payload := zw.UnpackResponse(buffer[:pos])
+			if payload == nil {
+				payload = zw.UnpackRequest(buffer[:pos])
+			}
+			payloads = append(payloads, payload)
+			st.Write([]byte{zw.FrameASK})
+			buffer = buffer[pos:]
+		}
+	}
+	return
+}
+
+func TestSimulator(t *testing.T) {
+	st := &Transport{}
+	if err := st.Open("test", api.ParamValues{ParamNameNodes: "2:switch, 5:dimmer", ParamNameDelay: uint32(1)}); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer st.Close()
+
+	t.Run("Version", func(t *testing.T) {
+		p := exchange(t, st, zw.DataRequest([]byte{zw.ZW_VERSION}), 1)
+		if len(p[0]) != 14 || p[0][0] != zw.ZW_VERSION || string(p[0][1:12]) != libraryVersion || p[0][13] != zw.ZW_LIB_CONTROLLER_STATIC {
+			t.Errorf("Unexpected version response: %x", p[0])
+		}
+	})
+
+	t.Run("Capabilities", func(t *testing.T) {
+		p := exchange(t, st, zw.DataRequest([]byte{zw.FUNC_ID_SERIAL_API_GET_CAPABILITIES}), 1)
+		c, ok := zw.ParseCapabilities(p[0])
+		if !ok || !c.Supports(zw.ZW_SEND_DATA) || c.Supports(zw.FUNC_ID_SERIAL_API_SETUP) || c.SupportsLongRange() {
+			t.Errorf("Unexpected capabilities: %x", p[0])
+		}
+	})
+
+	t.Run("Init data", func(t *testing.T) {
+		p := exchange(t, st, zw.DataRequest([]byte{zw.FUNC_ID_SERIAL_API_GET_INIT_DATA}), 1)
+		if nodes, ok := zw.ParseInitData(p[0]); !ok || len(nodes) != 3 || nodes[0] != 1 || nodes[1] != 2 || nodes[2] != 5 {
+			t.Errorf("Unexpected nodes: %v", nodes)
+		}
+	})
+
+	t.Run("Node info", func(t *testing.T) {
+		p := exchange(t, st, zw.RequestNodeInfo(zw.NodeIDType8Bit, 5), 2)
+		u, ok := zw.ParseApplicationUpdate(zw.NodeIDType8Bit, p[1])
+		if !ok || u.Status != zw.UPDATE_STATE_NODE_INFO_RECEIVED || u.NodeID != 5 || u.Generic != zw.GENERIC_TYPE_SWITCH_MULTILEVEL {
+			t.Errorf("Unexpected node info: %x", p[1])
+		}
+		p = exchange(t, st, zw.RequestNodeInfo(zw.NodeIDType8Bit, 7), 2)
+		if u, ok := zw.ParseApplicationUpdate(zw.NodeIDType8Bit, p[1]); !ok || u.Status != zw.UPDATE_STATE_NODE_INFO_REQ_FAILED {
+			t.Errorf("Unexpected node info: %x", p[1])
+		}
+	})
+
+	t.Run("Switch", func(t *testing.T) {
+		p := exchange(t, st, zw.SendData(zw.NodeIDType8Bit, 2, []byte{zw.COMMAND_CLASS_BASIC, zw.BASIC_SET, 0xFF}, 0x25, 7), 3)
+		if !bytes.Equal(p[0], []byte{zw.ZW_SEND_DATA, 1}) || !bytes.Equal(p[1], []byte{zw.ZW_SEND_DATA, 7, zw.TRANSMIT_COMPLETE_OK}) {
+			t.Errorf("Unexpected response or callback: %x, %x", p[0], p[1])
+		}
+		c, ok := zw.ParseApplicationCommand(zw.NodeIDType8Bit, p[2])
+		if !ok || c.NodeID != 2 || !bytes.Equal(c.Command, []byte{zw.COMMAND_CLASS_SWITCH_BINARY, zw.SWITCH_BINARY_REPORT, 0xFF}) {
+			t.Errorf("Unexpected switch report: %x", p[2])
+		}
+
+		p = exchange(t, st, zw.SendData(zw.NodeIDType8Bit, 9, []byte{zw.COMMAND_CLASS_BASIC, zw.BASIC_GET}, 0x25, 8), 2)
+		if !bytes.Equal(p[1], []byte{zw.ZW_SEND_DATA, 8, zw.TRANSMIT_COMPLETE_NO_ACK}) {
+			t.Errorf("Unexpected callback of sending to missing node: %x", p[1])
+		}
+	})
+
+	t.Run("Dimmer", func(t *testing.T) {
+		exchange(t, st, zw.SendData(zw.NodeIDType8Bit, 5, []byte{zw.COMMAND_CLASS_SWITCH_MULTILEVEL, zw.SWITCH_MULTILEVEL_SET, 40}, 0x25, 9), 3)
+		exchange(t, st, zw.SendData(zw.NodeIDType8Bit, 5, []byte{zw.COMMAND_CLASS_SWITCH_MULTILEVEL, zw.SWITCH_MULTILEVEL_SET, 0}, 0x25, 10), 3)
+		exchange(t, st, zw.SendData(zw.NodeIDType8Bit, 5, []byte{zw.COMMAND_CLASS_BASIC, zw.BASIC_SET, 0xFF}, 0x25, 11), 3)
+		p := exchange(t, st, zw.SendData(zw.NodeIDType8Bit, 5, []byte{zw.COMMAND_CLASS_BASIC, zw.BASIC_GET}, 0x25, 12), 3)
+		c, ok := zw.ParseApplicationCommand(zw.NodeIDType8Bit, p[2])
+		if !ok || c.NodeID != 5 || !bytes.Equal(c.Command, []byte{zw.COMMAND_CLASS_BASIC, zw.BASIC_REPORT, 40}) {
+			t.Errorf("The dimmer must restore the last level: %x", p[2])
+		}
+	})
+
+	t.Run("Multicast", func(t *testing.T) {
+		frame := zw.SendDataMulti(zw.NodeIDType8Bit, []uint16{2, 5}, []byte{zw.COMMAND_CLASS_BASIC, zw.BASIC_SET, 0}, 0x25, 13)
+		p := exchange(t, st, frame, 4)
+		if !bytes.Equal(p[1], []byte{zw.ZW_SEND_DATA_MULTI, 13, zw.TRANSMIT_COMPLETE_OK}) {
+			t.Errorf("Unexpected multicast callback: %x", p[1])
+		}
+	})
+
+	t.Run("Invalid frame", func(t *testing.T) {
+		frame := zw.DataRequest([]byte{zw.ZW_VERSION})
+		frame[len(frame)-1] ^= 0xFF
+		st.Write(frame)
+		select {
+		case <-st.ReadyToRead():
+		case <-time.After(time.Second):
+			t.Fatal("no reply to invalid frame")
+		}
+		data := make([]byte, 8)
+		if n, _ := st.Read(data); n != 1 || data[0] != zw.FrameNAK {
+			t.Errorf("Invalid frame must be rejected with NAK, got %x", data[:n])
+		}
+	})
+}
+
+func TestParseNodes(t *testing.T) {
+	if nodes, err := parseNodes(defaultNodes); err != nil || len(nodes) != 2 || nodes[2].nodeType != NodeTypeSwitch || nodes[3].nodeType != NodeTypeDimmer {
+		t.Errorf("Unexpected default nodes: %v, %v", nodes, err)
+	}
+	for _, value := range []string{"2", "1:switch", "233:switch", "2:lamp", "2:switch,2:dimmer"} {
+		if _, err := parseNodes(value); err == nil {
+			t.Errorf("The nodes '%s' must not be valid", value)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// feedEntry is the data (or the reading error) to deliver after the delay
+type feedEntry struct {
+	delay time.Duration
+	data  []byte
+	err   error
+}
+
+// Feed is the in-memory source for the Reader, it delivers the data in order with optional delays.
+// It is intended for the transports which emulate the device side
+type Feed struct {
+	pr      *io.PipeReader
+	pw      *io.PipeWriter
+	entries chan feedEntry
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewFeed creates the feed which is able to hold up to size pending entries
+func NewFeed(size int) *Feed {
+	f := &Feed{
+		entries: make(chan feedEntry, size),
+		stopCh:  make(chan struct{}),
+	}
+	f.pr, f.pw = io.Pipe()
+	f.wg.Add(1)
+	go f.feedLoop()
+	return f
+}
+
+// Source returns the reading side of the feed
+func (f *Feed) Source() io.Reader {
+	return f.pr
+}
+
+// Send queues the data to deliver after provided delay, counting from the moment the previous entry is delivered
+func (f *Feed) Send(delay time.Duration, data []byte) {
+	f.queue(feedEntry{delay: delay, data: data})
+}
+
+// Fail queues the reading error to deliver after provided delay, the entries after the error are not delivered
+func (f *Feed) Fail(delay time.Duration, err error) {
+	f.queue(feedEntry{delay: delay, err: err})
+}
+
+func (f *Feed) queue(e feedEntry) {
+	select {
+	case <-f.stopCh:
+	case f.entries <- e:
+	}
+}
+
+// Close stops the delivery and closes the reading side, the pending entries are dropped
+func (f *Feed) Close() error {
+	close(f.stopCh)
+	err := f.pr.Close()
+	f.wg.Wait()
+	return err
+}
+
+func (f *Feed) feedLoop() {
+	defer f.wg.Done()
+	failed := false // the entries are drained but not delivered after the failure
+	for {
+		var e feedEntry
+		select {
+		case <-f.stopCh:
+			return
+		case e = <-f.entries:
+		}
+		if failed {
+			continue
+		}
+		if e.delay > 0 {
+			select {
+			case <-f.stopCh:
+				return
+			case <-time.After(e.delay):
+			}
+		}
+		if e.err != nil {
+			f.pw.CloseWithError(e.err)
+			failed = true
+		} else if _, err := f.pw.Write(e.data); err != nil {
+			failed = true
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package stream
+
+import (
+	"io"
+	"sync"
+
+	"github.com/stas-makutin/howeve/defs"
+)
+
+const readBufferSize = 4096
+
+// Reader reads the blocking source in the background goroutine and provides non-blocking Read along with
+// ReadyToRead notifications, as required by defs.Transport
+type Reader struct {
+	lock    sync.Mutex
+	wg      sync.WaitGroup
+	running bool
+	buffer  []byte
+	err     error
+	event   chan struct{} // closed and replaced when data received or reader state changed
+	stopCh  chan struct{}
+}
+
+// Start starts reading provided source. The source must be closed by the caller to stop reading, see Stop
+func (r *Reader) Start(source io.Reader) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.running = true
+	r.buffer, r.err = nil, nil
+	if r.event == nil {
+		r.event = make(chan struct{})
+	}
+	r.wg.Add(1)
+	go r.readLoop(source)
+}
+
+// Stop marks the reader as stopped, calls closeFn which must close the source (unblock the pending read), and waits
+// until the background goroutine exits. Returns the result of closeFn
+func (r *Reader) Stop(closeFn func() error) (err error) {
+	r.lock.Lock()
+	running := r.running
+	r.running = false
+	r.lock.Unlock()
+
+	if running && closeFn != nil {
+		err = closeFn()
+	}
+	r.wg.Wait()
+	r.notify()
+	return
+}
+
+func (r *Reader) notify() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.event != nil {
+		close(r.event)
+	}
+	r.event = make(chan struct{})
+}
+
+func (r *Reader) readLoop(source io.Reader) {
+	defer r.wg.Done()
+	data := make([]byte, readBufferSize)
+	for {
+		n, err := source.Read(data)
+
+		r.lock.Lock()
+		if !r.running {
+			r.lock.Unlock()
+			return
+		}
+		if n > 0 {
+			r.buffer = append(r.buffer, data[:n]...)
+		}
+		if err != nil {
+			r.err = err
+		}
+		if n > 0 || err != nil {
+			close(r.event)
+			r.event = make(chan struct{})
+		}
+		r.lock.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// ReadyToRead function, singal in the channel if something could be read or reader state has changed.
+// The previously returned channel is abandoned
+func (r *Reader) ReadyToRead() <-chan struct{} {
+	// stop previous if any
+	r.lock.Lock()
+	if r.stopCh != nil {
+		close(r.stopCh)
+	}
+	stopCh := make(chan struct{})
+	r.stopCh = stopCh
+	if r.event == nil {
+		r.event = make(chan struct{})
+	}
+	r.lock.Unlock()
+
+	rc := make(chan struct{})
+	go func() {
+		for {
+			r.lock.Lock()
+			ready := !r.running || len(r.buffer) > 0 || r.err != nil
+			event := r.event
+			r.lock.Unlock()
+
+			if ready {
+				close(rc)
+				return
+			}
+
+			select {
+			case <-stopCh:
+				return
+			case <-event:
+			}
+		}
+	}()
+	return rc
+}
+
+// Read func, returns immediately with the data received so far, or the reading error if there is no data
+func (r *Reader) Read(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.running {
+		return 0, defs.ErrNotOpen
+	}
+	if len(r.buffer) > 0 {
+		n := copy(p, r.buffer)
+		r.buffer = r.buffer[n:]
+		return n, nil
+	}
+	return 0, r.err
+}
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/defs"
+)
+
+func waitReady(t testing.TB, r *Reader) {
+	select {
+	case <-r.ReadyToRead():
+	case <-time.After(time.Second):
+		t.Fatal("ReadyToRead is not signaled")
+	}
+}
+
+func TestReader(t *testing.T) {
+	var r Reader
+	if _, err := r.Read(make([]byte, 1)); err != defs.ErrNotOpen {
+		t.Errorf("Read of not started reader must fail with ErrNotOpen, got %v", err)
+	}
+	waitReady(t, &r) // not started reader is always "ready", Read reports the error
+
+	for i := 0; i < 2; i++ { // start - stop - start again
+		pr, pw := io.Pipe()
+		r.Start(pr)
+
+		ready := r.ReadyToRead()
+		select {
+		case <-ready:
+			t.Fatal("ReadyToRead must not signal without data")
+		case <-time.After(20 * time.Millisecond):
+		}
+		if n, err := r.Read(make([]byte, 1)); n != 0 || err != nil {
+			t.Errorf("Read without data must return immediately, got %d, %v", n, err)
+		}
+
+		go pw.Write([]byte{1, 2, 3})
+		waitReady(t, &r)
+		data := make([]byte, 2)
+		if n, err := r.Read(data); n != 2 || err != nil || !bytes.Equal(data, []byte{1, 2}) {
+			t.Errorf("Read result is %v, %d, %v", data, n, err)
+		}
+		waitReady(t, &r)
+		if n, err := r.Read(data); n != 1 || err != nil || data[0] != 3 {
+			t.Errorf("Read result is %v, %d, %v", data, n, err)
+		}
+
+		ready = r.ReadyToRead()
+		if err := r.Stop(pr.Close); err != nil {
+			t.Errorf("Stop failed: %v", err)
+		}
+		select {
+		case <-ready:
+		case <-time.After(time.Second):
+			t.Fatal("ReadyToRead must signal when the reader gets stopped")
+		}
+		if _, err := r.Read(data); err != defs.ErrNotOpen {
+			t.Errorf("Read of stopped reader must fail with ErrNotOpen, got %v", err)
+		}
+	}
+}
+
+func TestReaderSourceError(t *testing.T) {
+	var r Reader
+	pr, pw := io.Pipe()
+	r.Start(pr)
+	defer r.Stop(pr.Close)
+
+	pw.CloseWithError(io.ErrUnexpectedEOF)
+	waitReady(t, &r)
+	if _, err := r.Read(make([]byte, 1)); err != io.ErrUnexpectedEOF {
+		t.Errorf("Read must report the source error, got %v", err)
+	}
+}
+
+// BenchmarkReadLatency measures the time between the data arrival and the moment it becomes readable
+func BenchmarkReadLatency(b *testing.B) {
+	var r Reader
+	pr, pw := io.Pipe()
+	r.Start(pr)
+	defer r.Stop(pr.Close)
+
+	data := make([]byte, 16)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ready := r.ReadyToRead()
+		go pw.Write([]byte{byte(i)})
+		<-ready
+		if n, err := r.Read(data); n != 1 || err != nil {
+			b.Fatalf("Read result is %d, %v", n, err)
+		}
+	}
+}
+
+// pollingReader emulates the readiness polling with the fixed interval for comparison
+type pollingReader struct {
+	lock   sync.Mutex
+	buffer []byte
+}
+
+func (p *pollingReader) readyToRead(interval time.Duration) <-chan struct{} {
+	rc := make(chan struct{})
+	go func() {
+		for {
+			p.lock.Lock()
+			ready := len(p.buffer) > 0
+			p.lock.Unlock()
+			if ready {
+				close(rc)
+				return
+			}
+			time.Sleep(interval)
+		}
+	}()
+	return rc
+}
+
+// BenchmarkPollingReadLatency measures the same latency when the readiness is polled every 200 ms
+func BenchmarkPollingReadLatency(b *testing.B) {
+	var p pollingReader
+	for i := 0; i < b.N; i++ {
+		ready := p.readyToRead(200 * time.Millisecond)
+		go func() {
+			time.Sleep(time.Millisecond)
+			p.lock.Lock()
+			p.buffer = append(p.buffer, byte(i))
+			p.lock.Unlock()
+		}()
+		<-ready
+		p.lock.Lock()
+		p.buffer = p.buffer[:0]
+		p.lock.Unlock()
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"sync"
 
@@ -39,6 +40,7 @@ type serviceInfo struct {
 	key     *api.ServiceKey
 	alias   string
 	params  api.ParamValues
+	stream  defs.StreamSharer // the raw byte stream of the service, nil if the service has none
 }
 
 // servicesRegistry - registry of available services - services Task implementation
@@ -116,8 +118,8 @@ func (sr *servicesRegistry) Add(key *api.ServiceKey, params api.RawParamValues,
 
 	sr.cfg = append(sr.cfg, api.ServiceConfig{
 		Alias:     alias,
-		Protocol:  defs.ProtocolName(key.Protocol),
-		Transport: defs.TransportName(key.Transport),
+		Protocol:  string(key.Protocol),
+		Transport: string(key.Transport),
 		Entry:     key.Entry,
 		Params:    params,
 	})
@@ -204,6 +206,39 @@ func (sr *servicesRegistry) Status(key *api.ServiceKey, alias string) (defs.Serv
 	return si.service.Status(), true
 }
 
+// ResolvedEntry returns the actual entry the service's entry is resolved into, or empty string if the entry is not resolved
+func (sr *servicesRegistry) ResolvedEntry(key *api.ServiceKey, alias string) string {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+
+	if si := sr.findService(key, alias); si != nil {
+		return resolvedEntry(si.service)
+	}
+	return ""
+}
+
+func resolvedEntry(service defs.Service) string {
+	if r, ok := service.(defs.EntryResolver); ok {
+		return r.ResolvedEntry()
+	}
+	return ""
+}
+
+// SharedStream returns the raw byte stream of the service to share with the remote side
+func (sr *servicesRegistry) SharedStream(key *api.ServiceKey, alias string) (defs.StreamSharer, error) {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+
+	si := sr.findService(key, alias)
+	if si == nil {
+		return nil, defs.ErrServiceNotExists
+	}
+	if si.stream == nil {
+		return nil, defs.ErrNotSupported
+	}
+	return si.stream, nil
+}
+
 // List returns list of registered services to provided callback function. The services iteration will stop if callback function will return true
 func (sr *servicesRegistry) List(listFn defs.ListFunc) {
 	if listFn != nil {
@@ -211,7 +246,7 @@ func (sr *servicesRegistry) List(listFn defs.ListFunc) {
 		defer sr.lock.Unlock()
 
 		for _, si := range sr.services {
-			listFn(si.key, si.alias, si.service.Status(), si.params)
+			listFn(si.key, si.alias, si.service.Status(), resolvedEntry(si.service), si.params)
 		}
 	}
 }
@@ -252,6 +287,118 @@ func (sr *servicesRegistry) Send(key *api.ServiceKey, alias string, payload []by
 	return si.service.Send(payload)
 }
 
+// SendMulticast sends payload to several nodes of the service identified by (in order of priority): 1) service key; 2) alias
+func (sr *servicesRegistry) SendMulticast(ctx context.Context, key *api.ServiceKey, alias string, nodes []uint16, broadcast bool, followUp bool, payload []byte) ([]*api.NodeSendResult, error) {
+	sr.lock.Lock()
+	si := sr.findService(key, alias)
+	sr.lock.Unlock()
+
+	if si == nil {
+		return nil, defs.ErrServiceNotExists
+	}
+	ms, ok := si.service.(defs.MulticastSender)
+	if !ok {
+		return nil, defs.ErrNotSupported
+	}
+	return ms.SendMulticast(ctx, nodes, broadcast, followUp, payload)
+}
+
+// Nodes returns the list of network nodes of the service identified by (in order of priority): 1) service key; 2) alias
+func (sr *servicesRegistry) Nodes(key *api.ServiceKey, alias string) ([]*api.NodeInfo, error) {
+	sr.lock.Lock()
+	si := sr.findService(key, alias)
+	sr.lock.Unlock()
+
+	if si == nil {
+		return nil, defs.ErrServiceNotExists
+	}
+	nl, ok := si.service.(defs.NodeLister)
+	if !ok {
+		return nil, defs.ErrNotSupported
+	}
+	return nl.Nodes(), nil
+}
+
+// Statistics returns the radio statistics of the service identified by (in order of priority): 1) service key; 2) alias
+func (sr *servicesRegistry) Statistics(key *api.ServiceKey, alias string) (*api.ServiceStatistics, error) {
+	sr.lock.Lock()
+	si := sr.findService(key, alias)
+	sr.lock.Unlock()
+
+	if si == nil {
+		return nil, defs.ErrServiceNotExists
+	}
+	sp, ok := si.service.(defs.StatisticsProvider)
+	if !ok {
+		return nil, defs.ErrNotSupported
+	}
+	return sp.Statistics(), nil
+}
+
+// ReadRegisters reads the coils or registers of the device of the service identified by (in order of priority): 1) service key; 2) alias
+func (sr *servicesRegistry) ReadRegisters(ctx context.Context, key *api.ServiceKey, alias string, unit uint8, table string, address uint16, count uint16) ([]uint16, error) {
+	sr.lock.Lock()
+	si := sr.findService(key, alias)
+	sr.lock.Unlock()
+
+	if si == nil {
+		return nil, defs.ErrServiceNotExists
+	}
+	ra, ok := si.service.(defs.RegisterAccessor)
+	if !ok {
+		return nil, defs.ErrNotSupported
+	}
+	return ra.ReadRegisters(ctx, unit, table, address, count)
+}
+
+// WriteRegisters writes the coils or holding registers of the device of the service identified by (in order of priority): 1) service key; 2) alias
+func (sr *servicesRegistry) WriteRegisters(ctx context.Context, key *api.ServiceKey, alias string, unit uint8, table string, address uint16, values []uint16) error {
+	sr.lock.Lock()
+	si := sr.findService(key, alias)
+	sr.lock.Unlock()
+
+	if si == nil {
+		return defs.ErrServiceNotExists
+	}
+	ra, ok := si.service.(defs.RegisterAccessor)
+	if !ok {
+		return defs.ErrNotSupported
+	}
+	return ra.WriteRegisters(ctx, unit, table, address, values)
+}
+
+// DeviceCommand sends the typed command to the device of the service identified by (in order of priority): 1) service key; 2) alias
+func (sr *servicesRegistry) DeviceCommand(ctx context.Context, key *api.ServiceKey, alias string, device string, command string, level uint8) (uint8, error) {
+	sr.lock.Lock()
+	si := sr.findService(key, alias)
+	sr.lock.Unlock()
+
+	if si == nil {
+		return 0, defs.ErrServiceNotExists
+	}
+	dc, ok := si.service.(defs.DeviceCommander)
+	if !ok {
+		return 0, defs.ErrNotSupported
+	}
+	return dc.DeviceCommand(ctx, device, command, level)
+}
+
+// DeviceLinks reads the link records of the controller of the service identified by (in order of priority): 1) service key; 2) alias
+func (sr *servicesRegistry) DeviceLinks(ctx context.Context, key *api.ServiceKey, alias string) ([]*api.DeviceLink, error) {
+	sr.lock.Lock()
+	si := sr.findService(key, alias)
+	sr.lock.Unlock()
+
+	if si == nil {
+		return nil, defs.ErrServiceNotExists
+	}
+	ll, ok := si.service.(defs.LinkLister)
+	if !ok {
+		return nil, defs.ErrNotSupported
+	}
+	return ll.DeviceLinks(ctx)
+}
+
 func (sr *servicesRegistry) add(key *api.ServiceKey, params api.RawParamValues, alias string) error {
 	if _, ok := sr.services[*key]; ok {
 		return defs.ErrServiceExists
@@ -282,7 +429,10 @@ func (sr *servicesRegistry) add(key *api.ServiceKey, params api.RawParamValues,
 
 	service.Start()
 
-	si := &serviceInfo{service, key, alias, pv}
+	si := &serviceInfo{service: service, key: key, alias: alias, params: pv}
+	if ss, ok := service.(*sharedService); ok {
+		si.service, si.stream = ss.Service, ss.stream
+	}
 	sr.services[*key] = si
 	if alias != "" {
 		sr.aliases[alias] = si
@@ -350,11 +500,11 @@ func (sr *servicesRegistry) findService(key *api.ServiceKey, alias string) *serv
 }
 
 func (sr *servicesRegistry) findServiceCfg(key *api.ServiceKey) (int, bool) {
-	protocolName := defs.ProtocolName(key.Protocol)
-	transportName := defs.TransportName(key.Transport)
-
 	for i, cfg := range sr.cfg {
-		if cfg.Transport == transportName && cfg.Protocol == protocolName && cfg.Entry == key.Entry {
+		// the configuration could contain the names instead of the identifiers
+		protocol, _ := defs.ProtocolByName(cfg.Protocol)
+		transport, _ := defs.TransportByName(cfg.Transport)
+		if transport == key.Transport && protocol == key.Protocol && cfg.Entry == key.Entry {
 			return i, true
 		}
 	}
@@ -0,0 +1,25 @@
+package tcp
+
+import "github.com/stas-makutin/howeve/defs"
+
+// TCP transport parameters names
+const (
+	ParamNameConnectTimeout = "connectTimeout"
+	ParamNameKeepAlive      = "keepAlive"
+)
+
+var TransportInfo *defs.TransportInfo = &defs.TransportInfo{
+	Name: "TCP",
+	Params: defs.Params{
+		ParamNameConnectTimeout: {
+			Description:  "The connect timeout, milliseconds",
+			Type:         defs.ParamTypeUint32,
+			DefaultValue: "5000",
+		},
+		ParamNameKeepAlive: {
+			Description:  "The interval between keep-alive probes, milliseconds, 0 to disable",
+			Type:         defs.ParamTypeUint32,
+			DefaultValue: "30000",
+		},
+	},
+}
@@ -0,0 +1,103 @@
+package tcp
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/netdisco"
+)
+
+// TCP discovery parameters names
+const (
+	ParamNameMDNSServices  = "mdnsServices"
+	ParamNameMDNSAddress   = "mdnsAddress"
+	ParamNameSSDPTarget    = "ssdpTarget"
+	ParamNameSSDPAddress   = "ssdpAddress"
+	ParamNameSSDPPort      = "ssdpPort"
+	ParamNameSearchTimeout = "searchTimeout"
+)
+
+// Endpoint is the network endpoint (TCP serial bridge or the device itself) found by DiscoverEndpoints
+type Endpoint struct {
+	Entry       string // host:port
+	Description string
+}
+
+// DiscoverEndpoints - discover TCP endpoints announced over mDNS (the comma-separated list of DNS-SD service types)
+// or responded to SSDP search, SSDP responses do not carry the port so it must be provided
+func DiscoverEndpoints(ctx context.Context, params api.ParamValues) ([]*Endpoint, error) {
+	timeout := 3 * time.Second
+	if v, ok := params[ParamNameSearchTimeout]; ok {
+		timeout = time.Duration(v.(uint32)) * time.Millisecond
+	}
+
+	var wg sync.WaitGroup
+	var services []*netdisco.Service
+	var devices []*netdisco.Device
+	var mdnsErr, ssdpErr error
+
+	if v, _ := params[ParamNameMDNSServices].(string); v != "" {
+		address := netdisco.MDNSAddress
+		if a, _ := params[ParamNameMDNSAddress].(string); a != "" {
+			address = a
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			services, mdnsErr = netdisco.Browse(ctx, address, strings.Split(v, ","), timeout)
+		}()
+	}
+	port, _ := params[ParamNameSSDPPort].(uint16)
+	if v, _ := params[ParamNameSSDPTarget].(string); v != "" && port != 0 {
+		address := netdisco.SSDPAddress
+		if a, _ := params[ParamNameSSDPAddress].(string); a != "" {
+			address = a
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			devices, ssdpErr = netdisco.Search(ctx, address, v, timeout)
+		}()
+	}
+	wg.Wait()
+
+	if mdnsErr != nil && ssdpErr != nil {
+		return nil, mdnsErr
+	}
+
+	var rv []*Endpoint
+	found := make(map[string]bool)
+	add := func(entry, description string) {
+		if !found[entry] {
+			found[entry] = true
+			rv = append(rv, &Endpoint{Entry: entry, Description: description})
+		}
+	}
+	for _, s := range services {
+		add(s.Address(), s.Instance+" ["+strings.TrimSuffix(s.Host, ".")+"]")
+	}
+	for _, d := range devices {
+		host := d.IP.String()
+		if u, err := url.Parse(d.Location); err == nil && u.Hostname() != "" {
+			host = u.Hostname()
+		}
+		description := d.Server
+		if description == "" {
+			description = d.USN
+		}
+		add(net.JoinHostPort(host, strconv.Itoa(int(port))), description)
+	}
+	if len(rv) == 0 {
+		if mdnsErr != nil {
+			return nil, mdnsErr
+		}
+		return nil, ssdpErr
+	}
+	return rv, nil
+}
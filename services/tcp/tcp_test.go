@@ -0,0 +1,151 @@
+package tcp
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+)
+
+func TestTransport(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	tr := &Transport{}
+	if _, err := tr.Read(make([]byte, 1)); err != defs.ErrNotOpen {
+		t.Errorf("Read of not open transport must fail with ErrNotOpen, got %v", err)
+	}
+	if err := tr.Open(listener.Addr().String(), api.ParamValues{ParamNameConnectTimeout: uint32(1000), ParamNameKeepAlive: uint32(0)}); err != nil {
+		t.Fatalf("Unable to open the transport: %v", err)
+	}
+	defer tr.Close()
+
+	var peer net.Conn
+	select {
+	case peer = <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("The connection is not accepted")
+	}
+	defer peer.Close()
+
+	if n, err := tr.Read(make([]byte, 1)); n != 0 || err != nil {
+		t.Errorf("Read without data must return immediately, got %d, %v", n, err)
+	}
+
+	t.Run("Write", func(t *testing.T) {
+		if _, err := tr.Write([]byte{1, 2, 3}); err != nil {
+			t.Fatalf("Unable to write: %v", err)
+		}
+		data := make([]byte, 3)
+		peer.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := peer.Read(data); err != nil || !bytes.Equal(data, []byte{1, 2, 3}) {
+			t.Errorf("Received %v, %v", data, err)
+		}
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		ready := tr.ReadyToRead()
+		select {
+		case <-ready:
+			t.Fatal("ReadyToRead must not signal without data")
+		case <-time.After(50 * time.Millisecond):
+		}
+		peer.Write([]byte{4, 5, 6, 7})
+		select {
+		case <-ready:
+		case <-time.After(time.Second):
+			t.Fatal("ReadyToRead must signal when data received")
+		}
+		var received []byte
+		deadline := time.Now().Add(time.Second)
+		for len(received) < 4 && time.Now().Before(deadline) {
+			data := make([]byte, 2)
+			n, err := tr.Read(data)
+			if err != nil {
+				t.Fatalf("Unable to read: %v", err)
+			}
+			received = append(received, data[:n]...)
+		}
+		if !bytes.Equal(received, []byte{4, 5, 6, 7}) {
+			t.Errorf("Received %v", received)
+		}
+	})
+
+	t.Run("Peer closed", func(t *testing.T) {
+		ready := tr.ReadyToRead()
+		peer.Close()
+		select {
+		case <-ready:
+		case <-time.After(time.Second):
+			t.Fatal("ReadyToRead must signal when connection closed")
+		}
+		if _, err := tr.Read(make([]byte, 1)); err == nil {
+			t.Error("Read of closed connection must fail")
+		}
+	})
+}
+
+func TestConnectFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	tr := &Transport{}
+	if err := tr.Open(addr, api.ParamValues{ParamNameConnectTimeout: uint32(500)}); err == nil {
+		tr.Close()
+		t.Error("Connect to closed port must fail")
+	}
+}
+
+func TestDiscoverEndpoints(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			_, from, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				return
+			}
+			conn.WriteToUDP([]byte("HTTP/1.1 200 OK\r\nLOCATION: http://192.0.2.5/desc.xml\r\nSERVER: Bridge/1.0\r\nST: urn:test:device:Bridge:1\r\nUSN: uuid:1\r\n\r\n"), from)
+		}
+	}()
+
+	params := api.ParamValues{
+		ParamNameSSDPTarget:    "urn:test:device:Bridge:1",
+		ParamNameSSDPAddress:   conn.LocalAddr().String(),
+		ParamNameSearchTimeout: uint32(300),
+	}
+	if endpoints, err := DiscoverEndpoints(context.Background(), params); err != nil || len(endpoints) != 0 {
+		t.Errorf("SSDP search must be disabled without the port, endpoints %v, error %v", endpoints, err)
+	}
+
+	params[ParamNameSSDPPort] = uint16(6638)
+	endpoints, err := DiscoverEndpoints(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 1 || endpoints[0].Entry != "192.0.2.5:6638" || endpoints[0].Description != "Bridge/1.0" {
+		t.Errorf("Unexpected endpoints %+v", endpoints)
+	}
+}
@@ -0,0 +1,19 @@
+package udp
+
+import "github.com/stas-makutin/howeve/defs"
+
+// UDP transport parameters names
+const (
+	ParamNameLocalAddress = "localAddress"
+)
+
+var TransportInfo *defs.TransportInfo = &defs.TransportInfo{
+	Name: "UDP",
+	Params: defs.Params{
+		ParamNameLocalAddress: {
+			Description:  "The local address (host:port) to send the datagrams from, empty to choose automatically",
+			Type:         defs.ParamTypeString,
+			DefaultValue: "",
+		},
+	},
+}
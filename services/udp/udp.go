@@ -0,0 +1,90 @@
+package udp
+
+import (
+	"net"
+	"sync"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/stream"
+)
+
+// Transport struct - UDP Transport implementation, the entry is host:port of the remote side.
+// The datagrams are read as the byte stream, the protocol must be able to split it into the frames
+type Transport struct {
+	conn   *net.UDPConn
+	lock   sync.RWMutex
+	reader stream.Reader
+}
+
+func (t *Transport) ID() api.TransportIdentifier {
+	return api.TransportUDP
+}
+
+// Open func
+func (t *Transport) Open(entry string, params api.ParamValues) (err error) {
+	var local *net.UDPAddr
+	if v, ok := params[ParamNameLocalAddress]; ok && v.(string) != "" {
+		if local, err = net.ResolveUDPAddr("udp", v.(string)); err != nil {
+			return err
+		}
+	}
+	remote, err := net.ResolveUDPAddr("udp", entry)
+	if err != nil {
+		return err
+	}
+
+	t.Close()
+
+	conn, err := net.DialUDP("udp", local, remote)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.conn = conn
+	t.reader.Start(conn)
+	return nil
+}
+
+// Close func
+func (t *Transport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	conn := t.conn
+	t.conn = nil
+	return t.reader.Stop(func() error {
+		return conn.Close()
+	})
+}
+
+// ReadyToRead function, singal in the channel if something could be read from the socket or socket state has changed
+func (t *Transport) ReadyToRead() <-chan struct{} {
+	return t.reader.ReadyToRead()
+}
+
+// Read func, returns immediately with the data received so far
+func (t *Transport) Read(p []byte) (int, error) {
+	return t.reader.Read(p)
+}
+
+// Write func, sends the data as one datagram
+func (t *Transport) Write(p []byte) (int, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.conn != nil {
+		return t.conn.Write(p)
+	}
+	return 0, defs.ErrNotOpen
+}
+
+// LocalAddr returns the local address of the socket, nil if the transport is not open
+func (t *Transport) LocalAddr() *net.UDPAddr {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.conn != nil {
+		return t.conn.LocalAddr().(*net.UDPAddr)
+	}
+	return nil
+}
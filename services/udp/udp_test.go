@@ -0,0 +1,63 @@
+package udp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+)
+
+func TestTransport(t *testing.T) {
+	peer, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	defer peer.Close()
+
+	tr := &Transport{}
+	if _, err := tr.Read(make([]byte, 1)); err != defs.ErrNotOpen {
+		t.Errorf("Read of not open transport must fail with ErrNotOpen, got %v", err)
+	}
+	if err := tr.Open(peer.LocalAddr().String(), api.ParamValues{ParamNameLocalAddress: "127.0.0.1:0"}); err != nil {
+		t.Fatalf("Unable to open the transport: %v", err)
+	}
+	defer tr.Close()
+
+	if n, err := tr.Read(make([]byte, 1)); n != 0 || err != nil {
+		t.Errorf("Read without data must return immediately, got %d, %v", n, err)
+	}
+
+	if _, err := tr.Write([]byte{1, 2, 3}); err != nil {
+		t.Fatalf("Unable to write: %v", err)
+	}
+	data := make([]byte, 16)
+	peer.SetReadDeadline(time.Now().Add(time.Second))
+	n, from, err := peer.ReadFromUDP(data)
+	if err != nil || !bytes.Equal(data[:n], []byte{1, 2, 3}) {
+		t.Fatalf("Received %v, %v", data[:n], err)
+	}
+	if local := tr.LocalAddr(); local == nil || local.Port != from.Port {
+		t.Errorf("Unexpected local address %v, the datagram is sent from %v", local, from)
+	}
+
+	ready := tr.ReadyToRead()
+	if _, err := peer.WriteToUDP([]byte{4, 5}, from); err != nil {
+		t.Fatalf("Unable to write: %v", err)
+	}
+	select {
+	case <-ready:
+	case <-time.After(time.Second):
+		t.Fatal("The transport must be ready to read")
+	}
+	if n, err := tr.Read(data); err != nil || !bytes.Equal(data[:n], []byte{4, 5}) {
+		t.Errorf("Read %v, %v", data[:n], err)
+	}
+
+	tr.Close()
+	if _, err := tr.Write([]byte{1}); err != defs.ErrNotOpen {
+		t.Errorf("Write of closed transport must fail with ErrNotOpen, got %v", err)
+	}
+}
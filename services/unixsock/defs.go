@@ -0,0 +1,19 @@
+package unixsock
+
+import "github.com/stas-makutin/howeve/defs"
+
+// Unix domain socket transport parameters names
+const (
+	ParamNameConnectTimeout = "connectTimeout"
+)
+
+var TransportInfo *defs.TransportInfo = &defs.TransportInfo{
+	Name: "Unix",
+	Params: defs.Params{
+		ParamNameConnectTimeout: {
+			Description:  "The connect timeout, milliseconds",
+			Type:         defs.ParamTypeUint32,
+			DefaultValue: "5000",
+		},
+	},
+}
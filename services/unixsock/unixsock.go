@@ -0,0 +1,74 @@
+package unixsock
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/stream"
+)
+
+// Transport struct - Unix domain socket Transport implementation, the entry is the path of the stream socket
+type Transport struct {
+	conn   net.Conn
+	lock   sync.RWMutex
+	reader stream.Reader
+}
+
+func (t *Transport) ID() api.TransportIdentifier {
+	return api.TransportUnix
+}
+
+// Open func
+func (t *Transport) Open(entry string, params api.ParamValues) (err error) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if v, ok := params[ParamNameConnectTimeout]; ok {
+		dialer.Timeout = time.Duration(v.(uint32)) * time.Millisecond
+	}
+
+	t.Close()
+
+	conn, err := dialer.Dial("unix", entry)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.conn = conn
+	t.reader.Start(conn)
+	return nil
+}
+
+// Close func
+func (t *Transport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	conn := t.conn
+	t.conn = nil
+	return t.reader.Stop(func() error {
+		return conn.Close()
+	})
+}
+
+// ReadyToRead function, singal in the channel if something could be read from the socket or socket state has changed
+func (t *Transport) ReadyToRead() <-chan struct{} {
+	return t.reader.ReadyToRead()
+}
+
+// Read func, returns immediately with the data received so far
+func (t *Transport) Read(p []byte) (int, error) {
+	return t.reader.Read(p)
+}
+
+// Write func
+func (t *Transport) Write(p []byte) (int, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.conn != nil {
+		return t.conn.Write(p)
+	}
+	return 0, defs.ErrNotOpen
+}
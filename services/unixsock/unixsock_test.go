@@ -0,0 +1,90 @@
+package unixsock
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+)
+
+func TestTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "howeve.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("Unix domain sockets are not available: %v", err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	tr := &Transport{}
+	if _, err := tr.Write([]byte{1}); err != defs.ErrNotOpen {
+		t.Errorf("Write to not open transport must fail with ErrNotOpen, got %v", err)
+	}
+	if err := tr.Open(path, api.ParamValues{ParamNameConnectTimeout: uint32(1000)}); err != nil {
+		t.Fatalf("Unable to open the transport: %v", err)
+	}
+	defer tr.Close()
+
+	var peer net.Conn
+	select {
+	case peer = <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("The connection is not accepted")
+	}
+	defer peer.Close()
+
+	if _, err := tr.Write([]byte{1, 2, 3}); err != nil {
+		t.Fatalf("Unable to write: %v", err)
+	}
+	data := make([]byte, 3)
+	peer.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := peer.Read(data); err != nil || !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Errorf("Received %v, %v", data, err)
+	}
+
+	peer.Write([]byte{4, 5, 6})
+	var received []byte
+	for deadline := time.Now().Add(time.Second); len(received) < 3 && time.Now().Before(deadline); {
+		select {
+		case <-tr.ReadyToRead():
+		case <-time.After(time.Second):
+			t.Fatal("ReadyToRead must signal when data received")
+		}
+		n, err := tr.Read(data)
+		if err != nil {
+			t.Fatalf("Unable to read: %v", err)
+		}
+		received = append(received, data[:n]...)
+	}
+	if !bytes.Equal(received, []byte{4, 5, 6}) {
+		t.Errorf("Received %v", received)
+	}
+
+	peer.Close()
+	select {
+	case <-tr.ReadyToRead():
+	case <-time.After(time.Second):
+		t.Fatal("ReadyToRead must signal when connection closed")
+	}
+	if _, err := tr.Read(data); err == nil {
+		t.Error("Read of closed connection must fail")
+	}
+}
+
+func TestConnectFailure(t *testing.T) {
+	tr := &Transport{}
+	if err := tr.Open(filepath.Join(t.TempDir(), "missing.sock"), nil); err == nil {
+		tr.Close()
+		t.Error("Connect to not existing socket must fail")
+	}
+}
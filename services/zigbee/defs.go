@@ -0,0 +1,18 @@
+package zigbee
+
+// Zigbee parameters names
+const (
+	ParamNameNetworkMode   = "networkMode"
+	ParamNameChannel       = "channel"
+	ParamNamePanID         = "panId"
+	ParamNameExtendedPanID = "extendedPanId"
+	ParamNameNetworkKey    = "networkKey"
+	ParamNamePermitJoin    = "permitJoin"
+)
+
+// network mode parameter values
+const (
+	NetworkModeCoordinator = "coordinator" // form the new network if NCP is not joined to any
+	NetworkModeRouter      = "router"      // join the existing network if NCP is not joined to any
+	NetworkModeExisting    = "existing"    // only resume the network NCP is joined to
+)
@@ -0,0 +1,173 @@
+package zigbee
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/albenik/go-serial/v2/enumerator"
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/serial"
+	"github.com/stas-makutin/howeve/services/tcp"
+	zb "github.com/stas-makutin/howeve/zigbee"
+)
+
+// serial parameters, must match with default service parameters
+var discoverSerialParams api.ParamValues = api.ParamValues{
+	serial.ParamNameBaudRate:     int32(115200),
+	serial.ParamNameDataBits:     "8",
+	serial.ParamNameParity:       "none",
+	serial.ParamNameStopBits:     "1",
+	serial.ParamNameReadTimeout:  uint32(0),
+	serial.ParamNameWriteTimeout: uint32(0),
+}
+
+// the number of NCPs probed at the same time
+const maxDiscoveryWorkers = 4
+
+// DiscoverSerial - discover COM ports with EmberZNet NCPs, the ports are probed concurrently
+func DiscoverSerial(ctx context.Context, params api.ParamValues) ([]*api.DiscoveryEntry, error) {
+	ports, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return nil, err
+	}
+	if len(ports) <= 0 {
+		return nil, nil
+	}
+
+	names := make([]string, len(ports))
+	for i, port := range ports {
+		names[i] = port.Name
+	}
+	return defs.DiscoverConcurrently(ctx, names, maxDiscoveryWorkers, func(i int) *api.DiscoveryEntry {
+		port := ports[i]
+		ok, info := discoverNCP(ctx, &serial.Transport{}, port.Name, discoverSerialParams)
+		if !ok {
+			return nil
+		}
+		if info != "" {
+			info = " [" + info + "]"
+		}
+		entry := &api.DiscoveryEntry{
+			ServiceKey: api.ServiceKey{
+				Protocol:  api.ProtocolZigbee,
+				Transport: api.TransportSerial,
+				Entry:     port.Name,
+			},
+			Description: port.Product + info,
+		}
+		if port.IsUSB && port.SerialNumber != "" {
+			// refer to the USB device instead of the port name which may change after the device is replugged
+			entry.Entry = serial.USBEntry(port.VID, port.PID, port.SerialNumber)
+			entry.Description += " " + port.Name
+		}
+		return entry
+	}), nil
+}
+
+// DiscoverTCP - discover TCP serial bridges with EmberZNet NCPs, the bridges are found over mDNS or SSDP and probed concurrently
+func DiscoverTCP(ctx context.Context, params api.ParamValues) ([]*api.DiscoveryEntry, error) {
+	endpoints, err := tcp.DiscoverEndpoints(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		names[i] = endpoint.Entry
+	}
+	return defs.DiscoverConcurrently(ctx, names, maxDiscoveryWorkers, func(i int) *api.DiscoveryEntry {
+		endpoint := endpoints[i]
+		ok, info := discoverNCP(ctx, &tcp.Transport{}, endpoint.Entry, nil)
+		if !ok {
+			return nil
+		}
+		if info != "" {
+			info = " [" + info + "]"
+		}
+		return &api.DiscoveryEntry{
+			ServiceKey: api.ServiceKey{
+				Protocol:  api.ProtocolZigbee,
+				Transport: api.TransportTCP,
+				Entry:     endpoint.Entry,
+			},
+			Description: endpoint.Description + info,
+		}
+	}), nil
+}
+
+// discoverNCP verifies if there is EmberZNet NCP behind the transport entry: it must respond to ASH reset.
+// Returns true if NCP is found, along with the stack and EZSP versions if NCP responds to the version command
+func discoverNCP(ctx context.Context, t defs.Transport, entry string, params api.ParamValues) (bool, string) {
+	if err := t.Open(entry, params); err != nil {
+		return false, ""
+	}
+	defer t.Close()
+
+	r := &discoverReader{t: t, buffer: make([]byte, 256)}
+	if t.Write(zb.ASHResetFrame()); !r.expect(ctx, zb.ASHResetAck) {
+		return false, ""
+	}
+
+	info := ""
+	if t.Write(zb.ASHDataFrame(0, 0, false, zb.EZSPCommand(0, 0, zb.EZSP_VERSION, []byte{ezspVersion}))); r.expect(ctx, zb.ASHData) {
+		// acknowledge the response so NCP will not retransmit it
+		t.Write(zb.ASHAckFrame(1))
+		if f, err := zb.ParseEZSPFrame(0, r.frame.Data); err == nil && f.FrameID == zb.EZSP_VERSION {
+			if v, ok := zb.ParseVersion(f.Parameters); ok {
+				info = "EmberZNet " + v.StackVersionString() + " EZSP v" + strconv.Itoa(int(v.ProtocolVersion))
+			}
+		}
+	}
+	return true, info
+}
+
+// discoverReader reads ASH frames during the discovery
+type discoverReader struct {
+	t      defs.Transport
+	buffer []byte
+	rb, re int
+	frame  *zb.ASHFrame
+}
+
+// expect reads the frames until the frame of provided type is received, returns false on timeout or error
+func (r *discoverReader) expect(ctx context.Context, frameType zb.ASHFrameType) bool {
+	deadline := time.After(ashResetTimeout)
+	for {
+		for r.rb < r.re {
+			content, n := zb.SplitASHFrame(r.buffer[r.rb:r.re])
+			if n == 0 {
+				break
+			}
+			r.rb += n
+			if f, err := zb.ParseASHFrame(content); err == nil && f.Type == frameType {
+				r.frame = f
+				return true
+			}
+		}
+		if r.rb == r.re {
+			r.rb, r.re = 0, 0
+		} else if r.rb > 0 {
+			r.re = copy(r.buffer, r.buffer[r.rb:r.re])
+			r.rb = 0
+		}
+		if r.re == len(r.buffer) {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline:
+			return false
+		case <-r.t.ReadyToRead():
+		}
+
+		n, err := r.t.Read(r.buffer[r.re:])
+		if err != nil {
+			return false
+		}
+		r.re += n
+	}
+}
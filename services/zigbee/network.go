@@ -0,0 +1,248 @@
+package zigbee
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	zb "github.com/stas-makutin/howeve/zigbee"
+)
+
+// the EZSP protocol version requested from NCP, and the range of versions supported
+const (
+	ezspVersion    = 8
+	ezspMinVersion = 4
+	ezspMaxVersion = 13
+)
+
+// the time to wait for the network to be up
+const (
+	networkUpTimeout = 10 * time.Second
+	joinTimeout      = 30 * time.Second
+)
+
+// the delay before the next initialization attempt if the network initialization fails
+const initRetryDelay = 10 * time.Second
+
+// the endpoint of the service on NCP
+const localEndpoint = 1
+
+// the device ID of the service endpoint: configuration tool
+const localDeviceID = 0x0005
+
+// the well-known Zigbee 3.0 trust center link key, "ZigBeeAlliance09"
+var globalLinkKey = [16]byte{0x5a, 0x69, 0x67, 0x42, 0x65, 0x65, 0x41, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x63, 0x65, 0x30, 0x39}
+
+var errNoNetwork = errors.New("NCP is not joined to the network")
+
+// initNetwork negotiates EZSP version, registers the service endpoint, and resumes, forms or joins the network
+func (svc *Service) initNetwork(ctx context.Context) {
+	defer svc.stopWg.Done()
+
+	if err := svc.setupNetwork(ctx); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		svc.status.Store(fmt.Errorf("unable to initialize the network: %s", err.Error()))
+		svc.log(zbOcInitialize, zbOsFailure, err.Error())
+		select {
+		case <-ctx.Done():
+		case <-time.After(initRetryDelay):
+			svc.requestLinkReset()
+		}
+	}
+}
+
+func (svc *Service) setupNetwork(ctx context.Context) error {
+	version, err := svc.negotiateVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.statusCommand(ctx, zb.EZSP_ADD_ENDPOINT, zb.AddEndpoint(localEndpoint, zb.ProfileHomeAutomation, localDeviceID,
+		[]uint16{zb.ClusterBasic},
+		[]uint16{zb.ClusterOnOff, zb.ClusterLevel, zb.ClusterTemperature, zb.ClusterIASZone},
+	)); err != nil {
+		return fmt.Errorf("unable to add the endpoint: %w", err)
+	}
+
+	svc.drainStackStatus()
+	err = svc.statusCommand(ctx, zb.EZSP_NETWORK_INIT, zb.NetworkInit(version))
+	if err == nil {
+		err = svc.waitNetworkUp(ctx, networkUpTimeout)
+	} else if errors.Is(err, emberError(zb.EMBER_NOT_JOINED)) {
+		switch mode, _ := svc.params[ParamNameNetworkMode].(string); mode {
+		case NetworkModeExisting:
+			err = errNoNetwork
+		case NetworkModeRouter:
+			err = svc.joinNetwork(ctx)
+		default:
+			err = svc.formNetwork(ctx)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	response, err := svc.command(ctx, zb.EZSP_GET_NETWORK_PARAMETERS, nil)
+	if err != nil {
+		return err
+	}
+	_, nodeType, p, ok := zb.ParseNetworkParameters(response)
+	if !ok {
+		return errBadResponse
+	}
+	svc.log(zbOcInitialize, zbOsSuccess, strconv.Itoa(int(version)), strconv.Itoa(int(nodeType)),
+		fmt.Sprintf("%04x", p.PanID), fmt.Sprintf("%016x", p.ExtendedPanID), strconv.Itoa(int(p.RadioChannel)))
+
+	if duration, _ := svc.params[ParamNamePermitJoin].(uint8); duration > 0 {
+		if err := svc.statusCommand(ctx, zb.EZSP_PERMIT_JOINING, zb.PermitJoining(duration)); err != nil {
+			svc.log(zbOcJoin, zbOsFailure, err.Error())
+		}
+	}
+	return nil
+}
+
+// negotiateVersion requests the preferred EZSP version and switches to the one supported by NCP if it differs
+func (svc *Service) negotiateVersion(ctx context.Context) (uint8, error) {
+	desired := uint8(ezspVersion)
+	for attempt := 0; attempt < 2; attempt++ {
+		response, err := svc.command(ctx, zb.EZSP_VERSION, []byte{desired})
+		if err != nil {
+			return 0, err
+		}
+		info, ok := zb.ParseVersion(response)
+		if !ok {
+			return 0, errBadResponse
+		}
+		if info.ProtocolVersion < ezspMinVersion || info.ProtocolVersion > ezspMaxVersion {
+			return 0, fmt.Errorf("EZSP version %d is not supported", info.ProtocolVersion)
+		}
+		svc.version.Store(info.ProtocolVersion)
+		if info.ProtocolVersion == desired {
+			return desired, nil
+		}
+		// NCP accepts the commands only after the version command with its own version
+		desired = info.ProtocolVersion
+	}
+	return 0, errBadResponse
+}
+
+func (svc *Service) drainStackStatus() {
+	for {
+		select {
+		case <-svc.stackStatus:
+		default:
+			return
+		}
+	}
+}
+
+// waitNetworkUp waits for the stack status callback which reports the network is up
+func (svc *Service) waitNetworkUp(ctx context.Context, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return errors.New("the network is not up")
+		case status := <-svc.stackStatus:
+			switch status {
+			case zb.EMBER_NETWORK_UP:
+				return nil
+			case zb.EMBER_JOIN_FAILED, zb.EMBER_NOT_JOINED:
+				return emberError(status)
+			}
+		}
+	}
+}
+
+// networkParameters builds the network parameters from the service parameters, the missing values are random
+func (svc *Service) networkParameters() (*zb.NetworkParameters, error) {
+	p := &zb.NetworkParameters{RadioTxPower: 8, RadioChannel: 15}
+	if channel, ok := svc.params[ParamNameChannel].(uint8); ok {
+		if channel < 11 || channel > 26 {
+			return nil, fmt.Errorf("channel %d is not valid, expected 11 - 26", channel)
+		}
+		p.RadioChannel = channel
+	}
+	p.Channels = 1 << p.RadioChannel
+
+	var random [10]byte
+	rand.Read(random[:])
+	p.PanID, _ = svc.params[ParamNamePanID].(uint16)
+	if p.PanID == 0 || p.PanID == 0xffff {
+		p.PanID = binary.LittleEndian.Uint16(random[:]) & 0x3fff
+	}
+	if v, _ := svc.params[ParamNameExtendedPanID].(string); v != "" {
+		b, err := hex.DecodeString(v)
+		if err != nil || len(b) != 8 {
+			return nil, fmt.Errorf("extended PAN ID '%s' is not valid, expected 16 hexadecimal digits", v)
+		}
+		p.ExtendedPanID = binary.BigEndian.Uint64(b)
+	} else {
+		p.ExtendedPanID = binary.LittleEndian.Uint64(random[2:])
+	}
+	return p, nil
+}
+
+// networkKey returns the network key from the service parameters or the random one
+func (svc *Service) networkKey() (key [16]byte, err error) {
+	if v, _ := svc.params[ParamNameNetworkKey].(string); v != "" {
+		b, err := hex.DecodeString(v)
+		if err != nil || len(b) != len(key) {
+			return key, fmt.Errorf("network key is not valid, expected 32 hexadecimal digits")
+		}
+		copy(key[:], b)
+	} else {
+		rand.Read(key[:])
+	}
+	return
+}
+
+// formNetwork forms the new network with NCP as the coordinator and the trust center
+func (svc *Service) formNetwork(ctx context.Context) error {
+	p, err := svc.networkParameters()
+	if err != nil {
+		return err
+	}
+	key, err := svc.networkKey()
+	if err != nil {
+		return err
+	}
+	bitmask := uint16(zb.EMBER_HAVE_PRECONFIGURED_KEY | zb.EMBER_HAVE_NETWORK_KEY | zb.EMBER_REQUIRE_ENCRYPTED_KEY | zb.EMBER_TRUST_CENTER_GLOBAL_LINK_KEY)
+	if err := svc.statusCommand(ctx, zb.EZSP_SET_INITIAL_SECURITY_STATE, zb.InitialSecurityState(bitmask, globalLinkKey, key)); err != nil {
+		return fmt.Errorf("unable to set the security state: %w", err)
+	}
+	svc.drainStackStatus()
+	if err := svc.statusCommand(ctx, zb.EZSP_FORM_NETWORK, zb.FormNetwork(p)); err != nil {
+		return fmt.Errorf("unable to form the network: %w", err)
+	}
+	return svc.waitNetworkUp(ctx, networkUpTimeout)
+}
+
+// joinNetwork joins the existing network as the router
+func (svc *Service) joinNetwork(ctx context.Context) error {
+	p, err := svc.networkParameters()
+	if err != nil {
+		return err
+	}
+	if v, _ := svc.params[ParamNameExtendedPanID].(string); v == "" {
+		p.ExtendedPanID = 0 // join any network on the channel
+	}
+	bitmask := uint16(zb.EMBER_HAVE_PRECONFIGURED_KEY | zb.EMBER_REQUIRE_ENCRYPTED_KEY | zb.EMBER_TRUST_CENTER_GLOBAL_LINK_KEY)
+	if err := svc.statusCommand(ctx, zb.EZSP_SET_INITIAL_SECURITY_STATE, zb.InitialSecurityState(bitmask, globalLinkKey, [16]byte{})); err != nil {
+		return fmt.Errorf("unable to set the security state: %w", err)
+	}
+	svc.drainStackStatus()
+	if err := svc.statusCommand(ctx, zb.EZSP_JOIN_NETWORK, zb.JoinNetwork(zb.EMBER_ROUTER, p)); err != nil {
+		return fmt.Errorf("unable to join the network: %w", err)
+	}
+	return svc.waitNetworkUp(ctx, joinTimeout)
+}
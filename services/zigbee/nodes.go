@@ -0,0 +1,136 @@
+package zigbee
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stas-makutin/howeve/api"
+	zb "github.com/stas-makutin/howeve/zigbee"
+)
+
+// APS options of the messages sent by the service: retry and enable route discovery
+const apsOptions = 0x0040 | 0x0100
+
+// nodeInfo contains the information about the node of Zigbee network
+type nodeInfo struct {
+	id       uint16
+	eui64    uint64
+	clusters map[uint16]struct{}
+}
+
+// nodeInventory is the list of Zigbee network nodes which joined the network or sent messages
+type nodeInventory struct {
+	lock  sync.RWMutex
+	nodes map[uint16]*nodeInfo
+}
+
+func (ni *nodeInventory) node(id uint16) *nodeInfo {
+	if ni.nodes == nil {
+		ni.nodes = make(map[uint16]*nodeInfo)
+	}
+	node, ok := ni.nodes[id]
+	if !ok {
+		node = &nodeInfo{id: id, clusters: make(map[uint16]struct{})}
+		ni.nodes[id] = node
+	}
+	return node
+}
+
+// join adds the node which joined the network
+func (ni *nodeInventory) join(id uint16, eui64 uint64) {
+	ni.lock.Lock()
+	defer ni.lock.Unlock()
+	ni.node(id).eui64 = eui64
+}
+
+// remove removes the node which left the network
+func (ni *nodeInventory) remove(id uint16) {
+	ni.lock.Lock()
+	defer ni.lock.Unlock()
+	delete(ni.nodes, id)
+}
+
+// seen records the cluster the node sent the message from
+func (ni *nodeInventory) seen(id uint16, cluster uint16) {
+	ni.lock.Lock()
+	defer ni.lock.Unlock()
+	ni.node(id).clusters[cluster] = struct{}{}
+}
+
+// list returns the list of nodes sorted by the node identifier, the clusters are reported as command classes
+func (ni *nodeInventory) list() []*api.NodeInfo {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	nodes := make([]*api.NodeInfo, 0, len(ni.nodes))
+	for _, node := range ni.nodes {
+		info := &api.NodeInfo{ID: node.id}
+		for cluster := range node.clusters {
+			info.CommandClasses = append(info.CommandClasses, cluster)
+		}
+		sort.Slice(info.CommandClasses, func(i, j int) bool { return info.CommandClasses[i] < info.CommandClasses[j] })
+		nodes = append(nodes, info)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// Nodes is the implementation of defs.NodeLister interface
+func (svc *Service) Nodes() []*api.NodeInfo {
+	return svc.nodes.list()
+}
+
+// handleIncomingMessage decodes ZCL frame of the message received from the node
+func (svc *Service) handleIncomingMessage(m *zb.IncomingMessage) {
+	svc.nodes.seen(m.Sender, m.APS.ClusterID)
+	if m.APS.ProfileID != zb.ProfileHomeAutomation {
+		return
+	}
+	switch m.APS.ClusterID {
+	case zb.ClusterOnOff, zb.ClusterLevel, zb.ClusterTemperature, zb.ClusterIASZone:
+	default:
+		return
+	}
+
+	node := fmt.Sprintf("%04x", m.Sender)
+	cluster := fmt.Sprintf("%04x", m.APS.ClusterID)
+	f, err := zb.ParseZCLFrame(m.Message)
+	if err == nil {
+		var r *zb.Report
+		if r, err = zb.DecodeReport(m.APS.ClusterID, f); err == nil {
+			if !r.IsEmpty() {
+				svc.log(zbOcReport, node, cluster, r.String())
+			}
+			if r.ZoneEnroll {
+				// the enrollment is sent from another goroutine, the service loop can't wait for the response
+				svc.stopWg.Add(1)
+				go svc.enrollZone(svc.ctx, m.Sender, m.APS.SourceEndpoint)
+			}
+			return
+		}
+	}
+	svc.log(zbOcReport, node, cluster, zbOsError, err.Error())
+}
+
+// enrollZone responds to IAS zone enroll request, the node's zone ID is its short address
+func (svc *Service) enrollZone(ctx context.Context, node uint16, endpoint uint8) {
+	defer svc.stopWg.Done()
+
+	aps := &zb.APSFrame{
+		ProfileID:           zb.ProfileHomeAutomation,
+		ClusterID:           zb.ClusterIASZone,
+		SourceEndpoint:      localEndpoint,
+		DestinationEndpoint: endpoint,
+		Options:             apsOptions,
+	}
+	sequence := uint8(atomic.AddUint32(&svc.zclSequence, 1))
+	message := zb.ZCLCommand(true, sequence, zb.ZCLZoneEnrollResponse, zb.ZoneEnrollResponse(true, uint8(node)))
+	if err := svc.statusCommand(ctx, zb.EZSP_SEND_UNICAST, zb.SendUnicast(node, aps, sequence, message)); err != nil && ctx.Err() == nil {
+		svc.log(zbOcReport, fmt.Sprintf("%04x", node), fmt.Sprintf("%04x", zb.ClusterIASZone), zbOsFailure, err.Error())
+	} else if err == nil {
+		svc.log(zbOcReport, fmt.Sprintf("%04x", node), fmt.Sprintf("%04x", zb.ClusterIASZone), zbOsSuccess, "enrolled", strconv.Itoa(int(uint8(node))))
+	}
+}
@@ -0,0 +1,124 @@
+package zigbee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	zb "github.com/stas-makutin/howeve/zigbee"
+)
+
+// request timeouts
+const (
+	responseTimeout = 5 * time.Second
+)
+
+// request errors
+var (
+	errNoResponse     = errors.New("no response from NCP")
+	errInvalidCommand = errors.New("the command is rejected by NCP")
+	errBadResponse    = errors.New("the response of NCP is not valid")
+)
+
+// pendingRequest is EZSP command waiting for the response
+type pendingRequest struct {
+	sequence uint8
+	response chan *zb.EZSPFrame
+}
+
+// pendingRequests is the list of commands waiting for the NCP's response
+type pendingRequests struct {
+	lock    sync.Mutex
+	entries []*pendingRequest
+}
+
+func (pr *pendingRequests) add(sequence uint8) *pendingRequest {
+	r := &pendingRequest{sequence: sequence, response: make(chan *zb.EZSPFrame, 1)}
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	pr.entries = append(pr.entries, r)
+	return r
+}
+
+func (pr *pendingRequests) remove(r *pendingRequest) {
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	for i, entry := range pr.entries {
+		if entry == r {
+			pr.entries = append(pr.entries[:i], pr.entries[i+1:]...)
+			break
+		}
+	}
+}
+
+// response delivers the response frame to the pending request with the matching sequence, returns true if delivered
+func (pr *pendingRequests) response(f *zb.EZSPFrame) bool {
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	for _, entry := range pr.entries {
+		if entry.sequence == f.Sequence {
+			select {
+			case entry.response <- f:
+				return true
+			default:
+			}
+		}
+	}
+	return false
+}
+
+// nextSequence returns the next EZSP frame sequence number
+func (svc *Service) nextSequence() uint8 {
+	return uint8(atomic.AddUint32(&svc.sequence, 1))
+}
+
+// command sends EZSP command and waits for the response, returns the response parameters
+func (svc *Service) command(ctx context.Context, frameID uint16, parameters []byte) ([]byte, error) {
+	sequence := svc.nextSequence()
+	r := svc.pending.add(sequence)
+	defer svc.pending.remove(r)
+
+	if _, err := svc.enqueue(sequence, zb.EZSPPayload(frameID, parameters)); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(responseTimeout):
+		return nil, errNoResponse
+	case f := <-r.response:
+		if f.FrameID == zb.EZSP_INVALID_COMMAND {
+			return nil, errInvalidCommand
+		}
+		if f.FrameID != frameID {
+			return nil, fmt.Errorf("%w: frame id 0x%04x, expected 0x%04x", errBadResponse, f.FrameID, frameID)
+		}
+		return f.Parameters, nil
+	}
+}
+
+// statusCommand sends EZSP command which responds with Ember status byte, returns error if the status is not success
+func (svc *Service) statusCommand(ctx context.Context, frameID uint16, parameters []byte) error {
+	response, err := svc.command(ctx, frameID, parameters)
+	if err != nil {
+		return err
+	}
+	if len(response) < 1 {
+		return errBadResponse
+	}
+	if response[0] != zb.EMBER_SUCCESS {
+		return emberError(response[0])
+	}
+	return nil
+}
+
+// emberError is the not successful Ember status
+type emberError uint8
+
+func (e emberError) Error() string {
+	return fmt.Sprintf("Ember status 0x%02x", uint8(e))
+}
@@ -0,0 +1,477 @@
+package zigbee
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/log"
+	"github.com/stas-makutin/howeve/services/backoff"
+	"github.com/stas-makutin/howeve/services/serial"
+	"github.com/stas-makutin/howeve/utils/syncutil"
+	zb "github.com/stas-makutin/howeve/zigbee"
+)
+
+// log constants
+const (
+	// operation
+	zbOpService = "ZB"
+
+	zbOcTransportOpen  = "O"
+	zbOcTransportRead  = "R"
+	zbOcTransportWrite = "W"
+	zbOcReset          = "S"
+	zbOcAckTimeout     = "T"
+	zbOcFrame          = "D"
+	zbOcInitialize     = "I"
+	zbOcStackStatus    = "N"
+	zbOcJoin           = "J"
+	zbOcReport         = "Z"
+
+	zbOsSuccess = "0"
+	zbOsFailure = "F"
+	zbOsGaveUp  = "G"
+	zbOsError   = "E"
+)
+
+// ASH timings
+const (
+	ashResetTimeout = 3200 * time.Millisecond // the maximal time to wait for RSTACK
+	ashAckTimeout   = 1600 * time.Millisecond // the time to wait for DATA frame acknowledgement
+	ashMaxRetries   = 3                       // the number of DATA frame retransmissions before the link is reset
+)
+
+// outgoing is EZSP command waiting to be sent
+type outgoing struct {
+	message  *api.Message
+	sequence uint8
+	payload  []byte // frame ID (2 bytes, little-endian) and parameters
+}
+
+// unacknowledged is DATA frame sent and not acknowledged by NCP yet
+type unacknowledged struct {
+	frmNum  uint8
+	data    []byte
+	retries int
+	sent    time.Time
+}
+
+// Service Zigbee service implementation, works with Silicon Labs EmberZNet NCP using EZSP over ASH
+type Service struct {
+	transport defs.Transport
+	key       *api.ServiceKey
+	params    api.ParamValues
+
+	sendQueue chan *outgoing
+	linkReset chan struct{}
+
+	status syncutil.RLocked[error]
+
+	sequence    uint32
+	zclSequence uint32
+	pending     pendingRequests
+	version     syncutil.RLocked[uint8]
+	stackStatus chan uint8
+	nodes       nodeInventory
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stopWg sync.WaitGroup
+}
+
+// NewService creates new Zigbee service implementation using provided transport
+func NewService(transport defs.Transport, entry string, params api.ParamValues) (defs.Service, error) {
+	pv := serial.ServiceParams(transport, params)
+
+	return &Service{
+		transport:   transport,
+		key:         &api.ServiceKey{Protocol: api.ProtocolZigbee, Transport: transport.ID(), Entry: entry},
+		params:      pv,
+		sendQueue:   make(chan *outgoing, 10),
+		linkReset:   make(chan struct{}, 1),
+		stackStatus: make(chan uint8, 4),
+	}, nil
+}
+
+func (svc *Service) Start() {
+	svc.Stop()
+
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+
+	svc.stopWg.Add(1)
+	go svc.serviceLoop()
+}
+
+func (svc *Service) Stop() {
+	if svc.ctx == nil {
+		return // already stopped
+	}
+
+	svc.cancel()
+	svc.stopWg.Wait()
+
+PurgeLoop:
+	for {
+		select {
+		default:
+			break PurgeLoop
+		case o := <-svc.sendQueue:
+			defs.Messages.UpdateState(o.message.ID, api.OutgoingRejected)
+		}
+	}
+
+	svc.ctx, svc.cancel = nil, nil
+
+	svc.status.Store(defs.ErrStatusGood)
+}
+
+func (svc *Service) Status() defs.ServiceStatus {
+	err := svc.status.Load()
+	if err == nil {
+		return defs.ServiceStatus(defs.ErrStatusGood)
+	}
+	return err.(defs.ServiceStatus)
+}
+
+// ResolvedEntry returns the actual entry the transport is opened with, if the transport resolves the service entry
+func (svc *Service) ResolvedEntry() string {
+	if r, ok := svc.transport.(defs.EntryResolver); ok {
+		return r.ResolvedEntry()
+	}
+	return ""
+}
+
+// Send sends EZSP command to NCP, the payload is the frame ID (2 bytes, little-endian) followed by the command parameters.
+// The sequence number and the frame control are added by the service.
+func (svc *Service) Send(payload []byte) (*api.Message, error) {
+	if !validPayload(payload) {
+		return nil, defs.ErrBadPayload
+	}
+	return svc.enqueue(svc.nextSequence(), payload)
+}
+
+// validPayload verifies the command payload fits into DATA frame
+func validPayload(payload []byte) bool {
+	_, _, ok := zb.ParseEZSPPayload(payload)
+	return ok && len(payload)+3 <= zb.ASHMaxDataLength
+}
+
+func (svc *Service) enqueue(sequence uint8, payload []byte) (*api.Message, error) {
+	if !validPayload(payload) {
+		return nil, defs.ErrBadPayload
+	}
+
+	o := &outgoing{sequence: sequence, payload: payload}
+	o.message = defs.Messages.Register(svc.key, payload, api.OutgoingPending)
+
+QueueLoop:
+	for {
+		select {
+		default:
+			if svc.Status() == defs.ErrStatusGood {
+				defs.Messages.UpdateState(o.message.ID, api.OutgoingRejected)
+				return o.message, defs.ErrSendBusy
+			}
+			// purge message queue if service is unhealthy
+			queued := <-svc.sendQueue
+			defs.Messages.UpdateState(queued.message.ID, api.OutgoingRejected)
+		case svc.sendQueue <- o:
+			break QueueLoop
+		}
+	}
+
+	return o.message, nil
+}
+
+// requestLinkReset asks the service loop to reset ASH link, which reinitializes the network
+func (svc *Service) requestLinkReset() {
+	select {
+	case svc.linkReset <- struct{}{}:
+	default:
+	}
+}
+
+func (svc *Service) log(op string, fields ...string) {
+	log.Report(append([]string{
+		log.SrcSVC,
+		zbOpService,
+		op,
+		defs.ProtocolName(svc.key.Protocol),
+		defs.TransportName(svc.key.Transport),
+		svc.key.Entry,
+	}, fields...)...)
+}
+
+// write writes the frame, returns false and updates the service status on failure. The frame dropped while the transport
+// is held by the remote side is recovered by the ASH retransmission, the transport is not failed
+func (svc *Service) write(frame []byte) bool {
+	if n, err := svc.transport.Write(frame); err != nil || n != len(frame) {
+		if err == nil {
+			err = fmt.Errorf("%d bytes of %d written", n, len(frame))
+		}
+		svc.log(zbOcTransportWrite, zbOsFailure, err.Error())
+		if errors.Is(err, defs.ErrTransportHeld) {
+			return true
+		}
+		svc.status.Store(fmt.Errorf("unable to write using transport: %s", err.Error()))
+		return false
+	}
+	return true
+}
+
+func (svc *Service) serviceLoop() {
+	defer svc.transport.Close()
+	defer svc.stopWg.Done()
+
+	openBackoff := backoff.New(backoff.NewPolicy(svc.params))
+	open := true
+	buffer := make([]byte, 4096)
+	rb, re := 0, 0
+
+	// ASH link state
+	connected := false
+	var resetDeadline time.Time
+	var frmNum, ackNum uint8
+	var pendingData *unacknowledged
+	var initCancel context.CancelFunc
+	defer func() {
+		if initCancel != nil {
+			initCancel()
+		}
+	}()
+
+	reset := func() bool {
+		connected, pendingData = false, nil
+		resetDeadline = time.Now().Add(ashResetTimeout)
+		svc.version.Store(0)
+		return svc.write(zb.ASHResetFrame())
+	}
+	// acknowledged handles the acknowledgement number received from NCP
+	acknowledged := func(n uint8) {
+		if pendingData != nil && n == (pendingData.frmNum+1)%zb.ASHFrameNumbers {
+			pendingData = nil
+		}
+	}
+	retransmit := func() bool {
+		pendingData.retries++
+		pendingData.sent = time.Now()
+		return svc.write(zb.ASHDataFrame(pendingData.frmNum, ackNum, true, pendingData.data))
+	}
+
+ServiceLoop:
+	for {
+		if open {
+			open = false
+			rb, re = 0, 0
+			if err := svc.transport.Open(svc.key.Entry, svc.params); err != nil {
+				if !openBackoff.Retry(svc.ctx, svc.key, &svc.status, "unable to open transport", err, func(gaveUp bool) {
+					if gaveUp {
+						svc.log(zbOcTransportOpen, zbOsGaveUp, err.Error())
+					} else {
+						svc.log(zbOcTransportOpen, zbOsFailure, err.Error())
+					}
+				}) {
+					break ServiceLoop
+				}
+				open = true
+				continue
+			}
+			openBackoff.Reset()
+			svc.log(zbOcTransportOpen, zbOsSuccess)
+			svc.status.Store(defs.ErrStatusGood)
+			if !reset() {
+				open = true
+				continue
+			}
+		}
+
+		// the time of the next link event: RSTACK deadline or DATA frame retransmission
+		var timeout <-chan time.Time
+		if !connected {
+			timeout = time.After(time.Until(resetDeadline))
+		} else if pendingData != nil {
+			timeout = time.After(time.Until(pendingData.sent.Add(ashAckTimeout)))
+		}
+		var sendQueue chan *outgoing
+		if connected && pendingData == nil {
+			sendQueue = svc.sendQueue
+		}
+
+		select {
+		case <-svc.ctx.Done():
+			break ServiceLoop
+		case <-svc.linkReset:
+			open = !reset()
+			continue
+		case <-timeout:
+			if !connected {
+				svc.status.Store(errors.New("no response from NCP to ASH reset"))
+				svc.log(zbOcReset, zbOsFailure)
+				svc.transport.Close()
+				open = true
+			} else if pendingData.retries < ashMaxRetries {
+				svc.log(zbOcAckTimeout, strconv.Itoa(int(pendingData.frmNum)))
+				open = !retransmit()
+			} else {
+				svc.log(zbOcAckTimeout, strconv.Itoa(int(pendingData.frmNum)), zbOsFailure)
+				open = !reset()
+			}
+			continue
+		case o := <-sendQueue:
+			data := zb.EZSPCommand(svc.version.Load(), o.sequence, uint16(o.payload[0])|uint16(o.payload[1])<<8, o.payload[2:])
+			pendingData = &unacknowledged{frmNum: frmNum, data: data, sent: time.Now()}
+			frmNum = (frmNum + 1) % zb.ASHFrameNumbers
+			if svc.write(zb.ASHDataFrame(pendingData.frmNum, ackNum, false, data)) {
+				defs.Messages.UpdateState(o.message.ID, api.Outgoing)
+			} else {
+				defs.Messages.UpdateState(o.message.ID, api.OutgoingFailed)
+				open = true
+			}
+			continue
+		case <-svc.transport.ReadyToRead():
+		}
+
+		if rb == re {
+			rb, re = 0, 0
+		} else if re == len(buffer) {
+			// the buffer is full of garbage without the flag byte
+			svc.log(zbOcFrame, zbOsError, hex.EncodeToString(buffer[:20]))
+			rb, re = 0, 0
+		}
+		n, err := svc.transport.Read(buffer[re:])
+		if errors.Is(svc.ctx.Err(), context.Canceled) {
+			break ServiceLoop
+		}
+		if err != nil {
+			svc.status.Store(fmt.Errorf("unable to read using transport: %s", err.Error()))
+			svc.log(zbOcTransportRead, zbOsFailure, err.Error())
+			open = true
+			continue
+		}
+		re += n
+
+		for rb < re && !open {
+			content, n := zb.SplitASHFrame(buffer[rb:re])
+			if n == 0 {
+				if rb > 0 {
+					// keep the incomplete frame at the beginning of the buffer
+					re = copy(buffer, buffer[rb:re])
+					rb = 0
+				}
+				break
+			}
+			rb += n
+			if len(content) == 0 {
+				continue
+			}
+
+			f, err := zb.ParseASHFrame(content)
+			if err != nil {
+				svc.log(zbOcFrame, zbOsError, err.Error(), hex.EncodeToString(content))
+				if connected {
+					open = !svc.write(zb.ASHNakFrame(ackNum))
+				}
+				continue
+			}
+
+			switch f.Type {
+			case zb.ASHResetAck:
+				connected, frmNum, ackNum, pendingData = true, 0, 0, nil
+				svc.log(zbOcReset, zbOsSuccess, strconv.Itoa(int(f.Data[1])))
+				svc.status.Store(defs.ErrStatusGood)
+
+				if initCancel != nil {
+					initCancel()
+				}
+				initCancel = svc.startInit()
+
+			case zb.ASHError:
+				svc.log(zbOcReset, zbOsError, strconv.Itoa(int(f.Data[1])))
+				open = !reset()
+
+			case zb.ASHAck:
+				if connected {
+					acknowledged(f.AckNum)
+				}
+
+			case zb.ASHNak:
+				if connected && pendingData != nil && f.AckNum == pendingData.frmNum {
+					open = !retransmit()
+				}
+
+			case zb.ASHData:
+				if !connected {
+					break
+				}
+				acknowledged(f.AckNum)
+				if f.FrmNum != ackNum {
+					// out of sequence: acknowledge the retransmitted frame again or ask for the missing one
+					if f.ReTx {
+						open = !svc.write(zb.ASHAckFrame(ackNum))
+					} else {
+						open = !svc.write(zb.ASHNakFrame(ackNum))
+					}
+					break
+				}
+				ackNum = (ackNum + 1) % zb.ASHFrameNumbers
+				if open = !svc.write(zb.ASHAckFrame(ackNum)); open {
+					break
+				}
+				defs.Messages.Register(svc.key, f.Data, api.Incoming)
+				svc.handleEZSPFrame(f.Data)
+			}
+		}
+	}
+}
+
+// startInit starts the network initialization, returns the function which cancels it
+func (svc *Service) startInit() context.CancelFunc {
+	ctx, cancel := context.WithCancel(svc.ctx)
+	svc.stopWg.Add(1)
+	go svc.initNetwork(ctx)
+	return cancel
+}
+
+// handleEZSPFrame processes EZSP frame received from NCP
+func (svc *Service) handleEZSPFrame(data []byte) {
+	f, err := zb.ParseEZSPFrame(svc.version.Load(), data)
+	if err != nil {
+		svc.log(zbOcFrame, zbOsError, err.Error(), hex.EncodeToString(data))
+		return
+	}
+	if !f.IsCallback() {
+		svc.pending.response(f)
+		return
+	}
+
+	switch f.FrameID {
+	case zb.EZSP_STACK_STATUS_HANDLER:
+		if len(f.Parameters) < 1 {
+			return
+		}
+		svc.log(zbOcStackStatus, fmt.Sprintf("%02x", f.Parameters[0]))
+		select {
+		case svc.stackStatus <- f.Parameters[0]:
+		default:
+		}
+	case zb.EZSP_TRUST_CENTER_JOIN_HANDLER:
+		if j, ok := zb.ParseTrustCenterJoin(f.Parameters); ok {
+			svc.log(zbOcJoin, fmt.Sprintf("%04x", j.NodeID), fmt.Sprintf("%016x", j.EUI64), strconv.Itoa(int(j.Status)))
+			if j.Status == zb.EMBER_DEVICE_LEFT {
+				svc.nodes.remove(j.NodeID)
+			} else {
+				svc.nodes.join(j.NodeID, j.EUI64)
+			}
+		}
+	case zb.EZSP_INCOMING_MESSAGE_HANDLER:
+		if m, ok := zb.ParseIncomingMessage(f.Parameters); ok {
+			svc.handleIncomingMessage(m)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package zigbee
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/services/mock"
+	zb "github.com/stas-makutin/howeve/zigbee"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(mock.RunTests(m))
+}
+
+// ncpScript builds the mock transport script of NCP conversation, tracking ASH frame numbers and EZSP sequences
+type ncpScript struct {
+	m       *mock.Transport
+	version uint8
+	seq     uint8
+	hostFrm uint8
+	ncpFrm  uint8
+}
+
+func newNCPScript() *ncpScript {
+	s := &ncpScript{m: mock.New()}
+	s.m.Expect(zb.ASHResetFrame()).Reply(zb.ASHResetAckFrame(zb.ASHResetSoftware))
+	return s
+}
+
+// ncpFrame creates EZSP response or callback frame
+func ncpFrame(version, sequence uint8, frameID uint16, parameters []byte, callback bool) []byte {
+	frame := zb.EZSPCommand(version, sequence, frameID, parameters)
+	frame[1] = zb.EZSP_FC_RESPONSE
+	if callback {
+		frame[1] |= zb.EZSP_FC_CALLBACK_ASYNC
+	}
+	return frame
+}
+
+// ncpData creates DATA frame sent by NCP, returns the frame and the acknowledgement the host must reply with
+func (s *ncpScript) ncpData(ezsp []byte) (frame []byte, ack []byte) {
+	frame = zb.ASHDataFrame(s.ncpFrm, s.hostFrm, false, ezsp)
+	s.ncpFrm = (s.ncpFrm + 1) % zb.ASHFrameNumbers
+	return frame, zb.ASHAckFrame(s.ncpFrm)
+}
+
+// command expects the command from the host and replies with the response followed by callbacks (frame ID and parameters)
+func (s *ncpScript) command(frameID uint16, parameters, response []byte, callbacks ...[]byte) {
+	s.seq++
+	host := zb.ASHDataFrame(s.hostFrm, s.ncpFrm, false, zb.EZSPCommand(s.version, s.seq, frameID, parameters))
+	s.hostFrm = (s.hostFrm + 1) % zb.ASHFrameNumbers
+
+	frame, ack := s.ncpData(ncpFrame(s.version, s.seq, frameID, response, false))
+	replies, acks := [][]byte{frame}, [][]byte{ack}
+	for _, c := range callbacks {
+		frame, ack := s.ncpData(ncpFrame(s.version, 0, binary.LittleEndian.Uint16(c), c[2:], true))
+		replies, acks = append(replies, frame), append(acks, ack)
+	}
+	s.m.Expect(host).Reply(replies...)
+	for _, ack := range acks {
+		s.m.Expect(ack)
+	}
+}
+
+// callback emits the callback, expecting the host's acknowledgement
+func (s *ncpScript) callback(frameID uint16, parameters []byte) {
+	frame, ack := s.ncpData(ncpFrame(s.version, 0, frameID, parameters, true))
+	s.m.Emit(frame)
+	s.m.Expect(ack)
+}
+
+// negotiate adds the version negotiation and the endpoint registration
+func (s *ncpScript) negotiate(version uint8) {
+	if version != ezspVersion {
+		s.command(zb.EZSP_VERSION, []byte{ezspVersion}, []byte{version, 2, 0x30, 0x67})
+	}
+	s.command(zb.EZSP_VERSION, []byte{version}, []byte{version, 2, 0x30, 0x67})
+	s.version = version
+	s.command(zb.EZSP_ADD_ENDPOINT, zb.AddEndpoint(localEndpoint, zb.ProfileHomeAutomation, localDeviceID,
+		[]uint16{zb.ClusterBasic}, []uint16{zb.ClusterOnOff, zb.ClusterLevel, zb.ClusterTemperature, zb.ClusterIASZone}), []byte{zb.EMBER_SUCCESS})
+}
+
+var networkUp = zb.EZSPPayload(zb.EZSP_STACK_STATUS_HANDLER, []byte{zb.EMBER_NETWORK_UP})
+
+var testNetwork = &zb.NetworkParameters{ExtendedPanID: 0x0102030405060708, PanID: 0x1a62, RadioTxPower: 8, RadioChannel: 20, Channels: 1 << 20}
+
+func networkParametersResponse(p *zb.NetworkParameters) []byte {
+	return p.Encode([]byte{zb.EMBER_SUCCESS, zb.EMBER_COORDINATOR})
+}
+
+func newTestService(t *testing.T, m *mock.Transport, params api.ParamValues) *Service {
+	return mock.NewService[*Service](t, params, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(m, "test", params)
+	})
+}
+
+func TestServiceResumeNetwork(t *testing.T) {
+	s := newNCPScript()
+	s.negotiate(ezspVersion)
+	s.command(zb.EZSP_NETWORK_INIT, zb.NetworkInit(ezspVersion), []byte{zb.EMBER_SUCCESS}, networkUp)
+	s.command(zb.EZSP_GET_NETWORK_PARAMETERS, nil, networkParametersResponse(testNetwork))
+
+	svc := newTestService(t, s.m, nil)
+	svc.Start()
+	defer svc.Stop()
+
+	if err := s.m.Wait(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// temperature report of node 0x1234, -5 °C
+	s.callback(zb.EZSP_INCOMING_MESSAGE_HANDLER, incomingMessage(0x1234, 1, zb.ClusterTemperature,
+		[]byte{0x18, 1, zb.ZCLReportAttributes, 0, 0, zb.ZCLTypeInt16, 0x0c, 0xfe}))
+	// IAS zone enroll request of node 0x2345, the service must respond with the enroll response
+	s.callback(zb.EZSP_INCOMING_MESSAGE_HANDLER, incomingMessage(0x2345, 2, zb.ClusterIASZone,
+		[]byte{0x09, 2, zb.ZCLZoneEnrollRequest, 0x0d, 0x00, 0, 0}))
+	aps := &zb.APSFrame{ProfileID: zb.ProfileHomeAutomation, ClusterID: zb.ClusterIASZone, SourceEndpoint: localEndpoint, DestinationEndpoint: 2, Options: apsOptions}
+	s.command(zb.EZSP_SEND_UNICAST, zb.SendUnicast(0x2345, aps, 1, zb.ZCLCommand(true, 1, zb.ZCLZoneEnrollResponse, zb.ZoneEnrollResponse(true, 0x45))),
+		[]byte{zb.EMBER_SUCCESS, 1})
+
+	if err := s.m.Wait(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	nodes := svc.Nodes()
+	if len(nodes) != 2 || nodes[0].ID != 0x1234 || len(nodes[0].CommandClasses) != 1 || nodes[0].CommandClasses[0] != zb.ClusterTemperature || nodes[1].ID != 0x2345 {
+		t.Errorf("Unexpected nodes: %+v", nodes)
+	}
+	if svc.Status() != defs.ErrStatusGood {
+		t.Errorf("Unexpected service status: %v", svc.Status())
+	}
+}
+
+func TestServiceFormNetwork(t *testing.T) {
+	key := [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	bitmask := uint16(zb.EMBER_HAVE_PRECONFIGURED_KEY | zb.EMBER_HAVE_NETWORK_KEY | zb.EMBER_REQUIRE_ENCRYPTED_KEY | zb.EMBER_TRUST_CENTER_GLOBAL_LINK_KEY)
+
+	s := newNCPScript()
+	s.negotiate(7) // NCP does not support the requested version
+	s.command(zb.EZSP_NETWORK_INIT, zb.NetworkInit(7), []byte{zb.EMBER_NOT_JOINED})
+	s.command(zb.EZSP_SET_INITIAL_SECURITY_STATE, zb.InitialSecurityState(bitmask, globalLinkKey, key), []byte{zb.EMBER_SUCCESS})
+	s.command(zb.EZSP_FORM_NETWORK, zb.FormNetwork(testNetwork), []byte{zb.EMBER_SUCCESS}, networkUp)
+	s.command(zb.EZSP_GET_NETWORK_PARAMETERS, nil, networkParametersResponse(testNetwork))
+	s.command(zb.EZSP_PERMIT_JOINING, zb.PermitJoining(60), []byte{zb.EMBER_SUCCESS})
+
+	svc := newTestService(t, s.m, api.ParamValues{
+		ParamNameNetworkMode:   NetworkModeCoordinator,
+		ParamNameChannel:       uint8(20),
+		ParamNamePanID:         uint16(0x1a62),
+		ParamNameExtendedPanID: "0102030405060708",
+		ParamNameNetworkKey:    "0102030405060708090a0b0c0d0e0f10",
+		ParamNamePermitJoin:    uint8(60),
+	})
+	svc.Start()
+	defer svc.Stop()
+
+	if err := s.m.Wait(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if v := svc.version.Load(); v != 7 {
+		t.Errorf("EZSP version 7 expected, got %d", v)
+	}
+}
+
+func TestServiceRetransmit(t *testing.T) {
+	s := newNCPScript()
+	versionFrame := zb.EZSPCommand(0, 1, zb.EZSP_VERSION, []byte{ezspVersion})
+	// the first DATA frame is lost, the host must retransmit it
+	s.m.Expect(zb.ASHDataFrame(0, 0, false, versionFrame))
+	s.m.Expect(zb.ASHDataFrame(0, 0, true, versionFrame)).Reply(zb.ASHAckFrame(1))
+	s.hostFrm, s.seq = 1, 1
+	// NCP sends the response after the acknowledgement
+	frame, ack := s.ncpData(ncpFrame(0, 1, zb.EZSP_VERSION, []byte{ezspVersion, 2, 0x30, 0x67}, false))
+	s.m.Emit(frame)
+	s.m.Expect(ack)
+	s.version = ezspVersion
+	s.command(zb.EZSP_ADD_ENDPOINT, zb.AddEndpoint(localEndpoint, zb.ProfileHomeAutomation, localDeviceID,
+		[]uint16{zb.ClusterBasic}, []uint16{zb.ClusterOnOff, zb.ClusterLevel, zb.ClusterTemperature, zb.ClusterIASZone}), []byte{zb.EMBER_SUCCESS})
+	s.command(zb.EZSP_NETWORK_INIT, zb.NetworkInit(ezspVersion), []byte{zb.EMBER_SUCCESS}, networkUp)
+	s.command(zb.EZSP_GET_NETWORK_PARAMETERS, nil, networkParametersResponse(testNetwork))
+
+	svc := newTestService(t, s.m, nil)
+	svc.Start()
+	defer svc.Stop()
+
+	if err := s.m.Wait(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendCommand(t *testing.T) {
+	s := newNCPScript()
+	s.negotiate(ezspVersion)
+	s.command(zb.EZSP_NETWORK_INIT, zb.NetworkInit(ezspVersion), []byte{zb.EMBER_SUCCESS}, networkUp)
+	s.command(zb.EZSP_GET_NETWORK_PARAMETERS, nil, networkParametersResponse(testNetwork))
+	s.command(zb.EZSP_GET_EUI64, nil, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+
+	svc := newTestService(t, s.m, nil)
+	svc.Start()
+	defer svc.Stop()
+
+	mock.WaitFor(t, "the network initialization", func() bool { return len(s.m.Writes()) == 10 })
+	if _, err := svc.Send([]byte{1}); err != defs.ErrBadPayload {
+		t.Errorf("Bad payload error expected, got %v", err)
+	}
+	message, err := svc.Send(zb.EZSPPayload(zb.EZSP_GET_EUI64, nil))
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := s.m.Wait(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, msg := defs.Messages.Get(message.ID); msg.State != api.Outgoing {
+		t.Errorf("The message state is %v, expected %v", msg.State, api.Outgoing)
+	}
+}
+
+func TestDiscoverNCP(t *testing.T) {
+	s := newNCPScript()
+	s.seq = 0xff // the discovery uses the sequence 0
+	s.command(zb.EZSP_VERSION, []byte{ezspVersion}, []byte{ezspVersion, 2, 0x30, 0x67})
+
+	ok, info := discoverNCP(context.Background(), s.m, "test", nil)
+	if !ok || info != "EmberZNet 6.7.3 EZSP v8" {
+		t.Errorf("Unexpected discovery result: %v, '%s'", ok, info)
+	}
+	if err := s.m.Wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	m := mock.New()
+	m.Expect(zb.ASHResetFrame())
+	if ok, _ := discoverNCP(context.Background(), m, "test", nil); ok {
+		t.Error("No NCP expected if there is no reply to ASH reset")
+	}
+}
+
+// incomingMessage creates the parameters of incomingMessageHandler callback
+func incomingMessage(sender uint16, endpoint uint8, cluster uint16, message []byte) []byte {
+	aps := &zb.APSFrame{ProfileID: zb.ProfileHomeAutomation, ClusterID: cluster, SourceEndpoint: endpoint, DestinationEndpoint: localEndpoint}
+	b := aps.Encode([]byte{0})
+	b = append(b, 0xff, 0xc4)
+	b = binary.LittleEndian.AppendUint16(b, sender)
+	b = append(b, 0xff, 0xff, byte(len(message)))
+	return append(b, message...)
+}
@@ -0,0 +1,201 @@
+package zwave
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/events/handlers"
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+// battery monitoring parameters names
+const (
+	ParamNameBatteryThreshold    = "batteryThreshold"
+	ParamNameBatteryTimeout      = "batteryTimeout"
+	ParamNameBatteryPollInterval = "batteryPollInterval"
+)
+
+// the interval of checking the nodes which stopped reporting their battery level
+const batteryCheckInterval = time.Minute
+
+// batteryState is the last reported battery state of the node
+type batteryState struct {
+	level    byte
+	reported bool
+	updated  time.Time
+	low      bool // the low battery event was sent because of the level
+	stale    bool // the low battery event was sent because of the timeout
+}
+
+// batteryMonitor keeps the battery state of the nodes
+type batteryMonitor struct {
+	lock  sync.Mutex
+	nodes map[uint16]*batteryState
+}
+
+// lowBatteryEvent is the low battery event to send
+type lowBatteryEvent struct {
+	node    uint16
+	level   byte
+	reason  string
+	updated time.Time
+}
+
+// watch starts the monitoring of the node's battery, the node is considered as reported at the provided time
+func (bm *batteryMonitor) watch(node uint16, now time.Time) {
+	bm.lock.Lock()
+	defer bm.lock.Unlock()
+	if bm.nodes == nil {
+		bm.nodes = make(map[uint16]*batteryState)
+	}
+	if _, ok := bm.nodes[node]; !ok {
+		bm.nodes[node] = &batteryState{updated: now}
+	}
+}
+
+// report stores the battery level reported by the node, returns the low battery event if the threshold is crossed
+func (bm *batteryMonitor) report(node uint16, level byte, threshold byte, now time.Time) *lowBatteryEvent {
+	bm.lock.Lock()
+	defer bm.lock.Unlock()
+	if bm.nodes == nil {
+		bm.nodes = make(map[uint16]*batteryState)
+	}
+	bs, ok := bm.nodes[node]
+	if !ok {
+		bs = &batteryState{}
+		bm.nodes[node] = bs
+	}
+	bs.level, bs.reported, bs.updated, bs.stale = level, true, now, false
+
+	low := level == zw.BATTERY_LEVEL_LOW_WARNING || level <= threshold
+	if low && !bs.low {
+		bs.low = true
+		return &lowBatteryEvent{node: node, level: batteryLevel(level), reason: api.LowBatteryLevel, updated: now}
+	}
+	bs.low = low
+	return nil
+}
+
+// expired returns low battery events for the nodes which stopped reporting their battery level
+func (bm *batteryMonitor) expired(timeout time.Duration, now time.Time) (rv []*lowBatteryEvent) {
+	bm.lock.Lock()
+	defer bm.lock.Unlock()
+	for node, bs := range bm.nodes {
+		if !bs.stale && now.Sub(bs.updated) > timeout {
+			bs.stale = true
+			rv = append(rv, &lowBatteryEvent{node: node, level: batteryLevel(bs.level), reason: api.LowBatteryTimeout, updated: bs.updated})
+		}
+	}
+	return
+}
+
+// state returns the battery state of the node in API format or nil if the node has not reported its battery level
+func (bm *batteryMonitor) state(node uint16) *api.NodeBattery {
+	bm.lock.Lock()
+	defer bm.lock.Unlock()
+	if bs, ok := bm.nodes[node]; ok && bs.reported {
+		return &api.NodeBattery{Level: batteryLevel(bs.level), Low: bs.low, Updated: bs.updated}
+	}
+	return nil
+}
+
+// batteryLevel converts reported battery level to percents
+func batteryLevel(level byte) byte {
+	if level == zw.BATTERY_LEVEL_LOW_WARNING {
+		return 0
+	}
+	return level
+}
+
+func (svc *Service) batteryThreshold() byte {
+	if v, ok := svc.params[ParamNameBatteryThreshold]; ok {
+		return v.(uint8)
+	}
+	return 20
+}
+
+func (svc *Service) batteryTimeout() time.Duration {
+	batteryTimeout := 48 * time.Hour
+	if v, ok := svc.params[ParamNameBatteryTimeout]; ok {
+		batteryTimeout = time.Duration(v.(uint32)) * time.Millisecond
+	}
+	return batteryTimeout
+}
+
+func (svc *Service) batteryPollInterval() time.Duration {
+	batteryPollInterval := 6 * time.Hour
+	if v, ok := svc.params[ParamNameBatteryPollInterval]; ok {
+		batteryPollInterval = time.Duration(v.(uint32)) * time.Millisecond
+		if batteryPollInterval > 0 && batteryPollInterval < time.Minute {
+			batteryPollInterval = time.Minute
+		}
+	}
+	return batteryPollInterval
+}
+
+func (svc *Service) sendLowBattery(e *lowBatteryEvent) {
+	handlers.SendLowBattery(svc.key, e.node, e.level, e.reason, e.updated)
+}
+
+// handleBatteryReport processes Battery Report command received from the node
+func (svc *Service) handleBatteryReport(node uint16, command []byte) {
+	if level, ok := zw.ParseBatteryReport(command); ok {
+		if e := svc.battery.report(node, level, svc.batteryThreshold(), time.Now().UTC()); e != nil {
+			svc.sendLowBattery(e)
+		}
+	}
+}
+
+// batteryLoop polls the battery level of the nodes and checks the nodes which stopped reporting it
+func (svc *Service) batteryLoop(ctx context.Context) {
+	defer svc.stopWg.Done()
+
+	timeout := svc.batteryTimeout()
+	pollInterval := svc.batteryPollInterval()
+	var nextPoll time.Time
+	if pollInterval > 0 {
+		nextPoll = time.Now().Add(pollInterval)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(batteryCheckInterval):
+		}
+
+		now := time.Now()
+		if timeout > 0 {
+			for _, e := range svc.battery.expired(timeout, now.UTC()) {
+				svc.sendLowBattery(e)
+			}
+		}
+
+		if pollInterval <= 0 || now.Before(nextPoll) {
+			continue
+		}
+		nextPoll = now.Add(pollInterval)
+		if svc.Status() != defs.ErrStatusGood {
+			continue
+		}
+
+		// battery powered nodes are usually sleeping, the request is delivered when the node wakes up
+		t := svc.nodeIDType.Load()
+		for _, node := range svc.nodes.ids() {
+			if !svc.nodes.supports(node, zw.COMMAND_CLASS_BATTERY) {
+				continue
+			}
+			svc.battery.watch(node, now.UTC())
+			svc.opLock.Lock()
+			funcID := svc.nextFuncID()
+			svc.request(ctx, zw.ZW_SEND_DATA, funcID, zw.SendData(t, node, zw.BatteryGet(), txOptionsSingleCast, funcID))
+			svc.opLock.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}
+}
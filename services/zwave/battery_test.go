@@ -0,0 +1,109 @@
+package zwave
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+func TestBatteryMonitor(t *testing.T) {
+	const (
+		node      = 5
+		threshold = 20
+		timeout   = time.Hour
+	)
+	type step struct {
+		op     string        // report, watch or expire
+		at     time.Duration // the time of the step since the start
+		level  byte          // the reported level
+		reason string        // the reason of the expected low battery event, empty if no event is expected
+		event  byte          // the level of the expected low battery event
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		low   bool // the low flag of the final battery state
+	}{
+		{"threshold crossing", []step{
+			{op: "report", level: 50},
+			{op: "report", at: time.Minute, level: 21},
+			{op: "report", at: 2 * time.Minute, level: 20, reason: api.LowBatteryLevel, event: 20},
+		}, true},
+		{"no repeat while low", []step{
+			{op: "report", level: 15, reason: api.LowBatteryLevel, event: 15},
+			{op: "report", at: time.Minute, level: 10},
+			{op: "report", at: 2 * time.Minute, level: 20},
+		}, true},
+		{"re-arm after recovery", []step{
+			{op: "report", level: 10, reason: api.LowBatteryLevel, event: 10},
+			{op: "report", at: time.Minute, level: 80},
+			{op: "report", at: 2 * time.Minute, level: 10, reason: api.LowBatteryLevel, event: 10},
+		}, true},
+		{"recovered", []step{
+			{op: "report", level: 10, reason: api.LowBatteryLevel, event: 10},
+			{op: "report", at: time.Minute, level: 21},
+		}, false},
+		{"low warning", []step{
+			{op: "report", level: 90},
+			{op: "report", at: time.Minute, level: zw.BATTERY_LEVEL_LOW_WARNING, reason: api.LowBatteryLevel, event: 0},
+			{op: "report", at: 2 * time.Minute, level: zw.BATTERY_LEVEL_LOW_WARNING},
+		}, true},
+		{"stale timeout", []step{
+			{op: "report", level: 50},
+			{op: "expire", at: timeout},
+			{op: "expire", at: timeout + time.Minute, reason: api.LowBatteryTimeout, event: 50},
+			{op: "expire", at: 2 * timeout},
+		}, false},
+		{"report clears stale", []step{
+			{op: "report", level: 50},
+			{op: "expire", at: timeout + time.Minute, reason: api.LowBatteryTimeout, event: 50},
+			{op: "report", at: timeout + 2*time.Minute, level: 40},
+			{op: "expire", at: 2 * timeout},
+			{op: "expire", at: 2*timeout + 3*time.Minute, reason: api.LowBatteryTimeout, event: 40},
+		}, false},
+		{"never reported", []step{
+			{op: "watch"},
+			{op: "watch", at: time.Minute},
+			{op: "expire", at: timeout + time.Minute, reason: api.LowBatteryTimeout, event: 0},
+		}, false},
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var bm batteryMonitor
+			for i, s := range test.steps {
+				now := start.Add(s.at)
+				var events []*lowBatteryEvent
+				switch s.op {
+				case "report":
+					if e := bm.report(node, s.level, threshold, now); e != nil {
+						events = append(events, e)
+					}
+				case "watch":
+					bm.watch(node, now)
+				case "expire":
+					events = bm.expired(timeout, now)
+				}
+				if s.reason == "" {
+					if len(events) != 0 {
+						t.Errorf("step %d: unexpected event %+v", i+1, events[0])
+					}
+					continue
+				}
+				if len(events) != 1 {
+					t.Errorf("step %d: one event expected, got %d", i+1, len(events))
+					continue
+				}
+				if e := events[0]; e.node != node || e.reason != s.reason || e.level != s.event {
+					t.Errorf("step %d: unexpected event %+v", i+1, e)
+				}
+			}
+			if bs := bm.state(node); (bs != nil && bs.Low) != test.low {
+				t.Errorf("unexpected battery state %+v", bs)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package zwave
+
+import (
+	"context"
+	"time"
+
+	"github.com/stas-makutin/howeve/defs"
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+// ParamNameClockSyncInterval parameter name for the interval of the nodes clock synchronization
+const ParamNameClockSyncInterval = "clockSyncInterval"
+
+// the interval of checking if the nodes clock must be synchronized
+const clockCheckInterval = time.Minute
+
+func (svc *Service) clockSyncInterval() time.Duration {
+	clockSyncInterval := 24 * time.Hour
+	if v, ok := svc.params[ParamNameClockSyncInterval]; ok {
+		clockSyncInterval = time.Duration(v.(uint32)) * time.Millisecond
+		if clockSyncInterval > 0 && clockSyncInterval < clockCheckInterval {
+			clockSyncInterval = clockCheckInterval
+		}
+	}
+	return clockSyncInterval
+}
+
+// localTimeOffset returns the standard time zone offset and the daylight saving time offset and bounds in the year of provided local time
+func localTimeOffset(now time.Time) (offset, dstOffset time.Duration, dstStart, dstEnd time.Time) {
+	t := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+	yearEnd := t.AddDate(1, 0, 0)
+	_, stdOffset := t.Zone()
+	dstZoneOffset := stdOffset
+	for t.Before(yearEnd) {
+		_, zoneOffset := t.Zone()
+		start, end := t.ZoneBounds()
+		if t.IsDST() {
+			dstZoneOffset, dstStart, dstEnd = zoneOffset, start, end
+		} else {
+			stdOffset = zoneOffset
+		}
+		if end.IsZero() {
+			break
+		}
+		t = end
+	}
+	offset = time.Duration(stdOffset) * time.Second
+	dstOffset = time.Duration(dstZoneOffset-stdOffset) * time.Second
+	return
+}
+
+// handleTimeCommand answers Time command class requests received from the node
+func (svc *Service) handleTimeCommand(node uint16, command []byte) {
+	if len(command) < 2 {
+		return
+	}
+	now := time.Now()
+	var reply []byte
+	switch command[1] {
+	case zw.TIME_GET:
+		reply = zw.TimeReport(now)
+	case zw.DATE_GET:
+		reply = zw.DateReport(now)
+	case zw.TIME_OFFSET_GET:
+		reply = zw.TimeOffsetReport(localTimeOffset(now))
+	default:
+		return
+	}
+	// the reply is sent without callback, the service loop must not wait for it
+	svc.Send(zw.SendData(svc.nodeIDType.Load(), node, reply, txOptionsSingleCast, 0))
+}
+
+// clockLoop periodically sets the clock of the nodes which support Clock command class.
+// The clock is also set when the local time offset changes (daylight saving time starts or ends)
+func (svc *Service) clockLoop(ctx context.Context) {
+	defer svc.stopWg.Done()
+
+	syncInterval := svc.clockSyncInterval()
+	if syncInterval <= 0 {
+		return
+	}
+
+	var lastSync time.Time
+	lastOffset := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(clockCheckInterval):
+		}
+
+		now := time.Now()
+		_, offset := now.Zone()
+		if !lastSync.IsZero() && now.Sub(lastSync) < syncInterval && offset == lastOffset {
+			continue
+		}
+		if svc.Status() != defs.ErrStatusGood {
+			continue
+		}
+
+		t := svc.nodeIDType.Load()
+		synced := false
+		for _, node := range svc.nodes.ids() {
+			if !svc.nodes.supports(node, zw.COMMAND_CLASS_CLOCK) {
+				continue
+			}
+			svc.opLock.Lock()
+			funcID := svc.nextFuncID()
+			svc.request(ctx, zw.ZW_SEND_DATA, funcID, zw.SendData(t, node, zw.ClockSet(time.Now()), txOptionsSingleCast, funcID))
+			svc.opLock.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+			synced = true
+		}
+		// the nodes information may not be available yet, retry on the next check
+		if synced {
+			lastSync, lastOffset = now, offset
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package zwave
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/stas-makutin/howeve/api"
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+// ParamNameNodeIDType parameter name for the node identifiers type used in Serial API frames
+const ParamNameNodeIDType = "nodeIdType"
+
+// node identifiers type parameter values
+const (
+	NodeIDTypeAuto  = "auto"
+	NodeIDType8Bit  = "8bit"
+	NodeIDType16Bit = "16bit"
+)
+
+// initController detects the controller capabilities, selects the node identifiers type, and builds the node inventory
+func (svc *Service) initController(ctx context.Context) {
+	defer svc.stopWg.Done()
+
+	svc.opLock.Lock()
+	defer svc.opLock.Unlock()
+
+	var capabilities *zw.Capabilities
+	if response, _, err := svc.request(ctx, zw.FUNC_ID_SERIAL_API_GET_CAPABILITIES, 0, zw.DataRequest([]byte{zw.FUNC_ID_SERIAL_API_GET_CAPABILITIES})); err == nil {
+		capabilities, _ = zw.ParseCapabilities(response)
+	} else if ctx.Err() != nil {
+		return
+	}
+	svc.capabilities.Store(capabilities)
+	longRange := capabilities != nil && capabilities.SupportsLongRange()
+
+	if capabilities != nil && capabilities.Supports(zw.FUNC_ID_SERIAL_API_SETUP) {
+		// enable transmit reports in ZW_SEND_DATA callbacks, they are used to collect the radio statistics
+		svc.request(ctx, zw.FUNC_ID_SERIAL_API_SETUP, 0, zw.SerialAPISetupTxStatusReport(true))
+	}
+
+	nodeIDType := zw.NodeIDType8Bit
+	mode, _ := svc.params[ParamNameNodeIDType].(string)
+	if mode == NodeIDType16Bit || (mode != NodeIDType8Bit && longRange) {
+		response, _, err := svc.request(ctx, zw.FUNC_ID_SERIAL_API_SETUP, 0, zw.SerialAPISetupNodeIDType(zw.NodeIDType16Bit))
+		if err == nil && zw.ParseSerialAPISetupNodeIDType(response) {
+			nodeIDType = zw.NodeIDType16Bit
+		} else {
+			svc.log(zwOcInitialize, zwOsFailure, zwOfNodeIDType, NodeIDType16Bit)
+		}
+	} else if longRange {
+		// the node identifiers type persists in the controller, switch it back explicitly
+		svc.request(ctx, zw.FUNC_ID_SERIAL_API_SETUP, 0, zw.SerialAPISetupNodeIDType(zw.NodeIDType8Bit))
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	svc.nodeIDType.Store(nodeIDType)
+
+	response, _, err := svc.request(ctx, zw.FUNC_ID_SERIAL_API_GET_INIT_DATA, 0, zw.DataRequest([]byte{zw.FUNC_ID_SERIAL_API_GET_INIT_DATA}))
+	if err != nil {
+		if ctx.Err() == nil {
+			svc.log(zwOcInitialize, zwOsFailure, zwOfNodes, err.Error())
+		}
+		return
+	}
+	nodes, _ := zw.ParseInitData(response)
+
+	if nodeIDType == zw.NodeIDType16Bit {
+		for offset := byte(0); offset < 4; offset++ {
+			response, _, err := svc.request(ctx, zw.FUNC_ID_SERIAL_API_GET_LR_NODES, 0, zw.GetLongRangeNodes(offset))
+			if err != nil {
+				svc.log(zwOcInitialize, zwOsFailure, zwOfLongRangeNodes, err.Error())
+				break
+			}
+			lrNodes, more, ok := zw.ParseLongRangeNodes(response)
+			if !ok {
+				break
+			}
+			nodes = append(nodes, lrNodes...)
+			if !more {
+				break
+			}
+		}
+	}
+
+	svc.nodes.reset(nodes)
+	svc.log(zwOcInitialize, zwOsSuccess, nodeIDType.String(), strconv.FormatBool(longRange), strconv.Itoa(len(nodes)))
+
+	// request node information frames, the controller reports them using ZW_APPLICATION_UPDATE
+	for _, node := range nodes {
+		if ctx.Err() != nil {
+			return
+		}
+		svc.request(ctx, zw.ZW_REQUEST_NODE_INFO, 0, zw.RequestNodeInfo(nodeIDType, node))
+	}
+}
+
+// Nodes is the implementation of defs.NodeLister interface
+func (svc *Service) Nodes() []*api.NodeInfo {
+	nodes := svc.nodes.list()
+	for _, node := range nodes {
+		node.Battery = svc.battery.state(node.ID)
+	}
+	return nodes
+}
@@ -7,7 +7,10 @@ import (
 
 	"github.com/albenik/go-serial/v2/enumerator"
 	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
 	"github.com/stas-makutin/howeve/services/serial"
+	"github.com/stas-makutin/howeve/services/simulator"
+	"github.com/stas-makutin/howeve/services/tcp"
 	zw "github.com/stas-makutin/howeve/zwave"
 )
 
@@ -21,10 +24,19 @@ var discoverSerialParams api.ParamValues = api.ParamValues{
 	serial.ParamNameWriteTimeout: uint32(0),
 }
 
+// the number of the controllers probed at the same time
+const maxDiscoveryWorkers = 4
+
+// the entry of discovered simulated controller
+const simulatorEntry = "simulator"
+
 // ZW_Version ZWave serial API request data frame
 var zwVersionFrame = zw.DataRequest([]byte{zw.ZW_VERSION})
 
-// DiscoverSerial - discover COM ports with ZWave controllers
+// FUNC_ID_SERIAL_API_GET_CAPABILITIES ZWave serial API request data frame
+var zwCapabilitiesFrame = zw.DataRequest([]byte{zw.FUNC_ID_SERIAL_API_GET_CAPABILITIES})
+
+// DiscoverSerial - discover COM ports with ZWave controllers, the ports are probed concurrently
 func DiscoverSerial(ctx context.Context, params api.ParamValues) ([]*api.DiscoveryEntry, error) {
 	ports, err := enumerator.GetDetailedPortsList()
 	if err != nil {
@@ -34,46 +46,131 @@ func DiscoverSerial(ctx context.Context, params api.ParamValues) ([]*api.Discove
 		return nil, nil
 	}
 
-	var rv []*api.DiscoveryEntry
-	for _, port := range ports {
-		select {
-		case <-ctx.Done():
-			return nil, nil
-		default:
+	names := make([]string, len(ports))
+	for i, port := range ports {
+		names[i] = port.Name
+	}
+	return defs.DiscoverConcurrently(ctx, names, maxDiscoveryWorkers, func(i int) *api.DiscoveryEntry {
+		port := ports[i]
+		ok, info, longRange := discoverController(ctx, &serial.Transport{}, port.Name, discoverSerialParams)
+		if !ok {
+			return nil
 		}
-		if ok, info := discoverSerialPort(ctx, port.Name, discoverSerialParams); ok {
-			if info != "" {
-				info = " [" + info + "]"
-			}
-			rv = append(rv, &api.DiscoveryEntry{
-				ServiceKey: api.ServiceKey{
-					Protocol:  api.ProtocolZWave,
-					Transport: api.TransportSerial,
-					Entry:     port.Name,
-				},
-				Description: port.Product + info,
-			})
+		if longRange {
+			info = strings.TrimSpace(info + " LR")
+		}
+		if info != "" {
+			info = " [" + info + "]"
 		}
+		entry := &api.DiscoveryEntry{
+			ServiceKey: api.ServiceKey{
+				Protocol:  api.ProtocolZWave,
+				Transport: api.TransportSerial,
+				Entry:     port.Name,
+			},
+			Description: port.Product + info,
+		}
+		if port.IsUSB && port.SerialNumber != "" {
+			// refer to the USB device instead of the port name which may change after the device is replugged
+			entry.Entry = serial.USBEntry(port.VID, port.PID, port.SerialNumber)
+			entry.Description += " " + port.Name
+		}
+		if longRange {
+			entry.ParamValues = api.ParamValues{ParamNameNodeIDType: NodeIDType16Bit}
+		}
+		return entry
+	}), nil
+}
+
+// DiscoverSimulator - discover the simulated Z-Wave controller, it is probed the same way as the controllers on COM ports
+func DiscoverSimulator(ctx context.Context, params api.ParamValues) ([]*api.DiscoveryEntry, error) {
+	ok, info, _ := discoverController(ctx, &simulator.Transport{}, simulatorEntry, nil)
+	defs.DiscoveryProbed(ctx, simulatorEntry, 1, 1)
+	if !ok {
+		return nil, nil
 	}
-	return rv, nil
+	entry := &api.DiscoveryEntry{
+		ServiceKey: api.ServiceKey{
+			Protocol:  api.ProtocolZWave,
+			Transport: api.TransportSimulator,
+			Entry:     simulatorEntry,
+		},
+		Description: "Z-Wave controller simulator [" + info + "]",
+	}
+	defs.DiscoveryFound(ctx, entry)
+	return []*api.DiscoveryEntry{entry}, nil
 }
 
-func discoverSerialPort(ctx context.Context, port string, params api.ParamValues) (bool, string) {
-	t := &serial.Transport{}
+// DiscoverTCP - discover TCP serial bridges with Z-Wave controllers, the bridges are found over mDNS or SSDP and probed concurrently
+func DiscoverTCP(ctx context.Context, params api.ParamValues) ([]*api.DiscoveryEntry, error) {
+	endpoints, err := tcp.DiscoverEndpoints(ctx, params)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := t.Open(port, params); err != nil {
-		return false, ""
+	names := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		names[i] = endpoint.Entry
+	}
+	return defs.DiscoverConcurrently(ctx, names, maxDiscoveryWorkers, func(i int) *api.DiscoveryEntry {
+		endpoint := endpoints[i]
+		ok, info, longRange := discoverController(ctx, &tcp.Transport{}, endpoint.Entry, nil)
+		if !ok {
+			return nil
+		}
+		if longRange {
+			info = strings.TrimSpace(info + " LR")
+		}
+		if info != "" {
+			info = " [" + info + "]"
+		}
+		entry := &api.DiscoveryEntry{
+			ServiceKey: api.ServiceKey{
+				Protocol:  api.ProtocolZWave,
+				Transport: api.TransportTCP,
+				Entry:     endpoint.Entry,
+			},
+			Description: endpoint.Description + info,
+		}
+		if longRange {
+			entry.ParamValues = api.ParamValues{ParamNameNodeIDType: NodeIDType16Bit}
+		}
+		return entry
+	}), nil
+}
+
+// discoverController verifies if there is Z-Wave controller behind the transport entry.
+// Returns true if the controller is found, along with the library version and Z-Wave Long Range support flag
+func discoverController(ctx context.Context, t defs.Transport, entry string, params api.ParamValues) (bool, string, bool) {
+	if err := t.Open(entry, params); err != nil {
+		return false, "", false
 	}
 	defer t.Close()
 
+	payload := discoverRequest(ctx, t, zwVersionFrame)
+	// ZW_Version response
+	if len(payload) != 14 || payload[0] != zw.ZW_VERSION {
+		return false, "", false
+	}
+	info := strings.TrimRight(string(payload[1:13]), "\x00") // the library version, "Z-Wave x.yy"
+
+	longRange := false
+	if capabilities, ok := zw.ParseCapabilities(discoverRequest(ctx, t, zwCapabilitiesFrame)); ok {
+		longRange = capabilities.SupportsLongRange()
+	}
+	return true, info, longRange
+}
+
+// discoverRequest writes the request data frame and reads the controller's response, returns the response payload or nil
+func discoverRequest(ctx context.Context, t defs.Transport, frame []byte) []byte {
 	select {
 	case <-ctx.Done():
-		return false, ""
+		return nil
 	default:
 	}
 
-	if n, err := t.Write(zwVersionFrame); err != nil || n != len(zwVersionFrame) {
-		return false, ""
+	if n, err := t.Write(frame); err != nil || n != len(frame) {
+		return nil
 	}
 
 	buffer := make([]byte, 128)
@@ -82,15 +179,15 @@ func discoverSerialPort(ctx context.Context, port string, params api.ParamValues
 	for {
 		select {
 		case <-ctx.Done():
-			return false, ""
+			return nil
 		case <-time.After(time.Millisecond * 1500):
-			return false, ""
+			return nil
 		case <-t.ReadyToRead():
 		}
 
 		n, err := t.Read(buffer[re:])
 		if err != nil || n <= 0 {
-			return false, ""
+			return nil
 		}
 		re += n
 
@@ -99,24 +196,20 @@ func discoverSerialPort(ctx context.Context, port string, params api.ParamValues
 			switch seq {
 			case 0:
 				if buffer[rb] != zw.FrameASK {
-					return false, ""
+					return nil
 				}
 				seq = 1
 				rb++
 			case 1:
-				switch vr, _ := zw.ValidateDataFrame(buffer[rb:re]); vr {
+				switch vr, pos := zw.ValidateDataFrame(buffer[rb:re]); vr {
 				case zw.FrameOK:
-					if payload := zw.UnpackResponse(buffer[rb:re]); payload != nil {
-						// ZW_Version response
-						if payload[0] == zw.ZW_VERSION && len(payload) == 14 {
-							return true, strings.TrimRight(string(payload[1:13]), "\x00") // the library version, "Z-Wave x.yy"
-						}
-					}
-					return false, ""
+					// acknowledge the response so the controller will not retransmit it
+					t.Write([]byte{zw.FrameASK})
+					return zw.UnpackResponse(buffer[rb : rb+pos])
 				case zw.FrameIncomplete:
 					break ReadLoop
 				}
-				return false, ""
+				return nil
 			}
 		}
 	}
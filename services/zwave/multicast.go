@@ -0,0 +1,180 @@
+package zwave
+
+import (
+	"context"
+	"errors"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+// node send statuses
+const (
+	nodeStatusOK             = "ok"
+	nodeStatusNoAck          = "noAck"
+	nodeStatusFail           = "fail"
+	nodeStatusRoutingNotIdle = "routingNotIdle"
+	nodeStatusNoRoute        = "noRoute"
+	nodeStatusNoResponse     = "noResponse"
+	nodeStatusNoCallback     = "noCallback"
+	nodeStatusRejected       = "rejected"
+	nodeStatusNotSent        = "notSent"
+)
+
+// transmit options
+const (
+	txOptionsSingleCast = zw.TRANSMIT_OPTION_ACK | zw.TRANSMIT_OPTION_AUTO_ROUTE | zw.TRANSMIT_OPTION_EXPLORE
+	txOptionsMultiCast  = 0
+)
+
+// txStatus converts transmit status of ZW_SEND_DATA or ZW_SEND_DATA_MULTI callback or request error into the node send status
+func txStatus(callback []byte, err error) (bool, string) {
+	switch {
+	case errors.Is(err, errNoResponse):
+		return false, nodeStatusNoResponse
+	case errors.Is(err, errRequestRejected):
+		return false, nodeStatusRejected
+	case errors.Is(err, errNoCallback):
+		return false, nodeStatusNoCallback
+	case err != nil:
+		return false, nodeStatusNotSent
+	}
+	cb, ok := zw.ParseSendDataCallback(callback)
+	if !ok {
+		return false, nodeStatusFail
+	}
+	switch cb.TxStatus {
+	case zw.TRANSMIT_COMPLETE_OK:
+		return true, nodeStatusOK
+	case zw.TRANSMIT_COMPLETE_NO_ACK:
+		return false, nodeStatusNoAck
+	case zw.TRANSMIT_ROUTING_NOT_IDLE:
+		return false, nodeStatusRoutingNotIdle
+	case zw.TRANSMIT_COMPLETE_NOROUTE:
+		return false, nodeStatusNoRoute
+	}
+	return false, nodeStatusFail
+}
+
+// SendMulticast is the implementation of defs.MulticastSender interface.
+// The payload (command class, command, and parameters) is sent to the nodes using ZW_SEND_DATA_MULTI request, or to all nodes
+// as a broadcast. Optional single-cast follow-ups are sent to each node after that.
+func (svc *Service) SendMulticast(ctx context.Context, nodes []uint16, broadcast bool, followUp bool, payload []byte) ([]*api.NodeSendResult, error) {
+	if len(payload) <= 0 {
+		return nil, defs.ErrBadPayload
+	}
+	if len(nodes) <= 0 && !broadcast {
+		return nil, defs.ErrBadNodeID
+	}
+
+	t := svc.nodeIDType.Load()
+	var nodeIDs, classicIDs []uint16
+	results := make(map[uint16]*api.NodeSendResult)
+	for _, node := range nodes {
+		if !t.IsValidNodeID(node) {
+			return nil, defs.ErrBadNodeID
+		}
+		if _, ok := results[node]; !ok {
+			nodeIDs = append(nodeIDs, node)
+			if zw.IsClassicNodeID(node) {
+				classicIDs = append(classicIDs, node)
+			}
+			results[node] = &api.NodeSendResult{Node: node, Status: nodeStatusNotSent}
+		}
+	}
+
+	if !zw.ValidDataFrameLength(zw.SendData(t, zw.NODE_BROADCAST, payload, txOptionsSingleCast, 0xff)) {
+		return nil, defs.ErrBadPayload
+	}
+	if !broadcast && !zw.ValidDataFrameLength(zw.SendDataMulti(t, classicIDs[:min(len(classicIDs), zw.MULTICAST_MAX_NODES)], payload, txOptionsMultiCast, 0xff)) {
+		return nil, defs.ErrBadPayload
+	}
+
+	svc.opLock.Lock()
+	defer svc.opLock.Unlock()
+
+	var rv []*api.NodeSendResult
+	setResult := func(ids []uint16, success bool, status string) {
+		for _, id := range ids {
+			results[id].Success, results[id].Status = success, status
+		}
+	}
+	sendData := func(id uint16, txOptions byte) (bool, string, error) {
+		funcID := svc.nextFuncID()
+		_, callback, err := svc.request(ctx, zw.ZW_SEND_DATA, funcID, zw.SendData(t, id, payload, txOptions, funcID))
+		if errors.Is(err, defs.ErrSendBusy) || errors.Is(err, context.Canceled) {
+			return false, "", err
+		}
+		success, status := txStatus(callback, err)
+		return success, status, nil
+	}
+
+	if broadcast {
+		// Z-Wave Long Range nodes do not receive classic broadcast, they have own broadcast address
+		broadcastIDs := []uint16{zw.NODE_BROADCAST}
+		if t == zw.NodeIDType16Bit {
+			broadcastIDs = append(broadcastIDs, zw.NODE_BROADCAST_LR)
+		}
+		for _, broadcastID := range broadcastIDs {
+			success, status, err := sendData(broadcastID, txOptionsMultiCast)
+			if err != nil {
+				return nil, err
+			}
+			if len(nodeIDs) <= 0 {
+				rv = append(rv, &api.NodeSendResult{Node: broadcastID, Success: success, Status: status})
+				continue
+			}
+			for _, id := range nodeIDs {
+				if zw.IsLongRangeNodeID(id) == (broadcastID == zw.NODE_BROADCAST_LR) {
+					setResult([]uint16{id}, success, status)
+				}
+			}
+		}
+		if len(nodeIDs) <= 0 {
+			return rv, nil
+		}
+	} else {
+		for i := 0; i < len(classicIDs); i += zw.MULTICAST_MAX_NODES {
+			chunk := classicIDs[i:min(i+zw.MULTICAST_MAX_NODES, len(classicIDs))]
+			funcID := svc.nextFuncID()
+			_, callback, err := svc.request(ctx, zw.ZW_SEND_DATA_MULTI, funcID, zw.SendDataMulti(t, chunk, payload, txOptionsMultiCast, funcID))
+			if errors.Is(err, defs.ErrSendBusy) || errors.Is(err, context.Canceled) {
+				return nil, err
+			}
+			success, status := txStatus(callback, err)
+			setResult(chunk, success, status)
+		}
+		// Z-Wave Long Range does not support multicast, the nodes are addressed using single-cast
+		if !followUp {
+			for _, id := range nodeIDs {
+				if zw.IsLongRangeNodeID(id) {
+					success, status, err := sendData(id, txOptionsSingleCast)
+					if errors.Is(err, context.Canceled) {
+						return nil, err
+					}
+					if err == nil {
+						setResult([]uint16{id}, success, status)
+					}
+				}
+			}
+		}
+	}
+
+	if followUp {
+		for _, id := range nodeIDs {
+			success, status, err := sendData(id, txOptionsSingleCast)
+			if errors.Is(err, context.Canceled) {
+				return nil, err
+			}
+			if err == nil {
+				setResult([]uint16{id}, success, status)
+			}
+		}
+	}
+
+	for _, id := range nodeIDs {
+		rv = append(rv, results[id])
+	}
+	return rv, nil
+}
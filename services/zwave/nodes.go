@@ -0,0 +1,109 @@
+package zwave
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/stas-makutin/howeve/api"
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+// nodeInfo contains the information about the node of Z-Wave network
+type nodeInfo struct {
+	id             uint16
+	infoReceived   bool
+	basic          byte
+	generic        byte
+	specific       byte
+	commandClasses []byte
+}
+
+// nodeInventory is the list of Z-Wave network nodes known to the controller
+type nodeInventory struct {
+	lock  sync.RWMutex
+	nodes map[uint16]*nodeInfo
+}
+
+// reset replaces the list of nodes, the information about remaining nodes is preserved
+func (ni *nodeInventory) reset(ids []uint16) {
+	ni.lock.Lock()
+	defer ni.lock.Unlock()
+	nodes := make(map[uint16]*nodeInfo)
+	for _, id := range ids {
+		if node, ok := ni.nodes[id]; ok {
+			nodes[id] = node
+		} else {
+			nodes[id] = &nodeInfo{id: id}
+		}
+	}
+	ni.nodes = nodes
+}
+
+// update applies the content of ZW_APPLICATION_UPDATE request to the list of nodes
+func (ni *nodeInventory) update(u *zw.ApplicationUpdate) {
+	ni.lock.Lock()
+	defer ni.lock.Unlock()
+	if ni.nodes == nil {
+		ni.nodes = make(map[uint16]*nodeInfo)
+	}
+	switch u.Status {
+	case zw.UPDATE_STATE_DELETE_DONE:
+		delete(ni.nodes, u.NodeID)
+	case zw.UPDATE_STATE_NODE_INFO_RECEIVED, zw.UPDATE_STATE_NEW_ID_ASSIGNED:
+		node, ok := ni.nodes[u.NodeID]
+		if !ok {
+			node = &nodeInfo{id: u.NodeID}
+			ni.nodes[u.NodeID] = node
+		}
+		if u.Generic != 0 {
+			node.infoReceived = true
+			node.basic, node.generic, node.specific = u.Basic, u.Generic, u.Specific
+			node.commandClasses = append([]byte(nil), u.CommandClasses...)
+		}
+	}
+}
+
+// ids returns sorted list of nodes identifiers
+func (ni *nodeInventory) ids() []uint16 {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	ids := make([]uint16, 0, len(ni.nodes))
+	for id := range ni.nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// supports verifies if the node supports provided command class
+func (ni *nodeInventory) supports(id uint16, commandClass byte) bool {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	if node, ok := ni.nodes[id]; ok {
+		for _, cc := range node.commandClasses {
+			if cc == commandClass {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// list returns the list of nodes information in API format
+func (ni *nodeInventory) list() []*api.NodeInfo {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	rv := make([]*api.NodeInfo, 0, len(ni.nodes))
+	for _, node := range ni.nodes {
+		entry := &api.NodeInfo{ID: node.id, LongRange: zw.IsLongRangeNodeID(node.id)}
+		if node.infoReceived {
+			entry.Basic, entry.Generic, entry.Specific = node.basic, node.generic, node.specific
+			for _, cc := range node.commandClasses {
+				entry.CommandClasses = append(entry.CommandClasses, uint16(cc))
+			}
+		}
+		rv = append(rv, entry)
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].ID < rv[j].ID })
+	return rv
+}
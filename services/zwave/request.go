@@ -0,0 +1,172 @@
+package zwave
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+// request timeouts
+const (
+	responseTimeout = 1500 * time.Millisecond
+	callbackTimeout = 10 * time.Second
+)
+
+// request errors
+var (
+	errNoResponse      = errors.New("no response from the controller")
+	errNoCallback      = errors.New("no callback from the controller")
+	errRequestRejected = errors.New("the request is rejected by the controller")
+)
+
+// pendingRequest is the request data frame waiting for the response and, optionally, the callback from the controller
+type pendingRequest struct {
+	command  byte
+	funcID   byte
+	response chan []byte
+	callback chan []byte
+}
+
+// pendingRequests is the list of requests waiting for the controller's response or callback
+type pendingRequests struct {
+	lock    sync.Mutex
+	entries []*pendingRequest
+}
+
+func (pr *pendingRequests) add(command, funcID byte) *pendingRequest {
+	r := &pendingRequest{
+		command:  command,
+		funcID:   funcID,
+		response: make(chan []byte, 1),
+	}
+	if funcID != 0 {
+		r.callback = make(chan []byte, 1)
+	}
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	pr.entries = append(pr.entries, r)
+	return r
+}
+
+func (pr *pendingRequests) remove(r *pendingRequest) {
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	for i, entry := range pr.entries {
+		if entry == r {
+			pr.entries = append(pr.entries[:i], pr.entries[i+1:]...)
+			break
+		}
+	}
+}
+
+// response delivers the response payload to the first pending request with the matching command, returns true if delivered
+func (pr *pendingRequests) response(payload []byte) bool {
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	for _, entry := range pr.entries {
+		if entry.command == payload[0] {
+			select {
+			case entry.response <- payload:
+				return true
+			default:
+			}
+		}
+	}
+	return false
+}
+
+// callback delivers the callback payload to the pending request with the matching command and function id, returns true if delivered
+func (pr *pendingRequests) callback(payload []byte) bool {
+	if len(payload) < 2 {
+		return false
+	}
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	for _, entry := range pr.entries {
+		if entry.command == payload[0] && entry.callback != nil && entry.funcID == payload[1] {
+			select {
+			case entry.callback <- payload:
+				return true
+			default:
+			}
+		}
+	}
+	return false
+}
+
+// nextFuncID returns the next function (callback) identifier, never 0
+func (svc *Service) nextFuncID() byte {
+	for {
+		if id := byte(atomic.AddUint32(&svc.funcID, 1)); id != 0 {
+			return id
+		}
+	}
+}
+
+// handleDataFrame processes valid data frame received from the controller
+func (svc *Service) handleDataFrame(frame []byte) {
+	if payload := zw.UnpackResponse(frame); payload != nil {
+		svc.pending.response(payload)
+	} else if payload := zw.UnpackRequest(frame); payload != nil {
+		if payload[0] == zw.ZW_SEND_DATA {
+			svc.completeTransmission(payload)
+		}
+		if svc.pending.callback(payload) {
+			return
+		}
+		switch payload[0] {
+		case zw.ZW_APPLICATION_UPDATE:
+			if u, ok := zw.ParseApplicationUpdate(svc.nodeIDType.Load(), payload); ok {
+				svc.nodes.update(u)
+			}
+		case zw.ZW_APPLICATION_COMMAND_HANDLER:
+			if c, ok := zw.ParseApplicationCommand(svc.nodeIDType.Load(), payload); ok && len(c.Command) > 0 {
+				switch c.Command[0] {
+				case zw.COMMAND_CLASS_BATTERY:
+					svc.handleBatteryReport(c.NodeID, c.Command)
+				case zw.COMMAND_CLASS_TIME:
+					svc.handleTimeCommand(c.NodeID, c.Command)
+				}
+			}
+		}
+	}
+}
+
+// request sends the request data frame and waits for the response and, if funcID is not 0, for the callback.
+// The request data frame must contain command id and function id provided.
+func (svc *Service) request(ctx context.Context, command, funcID byte, frame []byte) (response []byte, callback []byte, err error) {
+	r := svc.pending.add(command, funcID)
+	defer svc.pending.remove(r)
+
+	if _, err = svc.Send(frame); err != nil {
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-time.After(responseTimeout):
+		return nil, nil, errNoResponse
+	case response = <-r.response:
+	}
+
+	if funcID == 0 {
+		return
+	}
+	if len(response) < 2 || response[1] == 0 {
+		return response, nil, errRequestRejected
+	}
+
+	select {
+	case <-ctx.Done():
+		return response, nil, ctx.Err()
+	case <-time.After(callbackTimeout):
+		return response, nil, errNoCallback
+	case callback = <-r.callback:
+	}
+	return
+}
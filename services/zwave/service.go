@@ -11,6 +11,7 @@ import (
 	"github.com/stas-makutin/howeve/api"
 	"github.com/stas-makutin/howeve/defs"
 	"github.com/stas-makutin/howeve/log"
+	"github.com/stas-makutin/howeve/services/backoff"
 	"github.com/stas-makutin/howeve/services/serial"
 	"github.com/stas-makutin/howeve/utils/syncutil"
 	zw "github.com/stas-makutin/howeve/zwave"
@@ -27,14 +28,19 @@ const (
 	zwOcReplyTimeout   = "T"
 	zwOcDataFrame      = "D"
 	zwOcUnknownFrame   = "U"
+	zwOcInitialize     = "I"
 
 	zwOsSuccess       = "0"
 	zwOsFailure       = "F"
 	zwOsWrongLength   = "L"
 	zwOsWrongChecksum = "C"
+	zwOsGaveUp        = "G"
 
-	zwOfWriteQueue = "Q"
-	zwOfWriteReply = "R"
+	zwOfWriteQueue     = "Q"
+	zwOfWriteReply     = "R"
+	zwOfNodeIDType     = "T"
+	zwOfNodes          = "N"
+	zwOfLongRangeNodes = "L"
 )
 
 // Service ZWave service implementation
@@ -47,17 +53,24 @@ type Service struct {
 
 	status syncutil.RLocked[error]
 
+	funcID  uint32
+	pending pendingRequests
+	opLock  sync.Mutex
+
+	nodeIDType   syncutil.RLocked[zw.NodeIDType]
+	capabilities syncutil.RLocked[*zw.Capabilities]
+	nodes        nodeInventory
+	statistics   radioStatistics
+	battery      batteryMonitor
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	stopWg sync.WaitGroup
 }
 
-// NewServiceSerial creates new zwave service implementation using serial transport
+// NewService creates new zwave service implementation using provided transport
 func NewService(transport defs.Transport, entry string, params api.ParamValues) (defs.Service, error) {
-	// explicitly override timeouts, they must be 0 for non-blocking read/write operations
-	pv := params.Copy()
-	pv[serial.ParamNameReadTimeout] = uint32(0)
-	pv[serial.ParamNameWriteTimeout] = uint32(0)
+	pv := serial.ServiceParams(transport, params)
 
 	return &Service{
 		transport: transport,
@@ -72,8 +85,11 @@ func (svc *Service) Start() {
 
 	svc.ctx, svc.cancel = context.WithCancel(context.Background())
 
-	svc.stopWg.Add(1)
+	svc.stopWg.Add(4)
 	go svc.serviceLoop()
+	go svc.rssiLoop(svc.ctx)
+	go svc.batteryLoop(svc.ctx)
+	go svc.clockLoop(svc.ctx)
 }
 
 func (svc *Service) Stop() {
@@ -107,6 +123,14 @@ func (svc *Service) Status() defs.ServiceStatus {
 	return err.(defs.ServiceStatus)
 }
 
+// ResolvedEntry returns the actual entry the transport is opened with, if the transport resolves the service entry
+func (svc *Service) ResolvedEntry() string {
+	if r, ok := svc.transport.(defs.EntryResolver); ok {
+		return r.ResolvedEntry()
+	}
+	return ""
+}
+
 func (svc *Service) Send(payload []byte) (*api.Message, error) {
 	if len(payload) <= 0 || len(payload) > 255 {
 		return nil, defs.ErrBadPayload
@@ -133,17 +157,6 @@ QueueLoop:
 	return message, nil
 }
 
-func (svc *Service) openTimeout() time.Duration {
-	openTimeout := time.Millisecond * 5000
-	if v, ok := svc.params[defs.ParamNameOpenAttemptsInterval]; ok {
-		openTimeout = time.Duration(v.(uint32)) * time.Millisecond
-		if openTimeout <= 0 {
-			openTimeout = 100 * time.Millisecond
-		}
-	}
-	return openTimeout
-}
-
 func (svc *Service) outgoingMaxTTL() time.Duration {
 	outgoingMaxTTL := time.Millisecond * 10000
 	if v, ok := svc.params[defs.ParamNameOutgoingMaxTTL]; ok {
@@ -172,7 +185,7 @@ func (svc *Service) serviceLoop() {
 	defer svc.transport.Close()
 	defer svc.stopWg.Done()
 
-	openTimeout := svc.openTimeout()
+	openBackoff := backoff.New(backoff.NewPolicy(svc.params))
 	outgoingMaxTTL := svc.outgoingMaxTTL()
 	open := true
 	expectReply := false
@@ -186,18 +199,24 @@ ServiceLoop:
 			expectReply = false
 			rb = re
 			if err := svc.transport.Open(svc.key.Entry, svc.params); err != nil {
-				svc.status.Store(fmt.Errorf("unable to open transport: %s", err.Error()))
-				svc.log(zwOcTransportOpen, zwOsFailure, err.Error())
-				select {
-				case <-svc.ctx.Done():
+				if !openBackoff.Retry(svc.ctx, svc.key, &svc.status, "unable to open transport", err, func(gaveUp bool) {
+					if gaveUp {
+						svc.log(zwOcTransportOpen, zwOsGaveUp, err.Error())
+					} else {
+						svc.log(zwOcTransportOpen, zwOsFailure, err.Error())
+					}
+				}) {
 					break ServiceLoop
-				case <-time.After(openTimeout):
-					open = true
-					continue
 				}
+				open = true
+				continue
 			} else {
+				openBackoff.Reset()
 				svc.log(zwOcTransportOpen, zwOsSuccess)
 				svc.status.Store(defs.ErrStatusGood)
+
+				svc.stopWg.Add(1)
+				go svc.initController(svc.ctx)
 			}
 		}
 
@@ -219,14 +238,20 @@ ServiceLoop:
 				if outgoingMaxTTL > 0 && time.Now().UTC().Sub(message.Time) > outgoingMaxTTL {
 					defs.Messages.UpdateState(message.ID, api.OutgoingTimedOut)
 				} else if n, err := svc.transport.Write(message.Payload); err != nil || n != len(message.Payload) {
-					svc.status.Store(fmt.Errorf("unable to write using transport: %s", err.Error()))
 					svc.log(zwOcTransportWrite, zwOsFailure, zwOfWriteQueue, err.Error())
 					defs.Messages.UpdateState(message.ID, api.OutgoingFailed)
-					open = true
+					if !errors.Is(err, defs.ErrTransportHeld) {
+						// the held transport is operational, only the message fails
+						svc.status.Store(fmt.Errorf("unable to write using transport: %s", err.Error()))
+						open = true
+					}
 				} else {
 					defs.Messages.UpdateState(message.ID, api.Outgoing)
 					if vr, _ := zw.ValidateDataFrame(message.Payload); vr == zw.FrameOK || vr == zw.FrameWrongChecksum {
 						expectReply = true
+						if vr == zw.FrameOK {
+							svc.trackTransmission(message.Payload)
+						}
 					}
 				}
 				continue
@@ -253,7 +278,7 @@ ServiceLoop:
 			for rb < re {
 				switch buffer[rb] {
 				case zw.FrameASK, zw.FrameNAK, zw.FrameCAN:
-					defs.Messages.Register(svc.key, buffer[rb:rb+1], api.Incoming)
+					defs.Messages.Register(svc.key, append([]byte(nil), buffer[rb]), api.Incoming)
 					rb++
 
 				case zw.FrameSOF:
@@ -262,7 +287,9 @@ ServiceLoop:
 					switch vr, pos := zw.ValidateDataFrame(buffer[rb:re]); vr {
 					case zw.FrameOK:
 						reply = []byte{zw.FrameASK}
-						defs.Messages.Register(svc.key, buffer[rb:rb+pos], api.Incoming)
+						frame := append([]byte(nil), buffer[rb:rb+pos]...)
+						defs.Messages.Register(svc.key, frame, api.Incoming)
+						svc.handleDataFrame(frame)
 						rb += pos
 
 					case zw.FrameIncomplete:
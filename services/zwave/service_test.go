@@ -0,0 +1,266 @@
+package zwave
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/events/handlers"
+	"github.com/stas-makutin/howeve/services/capture"
+	"github.com/stas-makutin/howeve/services/mock"
+	"github.com/stas-makutin/howeve/services/simulator"
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(mock.RunTests(m))
+}
+
+var ack = []byte{zw.FrameASK}
+
+// expectInit adds the controller initialization steps to the script
+func expectInit(m *mock.Transport, nodes byte) {
+	m.Expect(zw.DataRequest([]byte{zw.FUNC_ID_SERIAL_API_GET_CAPABILITIES})).
+		Reply(ack, zw.DataResponse([]byte{zw.FUNC_ID_SERIAL_API_GET_CAPABILITIES, 1, 0, 0x00, 0x86, 0x00, 0x01, 0x00, 0x5a}))
+	m.Expect(ack)
+	m.Expect(zw.DataRequest([]byte{zw.FUNC_ID_SERIAL_API_GET_INIT_DATA})).
+		Reply(ack, zw.DataResponse([]byte{zw.FUNC_ID_SERIAL_API_GET_INIT_DATA, 5, 0, 1, nodes}))
+	m.Expect(ack)
+}
+
+func newTestService(t *testing.T, m *mock.Transport) *Service {
+	return mock.NewService[*Service](t, api.ParamValues{
+		ParamNameRSSIInterval:        uint32(0),
+		ParamNameBatteryTimeout:      uint32(0),
+		ParamNameBatteryPollInterval: uint32(0),
+		ParamNameClockSyncInterval:   uint32(0),
+	}, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(m, "test", params)
+	})
+}
+
+func TestServiceInitialization(t *testing.T) {
+	m := mock.New()
+	expectInit(m, 0x03) // nodes 1 and 2
+	for _, node := range []uint16{1, 2} {
+		m.Expect(zw.RequestNodeInfo(zw.NodeIDType8Bit, node)).Reply(ack, zw.DataResponse([]byte{zw.ZW_REQUEST_NODE_INFO, 1}))
+		m.Expect(ack)
+	}
+	m.Emit(zw.DataRequest([]byte{zw.ZW_APPLICATION_UPDATE, zw.UPDATE_STATE_NODE_INFO_RECEIVED, 1, 5, 4, 0x10, 0x01, zw.COMMAND_CLASS_BATTERY, zw.COMMAND_CLASS_TIME}))
+	m.Expect(ack)
+	m.Emit(zw.DataRequest([]byte{zw.ZW_APPLICATION_COMMAND_HANDLER, 0, 1, 3, zw.COMMAND_CLASS_BATTERY, zw.BATTERY_REPORT, 75}))
+	m.Expect(ack)
+
+	svc := newTestService(t, m)
+	svc.Start()
+	defer svc.Stop()
+
+	if err := m.Wait(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	mock.WaitFor(t, "the battery report", func() bool {
+		nodes := svc.Nodes()
+		return len(nodes) == 2 && nodes[0].Battery != nil
+	})
+
+	nodes := svc.Nodes()
+	if nodes[0].ID != 1 || nodes[0].Generic != 0x10 || len(nodes[0].CommandClasses) != 2 || nodes[0].Battery.Level != 75 || nodes[0].Battery.Low {
+		t.Errorf("Unexpected node 1 information: %+v", nodes[0])
+	}
+	if nodes[1].ID != 2 || nodes[1].Generic != 0 || nodes[1].Battery != nil {
+		t.Errorf("Unexpected node 2 information: %+v", nodes[1])
+	}
+	if svc.Status() != defs.ErrStatusGood {
+		t.Errorf("Unexpected service status: %v", svc.Status())
+	}
+}
+
+func TestServiceReopen(t *testing.T) {
+	frame := zw.SendData(zw.NodeIDType8Bit, 1, zw.BatteryGet(), txOptionsSingleCast, 0)
+
+	m := mock.New()
+	m.FailOpen(errors.New("no device"))
+	expectInit(m, 0)
+	m.Expect(frame).WriteError(errors.New("write failed"))
+	expectInit(m, 0)
+	m.Expect(frame).Reply(ack)
+
+	svc := newTestService(t, m)
+	svc.Start()
+	defer svc.Stop()
+
+	mock.WaitFor(t, "the transport opened", func() bool { return m.Opens() == 2 })
+	mock.WaitFor(t, "the controller initialization", func() bool { return len(m.Writes()) == 4 })
+
+	message, err := svc.Send(frame)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	mock.WaitFor(t, "the transport reopened", func() bool { return m.Opens() == 3 })
+	if _, msg := defs.Messages.Get(message.ID); msg.State != api.OutgoingFailed {
+		t.Errorf("The message state is %v, expected %v", msg.State, api.OutgoingFailed)
+	}
+	mock.WaitFor(t, "the controller initialization", func() bool { return len(m.Writes()) == 9 })
+
+	message, err = svc.Send(frame)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := m.Wait(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	mock.WaitFor(t, "the message sent", func() bool {
+		_, msg := defs.Messages.Get(message.ID)
+		return msg.State == api.Outgoing
+	})
+}
+
+func TestServiceGaveUp(t *testing.T) {
+	gaveUp := make(chan *handlers.ServiceGaveUp, 1)
+	id := handlers.Dispatcher.Subscribe(func(event interface{}) {
+		if e, ok := event.(*handlers.ServiceGaveUp); ok {
+			gaveUp <- e
+		}
+	})
+	defer handlers.Dispatcher.Unsubscribe(id)
+
+	m := mock.New()
+	m.FailOpen(errors.New("no device"), errors.New("no device"), errors.New("no device"))
+
+	svc := mock.NewService[*Service](t, api.ParamValues{
+		defs.ParamNameOpenBackoffMultiplier: uint32(200),
+		defs.ParamNameOpenJitter:            uint8(0),
+		defs.ParamNameOpenMaxAttempts:       uint32(3),
+	}, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(m, "test", params)
+	})
+	start := time.Now()
+	svc.Start()
+
+	select {
+	case e := <-gaveUp:
+		if e.Attempts != 3 || e.Error != "no device" || *e.ServiceKey != *svc.key {
+			t.Errorf("Unexpected event: %+v", e.ServiceGaveUp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the service did not give up")
+	}
+	if d := time.Since(start); d < 300*time.Millisecond {
+		t.Errorf("The attempts must be delayed by 100 and 200 milliseconds, gave up after %v", d)
+	}
+	if status := svc.Status(); !errors.Is(status, defs.ErrOpenGaveUp) {
+		t.Errorf("Unexpected service status: %v", status)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if m.Opens() != 3 {
+		t.Errorf("No attempts to open expected after giving up, %d attempts", m.Opens())
+	}
+}
+
+func TestServiceTimeRequest(t *testing.T) {
+	m := mock.New()
+	expectInit(m, 0)
+	m.Emit(zw.DataRequest([]byte{zw.ZW_APPLICATION_COMMAND_HANDLER, 0, 5, 2, zw.COMMAND_CLASS_TIME, zw.TIME_GET}))
+	m.Expect(ack)
+	m.ExpectFunc(func(p []byte) bool {
+		payload := zw.UnpackRequest(p)
+		if payload == nil {
+			return false
+		}
+		node, funcID, ok := zw.ParseSendData(zw.NodeIDType8Bit, payload)
+		return ok && node == 5 && funcID == 0 && len(payload) > 4 && payload[3] == zw.COMMAND_CLASS_TIME && payload[4] == zw.TIME_REPORT
+	}, []byte("time report to node 5")).Reply(ack)
+
+	svc := newTestService(t, m)
+	svc.Start()
+	defer svc.Stop()
+
+	if err := m.Wait(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceSimulator(t *testing.T) {
+	zs := mock.NewService[*Service](t, api.ParamValues{
+		simulator.ParamNameNodes:     "2:switch,3:dimmer",
+		simulator.ParamNameDelay:     uint32(1),
+		ParamNameRSSIInterval:        uint32(0),
+		ParamNameBatteryPollInterval: uint32(0),
+		ParamNameClockSyncInterval:   uint32(0),
+	}, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(&simulator.Transport{}, "test", params)
+	})
+	zs.Start()
+
+	mock.WaitFor(t, "the nodes information", func() bool {
+		nodes := zs.Nodes()
+		return len(nodes) == 3 && nodes[0].Generic != 0 && nodes[1].Generic != 0 && nodes[2].Generic != 0
+	})
+	if nodes := zs.Nodes(); nodes[1].ID != 2 || nodes[1].Generic != zw.GENERIC_TYPE_SWITCH_BINARY || nodes[2].ID != 3 || nodes[2].Generic != zw.GENERIC_TYPE_SWITCH_MULTILEVEL {
+		t.Errorf("Unexpected nodes: %+v, %+v", nodes[1], nodes[2])
+	}
+
+	zs.opLock.Lock()
+	funcID := zs.nextFuncID()
+	_, callback, err := zs.request(zs.ctx, zw.ZW_SEND_DATA, funcID, zw.SendData(zw.NodeIDType8Bit, 3, []byte{zw.COMMAND_CLASS_SWITCH_MULTILEVEL, zw.SWITCH_MULTILEVEL_SET, 50}, txOptionsSingleCast, funcID))
+	zs.opLock.Unlock()
+	if err != nil || len(callback) < 3 || callback[2] != zw.TRANSMIT_COMPLETE_OK {
+		t.Fatalf("Unexpected send data result: %x, %v", callback, err)
+	}
+
+	report := zw.DataRequest([]byte{zw.ZW_APPLICATION_COMMAND_HANDLER, 0, 3, 3, zw.COMMAND_CLASS_SWITCH_MULTILEVEL, zw.SWITCH_MULTILEVEL_REPORT, 50})
+	mock.WaitFor(t, "the switch report", func() bool {
+		for _, payload := range defs.Messages.(*mock.MessageLog).Incoming() {
+			if bytes.Equal(payload, report) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func TestDiscoverSimulator(t *testing.T) {
+	entries, err := DiscoverSimulator(context.Background(), nil)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Unexpected discovery result: %v, %v", entries, err)
+	}
+	if entries[0].ServiceKey.Transport != api.TransportSimulator || entries[0].Description != "Z-Wave controller simulator [Z-Wave 7.18]" {
+		t.Errorf("Unexpected discovery entry: %+v", entries[0])
+	}
+}
+
+func TestServiceCaptureReplay(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "capture.log")
+	params := api.ParamValues{
+		simulator.ParamNameDelay:     uint32(1),
+		ParamNameRSSIInterval:        uint32(0),
+		ParamNameBatteryPollInterval: uint32(0),
+		ParamNameClockSyncInterval:   uint32(0),
+	}
+	nodesReceived := func(svc *Service) func() bool {
+		return func() bool {
+			nodes := svc.Nodes()
+			return len(nodes) == 3 && nodes[0].Generic != 0 && nodes[1].Generic != 0 && nodes[2].Generic != 0
+		}
+	}
+
+	zs := mock.NewService[*Service](t, params, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(capture.NewTransport(&simulator.Transport{}, fileName), "test", params)
+	})
+	zs.Start()
+	mock.WaitFor(t, "the nodes information", nodesReceived(zs))
+	zs.Stop()
+
+	params[capture.ParamNameTiming] = capture.TimingNone
+	zs = mock.NewService[*Service](t, params, func(params api.ParamValues) (defs.Service, error) {
+		return NewService(&capture.Replay{}, fileName, params)
+	})
+	zs.Start()
+	mock.WaitFor(t, "the replayed nodes information", nodesReceived(zs))
+}
@@ -0,0 +1,230 @@
+package zwave
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stas-makutin/howeve/api"
+	"github.com/stas-makutin/howeve/defs"
+	"github.com/stas-makutin/howeve/events/handlers"
+	zw "github.com/stas-makutin/howeve/zwave"
+)
+
+// ParamNameRSSIInterval parameter name for the background RSSI polling interval
+const ParamNameRSSIInterval = "rssiInterval"
+
+// the number of recent transmissions the node statistics averages are calculated on
+const statisticsWindow = 32
+
+// the maximal number of ZW_SEND_DATA requests waiting for the callback
+const maxTrackedTransmissions = 64
+
+// txSample is the outcome of the single transmission
+type txSample struct {
+	success bool
+	txTime  time.Duration
+	rssi    int8
+	hasRSSI bool
+}
+
+// nodeStatistics is the rolling radio statistics of the node
+type nodeStatistics struct {
+	sent, failed uint32
+	samples      [statisticsWindow]txSample
+	count, next  int
+	route        []uint16
+	routeRSSI    []int8
+	lastStatus   string
+	updated      time.Time
+}
+
+// radioStatistics is the radio statistics of the Z-Wave network
+type radioStatistics struct {
+	lock           sync.Mutex
+	nodes          map[uint16]*nodeStatistics
+	transmissions  map[byte]uint16 // function (callback) identifier -> node identifier
+	backgroundRSSI []int8
+}
+
+// track remembers the node of ZW_SEND_DATA request to match it with the callback later
+func (rs *radioStatistics) track(funcID byte, nodeID uint16) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	if rs.transmissions == nil || len(rs.transmissions) >= maxTrackedTransmissions {
+		rs.transmissions = make(map[byte]uint16)
+	}
+	rs.transmissions[funcID] = nodeID
+}
+
+// complete records the outcome of tracked ZW_SEND_DATA request, returns the node statistics snapshot or nil if the request is not tracked
+func (rs *radioStatistics) complete(funcID byte, success bool, status string, report *zw.TxReport) *api.NodeStatistics {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	nodeID, ok := rs.transmissions[funcID]
+	if !ok {
+		return nil
+	}
+	delete(rs.transmissions, funcID)
+
+	if rs.nodes == nil {
+		rs.nodes = make(map[uint16]*nodeStatistics)
+	}
+	ns, ok := rs.nodes[nodeID]
+	if !ok {
+		ns = &nodeStatistics{}
+		rs.nodes[nodeID] = ns
+	}
+
+	ns.sent++
+	if !success {
+		ns.failed++
+	}
+	sample := txSample{success: success}
+	if report != nil {
+		sample.txTime = report.TxTime
+		if success && zw.ValidRSSI(report.AckRSSI) {
+			sample.rssi, sample.hasRSSI = report.AckRSSI, true
+		}
+		ns.route = ns.route[:0]
+		for _, repeater := range report.Repeaters {
+			ns.route = append(ns.route, uint16(repeater))
+		}
+		ns.routeRSSI = append(append(ns.routeRSSI[:0], report.RepeatersRSSI...), report.AckRSSI)
+	}
+	ns.samples[ns.next] = sample
+	ns.next = (ns.next + 1) % statisticsWindow
+	if ns.count < statisticsWindow {
+		ns.count++
+	}
+	ns.lastStatus = status
+	ns.updated = time.Now().UTC()
+
+	return ns.snapshot(nodeID)
+}
+
+// setBackgroundRSSI stores the background RSSI values
+func (rs *radioStatistics) setBackgroundRSSI(rssi []int8) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.backgroundRSSI = rssi
+}
+
+// statistics returns the radio statistics in API format
+func (rs *radioStatistics) statistics() *api.ServiceStatistics {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rv := &api.ServiceStatistics{BackgroundRSSI: append([]int8(nil), rs.backgroundRSSI...)}
+	for nodeID, ns := range rs.nodes {
+		rv.Nodes = append(rv.Nodes, ns.snapshot(nodeID))
+	}
+	sort.Slice(rv.Nodes, func(i, j int) bool { return rv.Nodes[i].Node < rv.Nodes[j].Node })
+	return rv
+}
+
+func (ns *nodeStatistics) snapshot(nodeID uint16) *api.NodeStatistics {
+	rv := &api.NodeStatistics{
+		Node:       nodeID,
+		Sent:       ns.sent,
+		Failed:     ns.failed,
+		Recent:     uint32(ns.count),
+		Route:      append([]uint16(nil), ns.route...),
+		RouteRSSI:  append([]int8(nil), ns.routeRSSI...),
+		LastStatus: ns.lastStatus,
+		Updated:    ns.updated,
+	}
+	var txTime time.Duration
+	rssi, rssiCount := 0, 0
+	for i := 0; i < ns.count; i++ {
+		sample := &ns.samples[i]
+		txTime += sample.txTime
+		if ms := uint32(sample.txTime / time.Millisecond); ms > rv.TxTimeMax {
+			rv.TxTimeMax = ms
+		}
+		if sample.hasRSSI {
+			rssi += int(sample.rssi)
+			rssiCount++
+		}
+	}
+	if ns.count > 0 {
+		rv.TxTime = uint32(txTime / time.Duration(ns.count) / time.Millisecond)
+	}
+	if rssiCount > 0 {
+		rv.RSSI = int8(rssi / rssiCount)
+	}
+	return rv
+}
+
+// trackTransmission remembers the node of outgoing ZW_SEND_DATA request, if any
+func (svc *Service) trackTransmission(frame []byte) {
+	if payload := zw.UnpackRequest(frame); payload != nil {
+		if nodeID, funcID, ok := zw.ParseSendData(svc.nodeIDType.Load(), payload); ok && funcID != 0 && nodeID != zw.NODE_BROADCAST && nodeID != zw.NODE_BROADCAST_LR {
+			svc.statistics.track(funcID, nodeID)
+		}
+	}
+}
+
+// completeTransmission records the outcome of ZW_SEND_DATA request and notifies about updated node statistics
+func (svc *Service) completeTransmission(payload []byte) {
+	cb, ok := zw.ParseSendDataCallback(payload)
+	if !ok || cb.Command != zw.ZW_SEND_DATA {
+		return
+	}
+	success, status := txStatus(payload, nil)
+	if statistics := svc.statistics.complete(cb.FuncID, success, status, cb.Report); statistics != nil {
+		handlers.SendNodeStatistics(svc.key, statistics)
+	}
+}
+
+func (svc *Service) rssiInterval() time.Duration {
+	rssiInterval := time.Minute
+	if v, ok := svc.params[ParamNameRSSIInterval]; ok {
+		rssiInterval = time.Duration(v.(uint32)) * time.Millisecond
+		if rssiInterval > 0 && rssiInterval < time.Second {
+			rssiInterval = time.Second
+		}
+	}
+	return rssiInterval
+}
+
+// rssiLoop polls the background RSSI of the controller
+func (svc *Service) rssiLoop(ctx context.Context) {
+	defer svc.stopWg.Done()
+
+	rssiInterval := svc.rssiInterval()
+	if rssiInterval <= 0 {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(rssiInterval):
+		}
+
+		if svc.Status() != defs.ErrStatusGood {
+			continue
+		}
+		if capabilities := svc.capabilities.Load(); capabilities == nil || !capabilities.Supports(zw.FUNC_ID_ZW_GET_BACKGROUND_RSSI) {
+			continue
+		}
+
+		svc.opLock.Lock()
+		response, _, err := svc.request(ctx, zw.FUNC_ID_ZW_GET_BACKGROUND_RSSI, 0, zw.DataRequest([]byte{zw.FUNC_ID_ZW_GET_BACKGROUND_RSSI}))
+		svc.opLock.Unlock()
+
+		if err == nil {
+			if rssi, ok := zw.ParseBackgroundRSSI(response); ok {
+				svc.statistics.setBackgroundRSSI(rssi)
+			}
+		}
+	}
+}
+
+// Statistics is the implementation of defs.StatisticsProvider interface
+func (svc *Service) Statistics() *api.ServiceStatistics {
+	return svc.statistics.statistics()
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/stas-makutin/howeve/log"
 	"github.com/stas-makutin/howeve/messages"
 	"github.com/stas-makutin/howeve/services"
+	"github.com/stas-makutin/howeve/services/bridge"
+	"github.com/stas-makutin/howeve/services/plugin"
 	"github.com/stas-makutin/howeve/tasks"
 )
 
@@ -17,7 +19,9 @@ func init() {
 		{Name: "Log", Task: log.NewTask(appName)},
 		{Name: "Events", Task: handlers.NewTask()},
 		{Name: "Message Log", Task: messages.NewTask()},
+		{Name: "Plugins", Task: plugin.NewTask()},
 		{Name: "Services", Task: services.NewTask()},
+		{Name: "Bridges", Task: bridge.NewTask()},
 		{Name: "HTTP server", Task: httpsrv.NewTask()},
 	}
 }
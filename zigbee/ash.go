@@ -0,0 +1,266 @@
+package zigbee
+
+import "errors"
+
+// ASH (Asynchronous Serial Host) frame structure:
+//   Control byte
+//   ... Data (DATA frames: EZSP frame XORed with the pseudo-random sequence; RSTACK and ERROR: version and code)
+//   CRC-CCITT of control and data, high byte first
+//   Flag byte
+// The reserved bytes of control, data and CRC are escaped (byte stuffing) before the frame is written.
+
+// ASH reserved bytes
+const (
+	ASHFlag       = 0x7e // the end of the frame
+	ASHEscape     = 0x7d // the escaped byte follows, it is XORed with 0x20
+	ASHXOn        = 0x11 // resume transmission
+	ASHXOff       = 0x13 // stop transmission
+	ASHSubstitute = 0x18 // replaces the byte with the low-level communication error, the frame is discarded
+	ASHCancel     = 0x1a // terminates the frame in progress
+)
+
+// ASH frame types
+const (
+	ASHData ASHFrameType = iota
+	ASHAck
+	ASHNak
+	ASHReset
+	ASHResetAck
+	ASHError
+)
+
+// ASH reset and error codes reported by RSTACK and ERROR frames
+const (
+	ASHResetUnknown         = 0x00
+	ASHResetExternal        = 0x01
+	ASHResetPowerOn         = 0x02
+	ASHResetWatchdog        = 0x03
+	ASHResetAssert          = 0x06
+	ASHResetBootloader      = 0x09
+	ASHResetSoftware        = 0x0b
+	ASHErrorExceededMaxAcks = 0x51
+)
+
+// ASHVersion the ASH protocol version supported
+const ASHVersion = 2
+
+// ASH frames numbers are 3 bits wide
+const ASHFrameNumbers = 8
+
+// ASHMaxDataLength the maximal length of DATA frame data
+const ASHMaxDataLength = 128
+
+// ASH frame errors
+var (
+	ErrASHFrameLength = errors.New("ASH frame is too short or too long")
+	ErrASHChecksum    = errors.New("ASH frame CRC is not valid")
+	ErrASHEscape      = errors.New("ASH frame contains the reserved byte")
+	ErrASHControl     = errors.New("ASH frame control byte is not valid")
+)
+
+// ASHFrameType type of ASH frame
+type ASHFrameType uint8
+
+// ASHFrame is ASH frame
+type ASHFrame struct {
+	Type     ASHFrameType
+	FrmNum   uint8 // DATA: the frame number
+	AckNum   uint8 // DATA, ACK, NAK: the number of the next frame expected from the other side
+	ReTx     bool  // DATA: the frame is retransmitted
+	NotReady bool  // ACK, NAK: the host is not ready to receive callbacks
+	Data     []byte
+}
+
+// ASHCRC calculates CRC-16/CCITT-FALSE (polynomial 0x1021, initial value 0xFFFF)
+func ASHCRC(data []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// ASHRandomize XORs DATA frame data with the pseudo-random sequence, the same function reverts the data back
+func ASHRandomize(data []byte) []byte {
+	rv := make([]byte, len(data))
+	rand := byte(0x42)
+	for i, b := range data {
+		rv[i] = b ^ rand
+		if rand&1 == 0 {
+			rand >>= 1
+		} else {
+			rand = rand>>1 ^ 0xb8
+		}
+	}
+	return rv
+}
+
+func ashReserved(b byte) bool {
+	switch b {
+	case ASHFlag, ASHEscape, ASHXOn, ASHXOff, ASHSubstitute, ASHCancel:
+		return true
+	}
+	return false
+}
+
+// Control returns the control byte of the frame
+func (f *ASHFrame) Control() byte {
+	switch f.Type {
+	case ASHData:
+		control := (f.FrmNum&0x07)<<4 | f.AckNum&0x07
+		if f.ReTx {
+			control |= 0x08
+		}
+		return control
+	case ASHAck, ASHNak:
+		control := byte(0x80) | f.AckNum&0x07
+		if f.Type == ASHNak {
+			control |= 0x20
+		}
+		if f.NotReady {
+			control |= 0x08
+		}
+		return control
+	case ASHReset:
+		return 0xc0
+	case ASHResetAck:
+		return 0xc1
+	}
+	return 0xc2
+}
+
+// Encode returns the frame ready to be written: stuffed and terminated by the flag byte
+func (f *ASHFrame) Encode() []byte {
+	data := f.Data
+	if f.Type == ASHData {
+		data = ASHRandomize(data)
+	}
+	body := append([]byte{f.Control()}, data...)
+	crc := ASHCRC(body)
+	body = append(body, byte(crc>>8), byte(crc))
+
+	frame := make([]byte, 0, len(body)*2+2)
+	if f.Type == ASHReset {
+		// discard any partial frame the NCP may have received
+		frame = append(frame, ASHCancel)
+	}
+	for _, b := range body {
+		if ashReserved(b) {
+			frame = append(frame, ASHEscape, b^0x20)
+		} else {
+			frame = append(frame, b)
+		}
+	}
+	return append(frame, ASHFlag)
+}
+
+// ASHDataFrame creates DATA frame
+func ASHDataFrame(frmNum, ackNum uint8, reTx bool, data []byte) []byte {
+	return (&ASHFrame{Type: ASHData, FrmNum: frmNum, AckNum: ackNum, ReTx: reTx, Data: data}).Encode()
+}
+
+// ASHAckFrame creates ACK frame
+func ASHAckFrame(ackNum uint8) []byte {
+	return (&ASHFrame{Type: ASHAck, AckNum: ackNum}).Encode()
+}
+
+// ASHNakFrame creates NAK frame
+func ASHNakFrame(ackNum uint8) []byte {
+	return (&ASHFrame{Type: ASHNak, AckNum: ackNum}).Encode()
+}
+
+// ASHResetFrame creates RST frame, preceded by the cancel byte
+func ASHResetFrame() []byte {
+	return (&ASHFrame{Type: ASHReset}).Encode()
+}
+
+// ASHResetAckFrame creates RSTACK frame with provided reset code
+func ASHResetAckFrame(code byte) []byte {
+	return (&ASHFrame{Type: ASHResetAck, Data: []byte{ASHVersion, code}}).Encode()
+}
+
+// SplitASHFrame looks for the frame in the data read. Returns the frame content (without the flag byte) and the number
+// of bytes consumed, or 0 if the frame is incomplete. The content before the cancel byte is dropped, XON/XOFF bytes are skipped.
+func SplitASHFrame(data []byte) (frame []byte, n int) {
+	start := 0
+	for i, b := range data {
+		switch b {
+		case ASHCancel:
+			frame, start = frame[:0], i+1
+		case ASHXOn, ASHXOff:
+		case ASHFlag:
+			return frame, i + 1
+		default:
+			frame = append(frame, b)
+		}
+	}
+	if start > 0 && len(frame) == 0 {
+		// nothing but the cancelled content, consume it
+		return nil, start
+	}
+	return nil, 0
+}
+
+// ParseASHFrame parses the frame content returned by SplitASHFrame
+func ParseASHFrame(content []byte) (*ASHFrame, error) {
+	body := make([]byte, 0, len(content))
+	for i := 0; i < len(content); i++ {
+		b := content[i]
+		if b == ASHEscape {
+			if i++; i >= len(content) {
+				return nil, ErrASHEscape
+			}
+			b = content[i] ^ 0x20
+		} else if ashReserved(b) {
+			return nil, ErrASHEscape
+		}
+		body = append(body, b)
+	}
+	if len(body) < 3 || len(body) > ASHMaxDataLength+3 {
+		return nil, ErrASHFrameLength
+	}
+	if crc := ASHCRC(body[:len(body)-2]); byte(crc>>8) != body[len(body)-2] || byte(crc) != body[len(body)-1] {
+		return nil, ErrASHChecksum
+	}
+
+	control, data := body[0], body[1:len(body)-2]
+	f := &ASHFrame{}
+	switch {
+	case control&0x80 == 0:
+		if len(data) < 3 {
+			return nil, ErrASHFrameLength
+		}
+		f.Type, f.FrmNum, f.AckNum, f.ReTx = ASHData, control>>4&0x07, control&0x07, control&0x08 != 0
+		f.Data = ASHRandomize(data)
+		return f, nil
+	case control&0xe0 == 0x80:
+		f.Type = ASHAck
+	case control&0xe0 == 0xa0:
+		f.Type = ASHNak
+	case control == 0xc0:
+		f.Type = ASHReset
+		return f, nil
+	case control == 0xc1:
+		f.Type = ASHResetAck
+	case control == 0xc2:
+		f.Type = ASHError
+	default:
+		return nil, ErrASHControl
+	}
+	if f.Type == ASHAck || f.Type == ASHNak {
+		f.AckNum, f.NotReady = control&0x07, control&0x08 != 0
+		return f, nil
+	}
+	if len(data) != 2 {
+		return nil, ErrASHFrameLength
+	}
+	f.Data = append([]byte(nil), data...)
+	return f, nil
+}
@@ -0,0 +1,376 @@
+package zigbee
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// EZSP (EmberZNet Serial Protocol) frame structure:
+//   Sequence byte
+//   Frame control: 1 byte for legacy frames (EZSP version 4 and below, and the version command),
+//     2 bytes for EZSP version 8 and above; EZSP versions 5 - 7 use legacy frame control followed by 0xFF, 0x00
+//   Frame ID: 1 byte for legacy frames (and versions 5 - 7), 2 bytes, little-endian, for EZSP version 8 and above
+//   ... Parameters
+
+// EZSP frame IDs
+const (
+	EZSP_VERSION                     = 0x0000
+	EZSP_ADD_ENDPOINT                = 0x0002
+	EZSP_CALLBACK                    = 0x0006
+	EZSP_NO_CALLBACKS                = 0x0007
+	EZSP_NETWORK_INIT                = 0x0017
+	EZSP_NETWORK_STATE               = 0x0018
+	EZSP_STACK_STATUS_HANDLER        = 0x0019
+	EZSP_FORM_NETWORK                = 0x001e
+	EZSP_JOIN_NETWORK                = 0x001f
+	EZSP_LEAVE_NETWORK               = 0x0020
+	EZSP_PERMIT_JOINING              = 0x0022
+	EZSP_CHILD_JOIN_HANDLER          = 0x0023
+	EZSP_TRUST_CENTER_JOIN_HANDLER   = 0x0024
+	EZSP_GET_EUI64                   = 0x0026
+	EZSP_GET_NETWORK_PARAMETERS      = 0x0028
+	EZSP_SEND_UNICAST                = 0x0034
+	EZSP_MESSAGE_SENT_HANDLER        = 0x003f
+	EZSP_INCOMING_MESSAGE_HANDLER    = 0x0045
+	EZSP_SET_CONFIGURATION_VALUE     = 0x0053
+	EZSP_INVALID_COMMAND             = 0x0058
+	EZSP_SET_INITIAL_SECURITY_STATE  = 0x0068
+	EZSP_GET_CURRENT_SECURITY_STATE  = 0x0069
+	EZSP_LEGACY_EXTENDED_FRAME_ID    = 0x00ff // EZSP versions 5 - 7 extended header marker
+	EZSP_MIN_EXTENDED_HEADER_VERSION = 8
+)
+
+// Ember status codes
+const (
+	EMBER_SUCCESS        = 0x00
+	EMBER_ERR_FATAL      = 0x01
+	EMBER_INVALID_CALL   = 0x70
+	EMBER_NETWORK_UP     = 0x90
+	EMBER_NETWORK_DOWN   = 0x91
+	EMBER_NOT_JOINED     = 0x93
+	EMBER_JOIN_FAILED    = 0x94
+	EMBER_NETWORK_OPENED = 0x9c
+	EMBER_NETWORK_CLOSED = 0x9d
+)
+
+// Ember network states
+const (
+	EMBER_NO_NETWORK               = 0x00
+	EMBER_JOINING_NETWORK          = 0x01
+	EMBER_JOINED_NETWORK           = 0x02
+	EMBER_JOINED_NETWORK_NO_PARENT = 0x03
+	EMBER_LEAVING_NETWORK          = 0x04
+)
+
+// Ember node types
+const (
+	EMBER_COORDINATOR = 0x01
+	EMBER_ROUTER      = 0x02
+)
+
+// Ember initial security state bitmask
+const (
+	EMBER_HAVE_PRECONFIGURED_KEY       = 0x0100
+	EMBER_HAVE_NETWORK_KEY             = 0x0200
+	EMBER_REQUIRE_ENCRYPTED_KEY        = 0x0800
+	EMBER_TRUST_CENTER_GLOBAL_LINK_KEY = 0x0004
+)
+
+// EZSP frame control bits
+const (
+	EZSP_FC_RESPONSE       = 0x80 // the frame is the response or callback
+	EZSP_FC_CALLBACK_MASK  = 0x18 // callback type: 0 - not a callback, 1 - synchronous, 2 - asynchronous
+	EZSP_FC_CALLBACK_ASYNC = 0x10
+	EZSP_FC_TRUNCATED      = 0x02
+	EZSP_FC_OVERFLOW       = 0x01
+	EZSP_FC_HIGH_VERSION   = 0x01 // frame format version 1 in the high byte of extended frame control
+)
+
+// ErrEZSPFrame is returned if EZSP frame or its parameters are malformed
+var ErrEZSPFrame = errors.New("EZSP frame is not valid")
+
+// EZSPFrame is EZSP frame
+type EZSPFrame struct {
+	Sequence   uint8
+	Control    uint8 // the low byte of frame control
+	FrameID    uint16
+	Parameters []byte
+}
+
+// IsCallback returns true if the frame is the callback (asynchronous or in response to EZSP_CALLBACK command)
+func (f *EZSPFrame) IsCallback() bool {
+	return f.Control&EZSP_FC_CALLBACK_MASK != 0
+}
+
+// extendedHeader returns true if the frame of provided EZSP version uses 2 bytes frame ID
+func extendedHeader(version uint8, frameID uint16) bool {
+	return version >= EZSP_MIN_EXTENDED_HEADER_VERSION && frameID != EZSP_VERSION
+}
+
+// EZSPCommand creates EZSP command frame for provided protocol version. The version command always uses the legacy format,
+// as well as any command if the version is not negotiated yet (0).
+func EZSPCommand(version, sequence uint8, frameID uint16, parameters []byte) []byte {
+	frame := make([]byte, 0, len(parameters)+5)
+	switch {
+	case extendedHeader(version, frameID):
+		frame = append(frame, sequence, 0, EZSP_FC_HIGH_VERSION, byte(frameID), byte(frameID>>8))
+	case version >= 5 && frameID != EZSP_VERSION:
+		frame = append(frame, sequence, 0, EZSP_LEGACY_EXTENDED_FRAME_ID, 0, byte(frameID))
+	default:
+		frame = append(frame, sequence, 0, byte(frameID))
+	}
+	return append(frame, parameters...)
+}
+
+// ParseEZSPFrame parses EZSP frame of provided protocol version
+func ParseEZSPFrame(version uint8, data []byte) (*EZSPFrame, error) {
+	if len(data) < 3 {
+		return nil, ErrEZSPFrame
+	}
+	f := &EZSPFrame{Sequence: data[0], Control: data[1]}
+	switch {
+	case version >= EZSP_MIN_EXTENDED_HEADER_VERSION && data[2]&EZSP_FC_HIGH_VERSION != 0:
+		if len(data) < 5 {
+			return nil, ErrEZSPFrame
+		}
+		f.FrameID, f.Parameters = binary.LittleEndian.Uint16(data[3:]), data[5:]
+	case version >= 5 && data[2] == EZSP_LEGACY_EXTENDED_FRAME_ID:
+		if len(data) < 5 {
+			return nil, ErrEZSPFrame
+		}
+		f.FrameID, f.Parameters = uint16(data[4]), data[5:]
+	default:
+		f.FrameID, f.Parameters = uint16(data[2]), data[3:]
+	}
+	return f, nil
+}
+
+// EZSPPayload creates the payload of EZSP command: frame ID (2 bytes, little-endian) followed by parameters
+func EZSPPayload(frameID uint16, parameters []byte) []byte {
+	return append([]byte{byte(frameID), byte(frameID >> 8)}, parameters...)
+}
+
+// ParseEZSPPayload splits the payload created by EZSPPayload
+func ParseEZSPPayload(payload []byte) (frameID uint16, parameters []byte, ok bool) {
+	if len(payload) < 2 {
+		return 0, nil, false
+	}
+	return binary.LittleEndian.Uint16(payload), payload[2:], true
+}
+
+// VersionInfo is the response of the version command
+type VersionInfo struct {
+	ProtocolVersion uint8
+	StackType       uint8
+	StackVersion    uint16 // 4 bits each: major, minor, patch, special
+}
+
+// StackVersionString returns EmberZNet version in major.minor.patch format
+func (v *VersionInfo) StackVersionString() string {
+	digit := func(shift uint) byte { return byte('0' + v.StackVersion>>shift&0x0f) }
+	return string([]byte{digit(12), '.', digit(8), '.', digit(4)})
+}
+
+// ParseVersion parses the parameters of the version command response
+func ParseVersion(parameters []byte) (*VersionInfo, bool) {
+	if len(parameters) < 4 {
+		return nil, false
+	}
+	return &VersionInfo{
+		ProtocolVersion: parameters[0],
+		StackType:       parameters[1],
+		StackVersion:    binary.LittleEndian.Uint16(parameters[2:]),
+	}, true
+}
+
+// NetworkParameters is EmberNetworkParameters structure
+type NetworkParameters struct {
+	ExtendedPanID uint64
+	PanID         uint16
+	RadioTxPower  int8
+	RadioChannel  uint8
+	JoinMethod    uint8
+	NwkManagerID  uint16
+	NwkUpdateID   uint8
+	Channels      uint32
+}
+
+// networkParametersLength the size of encoded EmberNetworkParameters structure
+const networkParametersLength = 20
+
+// Encode appends encoded structure to the buffer
+func (p *NetworkParameters) Encode(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint64(b, p.ExtendedPanID)
+	b = binary.LittleEndian.AppendUint16(b, p.PanID)
+	b = append(b, byte(p.RadioTxPower), p.RadioChannel, p.JoinMethod)
+	b = binary.LittleEndian.AppendUint16(b, p.NwkManagerID)
+	b = append(b, p.NwkUpdateID)
+	return binary.LittleEndian.AppendUint32(b, p.Channels)
+}
+
+// ParseNetworkParameters parses the parameters of getNetworkParameters response: status, node type and network parameters
+func ParseNetworkParameters(parameters []byte) (status uint8, nodeType uint8, p *NetworkParameters, ok bool) {
+	if len(parameters) < 2+networkParametersLength {
+		return 0, 0, nil, false
+	}
+	b := parameters[2:]
+	return parameters[0], parameters[1], &NetworkParameters{
+		ExtendedPanID: binary.LittleEndian.Uint64(b),
+		PanID:         binary.LittleEndian.Uint16(b[8:]),
+		RadioTxPower:  int8(b[10]),
+		RadioChannel:  b[11],
+		JoinMethod:    b[12],
+		NwkManagerID:  binary.LittleEndian.Uint16(b[13:]),
+		NwkUpdateID:   b[15],
+		Channels:      binary.LittleEndian.Uint32(b[16:]),
+	}, true
+}
+
+// FormNetwork creates the parameters of formNetwork command
+func FormNetwork(p *NetworkParameters) []byte {
+	return p.Encode(nil)
+}
+
+// JoinNetwork creates the parameters of joinNetwork command
+func JoinNetwork(nodeType uint8, p *NetworkParameters) []byte {
+	return p.Encode([]byte{nodeType})
+}
+
+// NetworkInit creates the parameters of networkInit command, EZSP version 6 and above accept the init bitmask
+func NetworkInit(version uint8) []byte {
+	if version >= 6 {
+		return []byte{0, 0}
+	}
+	return nil
+}
+
+// PermitJoining creates the parameters of permitJoining command, duration 0xff permits joining until it is disabled
+func PermitJoining(duration uint8) []byte {
+	return []byte{duration}
+}
+
+// InitialSecurityState creates the parameters of setInitialSecurityState command
+func InitialSecurityState(bitmask uint16, preconfiguredKey, networkKey [16]byte) []byte {
+	b := binary.LittleEndian.AppendUint16(make([]byte, 0, 43), bitmask)
+	b = append(b, preconfiguredKey[:]...)
+	b = append(b, networkKey[:]...)
+	b = append(b, 0)                     // network key sequence number
+	return append(b, make([]byte, 8)...) // preconfigured trust center EUI64
+}
+
+// AddEndpoint creates the parameters of addEndpoint command
+func AddEndpoint(endpoint uint8, profileID, deviceID uint16, inputClusters, outputClusters []uint16) []byte {
+	b := []byte{endpoint}
+	b = binary.LittleEndian.AppendUint16(b, profileID)
+	b = binary.LittleEndian.AppendUint16(b, deviceID)
+	b = append(b, 0, byte(len(inputClusters)), byte(len(outputClusters))) // application flags and cluster counts
+	for _, c := range inputClusters {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	for _, c := range outputClusters {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+// APSFrame is EmberApsFrame structure
+type APSFrame struct {
+	ProfileID           uint16
+	ClusterID           uint16
+	SourceEndpoint      uint8
+	DestinationEndpoint uint8
+	Options             uint16
+	GroupID             uint16
+	Sequence            uint8
+}
+
+// apsFrameLength the size of encoded EmberApsFrame structure
+const apsFrameLength = 11
+
+// Encode appends encoded structure to the buffer
+func (a *APSFrame) Encode(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, a.ProfileID)
+	b = binary.LittleEndian.AppendUint16(b, a.ClusterID)
+	b = append(b, a.SourceEndpoint, a.DestinationEndpoint)
+	b = binary.LittleEndian.AppendUint16(b, a.Options)
+	b = binary.LittleEndian.AppendUint16(b, a.GroupID)
+	return append(b, a.Sequence)
+}
+
+func parseAPSFrame(b []byte) *APSFrame {
+	return &APSFrame{
+		ProfileID:           binary.LittleEndian.Uint16(b),
+		ClusterID:           binary.LittleEndian.Uint16(b[2:]),
+		SourceEndpoint:      b[4],
+		DestinationEndpoint: b[5],
+		Options:             binary.LittleEndian.Uint16(b[6:]),
+		GroupID:             binary.LittleEndian.Uint16(b[8:]),
+		Sequence:            b[10],
+	}
+}
+
+// SendUnicast creates the parameters of sendUnicast command which sends the message directly to the node
+func SendUnicast(destination uint16, aps *APSFrame, tag uint8, message []byte) []byte {
+	b := binary.LittleEndian.AppendUint16([]byte{0}, destination) // EMBER_OUTGOING_DIRECT
+	b = aps.Encode(b)
+	b = append(b, tag, byte(len(message)))
+	return append(b, message...)
+}
+
+// IncomingMessage is the content of incomingMessageHandler callback
+type IncomingMessage struct {
+	Type    uint8
+	APS     *APSFrame
+	LQI     uint8
+	RSSI    int8
+	Sender  uint16
+	Message []byte
+}
+
+// ParseIncomingMessage parses the parameters of incomingMessageHandler callback
+func ParseIncomingMessage(parameters []byte) (*IncomingMessage, bool) {
+	const header = 1 + apsFrameLength + 7
+	if len(parameters) < header {
+		return nil, false
+	}
+	b := parameters[1+apsFrameLength:]
+	length := int(b[6])
+	if len(parameters) < header+length {
+		return nil, false
+	}
+	return &IncomingMessage{
+		Type:    parameters[0],
+		APS:     parseAPSFrame(parameters[1:]),
+		LQI:     b[0],
+		RSSI:    int8(b[1]),
+		Sender:  binary.LittleEndian.Uint16(b[2:]),
+		Message: parameters[header : header+length],
+	}, true
+}
+
+// TrustCenterJoin is the content of trustCenterJoinHandler callback
+type TrustCenterJoin struct {
+	NodeID uint16
+	EUI64  uint64
+	Status uint8 // 0 - secured rejoin, 1 - unsecured join, 2 - device left, 3 - unsecured rejoin
+}
+
+// Trust center join statuses
+const (
+	EMBER_STANDARD_SECURITY_SECURED_REJOIN   = 0x00
+	EMBER_STANDARD_SECURITY_UNSECURED_JOIN   = 0x01
+	EMBER_DEVICE_LEFT                        = 0x02
+	EMBER_STANDARD_SECURITY_UNSECURED_REJOIN = 0x03
+)
+
+// ParseTrustCenterJoin parses the parameters of trustCenterJoinHandler callback
+func ParseTrustCenterJoin(parameters []byte) (*TrustCenterJoin, bool) {
+	if len(parameters) < 11 {
+		return nil, false
+	}
+	return &TrustCenterJoin{
+		NodeID: binary.LittleEndian.Uint16(parameters),
+		EUI64:  binary.LittleEndian.Uint64(parameters[2:]),
+		Status: parameters[10],
+	}, true
+}
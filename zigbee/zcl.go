@@ -0,0 +1,310 @@
+package zigbee
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ZCL (Zigbee Cluster Library) frame structure:
+//   Frame control byte
+//   Manufacturer code (2 bytes, little-endian), present if the manufacturer specific bit is set
+//   Transaction sequence number byte
+//   Command ID byte
+//   ... Payload
+
+// Home Automation profile
+const ProfileHomeAutomation = 0x0104
+
+// ZCL cluster IDs
+const (
+	ClusterBasic       = 0x0000
+	ClusterOnOff       = 0x0006
+	ClusterLevel       = 0x0008
+	ClusterTemperature = 0x0402
+	ClusterIASZone     = 0x0500
+)
+
+// ZCL frame control bits
+const (
+	ZCLClusterSpecific        = 0x01 // the command is cluster specific, otherwise it is global (profile wide)
+	ZCLManufacturerSpecific   = 0x04
+	ZCLServerToClient         = 0x08
+	ZCLDisableDefaultResponse = 0x10
+)
+
+// ZCL global commands
+const (
+	ZCLReadAttributes         = 0x00
+	ZCLReadAttributesResponse = 0x01
+	ZCLReportAttributes       = 0x0a
+	ZCLDefaultResponse        = 0x0b
+)
+
+// ZCL cluster specific commands
+const (
+	// On/Off cluster commands
+	ZCLOnOffOff    = 0x00
+	ZCLOnOffOn     = 0x01
+	ZCLOnOffToggle = 0x02
+
+	// Level cluster commands
+	ZCLLevelMoveToLevel      = 0x00
+	ZCLLevelMoveToLevelOnOff = 0x04
+
+	// IAS Zone cluster commands
+	ZCLZoneStatusChangeNotification = 0x00 // server to client
+	ZCLZoneEnrollRequest            = 0x01 // server to client
+	ZCLZoneEnrollResponse           = 0x00 // client to server
+)
+
+// ZCL attributes
+const (
+	AttributeOnOff         = 0x0000 // On/Off cluster
+	AttributeCurrentLevel  = 0x0000 // Level cluster
+	AttributeMeasuredValue = 0x0000 // Temperature cluster, 0.01 °C
+	AttributeZoneStatus    = 0x0002 // IAS Zone cluster
+)
+
+// ZCL status codes
+const (
+	ZCLStatusSuccess              = 0x00
+	ZCLStatusUnsupportedAttribute = 0x86
+)
+
+// ErrZCLFrame is returned if ZCL frame or its payload is malformed
+var ErrZCLFrame = errors.New("ZCL frame is not valid")
+
+// ZCLFrame is ZCL frame
+type ZCLFrame struct {
+	Control      uint8
+	Manufacturer uint16
+	Sequence     uint8
+	Command      uint8
+	Payload      []byte
+}
+
+// ClusterSpecific returns true if the command is cluster specific
+func (f *ZCLFrame) ClusterSpecific() bool {
+	return f.Control&ZCLClusterSpecific != 0
+}
+
+// ParseZCLFrame parses ZCL frame
+func ParseZCLFrame(data []byte) (*ZCLFrame, error) {
+	if len(data) < 3 {
+		return nil, ErrZCLFrame
+	}
+	f := &ZCLFrame{Control: data[0]}
+	if f.Control&ZCLManufacturerSpecific != 0 {
+		if len(data) < 5 {
+			return nil, ErrZCLFrame
+		}
+		f.Manufacturer = binary.LittleEndian.Uint16(data[1:])
+		data = data[2:]
+	}
+	f.Sequence, f.Command, f.Payload = data[1], data[2], data[3:]
+	return f, nil
+}
+
+// ZCLCommand creates ZCL frame, the command is sent from client to server
+func ZCLCommand(clusterSpecific bool, sequence, command uint8, payload []byte) []byte {
+	control := byte(ZCLDisableDefaultResponse)
+	if clusterSpecific {
+		control |= ZCLClusterSpecific
+	}
+	return append([]byte{control, sequence, command}, payload...)
+}
+
+// ZoneEnrollResponse creates the payload of IAS Zone enroll response command
+func ZoneEnrollResponse(success bool, zoneID uint8) []byte {
+	if success {
+		return []byte{0, zoneID}
+	}
+	return []byte{1, zoneID} // not supported
+}
+
+// ZCL data types
+const (
+	ZCLTypeBool     = 0x10
+	ZCLTypeBitmap8  = 0x18
+	ZCLTypeBitmap16 = 0x19
+	ZCLTypeUint8    = 0x20
+	ZCLTypeUint16   = 0x21
+	ZCLTypeUint32   = 0x23
+	ZCLTypeInt8     = 0x28
+	ZCLTypeInt16    = 0x29
+	ZCLTypeInt32    = 0x2b
+	ZCLTypeEnum8    = 0x30
+	ZCLTypeEnum16   = 0x31
+	ZCLTypeString   = 0x42
+)
+
+// zclTypeSize returns the size of the fixed size data type, 0 if not known or variable size
+func zclTypeSize(t uint8) int {
+	switch t {
+	case ZCLTypeBool, ZCLTypeBitmap8, ZCLTypeUint8, ZCLTypeInt8, ZCLTypeEnum8, 0x08: // 0x08 - data8
+		return 1
+	case ZCLTypeBitmap16, ZCLTypeUint16, ZCLTypeInt16, ZCLTypeEnum16, 0x09: // 0x09 - data16
+		return 2
+	case 0x0a, 0x1a, 0x22, 0x2a: // data24, bitmap24, uint24, int24
+		return 3
+	case ZCLTypeUint32, ZCLTypeInt32, 0x0b, 0x1b, 0x39: // data32, bitmap32, single precision float
+		return 4
+	}
+	return 0
+}
+
+// Attribute is ZCL attribute value
+type Attribute struct {
+	ID   uint16
+	Type uint8
+	Data []byte
+}
+
+// Int returns integer value of the numeric attribute
+func (a *Attribute) Int() (int64, bool) {
+	size := zclTypeSize(a.Type)
+	if size == 0 || size > 4 || len(a.Data) != size {
+		return 0, false
+	}
+	var v uint64
+	for i := size - 1; i >= 0; i-- {
+		v = v<<8 | uint64(a.Data[i])
+	}
+	switch a.Type {
+	case ZCLTypeInt8, ZCLTypeInt16, 0x2a, ZCLTypeInt32:
+		shift := 64 - uint(size)*8
+		return int64(v<<shift) >> shift, true
+	}
+	return int64(v), true
+}
+
+// parseAttributeValue parses the attribute value of provided type, returns the value and the rest of the data
+func parseAttributeValue(t uint8, data []byte) ([]byte, []byte, error) {
+	size := zclTypeSize(t)
+	if size == 0 && (t == ZCLTypeString || t == 0x41) && len(data) > 0 { // character or octet string
+		size = 1 + int(data[0])
+	}
+	if size == 0 || len(data) < size {
+		return nil, nil, ErrZCLFrame
+	}
+	return data[:size], data[size:], nil
+}
+
+// ParseAttributes parses the attributes of ZCL report attributes or read attributes response global command.
+// The attributes with not successful status of the read attributes response are skipped.
+func ParseAttributes(f *ZCLFrame) ([]*Attribute, error) {
+	if f.ClusterSpecific() || (f.Command != ZCLReportAttributes && f.Command != ZCLReadAttributesResponse) {
+		return nil, ErrZCLFrame
+	}
+	var attributes []*Attribute
+	data := f.Payload
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return nil, ErrZCLFrame
+		}
+		a := &Attribute{ID: binary.LittleEndian.Uint16(data)}
+		data = data[2:]
+		if f.Command == ZCLReadAttributesResponse {
+			status := data[0]
+			data = data[1:]
+			if status != ZCLStatusSuccess {
+				continue
+			}
+			if len(data) < 1 {
+				return nil, ErrZCLFrame
+			}
+		}
+		a.Type = data[0]
+		var err error
+		if a.Data, data, err = parseAttributeValue(a.Type, data[1:]); err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, a)
+	}
+	return attributes, nil
+}
+
+// Report is the decoded state reported by the device
+type Report struct {
+	Cluster     uint16
+	OnOff       *bool
+	Level       *uint8
+	Temperature *float64 // °C
+	ZoneStatus  *uint16  // IAS zone status bits: alarm1, alarm2, tamper, battery, ...
+	ZoneEnroll  bool     // the device requests IAS zone enrollment
+}
+
+// IsEmpty returns true if no state was decoded
+func (r *Report) IsEmpty() bool {
+	return r.OnOff == nil && r.Level == nil && r.Temperature == nil && r.ZoneStatus == nil && !r.ZoneEnroll
+}
+
+func (r *Report) String() string {
+	var parts []string
+	if r.OnOff != nil {
+		parts = append(parts, fmt.Sprintf("on=%t", *r.OnOff))
+	}
+	if r.Level != nil {
+		parts = append(parts, fmt.Sprintf("level=%d", *r.Level))
+	}
+	if r.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temperature=%.2f", *r.Temperature))
+	}
+	if r.ZoneStatus != nil {
+		parts = append(parts, fmt.Sprintf("zoneStatus=0x%04x", *r.ZoneStatus))
+	}
+	if r.ZoneEnroll {
+		parts = append(parts, "zoneEnroll")
+	}
+	return strings.Join(parts, " ")
+}
+
+// DecodeReport decodes the state of On/Off, Level, Temperature and IAS Zone clusters from ZCL frame sent by the device
+func DecodeReport(cluster uint16, f *ZCLFrame) (*Report, error) {
+	r := &Report{Cluster: cluster}
+	if f.ClusterSpecific() {
+		if cluster == ClusterIASZone && f.Control&ZCLServerToClient != 0 {
+			switch f.Command {
+			case ZCLZoneStatusChangeNotification:
+				if len(f.Payload) < 2 {
+					return nil, ErrZCLFrame
+				}
+				status := binary.LittleEndian.Uint16(f.Payload)
+				r.ZoneStatus = &status
+			case ZCLZoneEnrollRequest:
+				r.ZoneEnroll = true
+			}
+		}
+		return r, nil
+	}
+	if f.Command != ZCLReportAttributes && f.Command != ZCLReadAttributesResponse {
+		return r, nil
+	}
+	attributes, err := ParseAttributes(f)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attributes {
+		v, ok := a.Int()
+		if !ok {
+			continue
+		}
+		switch {
+		case cluster == ClusterOnOff && a.ID == AttributeOnOff:
+			on := v != 0
+			r.OnOff = &on
+		case cluster == ClusterLevel && a.ID == AttributeCurrentLevel:
+			level := uint8(v)
+			r.Level = &level
+		case cluster == ClusterTemperature && a.ID == AttributeMeasuredValue && v != -0x8000: // 0x8000 - invalid measurement
+			temperature := float64(v) / 100
+			r.Temperature = &temperature
+		case cluster == ClusterIASZone && a.ID == AttributeZoneStatus:
+			status := uint16(v)
+			r.ZoneStatus = &status
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,148 @@
+package zigbee
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestASHFrames(t *testing.T) {
+	if c := ASHCRC([]byte("123456789")); c != 0x29b1 {
+		t.Errorf("Unexpected CRC-16/CCITT-FALSE %04x", c)
+	}
+
+	t.Run("Known frames", func(t *testing.T) {
+		for _, tc := range []struct {
+			name     string
+			frame    []byte
+			expected []byte
+		}{
+			{"RST", ASHResetFrame(), []byte{0x1a, 0xc0, 0x38, 0xbc, 0x7e}},
+			{"RSTACK", ASHResetAckFrame(ASHResetSoftware), []byte{0xc1, 0x02, 0x0b, 0x0a, 0x52, 0x7e}},
+			{"ACK", ASHAckFrame(1), []byte{0x81, 0x60, 0x59, 0x7e}},
+			{"DATA version", ASHDataFrame(0, 0, false, EZSPCommand(0, 0, EZSP_VERSION, []byte{4})), []byte{0x00, 0x42, 0x21, 0xa8, 0x50, 0xed, 0x2c, 0x7e}},
+		} {
+			if !bytes.Equal(tc.frame, tc.expected) {
+				t.Errorf("%s frame is %x, expected %x", tc.name, tc.frame, tc.expected)
+			}
+		}
+	})
+
+	t.Run("Round trip with byte stuffing", func(t *testing.T) {
+		// the randomized data contains reserved bytes
+		data := ASHRandomize([]byte{ASHFlag, ASHEscape, ASHXOn, ASHXOff, ASHSubstitute, ASHCancel, 1, 2})
+		frame := ASHDataFrame(5, 3, true, data)
+		content, n := SplitASHFrame(append([]byte{ASHXOn}, frame...))
+		if n != len(frame)+1 {
+			t.Fatalf("The frame is not split, %d bytes consumed", n)
+		}
+		f, err := ParseASHFrame(content)
+		if err != nil {
+			t.Fatalf("Unable to parse the frame: %v", err)
+		}
+		if f.Type != ASHData || f.FrmNum != 5 || f.AckNum != 3 || !f.ReTx || !bytes.Equal(f.Data, data) {
+			t.Errorf("Unexpected frame: %+v", f)
+		}
+	})
+
+	t.Run("Incomplete and cancelled frames", func(t *testing.T) {
+		frame := ASHNakFrame(2)
+		if _, n := SplitASHFrame(frame[:len(frame)-1]); n != 0 {
+			t.Errorf("The incomplete frame must not be consumed, %d bytes consumed", n)
+		}
+		content, n := SplitASHFrame(append([]byte{0x01, 0x02, ASHCancel}, frame...))
+		if n != len(frame)+3 {
+			t.Fatalf("The frame is not split, %d bytes consumed", n)
+		}
+		if f, err := ParseASHFrame(content); err != nil || f.Type != ASHNak || f.AckNum != 2 {
+			t.Errorf("Unexpected frame: %+v, error: %v", f, err)
+		}
+	})
+
+	t.Run("Wrong CRC", func(t *testing.T) {
+		frame := ASHResetAckFrame(ASHResetPowerOn)
+		frame[2] ^= 0x01
+		content, _ := SplitASHFrame(frame)
+		if _, err := ParseASHFrame(content); err != ErrASHChecksum {
+			t.Errorf("CRC error expected, got %v", err)
+		}
+	})
+}
+
+func TestEZSPFrames(t *testing.T) {
+	params := []byte{1, 2}
+	for _, tc := range []struct {
+		version uint8
+		frame   []byte
+	}{
+		{4, []byte{7, 0, 0x17, 1, 2}},
+		{7, []byte{7, 0, 0xff, 0, 0x17, 1, 2}},
+		{8, []byte{7, 0, 1, 0x17, 0, 1, 2}},
+	} {
+		frame := EZSPCommand(tc.version, 7, EZSP_NETWORK_INIT, params)
+		if !bytes.Equal(frame, tc.frame) {
+			t.Errorf("EZSP v%d frame is %x, expected %x", tc.version, frame, tc.frame)
+		}
+		frame[1] = EZSP_FC_RESPONSE | EZSP_FC_CALLBACK_ASYNC
+		f, err := ParseEZSPFrame(tc.version, frame)
+		if err != nil || f.Sequence != 7 || f.FrameID != EZSP_NETWORK_INIT || !f.IsCallback() || !bytes.Equal(f.Parameters, params) {
+			t.Errorf("Unexpected EZSP v%d frame: %+v, error: %v", tc.version, f, err)
+		}
+	}
+	if frame := EZSPCommand(8, 1, EZSP_VERSION, []byte{8}); !bytes.Equal(frame, []byte{1, 0, 0, 8}) {
+		t.Errorf("The version command must use legacy format, got %x", frame)
+	}
+
+	v, ok := ParseVersion([]byte{8, 2, 0x30, 0x67})
+	if !ok || v.ProtocolVersion != 8 || v.StackVersionString() != "6.7.3" {
+		t.Errorf("Unexpected version: %+v", v)
+	}
+
+	incoming := []byte{0, 0x04, 0x01, 0x06, 0x00, 1, 1, 0, 0, 0, 0, 9, 200, 0xc4, 0x34, 0x12, 0, 0xff, 3, 0x18, 9, 0x0a}
+	if m, ok := ParseIncomingMessage(incoming); !ok || m.APS.ProfileID != ProfileHomeAutomation || m.APS.ClusterID != ClusterOnOff ||
+		m.Sender != 0x1234 || m.RSSI != -60 || !bytes.Equal(m.Message, []byte{0x18, 9, 0x0a}) {
+		t.Errorf("Unexpected incoming message: %+v", m)
+	}
+
+	p := &NetworkParameters{ExtendedPanID: 0x0102030405060708, PanID: 0x1a62, RadioTxPower: 8, RadioChannel: 15, Channels: 1 << 15}
+	if _, _, parsed, ok := ParseNetworkParameters(append([]byte{0, EMBER_COORDINATOR}, FormNetwork(p)...)); !ok || *parsed != *p {
+		t.Errorf("Unexpected network parameters: %+v", parsed)
+	}
+}
+
+func TestZCLDecoding(t *testing.T) {
+	decode := func(cluster uint16, data []byte) *Report {
+		f, err := ParseZCLFrame(data)
+		if err != nil {
+			t.Fatalf("Unable to parse ZCL frame %x: %v", data, err)
+		}
+		r, err := DecodeReport(cluster, f)
+		if err != nil {
+			t.Fatalf("Unable to decode ZCL frame %x: %v", data, err)
+		}
+		return r
+	}
+
+	if r := decode(ClusterOnOff, []byte{0x18, 1, ZCLReportAttributes, 0, 0, ZCLTypeBool, 1}); r.OnOff == nil || !*r.OnOff {
+		t.Errorf("On/Off report is not decoded: %v", r)
+	}
+	if r := decode(ClusterLevel, []byte{0x18, 2, ZCLReadAttributesResponse, 0, 0, 0, ZCLTypeUint8, 0x80}); r.Level == nil || *r.Level != 0x80 {
+		t.Errorf("Level read response is not decoded: %v", r)
+	}
+	if r := decode(ClusterTemperature, []byte{0x1c, 0x34, 0x12, 3, ZCLReportAttributes, 0, 0, ZCLTypeInt16, 0x0c, 0xfe}); r.Temperature == nil || *r.Temperature != -5 {
+		t.Errorf("Temperature report is not decoded: %v", r)
+	}
+	if r := decode(ClusterIASZone, []byte{0x09, 4, ZCLZoneStatusChangeNotification, 0x21, 0x00, 0, 1, 0, 0}); r.ZoneStatus == nil || *r.ZoneStatus != 0x21 {
+		t.Errorf("IAS zone status change is not decoded: %v", r)
+	}
+	if r := decode(ClusterIASZone, []byte{0x09, 5, ZCLZoneEnrollRequest, 0x0d, 0x00, 0, 0}); !r.ZoneEnroll {
+		t.Errorf("IAS zone enroll request is not decoded: %v", r)
+	}
+	if r := decode(ClusterOnOff, []byte{0x18, 6, ZCLReadAttributesResponse, 0, 0, ZCLStatusUnsupportedAttribute}); !r.IsEmpty() {
+		t.Errorf("Unsupported attribute must be skipped: %v", r)
+	}
+
+	f, _ := ParseZCLFrame([]byte{0x18, 7, ZCLReportAttributes, 0, 0, ZCLTypeUint16, 1})
+	if _, err := DecodeReport(ClusterLevel, f); err != ErrZCLFrame {
+		t.Errorf("Truncated attribute value must be rejected, got %v", err)
+	}
+}
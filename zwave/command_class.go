@@ -0,0 +1,70 @@
+package zwave
+
+// Command classes
+const (
+	COMMAND_CLASS_BASIC             = 0x20
+	COMMAND_CLASS_SWITCH_BINARY     = 0x25
+	COMMAND_CLASS_SWITCH_MULTILEVEL = 0x26
+	COMMAND_CLASS_BATTERY           = 0x80
+	COMMAND_CLASS_CLOCK             = 0x81
+	COMMAND_CLASS_TIME              = 0x8A
+)
+
+// Basic command class commands
+const (
+	BASIC_SET    = 0x01
+	BASIC_GET    = 0x02
+	BASIC_REPORT = 0x03
+)
+
+// Binary Switch command class commands
+const (
+	SWITCH_BINARY_SET    = 0x01
+	SWITCH_BINARY_GET    = 0x02
+	SWITCH_BINARY_REPORT = 0x03
+)
+
+// Multilevel Switch command class commands
+const (
+	SWITCH_MULTILEVEL_SET    = 0x01
+	SWITCH_MULTILEVEL_GET    = 0x02
+	SWITCH_MULTILEVEL_REPORT = 0x03
+)
+
+// Generic device classes
+const (
+	GENERIC_TYPE_STATIC_CONTROLLER = 0x02
+	GENERIC_TYPE_SWITCH_BINARY     = 0x10
+	GENERIC_TYPE_SWITCH_MULTILEVEL = 0x11
+)
+
+// Basic device classes
+const (
+	BASIC_TYPE_STATIC_CONTROLLER = 0x02
+	BASIC_TYPE_ROUTING_SLAVE     = 0x04
+)
+
+// Battery command class commands
+const (
+	BATTERY_GET    = 0x02
+	BATTERY_REPORT = 0x03
+)
+
+// the battery level reported by the device when its battery is low
+const BATTERY_LEVEL_LOW_WARNING = 0xFF
+
+// BatteryGet creates Battery Get command, to be sent using ZW_SEND_DATA request
+func BatteryGet() []byte {
+	return []byte{COMMAND_CLASS_BATTERY, BATTERY_GET}
+}
+
+// ParseBatteryReport parses Battery Report command, returns the battery level (0-100, or BATTERY_LEVEL_LOW_WARNING)
+func ParseBatteryReport(command []byte) (byte, bool) {
+	if len(command) < 3 || command[0] != COMMAND_CLASS_BATTERY || command[1] != BATTERY_REPORT {
+		return 0, false
+	}
+	if level := command[2]; level <= 100 || level == BATTERY_LEVEL_LOW_WARNING {
+		return level, true
+	}
+	return 0, false
+}
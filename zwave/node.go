@@ -0,0 +1,230 @@
+package zwave
+
+// NodeIDType defines the width of node identifiers in Serial API frames
+type NodeIDType byte
+
+// Node identifier types, the values match with SERIAL_API_SETUP_CMD_NODEID_BASETYPE_SET parameter
+const (
+	// 8-bit node identifiers, Z-Wave classic nodes only
+	NodeIDType8Bit = NodeIDType(1)
+	// 16-bit node identifiers, Z-Wave classic and Z-Wave Long Range nodes
+	NodeIDType16Bit = NodeIDType(2)
+)
+
+// Z-Wave Long Range node identifiers (12-bit)
+const (
+	LR_MIN_NODE_ID = 256
+	LR_MAX_NODE_ID = 4000
+	// the node identifier used to broadcast the frame to all Long Range nodes
+	NODE_BROADCAST_LR = 0x0FFF
+)
+
+// IsClassicNodeID verifies if the node identifier is valid Z-Wave classic node identifier
+func IsClassicNodeID(id uint16) bool {
+	return id >= 1 && id <= ZW_MAX_NODES
+}
+
+// IsLongRangeNodeID verifies if the node identifier is valid Z-Wave Long Range node identifier
+func IsLongRangeNodeID(id uint16) bool {
+	return id >= LR_MIN_NODE_ID && id <= LR_MAX_NODE_ID
+}
+
+// IsValidNodeID verifies if the node identifier could be used with provided node identifiers type
+func (t NodeIDType) IsValidNodeID(id uint16) bool {
+	if t == NodeIDType16Bit {
+		return IsClassicNodeID(id) || IsLongRangeNodeID(id)
+	}
+	return IsClassicNodeID(id)
+}
+
+// Size returns the size of the node identifier in Serial API frames, bytes
+func (t NodeIDType) Size() int {
+	if t == NodeIDType16Bit {
+		return 2
+	}
+	return 1
+}
+
+// String returns the name of the node identifiers type
+func (t NodeIDType) String() string {
+	if t == NodeIDType16Bit {
+		return "16bit"
+	}
+	return "8bit"
+}
+
+// AppendNodeID appends the node identifier to the frame body using provided node identifiers type (most significant byte first)
+func (t NodeIDType) AppendNodeID(body []byte, id uint16) []byte {
+	if t == NodeIDType16Bit {
+		return append(body, byte(id>>8), byte(id))
+	}
+	return append(body, byte(id))
+}
+
+// ReadNodeID reads the node identifier from the start of provided data, returns false if there is not enough data
+func (t NodeIDType) ReadNodeID(data []byte) (uint16, bool) {
+	if t == NodeIDType16Bit {
+		if len(data) < 2 {
+			return 0, false
+		}
+		return uint16(data[0])<<8 | uint16(data[1]), true
+	}
+	if len(data) < 1 {
+		return 0, false
+	}
+	return uint16(data[0]), true
+}
+
+// SerialAPISetupNodeIDType creates SERIAL_API_SETUP request data frame which switches the controller to provided node identifiers type
+func SerialAPISetupNodeIDType(t NodeIDType) []byte {
+	return DataRequest([]byte{FUNC_ID_SERIAL_API_SETUP, SERIAL_API_SETUP_CMD_NODEID_BASETYPE_SET, byte(t)})
+}
+
+// ParseSerialAPISetupNodeIDType parses SERIAL_API_SETUP response payload to SERIAL_API_SETUP_CMD_NODEID_BASETYPE_SET request
+// Returns true if the node identifiers type was set successfully
+func ParseSerialAPISetupNodeIDType(payload []byte) bool {
+	return len(payload) >= 3 && payload[0] == FUNC_ID_SERIAL_API_SETUP && payload[1] == SERIAL_API_SETUP_CMD_NODEID_BASETYPE_SET && payload[2] != 0
+}
+
+// Capabilities contains the data of FUNC_ID_SERIAL_API_GET_CAPABILITIES response
+type Capabilities struct {
+	AppVersion     byte
+	AppRevision    byte
+	ManufacturerID uint16
+	ProductType    uint16
+	ProductID      uint16
+	Functions      []byte // supported Serial API functions bitmask
+}
+
+// ParseCapabilities parses FUNC_ID_SERIAL_API_GET_CAPABILITIES response payload
+func ParseCapabilities(payload []byte) (*Capabilities, bool) {
+	if len(payload) < 9 || payload[0] != FUNC_ID_SERIAL_API_GET_CAPABILITIES {
+		return nil, false
+	}
+	return &Capabilities{
+		AppVersion:     payload[1],
+		AppRevision:    payload[2],
+		ManufacturerID: uint16(payload[3])<<8 | uint16(payload[4]),
+		ProductType:    uint16(payload[5])<<8 | uint16(payload[6]),
+		ProductID:      uint16(payload[7])<<8 | uint16(payload[8]),
+		Functions:      payload[9:],
+	}, true
+}
+
+// Supports verifies if provided Serial API function is supported by the controller
+func (c *Capabilities) Supports(function byte) bool {
+	if function == 0 {
+		return false
+	}
+	i := int(function-1) / 8
+	return i < len(c.Functions) && c.Functions[i]&(1<<((function-1)%8)) != 0
+}
+
+// SupportsLongRange verifies if the controller supports Z-Wave Long Range
+func (c *Capabilities) SupportsLongRange() bool {
+	return c.Supports(FUNC_ID_SERIAL_API_GET_LR_NODES)
+}
+
+// ParseInitData parses FUNC_ID_SERIAL_API_GET_INIT_DATA response payload and returns the list of Z-Wave classic nodes
+func ParseInitData(payload []byte) ([]uint16, bool) {
+	if len(payload) < 4 || payload[0] != FUNC_ID_SERIAL_API_GET_INIT_DATA {
+		return nil, false
+	}
+	l := int(payload[3])
+	if len(payload) < 4+l {
+		return nil, false
+	}
+	return nodesFromBitmask(payload[4:4+l], 1), true
+}
+
+// GetLongRangeNodes creates FUNC_ID_SERIAL_API_GET_LR_NODES request data frame, the offset is the number of 1024 nodes segment
+func GetLongRangeNodes(offset byte) []byte {
+	return DataRequest([]byte{FUNC_ID_SERIAL_API_GET_LR_NODES, offset})
+}
+
+// ParseLongRangeNodes parses FUNC_ID_SERIAL_API_GET_LR_NODES response payload
+// Returns the list of Z-Wave Long Range nodes and true if there are more nodes available with the next offset
+func ParseLongRangeNodes(payload []byte) (nodes []uint16, more bool, ok bool) {
+	if len(payload) < 4 || payload[0] != FUNC_ID_SERIAL_API_GET_LR_NODES {
+		return nil, false, false
+	}
+	l := int(payload[3])
+	if len(payload) < 4+l {
+		return nil, false, false
+	}
+	return nodesFromBitmask(payload[4:4+l], LR_MIN_NODE_ID+uint16(payload[2])*1024), payload[1] != 0, true
+}
+
+func nodesFromBitmask(bitmask []byte, base uint16) (nodes []uint16) {
+	for i, b := range bitmask {
+		for bit := 0; bit < 8; bit++ {
+			if b&(1<<bit) != 0 {
+				nodes = append(nodes, base+uint16(i*8+bit))
+			}
+		}
+	}
+	return
+}
+
+// RequestNodeInfo creates ZW_REQUEST_NODE_INFO request data frame
+func RequestNodeInfo(t NodeIDType, nodeID uint16) []byte {
+	return DataRequest(t.AppendNodeID([]byte{ZW_REQUEST_NODE_INFO}, nodeID))
+}
+
+// ApplicationUpdate contains the data of ZW_APPLICATION_UPDATE request
+type ApplicationUpdate struct {
+	Status         byte
+	NodeID         uint16
+	Basic          byte
+	Generic        byte
+	Specific       byte
+	CommandClasses []byte
+}
+
+// ParseApplicationUpdate parses ZW_APPLICATION_UPDATE request payload using provided node identifiers type
+func ParseApplicationUpdate(t NodeIDType, payload []byte) (*ApplicationUpdate, bool) {
+	if len(payload) < 2 || payload[0] != ZW_APPLICATION_UPDATE {
+		return nil, false
+	}
+	nodeID, ok := t.ReadNodeID(payload[2:])
+	if !ok {
+		return nil, false
+	}
+	u := &ApplicationUpdate{Status: payload[1], NodeID: nodeID}
+	data := payload[2+t.Size():]
+	if len(data) > 0 {
+		l := int(data[0])
+		if len(data) < 1+l {
+			return nil, false
+		}
+		info := data[1 : 1+l]
+		if len(info) >= 3 {
+			u.Basic, u.Generic, u.Specific = info[0], info[1], info[2]
+			u.CommandClasses = info[3:]
+		}
+	}
+	return u, true
+}
+
+// ApplicationCommand contains the data of ZW_APPLICATION_COMMAND_HANDLER request
+type ApplicationCommand struct {
+	RxStatus byte
+	NodeID   uint16
+	Command  []byte // command class, command, and parameters
+}
+
+// ParseApplicationCommand parses ZW_APPLICATION_COMMAND_HANDLER request payload using provided node identifiers type
+func ParseApplicationCommand(t NodeIDType, payload []byte) (*ApplicationCommand, bool) {
+	if len(payload) < 2 || payload[0] != ZW_APPLICATION_COMMAND_HANDLER {
+		return nil, false
+	}
+	nodeID, ok := t.ReadNodeID(payload[2:])
+	if !ok {
+		return nil, false
+	}
+	data := payload[2+t.Size():]
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return nil, false
+	}
+	return &ApplicationCommand{RxStatus: payload[1], NodeID: nodeID, Command: data[1 : 1+int(data[0])]}, true
+}
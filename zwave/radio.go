@@ -0,0 +1,102 @@
+package zwave
+
+import "time"
+
+// RSSI special values
+const (
+	// RSSI value is not available
+	RSSI_NOT_AVAILABLE = 127
+	// the receiver is saturated, RSSI is too high to measure precisely
+	RSSI_MAX_POWER_SATURATED = 126
+	// the signal is below the receiver sensitivity
+	RSSI_BELOW_SENSITIVITY = 125
+)
+
+// the maximal number of repeaters in the route
+const MAX_REPEATERS = 4
+
+// TxReport contains the transmit report of ZW_SEND_DATA callback
+//
+// The report body (follows the transmit status):
+//
+//	Transmit ticks (2 bytes, 10 ms units)
+//	Number of repeaters
+//	ACK RSSI
+//	... RSSI of repeaters (4 bytes)
+//	ACK channel number
+//	Transmit channel number
+//	Route scheme state
+//	... Repeaters (4 bytes)
+//	Route speed
+//	Route tries
+//	Last failed link from
+//	Last failed link to
+type TxReport struct {
+	TxTime             time.Duration
+	Repeaters          []byte
+	AckRSSI            int8
+	RepeatersRSSI      []int8
+	AckChannel         byte
+	TxChannel          byte
+	RouteSchemeState   byte
+	RouteSpeed         byte
+	RouteTries         byte
+	LastFailedLinkFrom byte
+	LastFailedLinkTo   byte
+}
+
+// txReportLength is the minimal length of the transmit report
+const txReportLength = 19
+
+// ParseTxReport parses the transmit report, which follows the transmit status in ZW_SEND_DATA callback payload
+func ParseTxReport(data []byte) (*TxReport, bool) {
+	if len(data) < txReportLength {
+		return nil, false
+	}
+	repeaters := int(data[2])
+	if repeaters > MAX_REPEATERS {
+		return nil, false
+	}
+	r := &TxReport{
+		TxTime:             time.Duration(uint16(data[0])<<8|uint16(data[1])) * 10 * time.Millisecond,
+		Repeaters:          append([]byte(nil), data[11:11+repeaters]...),
+		AckRSSI:            int8(data[3]),
+		AckChannel:         data[8],
+		TxChannel:          data[9],
+		RouteSchemeState:   data[10],
+		RouteSpeed:         data[15],
+		RouteTries:         data[16],
+		LastFailedLinkFrom: data[17],
+		LastFailedLinkTo:   data[18],
+	}
+	for i := 0; i < repeaters; i++ {
+		r.RepeatersRSSI = append(r.RepeatersRSSI, int8(data[4+i]))
+	}
+	return r, true
+}
+
+// ValidRSSI verifies if RSSI value is the measured value, not the special one
+func ValidRSSI(rssi int8) bool {
+	return rssi < RSSI_BELOW_SENSITIVITY
+}
+
+// SerialAPISetupTxStatusReport creates SERIAL_API_SETUP request data frame which enables or disables the transmit reports in ZW_SEND_DATA callbacks
+func SerialAPISetupTxStatusReport(enable bool) []byte {
+	var v byte
+	if enable {
+		v = 0xFF
+	}
+	return DataRequest([]byte{FUNC_ID_SERIAL_API_SETUP, SERIAL_API_SETUP_CMD_TX_STATUS_REPORT, v})
+}
+
+// ParseBackgroundRSSI parses FUNC_ID_ZW_GET_BACKGROUND_RSSI response payload, returns RSSI value for each channel
+func ParseBackgroundRSSI(payload []byte) ([]int8, bool) {
+	if len(payload) < 2 || payload[0] != FUNC_ID_ZW_GET_BACKGROUND_RSSI {
+		return nil, false
+	}
+	rv := make([]int8, 0, len(payload)-1)
+	for _, v := range payload[1:] {
+		rv = append(rv, int8(v))
+	}
+	return rv, true
+}
@@ -0,0 +1,86 @@
+package zwave
+
+// ZW_SEND_DATA request body:
+//   ZW_SEND_DATA
+//   Node ID (1 or 2 bytes, depends on node identifiers type)
+//   Data length
+//   ... Data (command class, command, parameters)
+//   Transmit options
+//   Function (callback) ID
+//
+// ZW_SEND_DATA_MULTI request body:
+//   ZW_SEND_DATA_MULTI
+//   Number of nodes
+//   ... Node IDs (1 or 2 bytes each, depends on node identifiers type)
+//   Data length
+//   ... Data
+//   Transmit options
+//   Function (callback) ID
+//
+// The response body for both requests is the command ID followed by non-zero byte if the request was accepted.
+// The callback (request data frame) body is: command ID, function ID, transmit status, and optional transmit report
+// (see ParseTxReport) if enabled by SERIAL_API_SETUP_CMD_TX_STATUS_REPORT
+
+// SendData creates ZW_SEND_DATA request data frame
+func SendData(t NodeIDType, nodeID uint16, data []byte, txOptions byte, funcID byte) []byte {
+	body := make([]byte, 0, len(data)+6)
+	body = t.AppendNodeID(append(body, ZW_SEND_DATA), nodeID)
+	body = append(body, byte(len(data)))
+	body = append(body, data...)
+	body = append(body, txOptions, funcID)
+	return DataRequest(body)
+}
+
+// SendDataMulti creates ZW_SEND_DATA_MULTI request data frame
+func SendDataMulti(t NodeIDType, nodeIDs []uint16, data []byte, txOptions byte, funcID byte) []byte {
+	body := make([]byte, 0, len(nodeIDs)*t.Size()+len(data)+5)
+	body = append(body, ZW_SEND_DATA_MULTI, byte(len(nodeIDs)))
+	for _, nodeID := range nodeIDs {
+		body = t.AppendNodeID(body, nodeID)
+	}
+	body = append(body, byte(len(data)))
+	body = append(body, data...)
+	body = append(body, txOptions, funcID)
+	return DataRequest(body)
+}
+
+// SendDataCallback contains the data of ZW_SEND_DATA or ZW_SEND_DATA_MULTI callback
+type SendDataCallback struct {
+	Command  byte
+	FuncID   byte
+	TxStatus byte
+	Report   *TxReport // nil if the callback does not contain the transmit report
+}
+
+// ParseSendDataCallback parses the payload of the request data frame (see UnpackRequest) as ZW_SEND_DATA or ZW_SEND_DATA_MULTI callback
+func ParseSendDataCallback(payload []byte) (*SendDataCallback, bool) {
+	if len(payload) < 3 || (payload[0] != ZW_SEND_DATA && payload[0] != ZW_SEND_DATA_MULTI) {
+		return nil, false
+	}
+	cb := &SendDataCallback{Command: payload[0], FuncID: payload[1], TxStatus: payload[2]}
+	if report, ok := ParseTxReport(payload[3:]); ok {
+		cb.Report = report
+	}
+	return cb, true
+}
+
+// ValidDataFrameLength verifies if the data frame created by DataFrame function has valid length
+func ValidDataFrameLength(frame []byte) bool {
+	l := len(frame) - 2
+	return l >= FrameMinLength && l <= FrameMaxLength
+}
+
+// ParseSendData parses ZW_SEND_DATA request payload using provided node identifiers type, returns the node and the function (callback) identifiers
+func ParseSendData(t NodeIDType, payload []byte) (nodeID uint16, funcID byte, ok bool) {
+	if len(payload) < 2 || payload[0] != ZW_SEND_DATA {
+		return 0, 0, false
+	}
+	if nodeID, ok = t.ReadNodeID(payload[1:]); !ok {
+		return 0, 0, false
+	}
+	data := payload[1+t.Size():]
+	if len(data) < 1 || len(data) != int(data[0])+3 {
+		return 0, 0, false
+	}
+	return nodeID, data[len(data)-1], true
+}
@@ -2,7 +2,32 @@ package zwave
 
 // Serial API command ID
 const (
-	ZW_VERSION = 0x15
+	FUNC_ID_SERIAL_API_GET_INIT_DATA    = 0x02
+	ZW_APPLICATION_COMMAND_HANDLER      = 0x04
+	FUNC_ID_SERIAL_API_GET_CAPABILITIES = 0x07
+	FUNC_ID_SERIAL_API_SETUP            = 0x0B
+	ZW_SEND_DATA                        = 0x13
+	ZW_SEND_DATA_MULTI                  = 0x14
+	ZW_VERSION                          = 0x15
+	FUNC_ID_ZW_GET_BACKGROUND_RSSI      = 0x3B
+	ZW_APPLICATION_UPDATE               = 0x49
+	ZW_REQUEST_NODE_INFO                = 0x60
+	FUNC_ID_SERIAL_API_GET_LR_NODES     = 0xDA
+)
+
+// SERIAL_API_SETUP sub-commands
+const (
+	SERIAL_API_SETUP_CMD_SUPPORTED           = 0x01
+	SERIAL_API_SETUP_CMD_TX_STATUS_REPORT    = 0x02
+	SERIAL_API_SETUP_CMD_NODEID_BASETYPE_SET = 0x80
+)
+
+// ZW_APPLICATION_UPDATE statuses
+const (
+	UPDATE_STATE_NODE_INFO_RECEIVED   = 0x84
+	UPDATE_STATE_NODE_INFO_REQ_FAILED = 0x81
+	UPDATE_STATE_NEW_ID_ASSIGNED      = 0x40
+	UPDATE_STATE_DELETE_DONE          = 0x20
 )
 
 // library type
@@ -16,3 +41,31 @@ const (
 	ZW_LIB_CONTROLLER_BRIDGE = 0x07
 	ZW_LIB_DUT               = 0x08
 )
+
+// node identifiers
+const (
+	// the maximal node identifier of the Z-Wave network
+	ZW_MAX_NODES = 232
+	// the node identifier used to send the frame to all nodes
+	NODE_BROADCAST = 0xFF
+	// the maximal number of nodes in ZW_SEND_DATA_MULTI request
+	MULTICAST_MAX_NODES = 64
+)
+
+// transmit options
+const (
+	TRANSMIT_OPTION_ACK        = 0x01
+	TRANSMIT_OPTION_LOW_POWER  = 0x02
+	TRANSMIT_OPTION_AUTO_ROUTE = 0x04
+	TRANSMIT_OPTION_NO_ROUTE   = 0x10
+	TRANSMIT_OPTION_EXPLORE    = 0x20
+)
+
+// transmit complete codes, reported by ZW_SEND_DATA and ZW_SEND_DATA_MULTI callbacks
+const (
+	TRANSMIT_COMPLETE_OK      = 0x00
+	TRANSMIT_COMPLETE_NO_ACK  = 0x01
+	TRANSMIT_COMPLETE_FAIL    = 0x02
+	TRANSMIT_ROUTING_NOT_IDLE = 0x03
+	TRANSMIT_COMPLETE_NOROUTE = 0x04
+)
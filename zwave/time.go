@@ -0,0 +1,67 @@
+package zwave
+
+import "time"
+
+// Time command class commands
+const (
+	TIME_GET           = 0x01
+	TIME_REPORT        = 0x02
+	DATE_GET           = 0x03
+	DATE_REPORT        = 0x04
+	TIME_OFFSET_SET    = 0x05
+	TIME_OFFSET_GET    = 0x06
+	TIME_OFFSET_REPORT = 0x07
+)
+
+// Clock command class commands
+const (
+	CLOCK_SET    = 0x04
+	CLOCK_GET    = 0x05
+	CLOCK_REPORT = 0x06
+)
+
+// TimeReport creates Time Report command with the local time of provided time value
+func TimeReport(t time.Time) []byte {
+	return []byte{COMMAND_CLASS_TIME, TIME_REPORT, byte(t.Hour()) & 0x1F, byte(t.Minute()), byte(t.Second())}
+}
+
+// DateReport creates Date Report command with the local date of provided time value
+func DateReport(t time.Time) []byte {
+	year := t.Year()
+	return []byte{COMMAND_CLASS_TIME, DATE_REPORT, byte(year >> 8), byte(year), byte(t.Month()), byte(t.Day())}
+}
+
+// TimeOffsetReport creates Time Offset Report command.
+// The offset is the standard time zone offset from UTC, dstOffset is the daylight saving time offset from the standard time,
+// dstStart and dstEnd are the local times of daylight saving time start and end (ignored if dstOffset is 0)
+func TimeOffsetReport(offset, dstOffset time.Duration, dstStart, dstEnd time.Time) []byte {
+	tzo := offsetMinutes(offset)
+	dst := offsetMinutes(dstOffset)
+	rv := []byte{COMMAND_CLASS_TIME, TIME_OFFSET_REPORT, byte(tzo[0]) | byte(tzo[1]/60), byte(tzo[1] % 60), byte(dst[0]) | byte(dst[1])&0x7F}
+	if dstOffset == 0 {
+		return append(rv, 0, 0, 0, 0, 0, 0)
+	}
+	return append(rv,
+		byte(dstStart.Month()), byte(dstStart.Day()), byte(dstStart.Hour()),
+		byte(dstEnd.Month()), byte(dstEnd.Day()), byte(dstEnd.Hour()),
+	)
+}
+
+// offsetMinutes converts the offset into the sign bit (0x80 if negative) and absolute number of minutes
+func offsetMinutes(offset time.Duration) [2]int {
+	minutes := int(offset / time.Minute)
+	if minutes < 0 {
+		return [2]int{0x80, -minutes}
+	}
+	return [2]int{0, minutes}
+}
+
+// ClockSet creates Clock Set command with the local weekday and time of provided time value
+func ClockSet(t time.Time) []byte {
+	// weekday: 1 - Monday, ..., 7 - Sunday
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return []byte{COMMAND_CLASS_CLOCK, CLOCK_SET, byte(weekday<<5) | byte(t.Hour())&0x1F, byte(t.Minute())}
+}
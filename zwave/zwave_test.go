@@ -1,6 +1,10 @@
 package zwave
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+	"time"
+)
 
 func TestZWaveUtils(t *testing.T) {
 
@@ -40,3 +44,248 @@ func TestZWaveUtils(t *testing.T) {
 		}
 	})
 }
+
+func TestSendData(t *testing.T) {
+	t.Run("ZW_SEND_DATA request", func(t *testing.T) {
+		frame := SendData(NodeIDType8Bit, 5, []byte{0x25, 0x01, 0xff}, TRANSMIT_OPTION_ACK, 0x12)
+		if res, pos := ValidateDataFrame(frame); res != FrameOK || pos != len(frame) {
+			t.Fatalf("The data frame is invalid. Validation result: %v, pos: %d", res, pos)
+		}
+		expected := []byte{ZW_SEND_DATA, 5, 3, 0x25, 0x01, 0xff, TRANSMIT_OPTION_ACK, 0x12}
+		if payload := UnpackRequest(frame); !bytes.Equal(payload, expected) {
+			t.Errorf("The request payload is %v, expected %v", payload, expected)
+		}
+	})
+
+	t.Run("ZW_SEND_DATA_MULTI request", func(t *testing.T) {
+		frame := SendDataMulti(NodeIDType8Bit, []uint16{2, 3, 4}, []byte{0x25, 0x01, 0x00}, 0, 0x34)
+		if res, pos := ValidateDataFrame(frame); res != FrameOK || pos != len(frame) {
+			t.Fatalf("The data frame is invalid. Validation result: %v, pos: %d", res, pos)
+		}
+		expected := []byte{ZW_SEND_DATA_MULTI, 3, 2, 3, 4, 3, 0x25, 0x01, 0x00, 0, 0x34}
+		if payload := UnpackRequest(frame); !bytes.Equal(payload, expected) {
+			t.Errorf("The request payload is %v, expected %v", payload, expected)
+		}
+	})
+
+	t.Run("ZW_SEND_DATA request with 16-bit node identifier", func(t *testing.T) {
+		frame := SendData(NodeIDType16Bit, 0x0123, []byte{0x25, 0x01, 0xff}, TRANSMIT_OPTION_ACK, 0x12)
+		expected := []byte{ZW_SEND_DATA, 0x01, 0x23, 3, 0x25, 0x01, 0xff, TRANSMIT_OPTION_ACK, 0x12}
+		if payload := UnpackRequest(frame); !bytes.Equal(payload, expected) {
+			t.Errorf("The request payload is %v, expected %v", payload, expected)
+		}
+	})
+
+	t.Run("ZW_SEND_DATA_MULTI request with 16-bit node identifiers", func(t *testing.T) {
+		frame := SendDataMulti(NodeIDType16Bit, []uint16{2, 3}, []byte{0x25, 0x01, 0x00}, 0, 0x34)
+		expected := []byte{ZW_SEND_DATA_MULTI, 2, 0, 2, 0, 3, 3, 0x25, 0x01, 0x00, 0, 0x34}
+		if payload := UnpackRequest(frame); !bytes.Equal(payload, expected) {
+			t.Errorf("The request payload is %v, expected %v", payload, expected)
+		}
+	})
+
+	t.Run("Too long ZW_SEND_DATA_MULTI request", func(t *testing.T) {
+		if ValidDataFrameLength(SendDataMulti(NodeIDType8Bit, make([]uint16, 200), make([]byte, 60), 0, 1)) {
+			t.Error("The data frame length must be invalid")
+		}
+	})
+
+	t.Run("Send data callback", func(t *testing.T) {
+		cb, ok := ParseSendDataCallback(UnpackRequest(DataRequest([]byte{ZW_SEND_DATA, 0x12, TRANSMIT_COMPLETE_NO_ACK})))
+		if !ok || cb.Command != ZW_SEND_DATA || cb.FuncID != 0x12 || cb.TxStatus != TRANSMIT_COMPLETE_NO_ACK {
+			t.Errorf("Unexpected callback parsing result: %v, %v", cb, ok)
+		}
+		if _, ok := ParseSendDataCallback([]byte{ZW_VERSION, 0x12, 0}); ok {
+			t.Error("ZW_VERSION must not be parsed as send data callback")
+		}
+	})
+}
+
+func TestNodeIDType(t *testing.T) {
+	for _, tc := range []struct {
+		t     NodeIDType
+		id    uint16
+		valid bool
+	}{
+		{NodeIDType8Bit, 0, false},
+		{NodeIDType8Bit, 1, true},
+		{NodeIDType8Bit, ZW_MAX_NODES, true},
+		{NodeIDType8Bit, ZW_MAX_NODES + 1, false},
+		{NodeIDType8Bit, LR_MIN_NODE_ID, false},
+		{NodeIDType16Bit, 1, true},
+		{NodeIDType16Bit, ZW_MAX_NODES + 1, false},
+		{NodeIDType16Bit, LR_MIN_NODE_ID, true},
+		{NodeIDType16Bit, LR_MAX_NODE_ID, true},
+		{NodeIDType16Bit, LR_MAX_NODE_ID + 1, false},
+	} {
+		if v := tc.t.IsValidNodeID(tc.id); v != tc.valid {
+			t.Errorf("%s node identifier %d validity is %v, expected %v", tc.t, tc.id, v, tc.valid)
+		}
+	}
+
+	for _, nt := range []NodeIDType{NodeIDType8Bit, NodeIDType16Bit} {
+		data := nt.AppendNodeID(nil, 200)
+		if len(data) != nt.Size() {
+			t.Errorf("%s node identifier size is %d, expected %d", nt, len(data), nt.Size())
+		}
+		if id, ok := nt.ReadNodeID(data); !ok || id != 200 {
+			t.Errorf("%s node identifier read as %d, %v", nt, id, ok)
+		}
+		if _, ok := nt.ReadNodeID(data[:len(data)-1]); ok {
+			t.Errorf("%s node identifier must not be read from truncated data", nt)
+		}
+	}
+}
+
+func TestControllerParsers(t *testing.T) {
+	t.Run("Capabilities", func(t *testing.T) {
+		functions := make([]byte, 32)
+		functions[(FUNC_ID_SERIAL_API_GET_LR_NODES-1)/8] |= 1 << ((FUNC_ID_SERIAL_API_GET_LR_NODES - 1) % 8)
+		functions[(ZW_SEND_DATA-1)/8] |= 1 << ((ZW_SEND_DATA - 1) % 8)
+		payload := append([]byte{FUNC_ID_SERIAL_API_GET_CAPABILITIES, 7, 18, 0x00, 0x86, 0x00, 0x01, 0x00, 0x5a}, functions...)
+		c, ok := ParseCapabilities(payload)
+		if !ok || c.AppVersion != 7 || c.AppRevision != 18 || c.ManufacturerID != 0x86 || c.ProductType != 1 || c.ProductID != 0x5a {
+			t.Fatalf("Unexpected capabilities parsing result: %v, %v", c, ok)
+		}
+		if !c.Supports(ZW_SEND_DATA) || c.Supports(ZW_SEND_DATA_MULTI) || !c.SupportsLongRange() {
+			t.Error("Unexpected supported functions")
+		}
+		if c, _ = ParseCapabilities(payload[:9+(FUNC_ID_SERIAL_API_GET_LR_NODES-1)/8]); c.SupportsLongRange() {
+			t.Error("Long Range must not be supported")
+		}
+	})
+
+	t.Run("Init data", func(t *testing.T) {
+		nodes, ok := ParseInitData([]byte{FUNC_ID_SERIAL_API_GET_INIT_DATA, 5, 0, 3, 0x05, 0x00, 0x80})
+		expected := []uint16{1, 3, 24}
+		if !ok || !equalNodes(nodes, expected) {
+			t.Errorf("The nodes are %v, expected %v", nodes, expected)
+		}
+	})
+
+	t.Run("Long Range nodes", func(t *testing.T) {
+		nodes, more, ok := ParseLongRangeNodes([]byte{FUNC_ID_SERIAL_API_GET_LR_NODES, 1, 0, 2, 0x03, 0x10})
+		expected := []uint16{256, 257, 268}
+		if !ok || !more || !equalNodes(nodes, expected) {
+			t.Errorf("The nodes are %v (%v, %v), expected %v", nodes, more, ok, expected)
+		}
+		nodes, more, ok = ParseLongRangeNodes([]byte{FUNC_ID_SERIAL_API_GET_LR_NODES, 0, 1, 1, 0x01})
+		expected = []uint16{1280}
+		if !ok || more || !equalNodes(nodes, expected) {
+			t.Errorf("The nodes are %v (%v, %v), expected %v", nodes, more, ok, expected)
+		}
+	})
+
+	t.Run("Application update", func(t *testing.T) {
+		u, ok := ParseApplicationUpdate(NodeIDType16Bit, []byte{ZW_APPLICATION_UPDATE, UPDATE_STATE_NODE_INFO_RECEIVED, 0x01, 0x02, 5, 4, 0x10, 0x01, 0x25, 0x86})
+		if !ok || u.NodeID != 258 || u.Basic != 4 || u.Generic != 0x10 || u.Specific != 1 || !bytes.Equal(u.CommandClasses, []byte{0x25, 0x86}) {
+			t.Errorf("Unexpected application update parsing result: %v, %v", u, ok)
+		}
+		if _, ok := ParseApplicationUpdate(NodeIDType8Bit, []byte{ZW_APPLICATION_UPDATE, UPDATE_STATE_NODE_INFO_RECEIVED, 5, 4, 0x10}); ok {
+			t.Error("Truncated application update must not be parsed")
+		}
+	})
+
+	t.Run("Application command", func(t *testing.T) {
+		c, ok := ParseApplicationCommand(NodeIDType8Bit, []byte{ZW_APPLICATION_COMMAND_HANDLER, 0, 7, 3, 0x80, 0x03, 0x64})
+		if !ok || c.NodeID != 7 || !bytes.Equal(c.Command, []byte{0x80, 0x03, 0x64}) {
+			t.Errorf("Unexpected application command parsing result: %v, %v", c, ok)
+		}
+	})
+}
+
+func TestRadioParsers(t *testing.T) {
+	t.Run("Send data callback with transmit report", func(t *testing.T) {
+		payload := []byte{
+			ZW_SEND_DATA, 0x12, TRANSMIT_COMPLETE_OK,
+			0x00, 0x05, // 50 ms
+			2, 0xC0, 0xB5, 0xBA, 0x7F, 0x7F, 0, 0, 0,
+			3, 7, 0, 0,
+			2, 1, 0, 0,
+		}
+		cb, ok := ParseSendDataCallback(payload)
+		if !ok || cb.Report == nil {
+			t.Fatalf("Unexpected callback parsing result: %v, %v", cb, ok)
+		}
+		r := cb.Report
+		if r.TxTime != 50*time.Millisecond || r.AckRSSI != -64 || !bytes.Equal(r.Repeaters, []byte{3, 7}) || len(r.RepeatersRSSI) != 2 || r.RepeatersRSSI[0] != -75 || r.RepeatersRSSI[1] != -70 || r.RouteSpeed != 2 || r.RouteTries != 1 {
+			t.Errorf("Unexpected transmit report: %+v", r)
+		}
+		if cb, ok := ParseSendDataCallback(payload[:3]); !ok || cb.Report != nil {
+			t.Errorf("Unexpected callback without transmit report parsing result: %v, %v", cb, ok)
+		}
+	})
+
+	t.Run("Send data request", func(t *testing.T) {
+		nodeID, funcID, ok := ParseSendData(NodeIDType16Bit, UnpackRequest(SendData(NodeIDType16Bit, 300, []byte{0x25, 0x02}, TRANSMIT_OPTION_ACK, 0x21)))
+		if !ok || nodeID != 300 || funcID != 0x21 {
+			t.Errorf("Unexpected send data parsing result: %d, %d, %v", nodeID, funcID, ok)
+		}
+	})
+
+	t.Run("Background RSSI", func(t *testing.T) {
+		rssi, ok := ParseBackgroundRSSI([]byte{FUNC_ID_ZW_GET_BACKGROUND_RSSI, 0xA1, 0x9F, RSSI_NOT_AVAILABLE})
+		if !ok || len(rssi) != 3 || rssi[0] != -95 || rssi[1] != -97 || ValidRSSI(rssi[2]) {
+			t.Errorf("Unexpected background RSSI parsing result: %v, %v", rssi, ok)
+		}
+	})
+}
+
+func TestBatteryReport(t *testing.T) {
+	for _, tc := range []struct {
+		command []byte
+		level   byte
+		ok      bool
+	}{
+		{[]byte{COMMAND_CLASS_BATTERY, BATTERY_REPORT, 57}, 57, true},
+		{[]byte{COMMAND_CLASS_BATTERY, BATTERY_REPORT, BATTERY_LEVEL_LOW_WARNING}, BATTERY_LEVEL_LOW_WARNING, true},
+		{[]byte{COMMAND_CLASS_BATTERY, BATTERY_REPORT, 101}, 0, false},
+		{[]byte{COMMAND_CLASS_BATTERY, BATTERY_GET}, 0, false},
+		{[]byte{0x25, BATTERY_REPORT, 50}, 0, false},
+	} {
+		if level, ok := ParseBatteryReport(tc.command); level != tc.level || ok != tc.ok {
+			t.Errorf("Battery report %v parsed as %d, %v, expected %d, %v", tc.command, level, ok, tc.level, tc.ok)
+		}
+	}
+}
+
+func TestTimeCommands(t *testing.T) {
+	loc := time.FixedZone("Test", -5*3600)
+	tm := time.Date(2026, 3, 8, 14, 7, 9, 0, loc) // Sunday
+
+	for _, tc := range []struct {
+		name     string
+		command  []byte
+		expected []byte
+	}{
+		{"Time Report", TimeReport(tm), []byte{COMMAND_CLASS_TIME, TIME_REPORT, 14, 7, 9}},
+		{"Date Report", DateReport(tm), []byte{COMMAND_CLASS_TIME, DATE_REPORT, 0x07, 0xEA, 3, 8}},
+		{"Clock Set", ClockSet(tm), []byte{COMMAND_CLASS_CLOCK, CLOCK_SET, 7<<5 | 14, 7}},
+		{
+			"Time Offset Report",
+			TimeOffsetReport(-5*time.Hour-30*time.Minute, time.Hour, time.Date(2026, 3, 8, 3, 0, 0, 0, loc), time.Date(2026, 11, 1, 1, 0, 0, 0, loc)),
+			[]byte{COMMAND_CLASS_TIME, TIME_OFFSET_REPORT, 0x85, 30, 60, 3, 8, 3, 11, 1, 1},
+		},
+		{
+			"Time Offset Report without DST",
+			TimeOffsetReport(2*time.Hour, 0, time.Time{}, time.Time{}),
+			[]byte{COMMAND_CLASS_TIME, TIME_OFFSET_REPORT, 2, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+	} {
+		if !bytes.Equal(tc.command, tc.expected) {
+			t.Errorf("%s is %v, expected %v", tc.name, tc.command, tc.expected)
+		}
+	}
+}
+
+func equalNodes(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}